@@ -0,0 +1,88 @@
+package kivik
+
+import (
+	"context"
+
+	"github.com/flimzy/kivik/driver"
+)
+
+// ActiveTask describes a long-running task, such as compaction, view indexing
+// or replication. Fields which do not apply to a task's type are left empty.
+type ActiveTask struct {
+	// Node is the cluster node running the task.
+	Node string `json:"node,omitempty"`
+	// PID is the identifier of the process running the task.
+	PID string `json:"pid,omitempty"`
+	// Type is the task type, such as "database_compaction", "view_compaction",
+	// "indexer" or "replication".
+	Type string `json:"type"`
+	// Database is the database the task operates on.
+	Database string `json:"database,omitempty"`
+	// DesignDocument is the design document, for indexing and view compaction.
+	DesignDocument string `json:"design_document,omitempty"`
+	// DocID is the ID of the replication document, for replications started
+	// from the _replicator database.
+	DocID string `json:"doc_id,omitempty"`
+	// Phase is the current phase of the task, for view compaction.
+	Phase string `json:"phase,omitempty"`
+	// Progress is the percentage of the task completed.
+	Progress int `json:"progress"`
+	// ChangesDone is the number of changes processed so far.
+	ChangesDone int64 `json:"changes_done"`
+	// TotalChanges is the total number of changes to process.
+	TotalChanges int64 `json:"total_changes"`
+	// StartedOn is the Unix timestamp at which the task started.
+	StartedOn int64 `json:"started_on"`
+	// UpdatedOn is the Unix timestamp at which the task last reported
+	// progress.
+	UpdatedOn int64 `json:"updated_on"`
+
+	// ReplicationID is the ID of the replication.
+	ReplicationID string `json:"replication_id,omitempty"`
+	// Source is the replication source.
+	Source string `json:"source,omitempty"`
+	// Target is the replication target.
+	Target string `json:"target,omitempty"`
+	// Continuous is true for continuous replications.
+	Continuous bool `json:"continuous,omitempty"`
+	// DocsRead is the number of documents read from the source.
+	DocsRead int64 `json:"docs_read,omitempty"`
+	// DocsWritten is the number of documents written to the target.
+	DocsWritten int64 `json:"docs_written,omitempty"`
+	// DocWriteFailures is the number of documents which failed to be written
+	// to the target.
+	DocWriteFailures int64 `json:"doc_write_failures,omitempty"`
+	// MissingRevisionsFound is the number of revisions found to be missing
+	// from the target.
+	MissingRevisionsFound int64 `json:"missing_revisions_found,omitempty"`
+	// RevisionsChecked is the number of revisions compared against the target.
+	RevisionsChecked int64 `json:"revisions_checked,omitempty"`
+	// SourceSeq is the latest update sequence of the source.
+	SourceSeq string `json:"source_seq,omitempty"`
+	// CheckpointedSourceSeq is the last checkpointed source sequence.
+	CheckpointedSourceSeq string `json:"checkpointed_source_seq,omitempty"`
+}
+
+// ActiveTasks calls ActiveTasksContext with a background context.
+func (c *Client) ActiveTasks() ([]ActiveTask, error) {
+	return c.ActiveTasksContext(context.Background())
+}
+
+// ActiveTasksContext returns the tasks, such as compaction, view indexing and
+// replication, currently running on the server. Not all drivers support this
+// method.
+func (c *Client) ActiveTasksContext(ctx context.Context) ([]ActiveTask, error) {
+	tasker, ok := c.driverClient.(driver.ActiveTasker)
+	if !ok {
+		return nil, ErrNotImplemented
+	}
+	tasks, err := tasker.ActiveTasksContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]ActiveTask, len(tasks))
+	for i, task := range tasks {
+		result[i] = ActiveTask(task)
+	}
+	return result, nil
+}
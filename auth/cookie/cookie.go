@@ -6,19 +6,30 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/url"
-	"strconv"
 	"strings"
-	"time"
-
-	"golang.org/x/net/context"
 
 	"github.com/flimzy/kivik"
 	"github.com/flimzy/kivik/auth"
 	"github.com/flimzy/kivik/authdb"
+	"github.com/flimzy/kivik/config"
 	"github.com/flimzy/kivik/errors"
 	"github.com/flimzy/kivik/serve"
 )
 
+func init() {
+	config.Register(
+		&config.Key{Section: "couch_httpd_auth", Name: "secure", Type: config.TypeBool,
+			Description: "Set the Secure attribute of session cookies. Defaults to true for HTTPS requests"},
+		&config.Key{Section: "couch_httpd_auth", Name: "http_only", Type: config.TypeBool,
+			Description: "Set the HttpOnly attribute of session cookies. Defaults to true"},
+		&config.Key{Section: "couch_httpd_auth", Name: "cookie_domain",
+			Description: "Domain attribute of session cookies"},
+		&config.Key{Section: "couch_httpd_auth", Name: "same_site",
+			Values:      []string{"", "strict", "lax", "none"},
+			Description: "SameSite attribute of session cookies"},
+	)
+}
+
 // Auth provides CouchDB Cookie authentication.
 type Auth struct{}
 
@@ -62,6 +73,11 @@ func (a *Auth) validateCookie(w http.ResponseWriter, r *http.Request) (*authdb.U
 	if err != nil || !valid {
 		return nil, nil
 	}
+	if refresh, _ := s.CookieNeedsRefresh(r.Context(), cookie.Value); refresh {
+		if err := setSessionCookie(w, r, user); err != nil {
+			serve.GetLogger(r).Error("Failed to refresh session cookie for %s: %s", user.Name, err)
+		}
+	}
 	return user, nil
 }
 
@@ -81,28 +97,16 @@ func postSession(w http.ResponseWriter, r *http.Request) error {
 	if err != nil {
 		return err
 	}
-	timeout, err := getSessionTimeout(r.Context(), s)
-	if err != nil {
-		return err
-	}
 	next, err := redirectURL(r)
 	if err != nil {
 		return err
 	}
 
 	// Success, so create a cookie
-	token, err := s.CreateAuthToken(r.Context(), *authData.Name, user.Salt, time.Now().Unix())
-	if err != nil {
+	if err := setSessionCookie(w, r, user); err != nil {
 		return err
 	}
 	w.Header().Set("Cache-Control", "must-revalidate")
-	http.SetCookie(w, &http.Cookie{
-		Name:     kivik.SessionCookieName,
-		Value:    token,
-		Path:     "/",
-		MaxAge:   timeout,
-		HttpOnly: true,
-	})
 	w.Header().Add("Content-Type", kivik.TypeJSON)
 	if next != "" {
 		w.Header().Add("Location", next)
@@ -135,12 +139,11 @@ func redirectURL(r *http.Request) (string, error) {
 }
 
 func deleteSession(w http.ResponseWriter, r *http.Request) error {
-	http.SetCookie(w, &http.Cookie{
-		Name:     kivik.SessionCookieName,
-		Value:    "",
-		Path:     "/",
-		MaxAge:   -1,
-		HttpOnly: true,
+	writeCookie(w, r, &http.Cookie{
+		Name:   kivik.SessionCookieName,
+		Value:  "",
+		Path:   "/",
+		MaxAge: -1,
 	})
 	w.Header().Add("Content-Type", kivik.TypeJSON)
 	w.Header().Set("Cache-Control", "must-revalidate")
@@ -149,13 +152,51 @@ func deleteSession(w http.ResponseWriter, r *http.Request) error {
 	})
 }
 
-func getSessionTimeout(ctx context.Context, s *serve.Service) (int, error) {
-	timeout, err := s.Config().GetContext(ctx, "couch_httpd_auth", "timeout")
-	if errors.StatusCode(err) == kivik.StatusNotFound {
-		return serve.DefaultSessionTimeout, nil
+// setSessionCookie issues a new session cookie for the user.
+func setSessionCookie(w http.ResponseWriter, r *http.Request, user *authdb.UserContext) error {
+	s := serve.GetService(r)
+	timeout, err := s.SessionTimeout(r.Context())
+	if err != nil {
+		return err
 	}
+	token, err := s.CreateAuthToken(r.Context(), user.Name, user.Salt, serve.Now().Unix())
 	if err != nil {
-		return 0, err
+		return err
 	}
-	return strconv.Atoi(timeout)
+	writeCookie(w, r, &http.Cookie{
+		Name:   kivik.SessionCookieName,
+		Value:  token,
+		Path:   "/",
+		MaxAge: timeout,
+	})
+	return nil
+}
+
+// writeCookie sets the Secure, HttpOnly and SameSite attributes of the cookie,
+// according to the couch_httpd_auth config section, then adds it to the
+// response. The Secure flag defaults to true for TLS connections, and HttpOnly
+// defaults to true.
+func writeCookie(w http.ResponseWriter, r *http.Request, cookie *http.Cookie) {
+	conf := serve.GetService(r).Config()
+	cookie.Secure = r.TLS != nil
+	if conf.IsSet("couch_httpd_auth", "secure") {
+		cookie.Secure = conf.GetBool("couch_httpd_auth", "secure")
+	}
+	cookie.HttpOnly = true
+	if conf.IsSet("couch_httpd_auth", "http_only") {
+		cookie.HttpOnly = conf.GetBool("couch_httpd_auth", "http_only")
+	}
+	cookie.Domain = conf.GetString("couch_httpd_auth", "cookie_domain")
+	value := cookie.String()
+	// The SameSite attribute is added manually, as http.Cookie only supports it
+	// as of Go 1.11.
+	switch strings.ToLower(conf.GetString("couch_httpd_auth", "same_site")) {
+	case "strict":
+		value += "; SameSite=Strict"
+	case "lax":
+		value += "; SameSite=Lax"
+	case "none":
+		value += "; SameSite=None"
+	}
+	w.Header().Add("Set-Cookie", value)
 }
@@ -1,8 +1,18 @@
 package cookie
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+
+	"github.com/flimzy/kivik"
+	"github.com/flimzy/kivik/auth"
+	"github.com/flimzy/kivik/authdb/confadmin"
+	"github.com/flimzy/kivik/serve"
 )
 
 type redirTest struct {
@@ -26,11 +36,15 @@ func TestRedirectURL(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.Name, func(t *testing.T) {
-			url := "/"
+			r, err := http.NewRequest("GET", "/", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
 			if test.Input != "-" {
-				url += "?next=" + test.Input
+				// Set the raw query directly, as NewRequest rejects control
+				// characters in the URL.
+				r.URL.RawQuery = "next=" + test.Input
 			}
-			r, _ := http.NewRequest("GET", url, nil)
 			result, err := redirectURL(r)
 			var errMsg string
 			if err != nil {
@@ -45,3 +59,70 @@ func TestRedirectURL(t *testing.T) {
 		})
 	}
 }
+
+type cookieAttrTest struct {
+	Name     string
+	Conf     map[string]string
+	TLS      bool
+	Expected string
+}
+
+func TestWriteCookie(t *testing.T) {
+	tests := []cookieAttrTest{
+		{Name: "Defaults", Expected: "AuthSession=foo; Path=/; Max-Age=600; HttpOnly"},
+		{Name: "TLS", TLS: true, Expected: "AuthSession=foo; Path=/; Max-Age=600; HttpOnly; Secure"},
+		{Name: "SecureOverride", TLS: true, Conf: map[string]string{"secure": "false"},
+			Expected: "AuthSession=foo; Path=/; Max-Age=600; HttpOnly"},
+		{Name: "NotHTTPOnly", Conf: map[string]string{"http_only": "false", "secure": "true"},
+			Expected: "AuthSession=foo; Path=/; Max-Age=600; Secure"},
+		{Name: "SameSite", Conf: map[string]string{"same_site": "Strict"},
+			Expected: "AuthSession=foo; Path=/; Max-Age=600; HttpOnly; SameSite=Strict"},
+	}
+	for _, test := range tests {
+		func(test cookieAttrTest) {
+			t.Run(test.Name, func(t *testing.T) {
+				s := &serve.Service{}
+				for key, value := range test.Conf {
+					_ = s.Config().Set("couch_httpd_auth", key, value)
+				}
+				r := httptest.NewRequest("GET", "/", nil)
+				r = r.WithContext(context.WithValue(r.Context(), serve.ServiceContextKey, s))
+				if test.TLS {
+					r.TLS = &tls.ConnectionState{}
+				}
+				w := httptest.NewRecorder()
+				writeCookie(w, r, &http.Cookie{Name: kivik.SessionCookieName, Value: "foo", Path: "/", MaxAge: 600})
+				if result := w.Header().Get("Set-Cookie"); result != test.Expected {
+					t.Errorf("Unexpected cookie.\nExpected: %s\n  Actual: %s\n", test.Expected, result)
+				}
+			})
+		}(test)
+	}
+}
+
+func TestPostSessionMetrics(t *testing.T) {
+	s := &serve.Service{AuthHandlers: []auth.Handler{&Auth{}}}
+	s.UserStore = confadmin.New(s.Config())
+	_ = s.Config().Set("admins", "test", "-pbkdf2-792221164f257de22ad72a8e94760388233e5714,7897f3451f59da741c87ec5f10fe7abe,10")
+	handler, err := s.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, password := range []string{"abc123", "wrong", "wrong"} {
+		req := httptest.NewRequest("POST", "/_session", strings.NewReader(`{"name":"test","password":"`+password+`"}`))
+		req.Header.Set("Content-Type", kivik.TypeJSON)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	buf := &bytes.Buffer{}
+	if err := s.Metrics().WritePrometheus(buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		`couchdb_auth_successes_total{method="cookie"} 1`,
+		`couchdb_auth_failures_total{method="cookie"} 2`,
+	} {
+		if !strings.Contains(buf.String(), expected+"\n") {
+			t.Errorf("%s missing from metrics:\n%s", expected, buf.String())
+		}
+	}
+}
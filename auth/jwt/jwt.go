@@ -0,0 +1,325 @@
+// Package jwt provides JSON Web Token bearer authentication, compatible with
+// CouchDB's JWT authentication handler.
+//
+// Tokens are passed in the Authorization header:
+//
+//	Authorization: Bearer <token>
+//
+// HS256, RS256 and ES256 signatures are supported. Keys are read from the
+// [jwt_keys] configuration section, where each key is named by its type and
+// key ID (the "kid" header of the token), or "_default" for tokens without a
+// key ID:
+//
+//	[jwt_keys]
+//	hmac:_default = <base64-encoded secret>
+//	rsa:foo = -----BEGIN PUBLIC KEY-----\nMIIBIjAN...\n-----END PUBLIC KEY-----\n
+//	ec:bar = -----BEGIN PUBLIC KEY-----\nMFkwEwYH...\n-----END PUBLIC KEY-----\n
+//
+// The user name is read from the "sub" claim, and the roles from the
+// "_couchdb.roles" claim. These, and the claims which must be present, may be
+// changed in the [jwt_auth] section:
+//
+//	[jwt_auth]
+//	required_claims = exp, iat
+//	name_claim = sub
+//	roles_claim_name = _couchdb.roles
+//	; or, to read a nested claim:
+//	roles_claim_path = realm_access.roles
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/flimzy/kivik/auth"
+	"github.com/flimzy/kivik/authdb"
+	"github.com/flimzy/kivik/config"
+	"github.com/flimzy/kivik/errors"
+	"github.com/flimzy/kivik/serve"
+)
+
+// Defaults for the [jwt_auth] configuration section.
+const (
+	DefaultNameClaim  = "sub"
+	DefaultRolesClaim = "_couchdb.roles"
+)
+
+var now = time.Now
+
+func init() {
+	config.Register(
+		&config.Key{Section: "jwt_auth", Name: "required_claims",
+			Description: "Comma-separated list of claims which tokens must contain"},
+		&config.Key{Section: "jwt_auth", Name: "name_claim", Default: DefaultNameClaim,
+			Description: "Claim containing the user name"},
+		&config.Key{Section: "jwt_auth", Name: "roles_claim_name", Default: DefaultRolesClaim,
+			Description: "Claim containing the user's roles"},
+		&config.Key{Section: "jwt_auth", Name: "roles_claim_path",
+			Description: "Dot-separated path to a nested claim containing the user's roles"},
+	)
+}
+
+// Auth provides JWT bearer authentication.
+type Auth struct{}
+
+var _ auth.Handler = &Auth{}
+
+// MethodName returns "jwt"
+func (a *Auth) MethodName() string {
+	return "jwt" // For compatibility with the name used by CouchDB
+}
+
+// Authenticate validates a bearer token. Requests without a bearer token fall
+// through to the next handler, but an invalid token results in a 401 error.
+func (a *Auth) Authenticate(w http.ResponseWriter, r *http.Request) (*authdb.UserContext, error) {
+	authHeader := r.Header.Get("Authorization")
+	if len(authHeader) < 7 || !strings.EqualFold(authHeader[:7], "bearer ") {
+		return nil, nil
+	}
+	s := serve.GetService(r)
+	user, err := Validate(s.Config(), strings.TrimSpace(authHeader[7:]))
+	if err != nil {
+		serve.GetLogger(r).Debug("JWT authentication failed: %s", err)
+		return nil, errors.Status(http.StatusUnauthorized, err.Error())
+	}
+	return user, nil
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+func decodeSegment(seg string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(seg, "="))
+}
+
+// Validate verifies the token's signature and claims against the
+// configuration, and returns the resulting user context.
+func Validate(conf *config.Config, token string) (*authdb.UserContext, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+	rawHeader, err := decodeSegment(parts[0])
+	if err != nil {
+		return nil, errors.New("malformed token header")
+	}
+	var hdr header
+	if err := json.Unmarshal(rawHeader, &hdr); err != nil {
+		return nil, errors.New("malformed token header")
+	}
+	sig, err := decodeSegment(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed token signature")
+	}
+	if err := verify(conf, hdr, []byte(parts[0]+"."+parts[1]), sig); err != nil {
+		return nil, err
+	}
+	rawClaims, err := decodeSegment(parts[1])
+	if err != nil {
+		return nil, errors.New("malformed token payload")
+	}
+	claims := map[string]interface{}{}
+	dec := json.NewDecoder(strings.NewReader(string(rawClaims)))
+	dec.UseNumber()
+	if err := dec.Decode(&claims); err != nil {
+		return nil, errors.New("malformed token payload")
+	}
+	if err := checkClaims(conf, claims); err != nil {
+		return nil, err
+	}
+	return userContext(conf, claims)
+}
+
+// keyName returns the [jwt_keys] key for the token.
+func keyName(kind, kid string) string {
+	if kid == "" {
+		kid = "_default"
+	}
+	return kind + ":" + kid
+}
+
+func getKey(conf *config.Config, kind, kid string) (string, error) {
+	key := conf.GetString("jwt_keys", keyName(kind, kid))
+	if key == "" {
+		return "", errors.Errorf("no key configured for %s", keyName(kind, kid))
+	}
+	// Newlines in PEM-encoded keys are escaped in the INI file.
+	return strings.Replace(key, `\n`, "\n", -1), nil
+}
+
+func parsePublicKey(key string) (interface{}, error) {
+	block, _ := pem.Decode([]byte(key))
+	if block == nil {
+		return nil, errors.New("invalid PEM-encoded public key")
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+func verify(conf *config.Config, hdr header, signed, sig []byte) error {
+	hash := sha256.Sum256(signed)
+	switch hdr.Alg {
+	case "HS256":
+		key, err := getKey(conf, "hmac", hdr.Kid)
+		if err != nil {
+			return err
+		}
+		secret, err := base64.StdEncoding.DecodeString(key)
+		if err != nil {
+			return errors.Wrap(err, "invalid HMAC key")
+		}
+		mac := hmac.New(sha256.New, secret)
+		_, _ = mac.Write(signed)
+		if !hmac.Equal(mac.Sum(nil), sig) {
+			return errors.New("signature verification failed")
+		}
+		return nil
+	case "RS256":
+		key, err := getKey(conf, "rsa", hdr.Kid)
+		if err != nil {
+			return err
+		}
+		pub, err := parsePublicKey(key)
+		if err != nil {
+			return err
+		}
+		rsaKey, ok := pub.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("configured key is not an RSA public key")
+		}
+		if err := rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, hash[:], sig); err != nil {
+			return errors.New("signature verification failed")
+		}
+		return nil
+	case "ES256":
+		key, err := getKey(conf, "ec", hdr.Kid)
+		if err != nil {
+			return err
+		}
+		pub, err := parsePublicKey(key)
+		if err != nil {
+			return err
+		}
+		ecKey, ok := pub.(*ecdsa.PublicKey)
+		if !ok {
+			return errors.New("configured key is not an EC public key")
+		}
+		if len(sig) != 64 {
+			return errors.New("signature verification failed")
+		}
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(ecKey, hash[:], r, s) {
+			return errors.New("signature verification failed")
+		}
+		return nil
+	}
+	return errors.Errorf("unsupported algorithm '%s'", hdr.Alg)
+}
+
+func numericClaim(claims map[string]interface{}, name string) (int64, bool, error) {
+	value, ok := claims[name]
+	if !ok {
+		return 0, false, nil
+	}
+	num, ok := value.(json.Number)
+	if !ok {
+		return 0, true, errors.Errorf("claim '%s' must be numeric", name)
+	}
+	f, err := num.Float64()
+	if err != nil {
+		return 0, true, errors.Errorf("claim '%s' must be numeric", name)
+	}
+	return int64(f), true, nil
+}
+
+func checkClaims(conf *config.Config, claims map[string]interface{}) error {
+	for _, name := range strings.Split(conf.GetString("jwt_auth", "required_claims"), ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		if _, ok := claims[name]; !ok {
+			return errors.Errorf("missing required claim '%s'", name)
+		}
+	}
+	t := now().Unix()
+	exp, ok, err := numericClaim(claims, "exp")
+	if err != nil {
+		return err
+	}
+	if ok && t >= exp {
+		return errors.New("token has expired")
+	}
+	nbf, ok, err := numericClaim(claims, "nbf")
+	if err != nil {
+		return err
+	}
+	if ok && t < nbf {
+		return errors.New("token is not yet valid")
+	}
+	return nil
+}
+
+func userContext(conf *config.Config, claims map[string]interface{}) (*authdb.UserContext, error) {
+	nameClaim := conf.GetString("jwt_auth", "name_claim")
+	if nameClaim == "" {
+		nameClaim = DefaultNameClaim
+	}
+	name, _ := claims[nameClaim].(string)
+	if name == "" {
+		return nil, errors.Errorf("missing '%s' claim", nameClaim)
+	}
+	var rawRoles interface{}
+	if path := conf.GetString("jwt_auth", "roles_claim_path"); path != "" {
+		rawRoles = lookupPath(claims, strings.Split(path, "."))
+	} else {
+		rolesClaim := conf.GetString("jwt_auth", "roles_claim_name")
+		if rolesClaim == "" {
+			rolesClaim = DefaultRolesClaim
+		}
+		rawRoles = claims[rolesClaim]
+	}
+	var roles []string
+	if rawRoles != nil {
+		list, ok := rawRoles.([]interface{})
+		if !ok {
+			return nil, errors.New("roles claim must be a list of strings")
+		}
+		for _, r := range list {
+			role, ok := r.(string)
+			if !ok {
+				return nil, errors.New("roles claim must be a list of strings")
+			}
+			roles = append(roles, role)
+		}
+	}
+	return &authdb.UserContext{
+		Name:  name,
+		Roles: roles,
+	}, nil
+}
+
+// lookupPath returns the value of a nested claim.
+func lookupPath(claims map[string]interface{}, path []string) interface{} {
+	var value interface{} = claims
+	for _, key := range path {
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = obj[key]
+	}
+	return value
+}
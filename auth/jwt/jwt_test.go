@@ -0,0 +1,163 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/kivik/authdb"
+	"github.com/flimzy/kivik/config"
+	"github.com/flimzy/kivik/serve/config/memconf"
+)
+
+type signer func(signed []byte) []byte
+
+func makeToken(t *testing.T, hdr, claims map[string]interface{}, sign signer) string {
+	h, err := json.Marshal(hdr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sign([]byte(signed)))
+}
+
+func pemKey(t *testing.T, pub interface{}) string {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	// Stored escaped, as it would be in an INI file
+	return strings.Replace(string(p), "\n", `\n`, -1)
+}
+
+type jwtTest struct {
+	Name     string
+	Conf     map[string]map[string]string
+	Token    string
+	Expected *authdb.UserContext
+	Err      string
+}
+
+func TestValidate(t *testing.T) {
+	now = func() time.Time { return time.Unix(1500000000, 0) }
+	defer func() { now = time.Now }()
+
+	secret := []byte("top secret")
+	hs256 := func(signed []byte) []byte {
+		mac := hmac.New(sha256.New, secret)
+		_, _ = mac.Write(signed)
+		return mac.Sum(nil)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs256 := func(signed []byte) []byte {
+		hash := sha256.Sum256(signed)
+		sig, e := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, hash[:])
+		if e != nil {
+			t.Fatal(e)
+		}
+		return sig
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	es256 := func(signed []byte) []byte {
+		hash := sha256.Sum256(signed)
+		r, s, e := ecdsa.Sign(rand.Reader, ecKey, hash[:])
+		if e != nil {
+			t.Fatal(e)
+		}
+		sig := make([]byte, 64)
+		rb, sb := r.Bytes(), s.Bytes()
+		copy(sig[32-len(rb):], rb)
+		copy(sig[64-len(sb):], sb)
+		return sig
+	}
+	keys := map[string]string{
+		"hmac:_default": base64.StdEncoding.EncodeToString(secret),
+		"rsa:rsakey":    pemKey(t, &rsaKey.PublicKey),
+		"ec:eckey":      pemKey(t, &ecKey.PublicKey),
+	}
+	hsHeader := map[string]interface{}{"alg": "HS256", "typ": "JWT"}
+	bob := map[string]interface{}{"sub": "bob", "_couchdb.roles": []string{"users"}}
+
+	tests := []jwtTest{
+		{Name: "HS256", Token: makeToken(t, hsHeader, bob, hs256),
+			Expected: &authdb.UserContext{Name: "bob", Roles: []string{"users"}}},
+		{Name: "RS256", Token: makeToken(t, map[string]interface{}{"alg": "RS256", "kid": "rsakey"}, bob, rs256),
+			Expected: &authdb.UserContext{Name: "bob", Roles: []string{"users"}}},
+		{Name: "ES256", Token: makeToken(t, map[string]interface{}{"alg": "ES256", "kid": "eckey"}, bob, es256),
+			Expected: &authdb.UserContext{Name: "bob", Roles: []string{"users"}}},
+		{Name: "BadSignature", Token: makeToken(t, hsHeader, bob, func(_ []byte) []byte { return []byte("xxx") }),
+			Err: "signature verification failed"},
+		{Name: "UnknownKey", Token: makeToken(t, map[string]interface{}{"alg": "RS256", "kid": "other"}, bob, rs256),
+			Err: "no key configured for rsa:other"},
+		{Name: "AlgNone", Token: makeToken(t, map[string]interface{}{"alg": "none"}, bob, func(_ []byte) []byte { return nil }),
+			Err: "unsupported algorithm 'none'"},
+		{Name: "Malformed", Token: "foo.bar", Err: "malformed token"},
+		{Name: "Expired", Token: makeToken(t, hsHeader, map[string]interface{}{"sub": "bob", "exp": 1400000000}, hs256),
+			Err: "token has expired"},
+		{Name: "NotYetValid", Token: makeToken(t, hsHeader, map[string]interface{}{"sub": "bob", "nbf": 1600000000}, hs256),
+			Err: "token is not yet valid"},
+		{Name: "Valid exp", Token: makeToken(t, hsHeader, map[string]interface{}{"sub": "bob", "exp": 1600000000}, hs256),
+			Expected: &authdb.UserContext{Name: "bob"}},
+		{Name: "MissingRequired", Token: makeToken(t, hsHeader, bob, hs256),
+			Conf: map[string]map[string]string{"jwt_auth": {"required_claims": "exp, iat"}},
+			Err:  "missing required claim 'exp'"},
+		{Name: "NoSubject", Token: makeToken(t, hsHeader, map[string]interface{}{"name": "bob"}, hs256),
+			Err: "missing 'sub' claim"},
+		{Name: "CustomClaims", Token: makeToken(t, hsHeader, map[string]interface{}{
+			"preferred_username": "bob",
+			"realm_access":       map[string]interface{}{"roles": []string{"admins"}},
+		}, hs256),
+			Conf:     map[string]map[string]string{"jwt_auth": {"name_claim": "preferred_username", "roles_claim_path": "realm_access.roles"}},
+			Expected: &authdb.UserContext{Name: "bob", Roles: []string{"admins"}}},
+	}
+	for _, test := range tests {
+		func(test jwtTest) {
+			t.Run(test.Name, func(t *testing.T) {
+				mc := memconf.New()
+				for key, value := range keys {
+					_ = mc.SetContext(context.Background(), "jwt_keys", key, value)
+				}
+				for sec, values := range test.Conf {
+					for key, value := range values {
+						_ = mc.SetContext(context.Background(), sec, key, value)
+					}
+				}
+				result, err := Validate(config.New(mc), test.Token)
+				var errMsg string
+				if err != nil {
+					errMsg = err.Error()
+				}
+				if errMsg != test.Err {
+					t.Errorf("Unexpected error.\nExpected: %s\n  Actual: %s\n", test.Err, errMsg)
+				}
+				if d := diff.AsJSON(test.Expected, result); d != "" {
+					t.Errorf("Unexpected result:\n%s\n", d)
+				}
+			})
+		}(test)
+	}
+}
@@ -0,0 +1,295 @@
+// Package lockout provides brute-force protection for auth handlers.
+//
+// A Lockout tracks failed authentication attempts for each combination of
+// username and client IP address. When the number of failures within the
+// window reaches the threshold, further attempts are rejected with
+// 429 Too Many Requests until the lockout expires. Each consecutive lockout
+// doubles in length, up to a maximum.
+//
+// The policy is read from the [chttpd_auth_lockout] configuration section:
+//
+//	mode          off, warn (log only) or enforce (default)
+//	threshold     failures which trigger a lockout (default 5)
+//	max_lifetime  window and initial lockout length, in ms (default 300000)
+//	max_backoff   maximum lockout length, in ms (default 3600000)
+//	max_objects   maximum number of tracked user/IP pairs (default 10000)
+package lockout
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flimzy/kivik"
+	"github.com/flimzy/kivik/auth"
+	"github.com/flimzy/kivik/authdb"
+	"github.com/flimzy/kivik/config"
+	"github.com/flimzy/kivik/errors"
+	"github.com/flimzy/kivik/serve"
+)
+
+// Lockout modes.
+const (
+	ModeOff     = "off"
+	ModeWarn    = "warn"
+	ModeEnforce = "enforce"
+)
+
+// Configuration defaults.
+const (
+	DefaultThreshold   = 5
+	DefaultMaxLifetime = 5 * time.Minute
+	DefaultMaxBackoff  = time.Hour
+	DefaultMaxObjects  = 10000
+)
+
+const confSection = "chttpd_auth_lockout"
+
+var now = time.Now
+
+func init() {
+	config.Register(
+		&config.Key{Section: confSection, Name: "mode", Default: ModeEnforce,
+			Values:      []string{ModeOff, ModeWarn, ModeEnforce},
+			Description: "Lockout mode: off, warn (log only) or enforce"},
+		&config.Key{Section: confSection, Name: "threshold", Type: config.TypeInt, Default: strconv.Itoa(DefaultThreshold), Min: 1, Max: 1 << 31,
+			Description: "Failed attempts which trigger a lockout"},
+		&config.Key{Section: confSection, Name: "max_lifetime", Type: config.TypeInt, Default: strconv.Itoa(int(DefaultMaxLifetime / time.Millisecond)), Min: 1, Max: 1 << 53,
+			Description: "Window for counting failures, and initial lockout length, in milliseconds"},
+		&config.Key{Section: confSection, Name: "max_backoff", Type: config.TypeInt, Default: strconv.Itoa(int(DefaultMaxBackoff / time.Millisecond)), Min: 1, Max: 1 << 53,
+			Description: "Maximum lockout length, in milliseconds"},
+		&config.Key{Section: confSection, Name: "max_objects", Type: config.TypeInt, Default: strconv.Itoa(DefaultMaxObjects), Min: 1, Max: 1 << 31,
+			Description: "Maximum number of tracked user/IP pairs"},
+	)
+}
+
+type policy struct {
+	mode        string
+	threshold   int
+	maxLifetime time.Duration
+	maxBackoff  time.Duration
+	maxObjects  int
+}
+
+func getPolicy(s *serve.Service) *policy {
+	conf := s.Config()
+	p := &policy{
+		mode:        strings.ToLower(conf.GetString(confSection, "mode")),
+		threshold:   int(conf.GetInt(confSection, "threshold")),
+		maxLifetime: time.Duration(conf.GetInt(confSection, "max_lifetime")) * time.Millisecond,
+		maxBackoff:  time.Duration(conf.GetInt(confSection, "max_backoff")) * time.Millisecond,
+		maxObjects:  int(conf.GetInt(confSection, "max_objects")),
+	}
+	if p.mode == "" {
+		p.mode = ModeEnforce
+	}
+	if p.threshold <= 0 {
+		p.threshold = DefaultThreshold
+	}
+	if p.maxLifetime <= 0 {
+		p.maxLifetime = DefaultMaxLifetime
+	}
+	if p.maxBackoff <= 0 {
+		p.maxBackoff = DefaultMaxBackoff
+	}
+	if p.maxObjects <= 0 {
+		p.maxObjects = DefaultMaxObjects
+	}
+	return p
+}
+
+type key struct {
+	username string
+	ip       string
+}
+
+type entry struct {
+	failures    int
+	windowStart time.Time
+	lockouts    int
+	lockedUntil time.Time
+}
+
+// expired returns true if the entry no longer affects future attempts.
+func (e *entry) expired(t time.Time, p *policy) bool {
+	return t.After(e.lockedUntil.Add(p.maxBackoff)) && t.After(e.windowStart.Add(p.maxLifetime))
+}
+
+// Lockout tracks failed authentication attempts. A single Lockout should be
+// shared by all wrapped handlers, so that failures are counted across them.
+type Lockout struct {
+	mu      sync.Mutex
+	entries map[key]*entry
+}
+
+// New returns a new Lockout.
+func New() *Lockout {
+	return &Lockout{entries: make(map[key]*entry)}
+}
+
+// Wrap returns an auth handler which applies the lockout policy to h.
+func (l *Lockout) Wrap(h auth.Handler) auth.Handler {
+	return &handler{Handler: h, lockout: l}
+}
+
+type handler struct {
+	auth.Handler
+	lockout *Lockout
+}
+
+var _ auth.Handler = &handler{}
+
+func (h *handler) Authenticate(w http.ResponseWriter, r *http.Request) (*authdb.UserContext, error) {
+	s := serve.GetService(r)
+	p := getPolicy(s)
+	if p.mode == ModeOff {
+		return h.Handler.Authenticate(w, r)
+	}
+	username, err := attemptedUser(r)
+	if err != nil {
+		return nil, err
+	}
+	k := key{username: username, ip: clientIP(r)}
+	if wait := h.lockout.locked(k); wait > 0 {
+		if p.mode == ModeEnforce {
+			w.Header().Set("Retry-After", strconv.Itoa(int((wait+time.Second-1)/time.Second)))
+			return nil, errors.Status(kivik.StatusTooManyRequests, "Too many failed authentication attempts. Try again later.")
+		}
+		serve.GetLogger(r).Warn("User '%s' from %s is locked out for another %s, but lockouts are not enforced", k.username, k.ip, wait)
+	}
+	user, err := h.Handler.Authenticate(w, r)
+	switch {
+	case errors.StatusCode(err) == kivik.StatusUnauthorized:
+		failures, locked := h.lockout.fail(k, p)
+		serve.GetLogger(r).Warn("Failed %s authentication for user '%s' from %s (%d failures)", h.MethodName(), k.username, k.ip, failures)
+		if locked > 0 {
+			if p.mode == ModeEnforce {
+				serve.GetLogger(r).Warn("Locking out user '%s' from %s for %s", k.username, k.ip, locked)
+			} else {
+				serve.GetLogger(r).Warn("Would lock out user '%s' from %s for %s, but lockouts are not enforced", k.username, k.ip, locked)
+			}
+		}
+	case user != nil:
+		h.lockout.succeed(k)
+	}
+	return user, err
+}
+
+// locked returns the time remaining until k's lockout expires, or 0.
+func (l *Lockout) locked(k key) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if e, ok := l.entries[k]; ok {
+		if wait := e.lockedUntil.Sub(now()); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
+
+// fail records a failed attempt. It returns the number of failures in the
+// current window, and the length of the lockout, if one was started.
+func (l *Lockout) fail(k key, p *policy) (failures int, locked time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	t := now()
+	e, ok := l.entries[k]
+	if !ok {
+		if len(l.entries) >= p.maxObjects {
+			l.purge(t, p)
+		}
+		e = &entry{}
+		l.entries[k] = e
+	}
+	if e.expired(t, p) {
+		*e = entry{}
+	}
+	if t.After(e.windowStart.Add(p.maxLifetime)) {
+		e.failures = 0
+		e.windowStart = t
+	}
+	e.failures++
+	failures = e.failures
+	if e.failures >= p.threshold {
+		locked = p.maxLifetime << uint(e.lockouts)
+		if locked > p.maxBackoff || locked <= 0 {
+			locked = p.maxBackoff
+		}
+		e.lockouts++
+		e.lockedUntil = t.Add(locked)
+		e.failures = 0
+		e.windowStart = t
+	}
+	return failures, locked
+}
+
+func (l *Lockout) succeed(k key) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.entries, k)
+}
+
+// purge removes expired entries. If the table is still full, it is cleared,
+// rather than growing without bound.
+func (l *Lockout) purge(t time.Time, p *policy) {
+	for k, e := range l.entries {
+		if e.expired(t, p) {
+			delete(l.entries, k)
+		}
+	}
+	if len(l.entries) >= p.maxObjects {
+		l.entries = make(map[key]*entry)
+	}
+}
+
+// clientIP returns the IP address of the client.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// maxSessionBody is the largest POST /_session body which is read to find
+// the attempted username.
+const maxSessionBody = 64 * 1024
+
+// attemptedUser returns the username the client is attempting to
+// authenticate as, from HTTP Basic Auth credentials, or the body of a
+// POST /_session request. The body is restored for the wrapped handler. An
+// error is returned if the body is larger than maxSessionBody.
+func attemptedUser(r *http.Request) (string, error) {
+	if username, _, ok := r.BasicAuth(); ok {
+		return username, nil
+	}
+	if r.Method != kivik.MethodPost || r.URL.Path != "/_session" || r.Body == nil {
+		return "", nil
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxSessionBody+1))
+	_ = r.Body.Close()
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return "", nil
+	}
+	if len(body) > maxSessionBody {
+		return "", errors.Status(kivik.StatusRequestEntityTooLarge, "Request body is too large")
+	}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), kivik.TypeJSON) {
+		var data struct {
+			Name string `json:"name"`
+		}
+		_ = json.Unmarshal(body, &data)
+		return data.Name, nil
+	}
+	values, _ := url.ParseQuery(string(body))
+	return values.Get("name"), nil
+}
@@ -0,0 +1,166 @@
+package lockout
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flimzy/kivik"
+	"github.com/flimzy/kivik/authdb"
+	"github.com/flimzy/kivik/errors"
+	"github.com/flimzy/kivik/logger"
+	"github.com/flimzy/kivik/serve"
+)
+
+// testHandler accepts the password abc123 for any user.
+type testHandler struct{}
+
+func (testHandler) MethodName() string { return "test" }
+
+func (testHandler) Authenticate(_ http.ResponseWriter, r *http.Request) (*authdb.UserContext, error) {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return nil, nil
+	}
+	if password != "abc123" {
+		return nil, errors.Status(kivik.StatusUnauthorized, "unauthorized")
+	}
+	return &authdb.UserContext{Name: username}, nil
+}
+
+type testLogger struct {
+	lines []string
+}
+
+func (l *testLogger) Init(_ map[string]string) error { return nil }
+func (l *testLogger) WriteLog(_ logger.LogLevel, msg string) error {
+	l.lines = append(l.lines, msg)
+	return nil
+}
+
+func mockNow(t time.Time) {
+	now = func() time.Time { return t }
+}
+
+func attempt(s *serve.Service, h *handler, ip, username, password string) (*httptest.ResponseRecorder, error) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = ip + ":12345"
+	r.SetBasicAuth(username, password)
+	r = r.WithContext(context.WithValue(r.Context(), serve.ServiceContextKey, s))
+	w := httptest.NewRecorder()
+	_, err := h.Authenticate(w, r)
+	return w, err
+}
+
+func TestLockout(t *testing.T) {
+	start := time.Now()
+	mockNow(start)
+	defer func() { now = time.Now }()
+	log := &testLogger{}
+	s := &serve.Service{LogWriter: log}
+	_ = s.Config().Set(confSection, "threshold", "3")
+	_ = s.Config().Set(confSection, "max_lifetime", "60000")
+	_ = s.Config().Set(confSection, "max_backoff", "150000")
+	h := New().Wrap(testHandler{}).(*handler)
+
+	for i := 0; i < 3; i++ {
+		if _, err := attempt(s, h, "10.0.0.1", "bob", "wrong"); errors.StatusCode(err) != kivik.StatusUnauthorized {
+			t.Fatalf("Expected Unauthorized, got %v", err)
+		}
+	}
+	w, err := attempt(s, h, "10.0.0.1", "bob", "abc123")
+	if errors.StatusCode(err) != kivik.StatusTooManyRequests {
+		t.Fatalf("Expected Too Many Requests, got %v", err)
+	}
+	if retry := w.Header().Get("Retry-After"); retry != "60" {
+		t.Errorf("Unexpected Retry-After: %s", retry)
+	}
+	if _, err := attempt(s, h, "10.0.0.2", "bob", "abc123"); err != nil {
+		t.Errorf("Other IPs should not be locked out: %s", err)
+	}
+	if _, err := attempt(s, h, "10.0.0.1", "alice", "abc123"); err != nil {
+		t.Errorf("Other users should not be locked out: %s", err)
+	}
+	var locked bool
+	for _, line := range log.lines {
+		if strings.Contains(line, "Locking out user 'bob' from 10.0.0.1") {
+			locked = true
+		}
+	}
+	if !locked {
+		t.Errorf("Lockout was not logged: %v", log.lines)
+	}
+
+	// The second lockout is twice as long.
+	mockNow(start.Add(61 * time.Second))
+	for i := 0; i < 3; i++ {
+		_, _ = attempt(s, h, "10.0.0.1", "bob", "wrong")
+	}
+	if w, _ := attempt(s, h, "10.0.0.1", "bob", "abc123"); w.Header().Get("Retry-After") != "120" {
+		t.Errorf("Unexpected Retry-After: %s", w.Header().Get("Retry-After"))
+	}
+
+	// The third is capped at max_backoff.
+	mockNow(start.Add(182 * time.Second))
+	for i := 0; i < 3; i++ {
+		_, _ = attempt(s, h, "10.0.0.1", "bob", "wrong")
+	}
+	if w, _ := attempt(s, h, "10.0.0.1", "bob", "abc123"); w.Header().Get("Retry-After") != "150" {
+		t.Errorf("Unexpected Retry-After: %s", w.Header().Get("Retry-After"))
+	}
+
+	mockNow(start.Add(333 * time.Second))
+	if _, err := attempt(s, h, "10.0.0.1", "bob", "abc123"); err != nil {
+		t.Errorf("Lockout should have expired: %s", err)
+	}
+}
+
+func TestLockoutModes(t *testing.T) {
+	for _, mode := range []string{ModeOff, ModeWarn} {
+		log := &testLogger{}
+		s := &serve.Service{LogWriter: log}
+		_ = s.Config().Set(confSection, "mode", mode)
+		_ = s.Config().Set(confSection, "threshold", "1")
+		h := New().Wrap(testHandler{}).(*handler)
+		_, _ = attempt(s, h, "10.0.0.1", "bob", "wrong")
+		if _, err := attempt(s, h, "10.0.0.1", "bob", "abc123"); err != nil {
+			t.Errorf("%s: Unexpected error: %s", mode, err)
+		}
+		var wouldLock, stillLocked bool
+		for _, line := range log.lines {
+			if strings.Contains(line, "Would lock out user 'bob' from 10.0.0.1") {
+				wouldLock = true
+			}
+			if strings.Contains(line, "User 'bob' from 10.0.0.1 is locked out") {
+				stillLocked = true
+			}
+		}
+		if warn := mode == ModeWarn; wouldLock != warn || stillLocked != warn {
+			t.Errorf("%s: Unexpected lockout logging: %v", mode, log.lines)
+		}
+	}
+}
+
+func TestAttemptedUser(t *testing.T) {
+	r := httptest.NewRequest("POST", "/_session", strings.NewReader(`{"name":"bob","password":"x"}`))
+	r.Header.Set("Content-Type", "application/json")
+	if name, err := attemptedUser(r); err != nil || name != "bob" {
+		t.Errorf("Unexpected JSON username: %s (%v)", name, err)
+	}
+	r = httptest.NewRequest("POST", "/_session", strings.NewReader("name=alice&password=x"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if name, err := attemptedUser(r); err != nil || name != "alice" {
+		t.Errorf("Unexpected form username: %s (%v)", name, err)
+	}
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("password") != "x" {
+		t.Errorf("Request body was not restored")
+	}
+	r = httptest.NewRequest("POST", "/_session", strings.NewReader("name=alice&password="+strings.Repeat("x", maxSessionBody)))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if _, err := attemptedUser(r); errors.StatusCode(err) != kivik.StatusRequestEntityTooLarge {
+		t.Errorf("Expected Request Entity Too Large for an oversized body, got %v", err)
+	}
+}
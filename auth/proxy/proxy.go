@@ -0,0 +1,105 @@
+// Package proxy provides CouchDB proxy authentication, as described at
+// http://docs.couchdb.org/en/2.0.0/api/server/authn.html#proxy-authentication
+//
+// A trusted reverse proxy authenticates the user, and passes the user name and
+// roles to the server in HTTP headers. If couch_httpd_auth.proxy_use_secret is
+// true, the proxy must also pass a token, which is the hex-encoded HMAC-SHA1
+// of the user name, keyed with couch_httpd_auth.secret.
+package proxy
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/flimzy/kivik/auth"
+	"github.com/flimzy/kivik/authdb"
+	"github.com/flimzy/kivik/config"
+	"github.com/flimzy/kivik/serve"
+)
+
+// Default header names, which may be overridden by the x_auth_username,
+// x_auth_roles and x_auth_token keys of the couch_httpd_auth config section.
+const (
+	DefaultUserNameHeader = "X-Auth-CouchDB-UserName"
+	DefaultRolesHeader    = "X-Auth-CouchDB-Roles"
+	DefaultTokenHeader    = "X-Auth-CouchDB-Token"
+)
+
+func init() {
+	config.Register(
+		&config.Key{Section: "couch_httpd_auth", Name: "x_auth_username", Default: DefaultUserNameHeader,
+			Description: "Header containing the proxy-authenticated user name"},
+		&config.Key{Section: "couch_httpd_auth", Name: "x_auth_roles", Default: DefaultRolesHeader,
+			Description: "Header containing the proxy-authenticated user's roles"},
+		&config.Key{Section: "couch_httpd_auth", Name: "x_auth_token", Default: DefaultTokenHeader,
+			Description: "Header containing the proxy authentication token"},
+		&config.Key{Section: "couch_httpd_auth", Name: "proxy_use_secret", Type: config.TypeBool,
+			Description: "Require a token, signed with couch_httpd_auth.secret, for proxy authentication"},
+	)
+}
+
+// Auth provides CouchDB proxy authentication.
+type Auth struct{}
+
+var _ auth.Handler = &Auth{}
+
+// MethodName returns "proxy"
+func (a *Auth) MethodName() string {
+	return "proxy" // For compatibility with the name used by CouchDB
+}
+
+// Authenticate authenticates a request using the headers set by the proxy.
+// Requests without a user name header, or with an invalid token, fall through
+// to the next handler.
+func (a *Auth) Authenticate(w http.ResponseWriter, r *http.Request) (*authdb.UserContext, error) {
+	s := serve.GetService(r)
+	conf := s.Config()
+	name := r.Header.Get(headerName(s, "x_auth_username", DefaultUserNameHeader))
+	if name == "" {
+		return nil, nil
+	}
+	if conf.GetBool("couch_httpd_auth", "proxy_use_secret") {
+		secret := conf.GetString("couch_httpd_auth", "secret")
+		if secret == "" {
+			serve.GetLogger(r).Warn("proxy_use_secret is enabled, but couch_httpd_auth.secret is not set; rejecting proxy authentication")
+			return nil, nil
+		}
+		token := r.Header.Get(headerName(s, "x_auth_token", DefaultTokenHeader))
+		if !ValidToken(secret, name, token) {
+			return nil, nil
+		}
+	}
+	var roles []string
+	for _, role := range strings.Split(r.Header.Get(headerName(s, "x_auth_roles", DefaultRolesHeader)), ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			roles = append(roles, role)
+		}
+	}
+	return &authdb.UserContext{
+		Name:  name,
+		Roles: roles,
+	}, nil
+}
+
+func headerName(s *serve.Service, key, def string) string {
+	if name := s.Config().GetString("couch_httpd_auth", key); name != "" {
+		return name
+	}
+	return def
+}
+
+// Token returns the proxy authentication token for the user name, as the proxy
+// must calculate it.
+func Token(secret, name string) string {
+	h := hmac.New(sha1.New, []byte(secret))
+	_, _ = h.Write([]byte(name))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ValidToken returns true if token is valid for the user name.
+func ValidToken(secret, name, token string) bool {
+	return hmac.Equal([]byte(Token(secret, name)), []byte(strings.ToLower(token)))
+}
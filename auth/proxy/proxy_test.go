@@ -0,0 +1,82 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/kivik/authdb"
+	"github.com/flimzy/kivik/logger"
+	"github.com/flimzy/kivik/serve"
+)
+
+type proxyTest struct {
+	Name     string
+	Conf     map[string]string
+	Headers  map[string]string
+	Expected *authdb.UserContext
+}
+
+func TestAuthenticate(t *testing.T) {
+	secret := "foo"
+	withSecret := map[string]string{"secret": secret, "proxy_use_secret": "true"}
+	tests := []proxyTest{
+		{Name: "NoHeaders"},
+		{Name: "UserOnly",
+			Headers:  map[string]string{"X-Auth-CouchDB-UserName": "bob"},
+			Expected: &authdb.UserContext{Name: "bob"}},
+		{Name: "UserAndRoles",
+			Headers:  map[string]string{"X-Auth-CouchDB-UserName": "bob", "X-Auth-CouchDB-Roles": "users, _admin"},
+			Expected: &authdb.UserContext{Name: "bob", Roles: []string{"users", "_admin"}}},
+		{Name: "CustomHeaders",
+			Conf:     map[string]string{"x_auth_username": "X-User", "x_auth_roles": "X-Roles"},
+			Headers:  map[string]string{"X-User": "bob", "X-Roles": "users"},
+			Expected: &authdb.UserContext{Name: "bob", Roles: []string{"users"}}},
+		{Name: "MissingToken", Conf: withSecret,
+			Headers: map[string]string{"X-Auth-CouchDB-UserName": "bob"}},
+		{Name: "InvalidToken", Conf: withSecret,
+			Headers: map[string]string{"X-Auth-CouchDB-UserName": "bob", "X-Auth-CouchDB-Token": Token("bar", "bob")}},
+		{Name: "ValidToken", Conf: withSecret,
+			Headers:  map[string]string{"X-Auth-CouchDB-UserName": "bob", "X-Auth-CouchDB-Token": Token(secret, "bob")},
+			Expected: &authdb.UserContext{Name: "bob"}},
+		{Name: "NoSecret", Conf: map[string]string{"proxy_use_secret": "true"},
+			Headers: map[string]string{"X-Auth-CouchDB-UserName": "bob", "X-Auth-CouchDB-Token": Token("", "bob")}},
+	}
+	for _, test := range tests {
+		func(test proxyTest) {
+			t.Run(test.Name, func(t *testing.T) {
+				s := &serve.Service{LogWriter: nopLogger{}}
+				for key, value := range test.Conf {
+					_ = s.Config().Set("couch_httpd_auth", key, value)
+				}
+				r, _ := http.NewRequest("GET", "/", nil)
+				r = r.WithContext(context.WithValue(r.Context(), serve.ServiceContextKey, s))
+				for key, value := range test.Headers {
+					r.Header.Set(key, value)
+				}
+				a := &Auth{}
+				result, err := a.Authenticate(nil, r)
+				if err != nil {
+					t.Fatalf("Unexpected error: %s", err)
+				}
+				if d := diff.AsJSON(test.Expected, result); d != "" {
+					t.Errorf("Unexpected result:\n%s\n", d)
+				}
+			})
+		}(test)
+	}
+}
+
+func TestToken(t *testing.T) {
+	// Calculated with: echo -n "bob" | openssl dgst -sha1 -hmac "foo"
+	expected := "472d5f5e16ed1355475b649d1197d157d7d89e8d"
+	if token := Token("foo", "bob"); token != expected {
+		t.Errorf("Unexpected token: %s", token)
+	}
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Init(_ map[string]string) error             { return nil }
+func (nopLogger) WriteLog(_ logger.LogLevel, _ string) error { return nil }
@@ -4,11 +4,7 @@ package authdb
 
 import (
 	"context"
-	"crypto/sha1"
 	"encoding/json"
-	"fmt"
-
-	"golang.org/x/crypto/pbkdf2"
 )
 
 // A UserStore provides an AuthHandler with access to a user store for.
@@ -39,10 +35,16 @@ type UserContext struct {
 	Salt string `json:"-"`
 }
 
-// ValidatePBKDF2 returns true if the calculated hash matches the derivedKey.
+// ValidatePBKDF2 returns true if the calculated PBKDF2-HMAC-SHA1 hash matches
+// the derivedKey.
 func ValidatePBKDF2(password, salt, derivedKey string, iterations int) bool {
-	hash := fmt.Sprintf("%x", pbkdf2.Key([]byte(password), []byte(salt), iterations, PBKDF2KeyLength, sha1.New))
-	return hash == derivedKey
+	ok, _ := (&PasswordHash{
+		Scheme:     SchemePBKDF2,
+		Salt:       salt,
+		Iterations: iterations,
+		DerivedKey: derivedKey,
+	}).Validate(password)
+	return ok
 }
 
 // MarshalJSON satisfies the json.Marshaler interface.
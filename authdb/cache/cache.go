@@ -0,0 +1,286 @@
+// Package cache provides a caching wrapper around an authdb.UserStore.
+package cache
+
+import (
+	"container/list"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flimzy/kivik"
+	"github.com/flimzy/kivik/authdb"
+	"github.com/flimzy/kivik/authdb/usersdb"
+	"github.com/flimzy/kivik/errors"
+)
+
+// Defaults, used when the corresponding Options fields are zero.
+const (
+	DefaultTTL        = 5 * time.Minute
+	DefaultMaxEntries = 1000
+)
+
+// retryDelay is how long to wait before reconnecting to the changes feed.
+var retryDelay = 5 * time.Second
+
+var now = time.Now
+
+// Options configures a Cache.
+type Options struct {
+	// TTL is how long successful lookups and validations are cached.
+	TTL time.Duration
+	// NegativeTTL is how long unknown users and failed validations are
+	// cached. If zero, negative results are not cached.
+	NegativeTTL time.Duration
+	// MaxEntries is the maximum number of cached results. When the cache is
+	// full, the least recently used entry is evicted.
+	MaxEntries int
+	// UsersDB is the database whose changes feed is watched, to invalidate
+	// the entries of changed users. If nil, and the store is itself backed
+	// by a kivik.DB (as is the case for usersdb), that database is used.
+	UsersDB *kivik.DB
+}
+
+// changesFeeder is implemented by *kivik.DB, and by any store which embeds
+// one.
+type changesFeeder interface {
+	ChangesContext(context.Context, kivik.Options) (*kivik.Rows, error)
+}
+
+type entry struct {
+	key      string
+	username string
+	user     *authdb.UserContext
+	err      error
+	expires  time.Time
+}
+
+// Cache is an authdb.UserStore which caches the results of another store.
+type Cache struct {
+	store authdb.UserStore
+	opts  Options
+	// secret is used to derive validation cache keys, so that passwords
+	// can't be recovered from the cache.
+	secret []byte
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+
+	cancel func()
+	done   chan struct{}
+}
+
+var _ authdb.UserStore = &Cache{}
+
+// New returns a new cache around store. If opts is nil, the defaults are
+// used. Close should be called when the cache is no longer needed, to stop
+// watching the changes feed.
+func New(store authdb.UserStore, opts *Options) *Cache {
+	c := &Cache{
+		store:   store,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+		secret:  make([]byte, sha256.Size),
+	}
+	if opts != nil {
+		c.opts = *opts
+	}
+	if c.opts.TTL <= 0 {
+		c.opts.TTL = DefaultTTL
+	}
+	if c.opts.MaxEntries <= 0 {
+		c.opts.MaxEntries = DefaultMaxEntries
+	}
+	if _, err := rand.Read(c.secret); err != nil {
+		panic(err)
+	}
+	var feed changesFeeder
+	if c.opts.UsersDB != nil {
+		feed = c.opts.UsersDB
+	} else if f, ok := store.(changesFeeder); ok {
+		feed = f
+	}
+	if feed != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		c.cancel = cancel
+		c.done = make(chan struct{})
+		go c.watch(ctx, feed)
+	}
+	return c
+}
+
+// Close stops watching the changes feed.
+func (c *Cache) Close() error {
+	if c.cancel != nil {
+		c.cancel()
+		<-c.done
+	}
+	return nil
+}
+
+// Validate returns the cached result of validating the credentials, or
+// passes the request to the underlying store.
+func (c *Cache) Validate(ctx context.Context, username, password string) (*authdb.UserContext, error) {
+	key := "v:" + c.passwordKey(username, password)
+	if ent, ok := c.get(key); ok {
+		return ent.user, ent.err
+	}
+	user, err := c.store.Validate(ctx, username, password)
+	c.set(key, username, user, err, errors.StatusCode(err) == kivik.StatusUnauthorized)
+	return copyUser(user), err
+}
+
+// UserCtx returns the cached user context, or passes the request to the
+// underlying store.
+func (c *Cache) UserCtx(ctx context.Context, username string) (*authdb.UserContext, error) {
+	key := "u:" + username
+	if ent, ok := c.get(key); ok {
+		return ent.user, ent.err
+	}
+	user, err := c.store.UserCtx(ctx, username)
+	c.set(key, username, user, err, errors.StatusCode(err) == kivik.StatusNotFound)
+	return copyUser(user), err
+}
+
+// Invalidate removes all cached results for username.
+func (c *Cache) Invalidate(username string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for e := c.lru.Front(); e != nil; {
+		next := e.Next()
+		if e.Value.(*entry).username == username {
+			c.remove(e)
+		}
+		e = next
+	}
+}
+
+// Purge removes all cached results.
+func (c *Cache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*list.Element)
+	c.lru.Init()
+}
+
+// Len returns the number of cached results.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+func (c *Cache) passwordKey(username, password string) string {
+	mac := hmac.New(sha256.New, c.secret)
+	_, _ = mac.Write([]byte(username + "\x00" + password))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// get returns a copy of the unexpired entry for key, if any.
+func (c *Cache) get(key string) (*entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	ent := e.Value.(*entry)
+	if now().After(ent.expires) {
+		c.remove(e)
+		return nil, false
+	}
+	c.lru.MoveToFront(e)
+	return &entry{user: copyUser(ent.user), err: ent.err}, true
+}
+
+// set caches a result. Errors are only cached when negative is true, and
+// negative caching is enabled.
+func (c *Cache) set(key, username string, user *authdb.UserContext, err error, negative bool) {
+	ttl := c.opts.TTL
+	if err != nil {
+		if !negative || c.opts.NegativeTTL <= 0 {
+			return
+		}
+		ttl = c.opts.NegativeTTL
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.remove(e)
+	}
+	for c.lru.Len() >= c.opts.MaxEntries {
+		c.remove(c.lru.Back())
+	}
+	c.entries[key] = c.lru.PushFront(&entry{
+		key:      key,
+		username: username,
+		user:     copyUser(user),
+		err:      err,
+		expires:  now().Add(ttl),
+	})
+}
+
+func (c *Cache) remove(e *list.Element) {
+	c.lru.Remove(e)
+	delete(c.entries, e.Value.(*entry).key)
+}
+
+func copyUser(user *authdb.UserContext) *authdb.UserContext {
+	if user == nil {
+		return nil
+	}
+	u := *user
+	if user.Roles != nil {
+		u.Roles = make([]string, len(user.Roles))
+		copy(u.Roles, user.Roles)
+	}
+	return &u
+}
+
+// watch invalidates the entries of changed users until ctx is cancelled.
+// Whenever the feed ends, it is resumed from the last sequence seen. If that
+// is not possible, the whole cache is purged, as changes may have been
+// missed.
+func (c *Cache) watch(ctx context.Context, feed changesFeeder) {
+	defer close(c.done)
+	since := "now"
+	for {
+		rows, err := feed.ChangesContext(ctx, kivik.Options{"feed": "continuous", "since": since})
+		if errors.StatusCode(err) == kivik.StatusNotImplemented {
+			return
+		}
+		if err == nil {
+			for rows.Next() {
+				if id := rows.ID(); strings.HasPrefix(id, usersdb.UserPrefix) {
+					c.Invalidate(strings.TrimPrefix(id, usersdb.UserPrefix))
+				}
+				if seq := rows.Seq(); seq != "" {
+					since = seq
+				}
+			}
+			if seq := rows.UpdateSeq(); seq != "" {
+				since = seq
+			}
+			_ = rows.Close()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil || since == "now" {
+			// There is no sequence to resume from, so start again from the
+			// current one, forgetting anything which may have changed.
+			c.Purge()
+			since = "now"
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryDelay):
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/flimzy/kivik"
+	"github.com/flimzy/kivik/authdb"
+	"github.com/flimzy/kivik/authdb/usersdb"
+	_ "github.com/flimzy/kivik/driver/memory"
+	"github.com/flimzy/kivik/errors"
+	"github.com/flimzy/kivik/test/kt"
+)
+
+// testStore knows a single user, bob, with the password abc123.
+type testStore struct {
+	calls int
+}
+
+var _ authdb.UserStore = &testStore{}
+
+func (s *testStore) Validate(_ context.Context, username, password string) (*authdb.UserContext, error) {
+	s.calls++
+	if username != "bob" || password != "abc123" {
+		return nil, errors.Status(kivik.StatusUnauthorized, "unauthorized")
+	}
+	return &authdb.UserContext{Name: "bob", Roles: []string{"staff"}}, nil
+}
+
+func (s *testStore) UserCtx(_ context.Context, username string) (*authdb.UserContext, error) {
+	s.calls++
+	if username != "bob" {
+		return nil, errors.Status(kivik.StatusNotFound, "missing")
+	}
+	return &authdb.UserContext{Name: "bob", Roles: []string{"staff"}}, nil
+}
+
+func mockNow(t time.Time) func() {
+	now = func() time.Time { return t }
+	return func() { now = time.Now }
+}
+
+func TestCacheTTL(t *testing.T) {
+	start := time.Now()
+	defer mockNow(start)()
+	store := &testStore{}
+	c := New(store, &Options{TTL: time.Minute})
+	defer c.Close()
+	for i := 0; i < 3; i++ {
+		if _, err := c.UserCtx(kt.CTX, "bob"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if store.calls != 1 {
+		t.Errorf("Expected 1 call to the store, got %d", store.calls)
+	}
+	mockNow(start.Add(2 * time.Minute))
+	if _, err := c.UserCtx(kt.CTX, "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if store.calls != 2 {
+		t.Errorf("Expected expired entry to be refreshed, got %d calls", store.calls)
+	}
+}
+
+func TestCacheValidate(t *testing.T) {
+	store := &testStore{}
+	c := New(store, nil)
+	defer c.Close()
+	if _, err := c.Validate(kt.CTX, "bob", "abc123"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Validate(kt.CTX, "bob", "abc123"); err != nil {
+		t.Fatal(err)
+	}
+	if store.calls != 1 {
+		t.Errorf("Expected 1 call to the store, got %d", store.calls)
+	}
+	if _, err := c.Validate(kt.CTX, "bob", "wrong"); errors.StatusCode(err) != kivik.StatusUnauthorized {
+		t.Errorf("Expected wrong password to be rejected, got %v", err)
+	}
+	if _, err := c.Validate(kt.CTX, "bob", "wrong"); errors.StatusCode(err) != kivik.StatusUnauthorized {
+		t.Errorf("Expected wrong password to be rejected, got %v", err)
+	}
+	if store.calls != 3 {
+		t.Errorf("Failures should not be cached without a NegativeTTL, got %d calls", store.calls)
+	}
+}
+
+func TestCacheNegative(t *testing.T) {
+	store := &testStore{}
+	c := New(store, &Options{NegativeTTL: time.Minute})
+	defer c.Close()
+	for i := 0; i < 2; i++ {
+		if _, err := c.UserCtx(kt.CTX, "alice"); errors.StatusCode(err) != kivik.StatusNotFound {
+			t.Errorf("Expected Not Found, got %v", err)
+		}
+		if _, err := c.Validate(kt.CTX, "bob", "wrong"); errors.StatusCode(err) != kivik.StatusUnauthorized {
+			t.Errorf("Expected Unauthorized, got %v", err)
+		}
+	}
+	if store.calls != 2 {
+		t.Errorf("Expected 2 calls to the store, got %d", store.calls)
+	}
+}
+
+func TestCacheMaxEntries(t *testing.T) {
+	store := &testStore{}
+	c := New(store, &Options{MaxEntries: 2, NegativeTTL: time.Minute})
+	defer c.Close()
+	for _, name := range []string{"alice", "bob", "carol"} {
+		_, _ = c.UserCtx(kt.CTX, name)
+	}
+	if l := c.Len(); l != 2 {
+		t.Errorf("Expected 2 entries, got %d", l)
+	}
+	_, _ = c.UserCtx(kt.CTX, "alice")
+	if store.calls != 4 {
+		t.Errorf("Expected the oldest entry to have been evicted, got %d calls", store.calls)
+	}
+}
+
+func TestCacheInvalidate(t *testing.T) {
+	store := &testStore{}
+	c := New(store, nil)
+	defer c.Close()
+	_, _ = c.UserCtx(kt.CTX, "bob")
+	_, _ = c.Validate(kt.CTX, "bob", "abc123")
+	c.Invalidate("bob")
+	if l := c.Len(); l != 0 {
+		t.Errorf("Expected empty cache, got %d entries", l)
+	}
+}
+
+func TestCacheCopiesResults(t *testing.T) {
+	c := New(&testStore{}, nil)
+	defer c.Close()
+	user, _ := c.UserCtx(kt.CTX, "bob")
+	user.Roles[0] = "_admin"
+	user, _ = c.UserCtx(kt.CTX, "bob")
+	if user.Roles[0] != "staff" {
+		t.Errorf("Cached user context was modified by the caller")
+	}
+}
+
+// endingFeed is a store whose changes feed returns as soon as it has caught
+// up, whatever feed is requested.
+type endingFeed struct {
+	testStore
+	db *kivik.DB
+
+	mu    sync.Mutex
+	opts  []kivik.Options
+	fails bool
+}
+
+func (f *endingFeed) ChangesContext(ctx context.Context, opts kivik.Options) (*kivik.Rows, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.opts = append(f.opts, opts)
+	if f.fails {
+		return nil, errors.Status(kivik.StatusBadRequest, "invalid since")
+	}
+	normal := kivik.Options{}
+	for key, value := range opts {
+		normal[key] = value
+	}
+	normal["feed"] = "normal"
+	return f.db.ChangesContext(ctx, normal)
+}
+
+// requests returns the options of each request for the feed.
+func (f *endingFeed) requests() []kivik.Options {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]kivik.Options{}, f.opts...)
+}
+
+func TestCacheFeedEnds(t *testing.T) {
+	defer func(delay time.Duration) { retryDelay = delay }(retryDelay)
+	retryDelay = time.Millisecond
+	client, err := kivik.New("memory", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = client.CreateDB("users"); err != nil {
+		t.Fatal(err)
+	}
+	db, _ := client.DB("users")
+	rev, err := db.Put(usersdb.UserPrefix+"bob", map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &endingFeed{db: db}
+	c := New(store, nil)
+	defer c.Close()
+	waitRequests := func(n int) []kivik.Options {
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			if reqs := store.requests(); len(reqs) >= n {
+				return reqs
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Timed out waiting for %d requests for the feed", n)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	reqs := waitRequests(1)
+	if reqs[0]["feed"] != "continuous" {
+		t.Errorf("Expected a continuous feed to be requested, got %v", reqs[0])
+	}
+	_, _ = c.UserCtx(kt.CTX, "bob")
+
+	// The feed is resumed from the last sequence, without purging the cache.
+	reqs = waitRequests(len(store.requests()) + 3)
+	if since := reqs[len(reqs)-1]["since"]; since != "1" {
+		t.Errorf("Expected the feed to be resumed from 1, got %v", since)
+	}
+	if l := c.Len(); l != 1 {
+		t.Errorf("Expected the cache to be kept, got %d entries", l)
+	}
+
+	// Changes made between requests are seen.
+	if _, err = db.Put(usersdb.UserPrefix+"bob", map[string]interface{}{"_rev": rev}); err != nil {
+		t.Fatal(err)
+	}
+	waitRequests(len(store.requests()) + 2)
+	if l := c.Len(); l != 0 {
+		t.Errorf("Expected bob's entry to be invalidated, got %d entries", l)
+	}
+
+	// The cache is purged if the feed can't be resumed.
+	_, _ = c.UserCtx(kt.CTX, "bob")
+	store.mu.Lock()
+	store.fails = true
+	store.mu.Unlock()
+	waitRequests(len(store.requests()) + 2)
+	if l := c.Len(); l != 0 {
+		t.Errorf("Expected the cache to be purged, got %d entries", l)
+	}
+	if reqs = store.requests(); reqs[len(reqs)-1]["since"] != "now" {
+		t.Errorf("Expected the feed to be restarted from now, got %v", reqs[len(reqs)-1])
+	}
+}
@@ -25,16 +25,29 @@ func New(c *config.Config) authdb.UserStore {
 }
 
 func (c *conf) Validate(ctx context.Context, username, password string) (*authdb.UserContext, error) {
-	derivedKey, salt, iterations, err := c.getKeySaltIter(ctx, username)
+	h, err := c.getHash(ctx, username)
 	if err != nil {
 		if errors.StatusCode(err) == kivik.StatusNotFound {
 			return nil, kivik.ErrUnauthorized
 		}
 		return nil, errors.Wrap(err, "unrecognized password hash")
 	}
-	if !authdb.ValidatePBKDF2(password, salt, derivedKey, iterations) {
+	ok, err := h.Validate(password)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
 		return nil, errors.Status(kivik.StatusUnauthorized, "unauthorized")
 	}
+	salt := h.Salt
+	if policy := authdb.ConfigPolicy(c.Config); policy.NeedsUpgrade(h) {
+		// A failed upgrade must not prevent the login, so errors are ignored.
+		if newHash, err := policy.NewHash(password); err == nil {
+			if err := c.SetContext(ctx, "admins", username, FormatHash(newHash)); err == nil {
+				salt = newHash.Salt
+			}
+		}
+	}
 	return &authdb.UserContext{
 		Name:  username,
 		Roles: []string{"_admin"},
@@ -42,28 +55,84 @@ func (c *conf) Validate(ctx context.Context, username, password string) (*authdb
 	}, nil
 }
 
-const hashPrefix = "-" + authdb.SchemePBKDF2 + "-"
+// Hash prefixes, as used by CouchDB in the [admins] section.
+const (
+	pbkdf2Prefix = "-" + authdb.SchemePBKDF2
+	simplePrefix = "-hashed-"
+	bcryptPrefix = "-" + authdb.SchemeBcrypt + "-"
+)
 
-func (c *conf) getKeySaltIter(ctx context.Context, username string) (key, salt string, iterations int, err error) {
-	hash, err := c.GetContext(ctx, "admins", username)
-	if err != nil {
-		return "", "", 0, err
+// FormatHash formats h for storage in the [admins] configuration section.
+// PBKDF2 hashes have the form -pbkdf2-key,salt,iterations, or
+// -pbkdf2:prf-key,salt,iterations for PRFs other than SHA1. Legacy hashes have
+// the form -hashed-sha,salt, and bcrypt hashes -bcrypt-hash,salt.
+func FormatHash(h *authdb.PasswordHash) string {
+	switch h.Scheme {
+	case authdb.SchemeSimple:
+		return simplePrefix + h.DerivedKey + "," + h.Salt
+	case authdb.SchemeBcrypt:
+		return bcryptPrefix + h.DerivedKey + "," + h.Salt
+	}
+	prefix := pbkdf2Prefix
+	if h.PRF != "" && h.PRF != authdb.PRFSHA1 {
+		prefix += ":" + h.PRF
+	}
+	return prefix + "-" + h.DerivedKey + "," + h.Salt + "," + strconv.Itoa(h.Iterations)
+}
+
+// parseHash parses a hash formatted by FormatHash.
+func parseHash(hash string) (*authdb.PasswordHash, error) {
+	h := &authdb.PasswordHash{}
+	var fields int
+	switch {
+	case strings.HasPrefix(hash, simplePrefix):
+		h.Scheme = authdb.SchemeSimple
+		hash = strings.TrimPrefix(hash, simplePrefix)
+		fields = 2
+	case strings.HasPrefix(hash, bcryptPrefix):
+		h.Scheme = authdb.SchemeBcrypt
+		hash = strings.TrimPrefix(hash, bcryptPrefix)
+		fields = 2
+	case strings.HasPrefix(hash, pbkdf2Prefix+"-"):
+		h.Scheme = authdb.SchemePBKDF2
+		hash = strings.TrimPrefix(hash, pbkdf2Prefix+"-")
+		fields = 3
+	case strings.HasPrefix(hash, pbkdf2Prefix+":"):
+		h.Scheme = authdb.SchemePBKDF2
+		parts := strings.SplitN(strings.TrimPrefix(hash, pbkdf2Prefix+":"), "-", 2)
+		if len(parts) != 2 {
+			return nil, errors.New("unrecognized hash format")
+		}
+		h.PRF, hash = parts[0], parts[1]
+		fields = 3
+	default:
+		return nil, errors.New("unrecognized password scheme")
 	}
-	if !strings.HasPrefix(hash, hashPrefix) {
-		return "", "", 0, errors.New("unrecognized password scheme")
+	parts := strings.Split(hash, ",")
+	if len(parts) != fields {
+		return nil, errors.New("unrecognized hash format")
 	}
-	parts := strings.Split(strings.TrimPrefix(hash, hashPrefix), ",")
-	if len(parts) != 3 {
-		return "", "", 0, errors.New("unrecognized hash format")
+	h.DerivedKey, h.Salt = parts[0], parts[1]
+	if fields == 3 {
+		iterations, err := strconv.Atoi(parts[2])
+		if err != nil {
+			return nil, errors.New("unrecognized hash format")
+		}
+		h.Iterations = iterations
 	}
-	if iterations, err = strconv.Atoi(parts[2]); err != nil {
-		return "", "", 0, errors.New("unrecognized has format")
+	return h, nil
+}
+
+func (c *conf) getHash(ctx context.Context, username string) (*authdb.PasswordHash, error) {
+	hash, err := c.GetContext(ctx, "admins", username)
+	if err != nil {
+		return nil, err
 	}
-	return parts[0], parts[1], iterations, nil
+	return parseHash(hash)
 }
 
 func (c *conf) UserCtx(ctx context.Context, username string) (*authdb.UserContext, error) {
-	_, salt, _, err := c.getKeySaltIter(ctx, username)
+	h, err := c.getHash(ctx, username)
 	if err != nil {
 		if errors.StatusCode(err) == kivik.StatusNotFound {
 			return nil, kivik.ErrNotFound
@@ -73,6 +142,6 @@ func (c *conf) UserCtx(ctx context.Context, username string) (*authdb.UserContex
 	return &authdb.UserContext{
 		Name:  username,
 		Roles: []string{"_admin"},
-		Salt:  salt,
+		Salt:  h.Salt,
 	}, nil
 }
@@ -3,6 +3,7 @@ package confadmin
 import (
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/flimzy/kivik"
@@ -90,3 +91,64 @@ func TestConfAdminAuth(t *testing.T) {
 		t.Errorf("Expected Not Found fetching roles for bad username.%s", msg)
 	}
 }
+
+func TestFormatHash(t *testing.T) {
+	hashes := []string{
+		"-pbkdf2-792221164f257de22ad72a8e94760388233e5714,7897f3451f59da741c87ec5f10fe7abe,10",
+		"-pbkdf2:sha256-341e56fd1e31e174a46346e364c48e11e814bc98ebceb1fe846ebd891ef386cd,7897f3451f59da741c87ec5f10fe7abe,10",
+		"-hashed-e3cd1f95e258938178592e830f189aa18d1ec1b5,7897f3451f59da741c87ec5f10fe7abe",
+	}
+	for _, hash := range hashes {
+		h, err := parseHash(hash)
+		if err != nil {
+			t.Errorf("Failed to parse %s: %s", hash, err)
+			continue
+		}
+		if ok, _ := h.Validate("abc123"); !ok {
+			t.Errorf("Failed to validate %s", hash)
+		}
+		if formatted := FormatHash(h); formatted != hash {
+			t.Errorf("Unexpected formatted hash: %s", formatted)
+		}
+	}
+}
+
+func TestUpgradeHash(t *testing.T) {
+	conf := config.New(memconf.New())
+	auth := New(conf)
+	conf.Set("admins", "test", "-hashed-e3cd1f95e258938178592e830f189aa18d1ec1b5,7897f3451f59da741c87ec5f10fe7abe")
+	conf.Set("couch_httpd_auth", "pbkdf2_prf", "sha256")
+	conf.Set("couch_httpd_auth", "iterations", "20")
+	uCtx, err := auth.Validate(kt.CTX, "test", "abc123")
+	if err != nil {
+		t.Fatalf("Validation failure for good password: %s", err)
+	}
+	hash := conf.GetString("admins", "test")
+	if !strings.HasPrefix(hash, "-pbkdf2:sha256-") || !strings.HasSuffix(hash, ",20") {
+		t.Errorf("Hash was not upgraded: %s", hash)
+	}
+	h, _ := parseHash(hash)
+	if uCtx.Salt != h.Salt {
+		t.Errorf("User context should have the new salt")
+	}
+	if _, err := auth.Validate(kt.CTX, "test", "abc123"); err != nil {
+		t.Errorf("Validation failure after upgrade: %s", err)
+	}
+}
+
+func TestNoDowngradeHash(t *testing.T) {
+	conf := config.New(memconf.New())
+	auth := New(conf)
+	strong, err := (&authdb.Policy{PRF: authdb.PRFSHA256, Iterations: 600}).NewHash("abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := FormatHash(strong)
+	conf.Set("admins", "test", hash)
+	if _, err := auth.Validate(kt.CTX, "test", "abc123"); err != nil {
+		t.Fatalf("Validation failure for good password: %s", err)
+	}
+	if stored := conf.GetString("admins", "test"); stored != hash {
+		t.Errorf("A stronger hash was replaced: %s", stored)
+	}
+}
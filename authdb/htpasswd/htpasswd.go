@@ -0,0 +1,195 @@
+// Package htpasswd provides an authentication user store backed by an
+// Apache-style htpasswd file, and an optional roles file.
+//
+// Password entries may be bcrypt ($2y$, $2a$ or $2b$), SHA1 ({SHA}) or
+// Apache MD5 ($apr1$) hashes, as created by the htpasswd tool. The roles file
+// contains one user per line, in the form:
+//
+//	username:role1,role2
+//
+// Blank lines and lines beginning with # are ignored in both files. The files
+// are re-read whenever their modification time changes.
+package htpasswd
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/flimzy/kivik"
+	"github.com/flimzy/kivik/authdb"
+	"github.com/flimzy/kivik/errors"
+)
+
+// file is a parsed file, and the modification time it had when read.
+type file struct {
+	name    string
+	modTime time.Time
+	entries map[string]string
+}
+
+// load re-reads the file if it has been modified since it was last read.
+func (f *file) load() error {
+	fi, err := os.Stat(f.name)
+	if err != nil {
+		return err
+	}
+	if f.entries != nil && fi.ModTime().Equal(f.modTime) {
+		return nil
+	}
+	r, err := os.Open(f.name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	entries := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		entries[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	if err := scanner.Err(); err != nil {
+		return errors.Wrapf(err, "failed to read %s", f.name)
+	}
+	f.entries = entries
+	f.modTime = fi.ModTime()
+	return nil
+}
+
+// Store is an htpasswd-backed authdb.UserStore.
+type Store struct {
+	mu        sync.Mutex
+	passwords *file
+	roles     *file
+}
+
+var _ authdb.UserStore = &Store{}
+
+// New returns a new user store, which reads passwords from passwordFile, and
+// roles from rolesFile. If rolesFile is empty, users have no roles.
+func New(passwordFile, rolesFile string) (*Store, error) {
+	s := &Store{passwords: &file{name: passwordFile}}
+	if rolesFile != "" {
+		s.roles = &file{name: rolesFile}
+	}
+	if _, err := s.lookup("", false); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+type user struct {
+	hash  string
+	roles []string
+}
+
+// lookup reloads the files if necessary, and returns the named user, if they
+// exist.
+func (s *Store) lookup(username string, required bool) (*user, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.passwords.load(); err != nil {
+		return nil, err
+	}
+	if s.roles != nil {
+		if err := s.roles.load(); err != nil {
+			return nil, err
+		}
+	}
+	hash, ok := s.passwords.entries[username]
+	if !ok {
+		if required {
+			return nil, errors.Status(kivik.StatusNotFound, "user not found")
+		}
+		return nil, nil
+	}
+	u := &user{hash: hash, roles: []string{}}
+	if s.roles != nil {
+		for _, role := range strings.Split(s.roles.entries[username], ",") {
+			if role = strings.TrimSpace(role); role != "" {
+				u.roles = append(u.roles, role)
+			}
+		}
+	}
+	return u, nil
+}
+
+// userCtx returns the user context for u. The salt, which is used to sign
+// session cookies, is the password hash, so that changing the password
+// invalidates existing sessions.
+func (u *user) userCtx(username string) *authdb.UserContext {
+	return &authdb.UserContext{
+		Name:  username,
+		Roles: u.roles,
+		Salt:  u.hash,
+	}
+}
+
+// Validate returns a user context object if the credentials are valid.
+func (s *Store) Validate(_ context.Context, username, password string) (*authdb.UserContext, error) {
+	u, err := s.lookup(username, true)
+	if err != nil {
+		if errors.StatusCode(err) == kivik.StatusNotFound {
+			return nil, errors.Status(kivik.StatusUnauthorized, "unauthorized")
+		}
+		return nil, err
+	}
+	ok, err := checkPassword(u.hash, password)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.Status(kivik.StatusUnauthorized, "unauthorized")
+	}
+	return u.userCtx(username), nil
+}
+
+// UserCtx returns a user context object if the user exists.
+func (s *Store) UserCtx(_ context.Context, username string) (*authdb.UserContext, error) {
+	u, err := s.lookup(username, true)
+	if err != nil {
+		return nil, err
+	}
+	return u.userCtx(username), nil
+}
+
+// checkPassword returns true if password matches the htpasswd hash.
+func checkPassword(hash, password string) (bool, error) {
+	switch {
+	case strings.HasPrefix(hash, "$2"):
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			return false, nil
+		}
+		return err == nil, err
+	case strings.HasPrefix(hash, "{SHA}"):
+		sum := sha1.Sum([]byte(password))
+		return secureCompare(hash[5:], base64.StdEncoding.EncodeToString(sum[:])), nil
+	case strings.HasPrefix(hash, apr1Magic):
+		parts := strings.SplitN(hash[len(apr1Magic):], "$", 2)
+		if len(parts) != 2 {
+			return false, errors.New("invalid apr1 hash")
+		}
+		return secureCompare(hash, apr1(password, parts[0])), nil
+	}
+	return false, errors.New("unsupported htpasswd hash")
+}
+
+func secureCompare(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
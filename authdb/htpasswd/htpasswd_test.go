@@ -0,0 +1,134 @@
+package htpasswd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/flimzy/kivik"
+	"github.com/flimzy/kivik/errors"
+	"github.com/flimzy/kivik/test/kt"
+)
+
+func TestAPR1(t *testing.T) {
+	tests := map[string]string{
+		"xxxxxxxx": "$apr1$xxxxxxxx$ercO2nG.W5RqPbnssIiR10",
+		"abc":      "$apr1$abc$llI8RTyiD0dGmtN4YE0yk1",
+	}
+	for salt, expected := range tests {
+		if result := apr1("abc123", salt); result != expected {
+			t.Errorf("Unexpected hash for salt %s: %s", salt, result)
+		}
+	}
+}
+
+type checkTest struct {
+	Name     string
+	Hash     string
+	Expected bool
+	Err      string
+}
+
+func TestCheckPassword(t *testing.T) {
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("abc123"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []checkTest{
+		{Name: "Bcrypt", Hash: string(bcryptHash), Expected: true},
+		{Name: "Bcrypt2y", Hash: "$2y" + string(bcryptHash[3:]), Expected: true},
+		{Name: "SHA", Hash: "{SHA}Y2fEjdGT1W6nsLqtJbGUVeUp9e4=", Expected: true},
+		{Name: "SHAWrong", Hash: "{SHA}qUqP5cyxm6YcTAhz05Hph5gvu9M="},
+		{Name: "APR1", Hash: "$apr1$xxxxxxxx$ercO2nG.W5RqPbnssIiR10", Expected: true},
+		{Name: "APR1Wrong", Hash: "$apr1$xxxxxxxx$Wrong.W5RqPbnssIiR10"},
+		{Name: "Plain", Hash: "abc123", Err: "unsupported htpasswd hash"},
+	}
+	for _, test := range tests {
+		func(test checkTest) {
+			t.Run(test.Name, func(t *testing.T) {
+				result, err := checkPassword(test.Hash, "abc123")
+				var msg string
+				if err != nil {
+					msg = err.Error()
+				}
+				if msg != test.Err {
+					t.Errorf("Unexpected error: %s", msg)
+				}
+				if result != test.Expected {
+					t.Errorf("Expected %t, got %t", test.Expected, result)
+				}
+			})
+		}(test)
+	}
+}
+
+func writeFile(t *testing.T, filename, content string, modTime time.Time) {
+	if err := ioutil.WriteFile(filename, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(filename, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "htpasswd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	passwords := filepath.Join(dir, "htpasswd")
+	roles := filepath.Join(dir, "roles")
+	start := time.Now().Add(-time.Hour)
+	writeFile(t, passwords, "# users\nbob:{SHA}Y2fEjdGT1W6nsLqtJbGUVeUp9e4=\n", start)
+	writeFile(t, roles, "bob: staff, boss\n", start)
+
+	store, err := New(passwords, roles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := store.Validate(kt.CTX, "bob", "abc123")
+	if err != nil {
+		t.Fatalf("Validation failure for good password: %s", err)
+	}
+	if !reflect.DeepEqual(user.Roles, []string{"staff", "boss"}) {
+		t.Errorf("Unexpected roles: %v", user.Roles)
+	}
+	if _, err := store.Validate(kt.CTX, "bob", "wrong"); errors.StatusCode(err) != kivik.StatusUnauthorized {
+		t.Errorf("Expected Unauthorized for bad password, got %v", err)
+	}
+	if _, err := store.Validate(kt.CTX, "alice", "abc123"); errors.StatusCode(err) != kivik.StatusUnauthorized {
+		t.Errorf("Expected Unauthorized for unknown user, got %v", err)
+	}
+	if _, err := store.UserCtx(kt.CTX, "alice"); errors.StatusCode(err) != kivik.StatusNotFound {
+		t.Errorf("Expected Not Found for unknown user, got %v", err)
+	}
+
+	writeFile(t, passwords, "bob:{SHA}Y2fEjdGT1W6nsLqtJbGUVeUp9e4=\nalice:$apr1$xxxxxxxx$ercO2nG.W5RqPbnssIiR10\n", start.Add(time.Minute))
+	writeFile(t, roles, "alice:admin\n", start.Add(time.Minute))
+	user, err = store.Validate(kt.CTX, "alice", "abc123")
+	if err != nil {
+		t.Fatalf("Expected new user after reload: %s", err)
+	}
+	if !reflect.DeepEqual(user.Roles, []string{"admin"}) {
+		t.Errorf("Unexpected roles after reload: %v", user.Roles)
+	}
+	user, err = store.UserCtx(kt.CTX, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(user.Roles) != 0 {
+		t.Errorf("Expected roles to be removed after reload: %v", user.Roles)
+	}
+}
+
+func TestNewMissingFile(t *testing.T) {
+	if _, err := New("/nonexistent/htpasswd", ""); err == nil {
+		t.Errorf("Expected error for missing file")
+	}
+}
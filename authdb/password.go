@@ -0,0 +1,240 @@
+package authdb
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strconv"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/pbkdf2"
+
+	"github.com/flimzy/kivik/config"
+	"github.com/flimzy/kivik/errors"
+)
+
+// Password schemes, as stored in the password_scheme field of a user
+// document.
+const (
+	// SchemeSimple is the legacy CouchDB 1.x scheme, where password_sha is
+	// the hex-encoded SHA1 hash of the password concatenated with the salt.
+	SchemeSimple = "simple"
+	// SchemeBcrypt stores a bcrypt hash in derived_key. The iteration count
+	// is the bcrypt cost.
+	SchemeBcrypt = "bcrypt"
+)
+
+// Pseudo-random functions used with SchemePBKDF2, as stored in the pbkdf2_prf
+// field of a user document.
+const (
+	PRFSHA1   = "sha"
+	PRFSHA256 = "sha256"
+	PRFSHA512 = "sha512"
+)
+
+// DefaultIterations is CouchDB's default number of PBKDF2 iterations.
+const DefaultIterations = 10
+
+// saltLength is the length, in bytes, of newly generated salts.
+const saltLength = 16
+
+var prfs = map[string]func() hash.Hash{
+	PRFSHA1:   sha1.New,
+	PRFSHA256: sha256.New,
+	PRFSHA512: sha512.New,
+}
+
+func init() {
+	config.Register(
+		&config.Key{Section: "couch_httpd_auth", Name: "password_scheme", Default: SchemePBKDF2,
+			Values:      []string{SchemePBKDF2, SchemeSimple, SchemeBcrypt},
+			Description: "Scheme used to hash new and upgraded passwords"},
+		&config.Key{Section: "couch_httpd_auth", Name: "pbkdf2_prf", Default: PRFSHA1,
+			Values:      []string{PRFSHA1, PRFSHA256, PRFSHA512},
+			Description: "Pseudo-random function used with the pbkdf2 password scheme"},
+		&config.Key{Section: "couch_httpd_auth", Name: "iterations", Type: config.TypeInt, Default: strconv.Itoa(DefaultIterations), Min: 1, Max: 1 << 31,
+			Description: "PBKDF2 iterations, or bcrypt cost, used to hash new and upgraded passwords"},
+	)
+}
+
+// PasswordHash is a stored password hash.
+type PasswordHash struct {
+	// Scheme is one of SchemePBKDF2, SchemeSimple or SchemeBcrypt.
+	Scheme string
+	// PRF is the PBKDF2 pseudo-random function. If empty, PRFSHA1 is assumed.
+	PRF string
+	// Salt is the password salt. It is also used to calculate cookie tokens,
+	// so is set for every scheme.
+	Salt string
+	// Iterations is the PBKDF2 iteration count, or the bcrypt cost.
+	Iterations int
+	// DerivedKey is the hex-encoded PBKDF2 key, the hex-encoded SHA1 hash for
+	// SchemeSimple, or the bcrypt hash.
+	DerivedKey string
+}
+
+func (h *PasswordHash) prf() string {
+	if h.PRF == "" {
+		return PRFSHA1
+	}
+	return h.PRF
+}
+
+// Validate returns true if password matches the hash. An error is returned if
+// the hash uses an unsupported scheme.
+func (h *PasswordHash) Validate(password string) (bool, error) {
+	switch h.Scheme {
+	case SchemePBKDF2:
+		key, err := derivePBKDF2(password, h.Salt, h.prf(), h.Iterations)
+		if err != nil {
+			return false, err
+		}
+		return hmac.Equal([]byte(key), []byte(h.DerivedKey)), nil
+	case SchemeSimple:
+		sum := sha1.Sum([]byte(password + h.Salt))
+		return hmac.Equal([]byte(hex.EncodeToString(sum[:])), []byte(h.DerivedKey)), nil
+	case SchemeBcrypt:
+		err := bcrypt.CompareHashAndPassword([]byte(h.DerivedKey), []byte(password))
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			return false, nil
+		}
+		return err == nil, err
+	}
+	return false, errors.Errorf("unsupported password scheme: %s", h.Scheme)
+}
+
+// derivePBKDF2 returns the hex-encoded PBKDF2 key. The key length is
+// PBKDF2KeyLength for SHA1, as in CouchDB, and the digest size otherwise.
+func derivePBKDF2(password, salt, prf string, iterations int) (string, error) {
+	fn, ok := prfs[prf]
+	if !ok {
+		return "", errors.Errorf("unsupported pbkdf2_prf: %s", prf)
+	}
+	keyLen := PBKDF2KeyLength
+	if prf != PRFSHA1 {
+		keyLen = fn().Size()
+	}
+	return fmt.Sprintf("%x", pbkdf2.Key([]byte(password), []byte(salt), iterations, keyLen, fn)), nil
+}
+
+// Policy describes how new passwords should be hashed.
+type Policy struct {
+	// Scheme is the password scheme. If empty, SchemePBKDF2 is used.
+	Scheme string
+	// PRF is the PBKDF2 pseudo-random function. If empty, PRFSHA1 is used.
+	PRF string
+	// Iterations is the PBKDF2 iteration count, or bcrypt cost. If zero,
+	// DefaultIterations is used.
+	Iterations int
+}
+
+// ConfigPolicy returns the password policy described by the password_scheme,
+// pbkdf2_prf and iterations keys of the couch_httpd_auth section.
+func ConfigPolicy(c *config.Config) *Policy {
+	return &Policy{
+		Scheme:     c.GetString("couch_httpd_auth", "password_scheme"),
+		PRF:        c.GetString("couch_httpd_auth", "pbkdf2_prf"),
+		Iterations: int(c.GetInt("couch_httpd_auth", "iterations")),
+	}
+}
+
+func (p *Policy) scheme() string {
+	if p == nil || p.Scheme == "" {
+		return SchemePBKDF2
+	}
+	return p.Scheme
+}
+
+func (p *Policy) prf() string {
+	if p == nil || p.PRF == "" {
+		return PRFSHA1
+	}
+	return p.PRF
+}
+
+func (p *Policy) iterations() int {
+	if p == nil || p.Iterations <= 0 {
+		return DefaultIterations
+	}
+	return p.Iterations
+}
+
+// NewHash hashes password according to the policy, with a random salt.
+func (p *Policy) NewHash(password string) (*PasswordHash, error) {
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, errors.Wrap(err, "failed to generate salt")
+	}
+	h := &PasswordHash{
+		Scheme:     p.scheme(),
+		Salt:       hex.EncodeToString(salt),
+		Iterations: p.iterations(),
+	}
+	switch h.Scheme {
+	case SchemePBKDF2:
+		h.PRF = p.prf()
+		key, err := derivePBKDF2(password, h.Salt, h.PRF, h.Iterations)
+		if err != nil {
+			return nil, err
+		}
+		h.DerivedKey = key
+	case SchemeSimple:
+		sum := sha1.Sum([]byte(password + h.Salt))
+		h.DerivedKey = hex.EncodeToString(sum[:])
+		h.Iterations = 0
+	case SchemeBcrypt:
+		if h.Iterations < bcrypt.MinCost {
+			h.Iterations = bcrypt.DefaultCost
+		}
+		key, err := bcrypt.GenerateFromPassword([]byte(password), h.Iterations)
+		if err != nil {
+			return nil, err
+		}
+		h.DerivedKey = string(key)
+	default:
+		return nil, errors.Errorf("unsupported password scheme: %s", h.Scheme)
+	}
+	return h, nil
+}
+
+// prfStrength ranks the PBKDF2 pseudo-random functions from weakest to
+// strongest.
+var prfStrength = map[string]int{
+	PRFSHA1:   1,
+	PRFSHA256: 2,
+	PRFSHA512: 3,
+}
+
+// NeedsUpgrade returns true if h is weaker than the policy, so should be
+// replaced after the next successful login. Hashes which are as strong as the
+// policy in every respect, or stronger in any, are never upgraded, so that
+// lowering the policy doesn't downgrade existing hashes. Legacy simple hashes
+// are weaker than any other scheme, but PBKDF2 and bcrypt hashes are not
+// comparable, so neither is replaced by the other.
+func (p *Policy) NeedsUpgrade(h *PasswordHash) bool {
+	scheme := p.scheme()
+	if h.Scheme != scheme {
+		return h.Scheme == SchemeSimple
+	}
+	switch h.Scheme {
+	case SchemePBKDF2:
+		hasPRF, wantPRF := prfStrength[h.prf()], prfStrength[p.prf()]
+		if hasPRF > wantPRF || h.Iterations > p.iterations() {
+			return false
+		}
+		return hasPRF < wantPRF || h.Iterations < p.iterations()
+	case SchemeBcrypt:
+		cost, err := bcrypt.Cost([]byte(h.DerivedKey))
+		want := p.iterations()
+		if want < bcrypt.MinCost {
+			want = bcrypt.DefaultCost
+		}
+		return err == nil && cost < want
+	}
+	return false
+}
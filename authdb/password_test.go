@@ -0,0 +1,135 @@
+package authdb
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testSalt = "7897f3451f59da741c87ec5f10fe7abe"
+
+type validateTest struct {
+	Name     string
+	Hash     *PasswordHash
+	Password string
+	Expected bool
+	Err      string
+}
+
+func TestPasswordHashValidate(t *testing.T) {
+	tests := []validateTest{
+		{Name: "PBKDF2", Password: "abc123", Expected: true,
+			Hash: &PasswordHash{Scheme: SchemePBKDF2, Salt: testSalt, Iterations: 10, DerivedKey: "792221164f257de22ad72a8e94760388233e5714"}},
+		{Name: "PBKDF2Wrong", Password: "foo",
+			Hash: &PasswordHash{Scheme: SchemePBKDF2, Salt: testSalt, Iterations: 10, DerivedKey: "792221164f257de22ad72a8e94760388233e5714"}},
+		{Name: "PBKDF2SHA256", Password: "abc123", Expected: true,
+			Hash: &PasswordHash{Scheme: SchemePBKDF2, PRF: PRFSHA256, Salt: testSalt, Iterations: 10, DerivedKey: "341e56fd1e31e174a46346e364c48e11e814bc98ebceb1fe846ebd891ef386cd"}},
+		{Name: "PBKDF2SHA512", Password: "abc123", Expected: true,
+			Hash: &PasswordHash{Scheme: SchemePBKDF2, PRF: PRFSHA512, Salt: testSalt, Iterations: 10, DerivedKey: "22af209d9702af5ee1613d6c872f6bb707ecb478651d754559ef4eebd6e52580af3a6dcfbef69572d2f771b7b9b61e97de59b7e746a5c813b341a302a887ce7e"}},
+		{Name: "UnknownPRF", Password: "abc123",
+			Hash: &PasswordHash{Scheme: SchemePBKDF2, PRF: "md5", Salt: testSalt, Iterations: 10},
+			Err:  "unsupported pbkdf2_prf: md5"},
+		{Name: "Simple", Password: "abc123", Expected: true,
+			Hash: &PasswordHash{Scheme: SchemeSimple, Salt: testSalt, DerivedKey: "e3cd1f95e258938178592e830f189aa18d1ec1b5"}},
+		{Name: "SimpleWrong", Password: "foo",
+			Hash: &PasswordHash{Scheme: SchemeSimple, Salt: testSalt, DerivedKey: "e3cd1f95e258938178592e830f189aa18d1ec1b5"}},
+		{Name: "UnknownScheme", Password: "abc123",
+			Hash: &PasswordHash{Scheme: "rot13"},
+			Err:  "unsupported password scheme: rot13"},
+	}
+	for _, test := range tests {
+		func(test validateTest) {
+			t.Run(test.Name, func(t *testing.T) {
+				result, err := test.Hash.Validate(test.Password)
+				var msg string
+				if err != nil {
+					msg = err.Error()
+				}
+				if msg != test.Err {
+					t.Errorf("Unexpected error: %s", msg)
+				}
+				if result != test.Expected {
+					t.Errorf("Expected %t, got %t", test.Expected, result)
+				}
+			})
+		}(test)
+	}
+}
+
+func TestPolicyNewHash(t *testing.T) {
+	policies := []*Policy{
+		nil,
+		{Scheme: SchemePBKDF2, PRF: PRFSHA256, Iterations: 20},
+		{Scheme: SchemeSimple},
+		{Scheme: SchemeBcrypt, Iterations: 4},
+	}
+	for _, policy := range policies {
+		h, err := policy.NewHash("abc123")
+		if err != nil {
+			t.Fatalf("Failed to hash password for %v: %s", policy, err)
+		}
+		if ok, err := h.Validate("abc123"); !ok || err != nil {
+			t.Errorf("New %s hash failed to validate: %v", h.Scheme, err)
+		}
+		if ok, _ := h.Validate("foo"); ok {
+			t.Errorf("New %s hash validated the wrong password", h.Scheme)
+		}
+		if policy.NeedsUpgrade(h) {
+			t.Errorf("New %s hash should not need an upgrade", h.Scheme)
+		}
+	}
+	if _, err := (&Policy{Scheme: "rot13"}).NewHash("abc123"); err == nil {
+		t.Errorf("Expected error for unknown scheme")
+	}
+}
+
+func TestPolicyNeedsUpgrade(t *testing.T) {
+	policy := &Policy{Scheme: SchemePBKDF2, PRF: PRFSHA256, Iterations: 1000}
+	tests := map[string]*PasswordHash{
+		"Simple":     {Scheme: SchemeSimple},
+		"SHA1":       {Scheme: SchemePBKDF2, Iterations: 1000},
+		"Iterations": {Scheme: SchemePBKDF2, PRF: PRFSHA256, Iterations: 10},
+	}
+	for name, h := range tests {
+		if !policy.NeedsUpgrade(h) {
+			t.Errorf("%s: Expected upgrade", name)
+		}
+	}
+	current := map[string]*PasswordHash{
+		"Current":        {Scheme: SchemePBKDF2, PRF: PRFSHA256, Iterations: 1000},
+		"MoreIterations": {Scheme: SchemePBKDF2, PRF: PRFSHA256, Iterations: 600000},
+		"StrongerPRF":    {Scheme: SchemePBKDF2, PRF: PRFSHA512, Iterations: 1000},
+		"SHA1ManyIters":  {Scheme: SchemePBKDF2, Iterations: 600000},
+		"SHA512FewIters": {Scheme: SchemePBKDF2, PRF: PRFSHA512, Iterations: 10},
+		"Bcrypt":         {Scheme: SchemeBcrypt},
+	}
+	for name, h := range current {
+		if policy.NeedsUpgrade(h) {
+			t.Errorf("%s: Hash should not be upgraded", name)
+		}
+	}
+}
+
+func TestPolicyNoDowngrade(t *testing.T) {
+	strong, err := (&Policy{Scheme: SchemePBKDF2, PRF: PRFSHA256, Iterations: 600}).NewHash("abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	weak := &Policy{}
+	if weak.NeedsUpgrade(strong) {
+		t.Errorf("A stronger hash must not be replaced by the default policy")
+	}
+	if (&Policy{Scheme: SchemeSimple}).NeedsUpgrade(strong) {
+		t.Errorf("A PBKDF2 hash must not be replaced by a simple hash")
+	}
+	bcryptHash, err := (&Policy{Scheme: SchemeBcrypt, Iterations: bcrypt.MinCost + 1}).NewHash("abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (&Policy{Scheme: SchemeBcrypt, Iterations: bcrypt.MinCost}).NeedsUpgrade(bcryptHash) {
+		t.Errorf("A bcrypt hash must not be replaced by a lower cost")
+	}
+	if !(&Policy{Scheme: SchemeBcrypt, Iterations: bcrypt.MinCost + 2}).NeedsUpgrade(bcryptHash) {
+		t.Errorf("A bcrypt hash should be replaced by a higher cost")
+	}
+}
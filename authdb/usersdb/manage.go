@@ -0,0 +1,264 @@
+package usersdb
+
+import (
+	"context"
+	"strings"
+
+	"github.com/flimzy/kivik"
+	"github.com/flimzy/kivik/authdb"
+	"github.com/flimzy/kivik/errors"
+)
+
+// DocID returns the document ID of the named user.
+func DocID(name string) string {
+	return UserPrefix + name
+}
+
+// IsUserDocID returns true if docID has the form of a user document ID.
+func IsUserDocID(docID string) bool {
+	return strings.HasPrefix(docID, UserPrefix)
+}
+
+// Manager provides write access to the user documents of a _users database.
+// Changes are checked against the same rules as CouchDB's _users validation
+// function, and plain-text passwords are hashed before they are stored.
+type Manager struct {
+	db *kivik.DB
+	// Policy describes how new passwords are hashed. If nil, CouchDB's
+	// default of PBKDF2-HMAC-SHA1 with 10 iterations is used.
+	Policy *authdb.Policy
+}
+
+// NewManager returns a new Manager for the provided users database.
+func NewManager(userDB *kivik.DB) *Manager {
+	return &Manager{db: userDB}
+}
+
+// getUserDoc returns the current user document, or nil if it does not exist.
+func (m *Manager) getUserDoc(ctx context.Context, docID string) (map[string]interface{}, error) {
+	var doc map[string]interface{}
+	if err := m.db.GetContext(ctx, docID, &doc, nil); err != nil {
+		if errors.StatusCode(err) == kivik.StatusNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return doc, nil
+}
+
+// GetUser returns the user document with the given ID, on behalf of actor.
+// Only admins may read the documents of other users.
+func (m *Manager) GetUser(ctx context.Context, actor *authdb.UserContext, docID string, options kivik.Options) (map[string]interface{}, error) {
+	var doc map[string]interface{}
+	if err := m.db.GetContext(ctx, docID, &doc, options); err != nil {
+		return nil, err
+	}
+	if !isAdmin(actor) && !isOwner(actor, doc) {
+		return nil, forbidden(actor, "You may only read your own user document.")
+	}
+	return doc, nil
+}
+
+// PutUser creates or updates the user document with the given ID, on behalf
+// of actor, which is nil for anonymous users. If the document contains a
+// plain-text password field, it is replaced with a hash.
+func (m *Manager) PutUser(ctx context.Context, actor *authdb.UserContext, docID string, doc map[string]interface{}) (rev string, err error) {
+	oldDoc, err := m.getUserDoc(ctx, docID)
+	if err != nil {
+		return "", err
+	}
+	if err := ValidateUpdate(docID, doc, oldDoc, actor); err != nil {
+		return "", err
+	}
+	if err := m.hashPassword(doc); err != nil {
+		return "", err
+	}
+	return m.db.PutContext(ctx, docID, doc)
+}
+
+// DeleteUser deletes the user document with the given ID, on behalf of
+// actor. Only admins may delete the documents of other users.
+func (m *Manager) DeleteUser(ctx context.Context, actor *authdb.UserContext, docID, rev string) (newRev string, err error) {
+	oldDoc, err := m.getUserDoc(ctx, docID)
+	if err != nil {
+		return "", err
+	}
+	if err := ValidateUpdate(docID, map[string]interface{}{"_deleted": true}, oldDoc, actor); err != nil {
+		return "", err
+	}
+	return m.db.DeleteContext(ctx, docID, rev)
+}
+
+// CreateUser creates a new user, on behalf of actor. Only admins may assign
+// roles to a new user.
+func (m *Manager) CreateUser(ctx context.Context, actor *authdb.UserContext, name, password string, roles []string) (rev string, err error) {
+	if roles == nil {
+		roles = []string{}
+	}
+	return m.PutUser(ctx, actor, DocID(name), map[string]interface{}{
+		"_id":      DocID(name),
+		"name":     name,
+		"type":     "user",
+		"roles":    roles,
+		"password": password,
+	})
+}
+
+// SetPassword changes the password of an existing user, on behalf of actor.
+func (m *Manager) SetPassword(ctx context.Context, actor *authdb.UserContext, name, password string) (rev string, err error) {
+	doc, err := m.getUserDoc(ctx, DocID(name))
+	if err != nil {
+		return "", err
+	}
+	if doc == nil {
+		return "", errors.Status(kivik.StatusNotFound, "missing")
+	}
+	doc["password"] = password
+	return m.PutUser(ctx, actor, DocID(name), doc)
+}
+
+// hashPassword replaces a plain-text password field in doc with a hash,
+// according to the manager's policy.
+func (m *Manager) hashPassword(doc map[string]interface{}) error {
+	value, ok := doc["password"]
+	if !ok || value == nil {
+		delete(doc, "password")
+		return nil
+	}
+	password, ok := value.(string)
+	if !ok {
+		return errors.Status(kivik.StatusBadRequest, "password must be a string")
+	}
+	h, err := m.Policy.NewHash(password)
+	if err != nil {
+		return err
+	}
+	setHash(doc, h)
+	return nil
+}
+
+func isAdmin(actor *authdb.UserContext) bool {
+	if actor == nil {
+		return false
+	}
+	for _, role := range actor.Roles {
+		if role == "_admin" {
+			return true
+		}
+	}
+	return false
+}
+
+// isOwner returns true if doc belongs to actor.
+func isOwner(actor *authdb.UserContext, doc map[string]interface{}) bool {
+	if actor == nil || actor.Name == "" || doc == nil {
+		return false
+	}
+	name, _ := doc["name"].(string)
+	return name == actor.Name
+}
+
+// forbidden returns a 401 error for anonymous users, or a 403 error otherwise.
+func forbidden(actor *authdb.UserContext, msg string) error {
+	if actor == nil {
+		return errors.Status(kivik.StatusUnauthorized, msg)
+	}
+	return errors.Status(kivik.StatusForbidden, msg)
+}
+
+// ValidateUpdate checks a change to a user document, following the rules of
+// CouchDB's _users design document. oldDoc should be nil when a new user is
+// being created, and actor nil when the change is made anonymously.
+func ValidateUpdate(docID string, newDoc, oldDoc map[string]interface{}, actor *authdb.UserContext) error {
+	admin := isAdmin(actor)
+	if deleted, _ := newDoc["_deleted"].(bool); deleted {
+		if admin || isOwner(actor, oldDoc) {
+			return nil
+		}
+		return forbidden(actor, "You may only delete your own user document.")
+	}
+	if newDoc["type"] != "user" {
+		return errors.Status(kivik.StatusForbidden, "doc.type must be user")
+	}
+	name, ok := newDoc["name"].(string)
+	if !ok {
+		return errors.Status(kivik.StatusForbidden, "doc.name must be a string")
+	}
+	if name == "" {
+		return errors.Status(kivik.StatusForbidden, "doc.name must not be empty")
+	}
+	if strings.HasPrefix(name, "_") {
+		return errors.Status(kivik.StatusForbidden, "Username may not start with underscore.")
+	}
+	if docID != DocID(name) {
+		return errors.Statusf(kivik.StatusForbidden, "Doc ID must be of the form %sname", UserPrefix)
+	}
+	roles, err := docRoles(newDoc)
+	if err != nil {
+		return err
+	}
+	for _, role := range roles {
+		if strings.HasPrefix(role, "_") {
+			return errors.Status(kivik.StatusForbidden, "No system roles (starting with underscore) in users db.")
+		}
+	}
+	if _, ok := newDoc["password_sha"]; ok {
+		if _, ok := newDoc["salt"]; !ok {
+			return errors.Status(kivik.StatusForbidden, "Users with password_sha must have a salt.")
+		}
+	}
+	if oldDoc != nil {
+		if oldName, _ := oldDoc["name"].(string); oldName != name {
+			return errors.Status(kivik.StatusForbidden, "Usernames can not be changed.")
+		}
+	}
+	if admin {
+		return nil
+	}
+	if oldDoc == nil {
+		if len(roles) > 0 {
+			return forbidden(actor, "Only _admin may set roles")
+		}
+		return nil
+	}
+	if !isOwner(actor, oldDoc) {
+		return forbidden(actor, "You may only update your own user document.")
+	}
+	oldRoles, _ := docRoles(oldDoc)
+	if !sameRoles(roles, oldRoles) {
+		return forbidden(actor, "Only _admin may edit roles")
+	}
+	return nil
+}
+
+// docRoles returns the roles of a user document.
+func docRoles(doc map[string]interface{}) ([]string, error) {
+	list, ok := doc["roles"].([]interface{})
+	if !ok {
+		if roles, ok := doc["roles"].([]string); ok {
+			return roles, nil
+		}
+		return nil, errors.Status(kivik.StatusForbidden, "doc.roles must be an array")
+	}
+	roles := make([]string, len(list))
+	for i, role := range list {
+		str, ok := role.(string)
+		if !ok {
+			return nil, errors.Status(kivik.StatusForbidden, "doc.roles can only contain strings")
+		}
+		roles[i] = str
+	}
+	return roles, nil
+}
+
+func sameRoles(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,106 @@
+package usersdb
+
+import (
+	"testing"
+
+	"github.com/flimzy/kivik/authdb"
+)
+
+type validateTest struct {
+	Name   string
+	DocID  string
+	NewDoc map[string]interface{}
+	OldDoc map[string]interface{}
+	Actor  *authdb.UserContext
+	Err    string
+}
+
+func userDoc(name string, roles ...interface{}) map[string]interface{} {
+	if roles == nil {
+		roles = []interface{}{}
+	}
+	return map[string]interface{}{
+		"_id":   DocID(name),
+		"name":  name,
+		"type":  "user",
+		"roles": roles,
+	}
+}
+
+func TestValidateUpdate(t *testing.T) {
+	admin := &authdb.UserContext{Name: "admin", Roles: []string{"_admin"}}
+	bob := &authdb.UserContext{Name: "bob", Roles: []string{"staff"}}
+	tests := []validateTest{
+		{Name: "AnonSignup", DocID: DocID("bob"), NewDoc: userDoc("bob")},
+		{Name: "AnonSignupRoles", DocID: DocID("bob"), NewDoc: userDoc("bob", "staff"),
+			Err: "401 Only _admin may set roles"},
+		{Name: "AdminCreateRoles", DocID: DocID("bob"), NewDoc: userDoc("bob", "staff"), Actor: admin},
+		{Name: "SystemRole", DocID: DocID("bob"), NewDoc: userDoc("bob", "_admin"), Actor: admin,
+			Err: "403 No system roles (starting with underscore) in users db."},
+		{Name: "WrongType", DocID: DocID("bob"), NewDoc: map[string]interface{}{"name": "bob", "type": "admin", "roles": []interface{}{}},
+			Err: "403 doc.type must be user"},
+		{Name: "MissingName", DocID: DocID("bob"), NewDoc: map[string]interface{}{"type": "user", "roles": []interface{}{}},
+			Err: "403 doc.name must be a string"},
+		{Name: "UnderscoreName", DocID: DocID("_bob"), NewDoc: userDoc("_bob"),
+			Err: "403 Username may not start with underscore."},
+		{Name: "IDMismatch", DocID: DocID("alice"), NewDoc: userDoc("bob"),
+			Err: "403 Doc ID must be of the form org.couchdb.user:name"},
+		{Name: "BadRoles", DocID: DocID("bob"), NewDoc: map[string]interface{}{"name": "bob", "type": "user", "roles": "staff"},
+			Err: "403 doc.roles must be an array"},
+		{Name: "NonStringRole", DocID: DocID("bob"), NewDoc: userDoc("bob", 1),
+			Err: "403 doc.roles can only contain strings"},
+		{Name: "SelfUpdate", DocID: DocID("bob"), NewDoc: userDoc("bob", "staff"), OldDoc: userDoc("bob", "staff"), Actor: bob},
+		{Name: "SelfRoleChange", DocID: DocID("bob"), NewDoc: userDoc("bob", "staff", "boss"), OldDoc: userDoc("bob", "staff"), Actor: bob,
+			Err: "403 Only _admin may edit roles"},
+		{Name: "OtherUpdate", DocID: DocID("alice"), NewDoc: userDoc("alice"), OldDoc: userDoc("alice"), Actor: bob,
+			Err: "403 You may only update your own user document."},
+		{Name: "AnonUpdate", DocID: DocID("bob"), NewDoc: userDoc("bob"), OldDoc: userDoc("bob"),
+			Err: "401 You may only update your own user document."},
+		{Name: "AdminRoleChange", DocID: DocID("bob"), NewDoc: userDoc("bob", "boss"), OldDoc: userDoc("bob", "staff"), Actor: admin},
+		{Name: "SelfDelete", DocID: DocID("bob"), NewDoc: map[string]interface{}{"_deleted": true}, OldDoc: userDoc("bob"), Actor: bob},
+		{Name: "OtherDelete", DocID: DocID("alice"), NewDoc: map[string]interface{}{"_deleted": true}, OldDoc: userDoc("alice"), Actor: bob,
+			Err: "403 You may only delete your own user document."},
+		{Name: "AdminDelete", DocID: DocID("alice"), NewDoc: map[string]interface{}{"_deleted": true}, OldDoc: userDoc("alice"), Actor: admin},
+	}
+	for _, test := range tests {
+		func(test validateTest) {
+			t.Run(test.Name, func(t *testing.T) {
+				var msg string
+				if err := ValidateUpdate(test.DocID, test.NewDoc, test.OldDoc, test.Actor); err != nil {
+					msg = err.Error()
+				}
+				if msg != test.Err {
+					t.Errorf("Unexpected error: %s", msg)
+				}
+			})
+		}(test)
+	}
+}
+
+func TestHashPassword(t *testing.T) {
+	m := &Manager{Policy: &authdb.Policy{Iterations: 5}}
+	doc := userDoc("bob")
+	doc["password"] = "abc123"
+	doc["password_sha"] = "stale"
+	if err := m.hashPassword(doc); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := doc["password"]; ok {
+		t.Errorf("Plain-text password should have been removed")
+	}
+	if _, ok := doc["password_sha"]; ok {
+		t.Errorf("Old password_sha should have been removed")
+	}
+	if doc["password_scheme"] != authdb.SchemePBKDF2 {
+		t.Errorf("Unexpected password scheme: %v", doc["password_scheme"])
+	}
+	if doc["iterations"] != 5 {
+		t.Errorf("Unexpected iterations: %v", doc["iterations"])
+	}
+	if !authdb.ValidatePBKDF2("abc123", doc["salt"].(string), doc["derived_key"].(string), 5) {
+		t.Errorf("Derived key does not validate")
+	}
+	if err := m.hashPassword(map[string]interface{}{"password": 123}); err == nil || err.Error() != "400 password must be a string" {
+		t.Errorf("Unexpected error for non-string password: %v", err)
+	}
+}
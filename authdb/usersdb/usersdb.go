@@ -3,67 +3,148 @@ package usersdb
 
 import (
 	"context"
-	"crypto/sha1"
-	"fmt"
-
-	"golang.org/x/crypto/pbkdf2"
 
 	"github.com/flimzy/kivik"
 	"github.com/flimzy/kivik/authdb"
+	"github.com/flimzy/kivik/config"
 	"github.com/flimzy/kivik/errors"
 )
 
-const userPrefix = "org.couchdb.user:"
+// UserPrefix is the prefix of every user document ID.
+const UserPrefix = "org.couchdb.user:"
 
 type db struct {
 	*kivik.DB
+	conf *config.Config
 }
 
 var _ authdb.UserStore = &db{}
 
 // New returns a new authdb.UserStore backed by a the provided database.
+// Password hashes are never upgraded, so the database is only read.
 func New(userDB *kivik.DB) authdb.UserStore {
-	return &db{userDB}
+	return &db{DB: userDB}
+}
+
+// NewWithConfig returns a new authdb.UserStore backed by the provided
+// database. Password hashes which are weaker than the policy described by the
+// server configuration are upgraded on successful login. See
+// authdb.ConfigPolicy and authdb.Policy.NeedsUpgrade.
+func NewWithConfig(userDB *kivik.DB, conf *config.Config) authdb.UserStore {
+	return &db{DB: userDB, conf: conf}
+}
+
+// policy returns the password policy, or nil if hashes are not to be
+// upgraded.
+func (db *db) policy() *authdb.Policy {
+	if db.conf == nil {
+		return nil
+	}
+	return authdb.ConfigPolicy(db.conf)
 }
 
 type user struct {
 	Name           string   `json:"name"`
 	Roles          []string `json:"roles"`
 	PasswordScheme string   `json:"password_scheme,omitempty"`
+	PRF            string   `json:"pbkdf2_prf,omitempty"`
 	Salt           string   `json:"salt,omitempty"`
 	Iterations     int      `json:"iterations,omitempty"`
 	DerivedKey     string   `json:"derived_key,omitempty"`
+	PasswordSHA    string   `json:"password_sha,omitempty"`
+}
+
+// hash returns the stored password hash. Documents created by CouchDB 1.x may
+// have a password_sha field, with no password_scheme.
+func (u *user) hash() *authdb.PasswordHash {
+	h := &authdb.PasswordHash{
+		Scheme:     u.PasswordScheme,
+		PRF:        u.PRF,
+		Salt:       u.Salt,
+		Iterations: u.Iterations,
+		DerivedKey: u.DerivedKey,
+	}
+	if h.Scheme == "" && u.PasswordSHA != "" {
+		h.Scheme = authdb.SchemeSimple
+	}
+	if h.Scheme == authdb.SchemeSimple {
+		h.DerivedKey = u.PasswordSHA
+	}
+	return h
 }
 
 func (db *db) Validate(ctx context.Context, username, password string) (*authdb.UserContext, error) {
 	var u user
-	if err := db.GetContext(ctx, userPrefix+username, &u, nil); err != nil {
+	if err := db.GetContext(ctx, UserPrefix+username, &u, nil); err != nil {
 		if errors.StatusCode(err) == kivik.StatusNotFound {
 			err = errors.Status(kivik.StatusUnauthorized, "unauthorized")
 		}
 		return nil, err
 	}
-	switch u.PasswordScheme {
-	case "":
+	h := u.hash()
+	if h.Scheme == "" {
 		return nil, errors.New("no password scheme set for user")
-	case authdb.SchemePBKDF2:
-	default:
-		return nil, errors.Errorf("unsupported password scheme: %s", u.PasswordScheme)
 	}
-	key := fmt.Sprintf("%x", pbkdf2.Key([]byte(password), []byte(u.Salt), u.Iterations, authdb.PBKDF2KeyLength, sha1.New))
-	if key != u.DerivedKey {
+	ok, err := h.Validate(password)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
 		return nil, errors.Status(kivik.StatusUnauthorized, "unauthorized")
 	}
+	salt := u.Salt
+	if policy := db.policy(); policy != nil && policy.NeedsUpgrade(h) {
+		// A failed upgrade must not prevent the login, so errors are ignored,
+		// and the old hash is tried again next time.
+		if newHash, err := db.upgrade(ctx, username, password, policy); err == nil {
+			salt = newHash.Salt
+		}
+	}
 	return &authdb.UserContext{
 		Name:  u.Name,
 		Roles: u.Roles,
-		Salt:  u.Salt,
+		Salt:  salt,
 	}, nil
 }
 
+// upgrade re-hashes the user's password according to policy.
+func (db *db) upgrade(ctx context.Context, username, password string, policy *authdb.Policy) (*authdb.PasswordHash, error) {
+	var doc map[string]interface{}
+	if err := db.GetContext(ctx, UserPrefix+username, &doc, nil); err != nil {
+		return nil, err
+	}
+	h, err := policy.NewHash(password)
+	if err != nil {
+		return nil, err
+	}
+	setHash(doc, h)
+	if _, err := db.PutContext(ctx, UserPrefix+username, doc); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// setHash stores h in a user document, replacing any previous hash.
+func setHash(doc map[string]interface{}, h *authdb.PasswordHash) {
+	for _, field := range []string{"password", "password_sha", "pbkdf2_prf", "iterations", "derived_key"} {
+		delete(doc, field)
+	}
+	doc["password_scheme"] = h.Scheme
+	doc["salt"] = h.Salt
+	switch h.Scheme {
+	case authdb.SchemeSimple:
+		doc["password_sha"] = h.DerivedKey
+		return
+	case authdb.SchemePBKDF2:
+		doc["pbkdf2_prf"] = h.PRF
+	}
+	doc["iterations"] = h.Iterations
+	doc["derived_key"] = h.DerivedKey
+}
+
 func (db *db) UserCtx(ctx context.Context, username string) (*authdb.UserContext, error) {
 	var u user
-	err := db.GetContext(ctx, userPrefix+username, &u, nil)
+	err := db.GetContext(ctx, UserPrefix+username, &u, nil)
 	return &authdb.UserContext{
 		Name:  u.Name,
 		Roles: u.Roles,
@@ -7,8 +7,11 @@ import (
 
 	"github.com/flimzy/kivik"
 	"github.com/flimzy/kivik/authdb"
+	"github.com/flimzy/kivik/config"
 	_ "github.com/flimzy/kivik/driver/couchdb"
+	_ "github.com/flimzy/kivik/driver/memory"
 	"github.com/flimzy/kivik/errors"
+	"github.com/flimzy/kivik/serve/config/memconf"
 	"github.com/flimzy/kivik/test/kt"
 )
 
@@ -79,3 +82,75 @@ func TestCouchAuth(t *testing.T) {
 		t.Errorf("Expected Not Found fetching roles for bad username.%s", msg)
 	}
 }
+
+// memoryUsersDB returns a memory database containing a user, testUsersdb,
+// whose password hash was created according to policy.
+func memoryUsersDB(t *testing.T, policy *authdb.Policy) *kivik.DB {
+	client, err := kivik.New("memory", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = client.CreateDB("users"); err != nil {
+		t.Fatal(err)
+	}
+	db, err := client.DB("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewManager(db)
+	m.Policy = policy
+	if _, err = m.CreateUser(kt.CTX, &authdb.UserContext{Roles: []string{"_admin"}}, "testUsersdb", "abc123", nil); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestUpgradeHash(t *testing.T) {
+	conf := config.New(memconf.New())
+	conf.Set("couch_httpd_auth", "pbkdf2_prf", "sha256")
+	conf.Set("couch_httpd_auth", "iterations", "20")
+	type upgradeTest struct {
+		Name     string
+		Policy   *authdb.Policy
+		Store    func(*kivik.DB) authdb.UserStore
+		Upgraded bool
+	}
+	tests := []upgradeTest{
+		{
+			Name:     "Weaker",
+			Policy:   &authdb.Policy{Iterations: 10},
+			Store:    func(db *kivik.DB) authdb.UserStore { return NewWithConfig(db, conf) },
+			Upgraded: true,
+		},
+		{
+			Name:   "Stronger",
+			Policy: &authdb.Policy{PRF: authdb.PRFSHA256, Iterations: 600},
+			Store:  func(db *kivik.DB) authdb.UserStore { return NewWithConfig(db, conf) },
+		},
+		{
+			Name:   "ReadOnly",
+			Policy: &authdb.Policy{Iterations: 10},
+			Store:  New,
+		},
+	}
+	for _, test := range tests {
+		func(test upgradeTest) {
+			t.Run(test.Name, func(t *testing.T) {
+				db := memoryUsersDB(t, test.Policy)
+				var before, after map[string]interface{}
+				if err := db.Get(testUser.ID, &before, nil); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := test.Store(db).Validate(kt.CTX, "testUsersdb", "abc123"); err != nil {
+					t.Fatalf("Validation failure for good password: %s", err)
+				}
+				if err := db.Get(testUser.ID, &after, nil); err != nil {
+					t.Fatal(err)
+				}
+				if upgraded := before["_rev"] != after["_rev"]; upgraded != test.Upgraded {
+					t.Errorf("Expected upgraded=%t, got %t", test.Upgraded, upgraded)
+				}
+			})
+		}(test)
+	}
+}
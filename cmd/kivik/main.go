@@ -4,21 +4,36 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 
 	"github.com/flimzy/kivik"
+	"github.com/flimzy/kivik/authdb/authgroup"
+	"github.com/flimzy/kivik/authdb/confadmin"
+	"github.com/flimzy/kivik/authdb/htpasswd"
+	"github.com/flimzy/kivik/config"
 	"github.com/flimzy/kivik/driver"
 	_ "github.com/flimzy/kivik/driver/couchdb"
 	_ "github.com/flimzy/kivik/driver/memory"
 	"github.com/flimzy/kivik/driver/proxy"
 	"github.com/flimzy/kivik/logger"
+	"github.com/flimzy/kivik/logger/jsonlog"
 	"github.com/flimzy/kivik/logger/logfile"
 	"github.com/flimzy/kivik/serve"
+	"github.com/flimzy/kivik/serve/config/fileconf"
+	"github.com/flimzy/kivik/serve/config/layered"
+	"github.com/flimzy/kivik/serve/config/memconf"
 	"github.com/flimzy/kivik/test"
 )
 
+// shutdownTimeout is the time allowed for in-flight requests to complete when
+// the server receives SIGTERM or SIGINT.
+const shutdownTimeout = 30 * time.Second
+
 func main() {
 	var verbose bool
 	pflag.BoolVarP(&verbose, "verbose", "v", false, "Verbose output")
@@ -37,8 +52,57 @@ func main() {
 	cmdServe.Flags().StringVarP(&dsn, "dsn", "", "", "Data source name")
 	var logFile string
 	cmdServe.Flags().StringVarP(&logFile, "log", "l", "", "Server log file")
+	var logFormat string
+	cmdServe.Flags().StringVarP(&logFormat, "log-format", "", "couchdb", "Server log format: couchdb or json")
+	var configFile string
+	cmdServe.Flags().StringVarP(&configFile, "config", "", "", "INI file in which to persist configuration changes")
+	var configDir string
+	cmdServe.Flags().StringVarP(&configDir, "config-dir", "", "", "Directory containing default.ini, local.ini and local.d/*.ini")
+	var htpasswdFile, rolesFile string
+	cmdServe.Flags().StringVarP(&htpasswdFile, "htpasswd", "", "", "htpasswd file from which to authenticate users")
+	cmdServe.Flags().StringVarP(&rolesFile, "roles", "", "", "File of user roles, for use with --htpasswd")
 	cmdServe.Run = func(cmd *cobra.Command, args []string) {
 		service := &serve.Service{}
+		// Settings from flags apply to this run only, so they are kept in a
+		// read-only layer above any configuration files.
+		flagConf := memconf.New()
+		if logFile != "" {
+			_ = flagConf.SetContext(context.Background(), "log", "file", logFile)
+		}
+		withFlags := func(name string, conf driver.Config) *config.Config {
+			return config.New(layered.New(
+				&layered.Layer{Name: name, Config: conf},
+				&layered.Layer{Name: "command line", Config: flagConf, ReadOnly: true},
+			))
+		}
+		if configFile != "" {
+			conf, err := fileconf.New(configFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to read config: %s", err)
+				os.Exit(1)
+			}
+			service.SetConfig(withFlags(configFile, conf))
+		}
+		if configDir != "" {
+			if configFile != "" {
+				fmt.Fprintf(os.Stderr, "--config and --config-dir are mutually exclusive")
+				os.Exit(1)
+			}
+			conf, err := layered.Load(configDir)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to read config: %s", err)
+				os.Exit(1)
+			}
+			service.SetConfig(withFlags(configDir, conf))
+		}
+		if htpasswdFile != "" {
+			users, err := htpasswd.New(htpasswdFile, rolesFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to read htpasswd file: %s", err)
+				os.Exit(1)
+			}
+			service.UserStore = authgroup.New(confadmin.New(service.Config()), users)
+		}
 
 		client, err := kivik.New(driverName, dsn)
 		if err != nil {
@@ -50,8 +114,19 @@ func main() {
 			logger.LogWriter
 		}
 		if logFile != "" {
-			log = &logfile.Logger{}
-			service.Config().Set("log", "file", logFile)
+			switch logFormat {
+			case "couchdb":
+				log = &logfile.Logger{}
+			case "json":
+				log = &jsonlog.Logger{}
+			default:
+				fmt.Fprintf(os.Stderr, "Unknown log format: %s", logFormat)
+				os.Exit(1)
+			}
+			if configFile == "" && configDir == "" {
+				// The default configuration is not persisted.
+				_ = service.Config().Set("log", "file", logFile)
+			}
 			service.LogWriter = log
 			kivik.Register("loggingClient", loggingClient{
 				Client:    proxy.NewClient(client),
@@ -66,9 +141,26 @@ func main() {
 		if listenAddr != "" {
 			service.Bind(listenAddr)
 		}
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, syscall.SIGTERM, os.Interrupt)
+		// stopped is closed once shutdown has completed, so that we don't
+		// exit while requests are still being drained.
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+			<-sigs
+			ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer cancel()
+			if err := service.Shutdown(ctx); err != nil {
+				fmt.Fprintf(os.Stderr, "Shutdown failed: %s\n", err)
+			}
+		}()
 		fmt.Printf("Listening on %s\n", listenAddr)
-		fmt.Println(service.Start())
-		os.Exit(1)
+		if err := service.Start(); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		<-stopped
 	}
 
 	cmdTest := &cobra.Command{
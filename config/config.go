@@ -3,8 +3,10 @@ package config
 import (
 	"context"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/flimzy/kivik/driver"
 	"github.com/flimzy/kivik/errors"
@@ -13,11 +15,88 @@ import (
 // Config allows reading and setting CouchDB server configuration.
 type Config struct {
 	driver.Config
+
+	mu          sync.RWMutex
+	subscribers map[int]func(secName, key string)
+	nextSubID   int
+	schema      *Schema
+}
+
+// SetSchema attaches a schema to the configuration. Values are validated
+// against the schema when set, and registered defaults are returned for keys
+// which are not set.
+func (c *Config) SetSchema(schema *Schema) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.schema = schema
+}
+
+// Schema returns the attached schema, or nil.
+func (c *Config) Schema() *Schema {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.schema
+}
+
+// withDefaults adds the schema's default values for secName (or all sections,
+// if empty) to conf, where not already set.
+func (c *Config) withDefaults(conf map[string]map[string]string, secName string) map[string]map[string]string {
+	schema := c.Schema()
+	if schema == nil {
+		return conf
+	}
+	for name, sec := range schema.defaults(secName) {
+		if conf == nil {
+			conf = make(map[string]map[string]string)
+		}
+		if conf[name] == nil {
+			conf[name] = make(map[string]string)
+		}
+		for key, value := range sec {
+			if _, ok := conf[name][key]; !ok {
+				conf[name][key] = value
+			}
+		}
+	}
+	return conf
 }
 
 // New instantiates a new configuration interface.
 func New(conf driver.Config) *Config {
-	return &Config{conf}
+	return &Config{Config: conf}
+}
+
+// Subscribe registers fn to be called whenever a configuration value is
+// changed or deleted through c. Changes made directly to the underlying
+// backend are not detected. fn is called synchronously, after the change has
+// been successfully stored, so it must not block. The returned function
+// cancels the subscription.
+func (c *Config) Subscribe(fn func(secName, key string)) (unsubscribe func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.subscribers == nil {
+		c.subscribers = make(map[int]func(string, string))
+	}
+	id := c.nextSubID
+	c.nextSubID++
+	c.subscribers[id] = fn
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.subscribers, id)
+	}
+}
+
+func (c *Config) notify(secName, key string) {
+	c.mu.RLock()
+	subs := make([]func(string, string), 0, len(c.subscribers))
+	for _, fn := range c.subscribers {
+		subs = append(subs, fn)
+	}
+	c.mu.RUnlock()
+	for _, fn := range subs {
+		fn(secName, key)
+	}
 }
 
 // GetAll calls GetAllContext with a background context.
@@ -25,9 +104,14 @@ func (c *Config) GetAll() (map[string]map[string]string, error) {
 	return c.GetAllContext(context.Background())
 }
 
-// GetAllContext returns the complete server configuration.
+// GetAllContext returns the complete server configuration, including any
+// defaults from the attached schema.
 func (c *Config) GetAllContext(ctx context.Context) (map[string]map[string]string, error) {
-	return c.Config.GetAllContext(ctx)
+	conf, err := c.Config.GetAllContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c.withDefaults(conf, ""), nil
 }
 
 // Set calls SetContext with a background context.
@@ -35,9 +119,19 @@ func (c *Config) Set(secName, key, value string) error {
 	return c.SetContext(context.Background(), secName, key, value)
 }
 
-// SetContext sets the specified configuration option.
+// SetContext sets the specified configuration option. If a schema is
+// attached, invalid values are rejected with a 400 Bad Request error.
 func (c *Config) SetContext(ctx context.Context, secName, key, value string) error {
-	return c.Config.SetContext(ctx, secName, key, value)
+	if schema := c.Schema(); schema != nil {
+		if err := schema.Validate(secName, key, value); err != nil {
+			return err
+		}
+	}
+	if err := c.Config.SetContext(ctx, secName, key, value); err != nil {
+		return err
+	}
+	c.notify(secName, key)
+	return nil
 }
 
 // Delete calls DeleteContext with a background context.
@@ -47,7 +141,11 @@ func (c *Config) Delete(secName, key string) error {
 
 // DeleteContext deletes the specified key from the configuration.
 func (c *Config) DeleteContext(ctx context.Context, secName, key string) error {
-	return c.Config.DeleteContext(ctx, secName, key)
+	if err := c.Config.DeleteContext(ctx, secName, key); err != nil {
+		return err
+	}
+	c.notify(secName, key)
+	return nil
 }
 
 // GetSection calls GetSectionContext with a background context.
@@ -62,7 +160,10 @@ func (c *Config) GetSectionContext(ctx context.Context, secName string) (map[str
 		if errors.StatusCode(err) == http.StatusNotFound {
 			err = nil
 		}
-		return sec, err
+		if err != nil {
+			return nil, err
+		}
+		return c.withDefaults(map[string]map[string]string{secName: sec}, secName)[secName], nil
 	}
 	conf, err := c.GetAllContext(ctx)
 	if err != nil {
@@ -76,14 +177,37 @@ func (c *Config) Get(secName, key string) (string, error) {
 	return c.GetContext(context.Background(), secName, key)
 }
 
-// GetContext retrieves a specific config value.
+// GetContext retrieves a specific config value. If the key is not set, the
+// default from the attached schema is returned, if any.
 func (c *Config) GetContext(ctx context.Context, secName, key string) (string, error) {
+	value, err := c.getContext(ctx, secName, key)
+	if errors.StatusCode(err) == http.StatusNotFound {
+		if schema := c.Schema(); schema != nil {
+			if k, ok := schema.Lookup(secName, key); ok && k.Default != "" {
+				return k.Default, nil
+			}
+		}
+	}
+	return value, err
+}
+
+// getContext returns a value from the backend, ignoring schema defaults.
+func (c *Config) getContext(ctx context.Context, secName, key string) (string, error) {
 	if itemer, ok := c.Config.(driver.ConfigItem); ok {
 		return itemer.GetContext(ctx, secName, key)
 	}
-	sec, err := c.GetSectionContext(ctx, secName)
-	if err != nil {
-		return "", err
+	var sec map[string]string
+	if sectioner, ok := c.Config.(driver.ConfigSection); ok {
+		var err error
+		if sec, err = sectioner.GetSectionContext(ctx, secName); err != nil {
+			return "", err
+		}
+	} else {
+		conf, err := c.Config.GetAllContext(ctx)
+		if err != nil {
+			return "", err
+		}
+		sec = conf[secName]
 	}
 	if value, ok := sec[key]; ok {
 		return value, nil
@@ -91,9 +215,10 @@ func (c *Config) GetContext(ctx context.Context, secName, key string) (string, e
 	return "", errors.Status(http.StatusNotFound, "config key not found")
 }
 
-// IsSet returns true iff the requested key is set.
+// IsSet returns true iff the requested key is explicitly configured. Schema
+// defaults are not considered.
 func (c *Config) IsSet(secName, key string) bool {
-	_, err := c.Get(secName, key)
+	_, err := c.getContext(context.Background(), secName, key)
 	return err == nil
 }
 
@@ -103,16 +228,95 @@ func (c *Config) GetString(secName, key string) string {
 	return value
 }
 
-// GetInt returns the requested value as an int64.
+// GetInt calls GetIntContext with a background context. Zero is returned if
+// the value is unset or is not an integer.
 func (c *Config) GetInt(secName, key string) int64 {
-	value, _ := c.Get(secName, key)
-	i, _ := strconv.ParseInt(value, 10, 64)
+	i, _ := c.GetIntContext(context.Background(), secName, key)
 	return i
 }
 
-// GetBool returns the requested value as a boolean. A value is considered
-// true if it equals "true" (case insensitive).
+// GetIntContext returns the requested value as an int64. A 400 Bad Request
+// error is returned if the value is not an integer.
+func (c *Config) GetIntContext(ctx context.Context, secName, key string) (int64, error) {
+	value, err := c.GetContext(ctx, secName, key)
+	if err != nil {
+		return 0, err
+	}
+	i, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, errors.Statusf(http.StatusBadRequest, "invalid value %q for %s.%s: must be an integer", value, secName, key)
+	}
+	return i, nil
+}
+
+// GetBool calls GetBoolContext with a background context. False is returned
+// if the value is unset or is not a boolean.
 func (c *Config) GetBool(secName, key string) bool {
-	value, _ := c.Get(secName, key)
-	return strings.ToLower(value) == "true"
+	b, _ := c.GetBoolContext(context.Background(), secName, key)
+	return b
+}
+
+// GetBoolContext returns the requested value as a boolean. The value must be
+// "true" or "false" (case insensitive), or a 400 Bad Request error is
+// returned.
+func (c *Config) GetBoolContext(ctx context.Context, secName, key string) (bool, error) {
+	value, err := c.GetContext(ctx, secName, key)
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(value) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	return false, errors.Statusf(http.StatusBadRequest, "invalid value %q for %s.%s: must be true or false", value, secName, key)
+}
+
+// Validate calls ValidateContext with a background context.
+func (c *Config) Validate() error {
+	return c.ValidateContext(context.Background())
+}
+
+// ValidateContext checks every configured value against the attached schema,
+// so that invalid values read from files or the environment can be reported
+// when the configuration is loaded. A 400 Bad Request error listing each
+// invalid value is returned.
+func (c *Config) ValidateContext(ctx context.Context) error {
+	schema := c.Schema()
+	if schema == nil {
+		return nil
+	}
+	conf, err := c.Config.GetAllContext(ctx)
+	if err != nil {
+		return err
+	}
+	var invalid []string
+	for secName, sec := range conf {
+		for key, value := range sec {
+			if err := schema.Validate(secName, key, value); err != nil {
+				invalid = append(invalid, errors.Reason(err))
+			}
+		}
+	}
+	if len(invalid) == 0 {
+		return nil
+	}
+	sort.Strings(invalid)
+	return errors.Status(http.StatusBadRequest, strings.Join(invalid, "; "))
+}
+
+// Origins calls OriginsContext with a background context.
+func (c *Config) Origins() (map[string]map[string]string, error) {
+	return c.OriginsContext(context.Background())
+}
+
+// OriginsContext returns the source of each configuration value, in the same
+// structure as GetAllContext. A Not Implemented error is returned if the
+// backend does not support reporting origins.
+func (c *Config) OriginsContext(ctx context.Context) (map[string]map[string]string, error) {
+	if originer, ok := c.Config.(driver.ConfigOriginer); ok {
+		return originer.OriginsContext(ctx)
+	}
+	return nil, errors.Status(http.StatusNotImplemented, "configuration backend does not report origins")
 }
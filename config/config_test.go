@@ -144,3 +144,19 @@ func TestFullConfiger(t *testing.T) {
 		t.Errorf("Full Configer log differs:\n%s\n", d)
 	}
 }
+
+func TestSubscribe(t *testing.T) {
+	c := &Config{Config: &testMinConfig{}}
+	var changes []string
+	unsubscribe := c.Subscribe(func(secName, key string) {
+		changes = append(changes, fmt.Sprintf("%s.%s", secName, key))
+	})
+	_ = c.Set("fruit", "apple", "green")
+	_ = c.Delete("fruit", "banana")
+	unsubscribe()
+	_ = c.Set("fruit", "cherry", "red")
+	expected := []string{"fruit.apple", "fruit.banana"}
+	if d := diff.TextSlices(expected, changes); d != "" {
+		t.Errorf("Unexpected notifications:\n%s\n", d)
+	}
+}
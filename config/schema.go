@@ -0,0 +1,191 @@
+package config
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/flimzy/kivik/errors"
+)
+
+// Type is the type of a configuration value.
+type Type string
+
+// Configuration value types.
+const (
+	TypeString Type = "string"
+	TypeInt    Type = "int"
+	TypeFloat  Type = "float"
+	TypeBool   Type = "bool"
+)
+
+// Key describes a configuration key.
+type Key struct {
+	Section string `json:"section"`
+	Name    string `json:"name"`
+	Type    Type   `json:"type"`
+	// Default is returned when the key is not set.
+	Default string `json:"default,omitempty"`
+	// Min and Max, if Min < Max, are the inclusive bounds of numeric values.
+	Min float64 `json:"min,omitempty"`
+	Max float64 `json:"max,omitempty"`
+	// Values, if set, is the list of permitted values, which are compared
+	// case-insensitively.
+	Values      []string `json:"values,omitempty"`
+	Description string   `json:"description,omitempty"`
+}
+
+// Validate returns a 400 Bad Request error if value is not valid for the key.
+func (k *Key) Validate(value string) error {
+	var num float64
+	switch k.Type {
+	case TypeInt:
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return k.invalid(value, "an integer")
+		}
+		num = float64(i)
+	case TypeFloat:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return k.invalid(value, "a number")
+		}
+		num = f
+	case TypeBool:
+		if v := strings.ToLower(value); v != "true" && v != "false" {
+			return k.invalid(value, "true or false")
+		}
+	}
+	if k.Min < k.Max && (num < k.Min || num > k.Max) {
+		return k.invalid(value, "between "+formatFloat(k.Min)+" and "+formatFloat(k.Max))
+	}
+	if len(k.Values) > 0 {
+		for _, v := range k.Values {
+			if strings.EqualFold(v, value) {
+				return nil
+			}
+		}
+		return k.invalid(value, "one of "+strings.Join(k.Values, ", "))
+	}
+	return nil
+}
+
+func (k *Key) invalid(value, want string) error {
+	return errors.Statusf(http.StatusBadRequest, "invalid value %q for %s.%s: must be %s", value, k.Section, k.Name, want)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// Schema is a registry of configuration keys.
+type Schema struct {
+	mu   sync.RWMutex
+	keys map[string]map[string]*Key
+}
+
+// NewSchema returns a new, empty schema.
+func NewSchema() *Schema {
+	return &Schema{keys: make(map[string]map[string]*Key)}
+}
+
+// DefaultSchema is the schema to which Register adds keys. Subsystems
+// register the keys they use from init functions.
+var DefaultSchema = NewSchema()
+
+// Register adds keys to DefaultSchema.
+func Register(keys ...*Key) {
+	DefaultSchema.Register(keys...)
+}
+
+// Register adds keys to the schema, replacing any previous definitions.
+// It panics if a key has no section or name, or if a key's default value is
+// not valid.
+func (s *Schema) Register(keys ...*Key) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, k := range keys {
+		if k.Section == "" || k.Name == "" {
+			panic("config: key section and name are required")
+		}
+		if k.Type == "" {
+			k.Type = TypeString
+		}
+		if k.Default != "" {
+			if err := k.Validate(k.Default); err != nil {
+				panic("config: " + err.Error())
+			}
+		}
+		if _, ok := s.keys[k.Section]; !ok {
+			s.keys[k.Section] = make(map[string]*Key)
+		}
+		s.keys[k.Section][k.Name] = k
+	}
+}
+
+// Lookup returns the definition of a key, if registered.
+func (s *Schema) Lookup(secName, key string) (*Key, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	k, ok := s.keys[secName][key]
+	return k, ok
+}
+
+// Validate checks value against the key's definition. Unregistered keys are
+// always valid.
+func (s *Schema) Validate(secName, key, value string) error {
+	if k, ok := s.Lookup(secName, key); ok {
+		return k.Validate(value)
+	}
+	return nil
+}
+
+// Keys returns all registered keys, sorted by section and name.
+func (s *Schema) Keys() []*Key {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var keys []*Key
+	for _, sec := range s.keys {
+		for _, k := range sec {
+			keys = append(keys, k)
+		}
+	}
+	sort.Sort(keyList(keys))
+	return keys
+}
+
+// defaults returns the default values of the keys in secName, or of all
+// sections if secName is empty.
+func (s *Schema) defaults(secName string) map[string]map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	defaults := make(map[string]map[string]string)
+	for name, sec := range s.keys {
+		if secName != "" && name != secName {
+			continue
+		}
+		for _, k := range sec {
+			if k.Default == "" {
+				continue
+			}
+			if _, ok := defaults[name]; !ok {
+				defaults[name] = make(map[string]string)
+			}
+			defaults[name][k.Name] = k.Default
+		}
+	}
+	return defaults
+}
+
+type keyList []*Key
+
+func (l keyList) Len() int      { return len(l) }
+func (l keyList) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
+func (l keyList) Less(i, j int) bool {
+	if l[i].Section != l[j].Section {
+		return l[i].Section < l[j].Section
+	}
+	return l[i].Name < l[j].Name
+}
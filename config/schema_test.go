@@ -0,0 +1,202 @@
+package config
+
+import (
+	"context"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/kivik/errors"
+)
+
+func testSchema() *Schema {
+	s := NewSchema()
+	s.Register(
+		&Key{Section: "httpd", Name: "port", Type: TypeInt, Min: 0, Max: 65535, Default: "5984"},
+		&Key{Section: "httpd", Name: "enable_cors", Type: TypeBool},
+		&Key{Section: "log", Name: "level", Default: "info", Values: []string{"debug", "info", "error"}},
+		&Key{Section: "stats", Name: "rate", Type: TypeFloat, Min: 0, Max: 1},
+	)
+	return s
+}
+
+func TestKeyValidate(t *testing.T) {
+	type vTest struct {
+		name     string
+		secName  string
+		key      string
+		value    string
+		expected string
+	}
+	tests := []vTest{
+		{name: "ValidInt", secName: "httpd", key: "port", value: "5984"},
+		{name: "NotInt", secName: "httpd", key: "port", value: "abc",
+			expected: `400 invalid value "abc" for httpd.port: must be an integer`},
+		{name: "OutOfRange", secName: "httpd", key: "port", value: "70000",
+			expected: `400 invalid value "70000" for httpd.port: must be between 0 and 65535`},
+		{name: "ValidBool", secName: "httpd", key: "enable_cors", value: "TRUE"},
+		{name: "InvalidBool", secName: "httpd", key: "enable_cors", value: "yes",
+			expected: `400 invalid value "yes" for httpd.enable_cors: must be true or false`},
+		{name: "ValidEnum", secName: "log", key: "level", value: "Debug"},
+		{name: "InvalidEnum", secName: "log", key: "level", value: "verbose",
+			expected: `400 invalid value "verbose" for log.level: must be one of debug, info, error`},
+		{name: "ValidFloat", secName: "stats", key: "rate", value: "0.5"},
+		{name: "InvalidFloat", secName: "stats", key: "rate", value: "1.5",
+			expected: `400 invalid value "1.5" for stats.rate: must be between 0 and 1`},
+		{name: "Unregistered", secName: "foo", key: "bar", value: "anything"},
+	}
+	schema := testSchema()
+	for _, test := range tests {
+		func(test vTest) {
+			t.Run(test.name, func(t *testing.T) {
+				var msg string
+				if err := schema.Validate(test.secName, test.key, test.value); err != nil {
+					msg = err.Error()
+				}
+				if msg != test.expected {
+					t.Errorf("Unexpected error: %s", msg)
+				}
+			})
+		}(test)
+	}
+}
+
+func TestRegisterInvalidDefault(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("Expected panic for invalid default")
+		}
+	}()
+	NewSchema().Register(&Key{Section: "a", Name: "b", Type: TypeInt, Default: "x"})
+}
+
+func TestSchemaKeys(t *testing.T) {
+	var names []string
+	for _, k := range testSchema().Keys() {
+		names = append(names, k.Section+"."+k.Name)
+	}
+	expected := []string{"httpd.enable_cors", "httpd.port", "log.level", "stats.rate"}
+	if d := diff.AsJSON(expected, names); d != "" {
+		t.Error(d)
+	}
+}
+
+func TestConfigSchema(t *testing.T) {
+	tc := &testMinConfig{}
+	c := New(tc)
+	c.SetSchema(testSchema())
+	ctx := context.Background()
+
+	if err := c.SetContext(ctx, "httpd", "port", "http"); errors.StatusCode(err) != 400 {
+		t.Errorf("Expected Bad Request, got %v", err)
+	}
+	if err := c.SetContext(ctx, "httpd", "port", "5985"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if d := diff.AsJSON([]string{"Set(httpd,port,5985)"}, tc.log); d != "" {
+		t.Errorf("Invalid value should not be stored:\n%s", d)
+	}
+
+	all, err := c.GetAllContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]map[string]string{
+		"fruit": {"apple": "red"},
+		"httpd": {"port": "5984"},
+		"log":   {"level": "info"},
+	}
+	if d := diff.AsJSON(expected, all); d != "" {
+		t.Errorf("Unexpected config:\n%s\n", d)
+	}
+	if value, _ := c.GetContext(ctx, "log", "level"); value != "info" {
+		t.Errorf("Expected default value, got %s", value)
+	}
+	if _, err := c.GetContext(ctx, "httpd", "enable_cors"); errors.StatusCode(err) != 404 {
+		t.Errorf("Expected Not Found for key without default, got %v", err)
+	}
+	sec, err := c.GetSectionContext(ctx, "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := diff.AsJSON(map[string]string{"level": "info"}, sec); d != "" {
+		t.Errorf("Unexpected section:\n%s\n", d)
+	}
+}
+
+// mapConfig is a minimal backend whose values are not validated, as when they
+// are read from a file or the environment.
+type mapConfig map[string]map[string]string
+
+func (c mapConfig) GetAllContext(_ context.Context) (map[string]map[string]string, error) {
+	return c, nil
+}
+
+func (c mapConfig) SetContext(_ context.Context, secName, key, value string) error {
+	if _, ok := c[secName]; !ok {
+		c[secName] = make(map[string]string)
+	}
+	c[secName][key] = value
+	return nil
+}
+
+func (c mapConfig) DeleteContext(_ context.Context, secName, key string) error {
+	delete(c[secName], key)
+	return nil
+}
+
+func TestConfigValidate(t *testing.T) {
+	backend := mapConfig{
+		"httpd": {"port": "http", "enable_cors": "yes"},
+		"log":   {"level": "debug"},
+		"foo":   {"bar": "anything"},
+	}
+	c := New(backend)
+	c.SetSchema(testSchema())
+	ctx := context.Background()
+
+	err := c.ValidateContext(ctx)
+	if errors.StatusCode(err) != 400 {
+		t.Fatalf("Expected Bad Request, got %v", err)
+	}
+	expected := `400 invalid value "http" for httpd.port: must be an integer; invalid value "yes" for httpd.enable_cors: must be true or false`
+	if err.Error() != expected {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if _, err := c.GetIntContext(ctx, "httpd", "port"); errors.StatusCode(err) != 400 {
+		t.Errorf("Expected Bad Request for invalid int, got %v", err)
+	}
+	if port := c.GetInt("httpd", "port"); port != 0 {
+		t.Errorf("Expected 0 for invalid int, got %d", port)
+	}
+	if _, err := c.GetBoolContext(ctx, "httpd", "enable_cors"); errors.StatusCode(err) != 400 {
+		t.Errorf("Expected Bad Request for invalid bool, got %v", err)
+	}
+
+	backend["httpd"] = map[string]string{"enable_cors": "TRUE"}
+	if err := c.ValidateContext(ctx); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if port, err := c.GetIntContext(ctx, "httpd", "port"); err != nil || port != 5984 {
+		t.Errorf("Expected default port, got %d (%v)", port, err)
+	}
+	if cors, err := c.GetBoolContext(ctx, "httpd", "enable_cors"); err != nil || !cors {
+		t.Errorf("Expected enable_cors to be true, got %t (%v)", cors, err)
+	}
+}
+
+func TestIsSet(t *testing.T) {
+	c := New(mapConfig{})
+	c.SetSchema(testSchema())
+	if c.IsSet("log", "level") {
+		t.Errorf("A schema default should not be reported as set")
+	}
+	if value := c.GetString("log", "level"); value != "info" {
+		t.Errorf("Expected default value, got %s", value)
+	}
+	if err := c.Set("log", "level", "debug"); err != nil {
+		t.Fatal(err)
+	}
+	if !c.IsSet("log", "level") {
+		t.Errorf("An explicit value should be reported as set")
+	}
+}
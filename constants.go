@@ -39,9 +39,11 @@ const (
 	StatusResourceNotAllowed           = 405
 	StatusConflict                     = 409
 	StatusPreconditionFailed           = 412
+	StatusRequestEntityTooLarge        = 413
 	StatusBadContentType               = 415
 	StatusRequestedRangeNotSatisfiable = 416
 	StatusExpectationFailed            = 417
+	StatusTooManyRequests              = 429
 	StatusInternalServerError          = 500
 	StatusNotImplemented               = 501
 )
@@ -201,6 +201,9 @@ func (db *DB) SecurityContext(ctx context.Context) (*Security, error) {
 	if err != nil {
 		return nil, err
 	}
+	if s == nil {
+		return &Security{}, nil
+	}
 	return &Security{
 		Admins:  Members(s.Admins),
 		Members: Members(s.Members),
@@ -274,6 +277,9 @@ func (db *DB) Changes(options Options) (*Rows, error) {
 
 // ChangesContext returns an iterator over the real-time changes feed. The
 // feed remains open until explicitly closed, or an error is encountered.
+// With the couchdb driver, the feed defaults to feed=continuous and
+// since=now; both may be overridden by options, in which case the normal and
+// longpoll feeds end once caught up.
 // See http://couchdb.readthedocs.io/en/latest/api/database/changes.html#get--db-_changes
 func (db *DB) ChangesContext(ctx context.Context, options Options) (*Rows, error) {
 	rowsi, err := db.driverDB.ChangesContext(ctx, options)
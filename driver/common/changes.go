@@ -0,0 +1,131 @@
+package common
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/flimzy/kivik/driver"
+	"github.com/flimzy/kivik/errors"
+)
+
+// Change returns the document's entry in a changes feed. With allDocs, all
+// leaf revisions are reported, as for style=all_docs, rather than only the
+// winning revision.
+func (doc *Document) Change(docID string, allDocs bool) driver.Row {
+	winner := doc.Winner()
+	row := driver.Row{
+		ID:      docID,
+		Seq:     driver.SequenceID(strconv.FormatInt(doc.Seq, 10)),
+		Deleted: winner.Deleted,
+		Changes: driver.Changes{winner.Rev},
+	}
+	if allDocs {
+		row.Changes = row.Changes[:0]
+		for _, leaf := range doc.Leaves() {
+			row.Changes = append(row.Changes, leaf.Rev)
+		}
+		sort.Strings(row.Changes)
+	}
+	return row
+}
+
+// SortChanges sorts changes by their numeric sequence.
+func SortChanges(changes []driver.Row) {
+	sort.Sort(changeList(changes))
+}
+
+type changeList []driver.Row
+
+func (c changeList) Len() int      { return len(c) }
+func (c changeList) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
+func (c changeList) Less(i, j int) bool {
+	a, _ := strconv.ParseInt(string(c[i].Seq), 10, 64)
+	b, _ := strconv.ParseInt(string(c[j].Seq), 10, 64)
+	return a < b
+}
+
+// SinceOption parses the since option of a changes feed, which may be a
+// number, or "now", in which case now is returned.
+func SinceOption(since interface{}, now int64) (int64, error) {
+	switch v := since.(type) {
+	case nil:
+		return 0, nil
+	case int:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case float64:
+		return int64(v), nil
+	case string:
+		if v == "now" {
+			return now, nil
+		}
+		seq, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return 0, errors.Status(http.StatusBadRequest, "invalid since value")
+		}
+		return seq, nil
+	}
+	return 0, errors.Status(http.StatusBadRequest, "invalid since value")
+}
+
+// continuousChanges is a continuous changes feed.
+type continuousChanges struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	since  int64
+	poll   func(since int64) ([]driver.Row, error)
+	wait   func(ctx context.Context, since int64)
+	rows   []driver.Row
+}
+
+var _ driver.Rows = &continuousChanges{}
+
+// ContinuousChanges returns a continuous changes feed, starting after since.
+// poll returns the changes after a sequence, in order, and wait blocks until
+// there may be changes after a sequence, or ctx is cancelled. The feed ends
+// when ctx is cancelled, or the feed is closed.
+func ContinuousChanges(ctx context.Context, since int64, poll func(since int64) ([]driver.Row, error), wait func(ctx context.Context, since int64)) driver.Rows {
+	ctx, cancel := context.WithCancel(ctx)
+	return &continuousChanges{
+		ctx:    ctx,
+		cancel: cancel,
+		since:  since,
+		poll:   poll,
+		wait:   wait,
+	}
+}
+
+func (c *continuousChanges) Offset() int64     { return 0 }
+func (c *continuousChanges) TotalRows() int64  { return 0 }
+func (c *continuousChanges) UpdateSeq() string { return "" }
+
+func (c *continuousChanges) Close() error {
+	c.cancel()
+	return nil
+}
+
+func (c *continuousChanges) Next(row *driver.Row) error {
+	for len(c.rows) == 0 {
+		if c.ctx.Err() != nil {
+			return io.EOF
+		}
+		rows, err := c.poll(c.since)
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			c.wait(c.ctx, c.since)
+			continue
+		}
+		c.rows = rows
+	}
+	*row, c.rows = c.rows[0], c.rows[1:]
+	if seq, err := strconv.ParseInt(string(row.Seq), 10, 64); err == nil {
+		c.since = seq
+	}
+	return nil
+}
@@ -0,0 +1,348 @@
+package common
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/flimzy/kivik/errors"
+)
+
+// Document is the revision tree of a document. Revisions whose bodies are
+// not stored, such as the ancestors of replicated revisions, are only
+// recorded in the history of their descendants.
+type Document struct {
+	Revs map[string]*Revision `json:"revs"`
+	// Seq is the update sequence of the document's most recent change.
+	Seq int64 `json:"seq"`
+}
+
+// Revision is a stored revision of a document.
+type Revision struct {
+	ID      string                 `json:"id"`
+	Rev     string                 `json:"rev"`
+	Deleted bool                   `json:"deleted,omitempty"`
+	Data    map[string]interface{} `json:"data"`
+	// History lists the revision and its ancestors, most recent first.
+	History []string `json:"history"`
+}
+
+// RevGeneration returns the generation of a revision, or 0 if rev is not a
+// valid revision.
+func RevGeneration(rev string) int {
+	gen, _ := strconv.Atoi(strings.SplitN(rev, "-", 2)[0])
+	return gen
+}
+
+// newRevision returns a revision ID for data, descending from parent, which
+// may be empty.
+func newRevision(parent string, data map[string]interface{}) (string, error) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	sum := md5.Sum(append([]byte(parent), body...))
+	return fmt.Sprintf("%d-%s", RevGeneration(parent)+1, hex.EncodeToString(sum[:])), nil
+}
+
+// Leaves returns the revisions of the document which have no descendants.
+func (doc *Document) Leaves() []*Revision {
+	parents := make(map[string]bool)
+	for _, rev := range doc.Revs {
+		for _, ancestor := range rev.History[1:] {
+			parents[ancestor] = true
+		}
+	}
+	var leaves []*Revision
+	for id, rev := range doc.Revs {
+		if !parents[id] {
+			leaves = append(leaves, rev)
+		}
+	}
+	return leaves
+}
+
+// Winner returns the winning revision, as chosen by CouchDB: the
+// non-deleted leaf with the highest generation, with ties broken by revision
+// ID.
+func (doc *Document) Winner() *Revision {
+	var winner *Revision
+	for _, rev := range doc.Leaves() {
+		if winner == nil || revWins(rev, winner) {
+			winner = rev
+		}
+	}
+	return winner
+}
+
+func revWins(a, b *Revision) bool {
+	if a.Deleted != b.Deleted {
+		return !a.Deleted
+	}
+	if ga, gb := RevGeneration(a.Rev), RevGeneration(b.Rev); ga != gb {
+		return ga > gb
+	}
+	return a.Rev > b.Rev
+}
+
+// Known returns true if rev is in the document's revision tree.
+func (doc *Document) Known(rev string) bool {
+	if _, ok := doc.Revs[rev]; ok {
+		return true
+	}
+	for _, r := range doc.Revs {
+		for _, ancestor := range r.History {
+			if ancestor == rev {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Add stores rev in the document, truncating its history to revsLimit
+// entries, and records seq as the document's update sequence.
+func (doc *Document) Add(rev *Revision, revsLimit int, seq int64) {
+	if doc.Revs == nil {
+		doc.Revs = make(map[string]*Revision)
+	}
+	if len(rev.History) > revsLimit {
+		rev.History = rev.History[:revsLimit]
+	}
+	doc.Revs[rev.Rev] = rev
+	doc.Seq = seq
+}
+
+// split separates the special fields of a document from its body.
+func split(doc map[string]interface{}) (id, rev string, deleted bool, history []string, data map[string]interface{}) {
+	data = make(map[string]interface{}, len(doc))
+	for key, value := range doc {
+		switch key {
+		case "_id":
+			id, _ = value.(string)
+		case "_rev":
+			rev, _ = value.(string)
+		case "_deleted":
+			deleted, _ = value.(bool)
+		case "_revisions":
+			history = parseRevisions(value)
+		default:
+			data[key] = value
+		}
+	}
+	return id, rev, deleted, history, data
+}
+
+// parseRevisions converts a _revisions object into a list of revisions,
+// most recent first.
+func parseRevisions(value interface{}) []string {
+	revisions, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	start, _ := revisions["start"].(float64)
+	ids, _ := revisions["ids"].([]interface{})
+	history := make([]string, 0, len(ids))
+	for i, id := range ids {
+		hash, _ := id.(string)
+		history = append(history, fmt.Sprintf("%d-%s", int(start)-i, hash))
+	}
+	return history
+}
+
+// NewEdit returns a new revision of a document, as a child of the leaf
+// revision given in body's _rev. existing is the current revision tree, or
+// nil for a new document.
+func NewEdit(existing *Document, docID string, body map[string]interface{}) (*Revision, error) {
+	_, parent, deleted, _, data := split(body)
+	var history []string
+	if existing != nil {
+		if parent == "" {
+			if winner := existing.Winner(); winner.Deleted {
+				parent = winner.Rev
+			}
+		}
+		if parent == "" {
+			return nil, errors.Status(http.StatusConflict, "Document update conflict.")
+		}
+		leaf := false
+		for _, rev := range existing.Leaves() {
+			if rev.Rev == parent {
+				leaf, history = true, rev.History
+			}
+		}
+		if !leaf {
+			return nil, errors.Status(http.StatusConflict, "Document update conflict.")
+		}
+	} else if parent != "" {
+		return nil, errors.Status(http.StatusConflict, "Document update conflict.")
+	}
+	rev, err := newRevision(parent, data)
+	if err != nil {
+		return nil, err
+	}
+	return &Revision{
+		Data:    data,
+		ID:      docID,
+		Rev:     rev,
+		Deleted: deleted,
+		History: append([]string{rev}, history...),
+	}, nil
+}
+
+// Replicated returns the revision of a document given in body, with its
+// history from _revisions, as CouchDB stores it with new_edits=false. nil is
+// returned if existing already contains the revision.
+func Replicated(existing *Document, body map[string]interface{}) (*Revision, error) {
+	docID, rev, deleted, history, data := split(body)
+	if docID == "" || RevGeneration(rev) == 0 {
+		return nil, errors.Status(http.StatusBadRequest, "Invalid rev format")
+	}
+	if len(history) == 0 || history[0] != rev {
+		history = []string{rev}
+	}
+	if existing != nil {
+		if _, ok := existing.Revs[rev]; ok {
+			return nil, nil
+		}
+	}
+	return &Revision{
+		Data:    data,
+		ID:      docID,
+		Rev:     rev,
+		Deleted: deleted,
+		History: history,
+	}, nil
+}
+
+// PutLocal returns the new value of a _local document, which is not
+// replicated, and has no revision history. old is the current value, or nil.
+// A nil document is returned if body deletes the document.
+func PutLocal(old map[string]interface{}, docID string, body map[string]interface{}) (doc map[string]interface{}, rev string, err error) {
+	_, rev, deleted, _, data := split(body)
+	gen := 0
+	if old != nil {
+		if oldRev, _ := old["_rev"].(string); rev != oldRev {
+			return nil, "", errors.Status(http.StatusConflict, "Document update conflict.")
+		}
+		gen, _ = strconv.Atoi(strings.TrimPrefix(rev, "0-"))
+	}
+	if deleted {
+		return nil, "0-0", nil
+	}
+	newRev := "0-" + strconv.Itoa(gen+1)
+	data["_id"] = docID
+	data["_rev"] = newRev
+	return data, newRev, nil
+}
+
+// Body returns the revision as a document, including its special fields.
+func (r *Revision) Body(revs bool) map[string]interface{} {
+	doc := make(map[string]interface{}, len(r.Data)+4)
+	for key, value := range r.Data {
+		doc[key] = value
+	}
+	doc["_id"] = r.ID
+	doc["_rev"] = r.Rev
+	if r.Deleted {
+		doc["_deleted"] = true
+	}
+	if revs {
+		ids := make([]string, len(r.History))
+		for i, rev := range r.History {
+			ids[i] = strings.SplitN(rev, "-", 2)[1]
+		}
+		doc["_revisions"] = map[string]interface{}{
+			"start": RevGeneration(r.Rev),
+			"ids":   ids,
+		}
+	}
+	return doc
+}
+
+// OpenRevs returns the requested revisions in the format of CouchDB's
+// open_revs option, which is either "all", for all leaf revisions, or a JSON
+// array of revisions.
+func (doc *Document) OpenRevs(value interface{}, revs bool) ([]map[string]interface{}, error) {
+	var ids []string
+	switch v := value.(type) {
+	case []string:
+		ids = v
+	case string:
+		if v == "all" {
+			for _, leaf := range doc.Leaves() {
+				ids = append(ids, leaf.Rev)
+			}
+			break
+		}
+		if err := json.Unmarshal([]byte(v), &ids); err != nil {
+			return nil, errors.WrapStatus(http.StatusBadRequest, err)
+		}
+	default:
+		return nil, errors.Status(http.StatusBadRequest, "invalid open_revs")
+	}
+	results := make([]map[string]interface{}, len(ids))
+	for i, id := range ids {
+		if rev, ok := doc.Revs[id]; ok {
+			results[i] = map[string]interface{}{"ok": rev.Body(revs)}
+		} else {
+			results[i] = map[string]interface{}{"missing": id}
+		}
+	}
+	return results, nil
+}
+
+// Get returns the revision requested by the rev, revs and open_revs options,
+// as a document, or, for open_revs, as a list of results.
+func (doc *Document) Get(opts map[string]interface{}) (interface{}, error) {
+	revs := boolOption(opts, "revs")
+	if openRevs, ok := opts["open_revs"]; ok {
+		return doc.OpenRevs(openRevs, revs)
+	}
+	var rev *Revision
+	if id, _ := opts["rev"].(string); id != "" {
+		if rev = doc.Revs[id]; rev == nil {
+			return nil, errors.Status(http.StatusNotFound, "missing")
+		}
+	} else if rev = doc.Winner(); rev.Deleted {
+		return nil, errors.Status(http.StatusNotFound, "deleted")
+	}
+	return rev.Body(revs), nil
+}
+
+// boolOption returns true if the option is set to true, or the string "true".
+func boolOption(opts map[string]interface{}, key string) bool {
+	switch v := opts[key].(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
+
+// ToMap converts a document to a map, as it would be stored as JSON.
+func ToMap(doc interface{}) (map[string]interface{}, error) {
+	body, err := json.Marshal(doc)
+	if err != nil {
+		return nil, errors.WrapStatus(http.StatusBadRequest, err)
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, errors.WrapStatus(http.StatusBadRequest, err)
+	}
+	return result, nil
+}
+
+// Decode copies a stored value into dst, as though it had been read as JSON.
+func Decode(src, dst interface{}) error {
+	body, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, dst)
+}
@@ -0,0 +1,55 @@
+package common
+
+import (
+	"io"
+
+	"github.com/flimzy/kivik/driver"
+)
+
+// Rows iterates over a result set built in memory.
+type Rows struct {
+	Rows []driver.Row
+	Seq  string
+}
+
+var _ driver.Rows = &Rows{}
+
+// Offset returns 0.
+func (r *Rows) Offset() int64 { return 0 }
+
+// TotalRows returns 0.
+func (r *Rows) TotalRows() int64 { return 0 }
+
+// UpdateSeq returns Seq.
+func (r *Rows) UpdateSeq() string { return r.Seq }
+
+// Close does nothing.
+func (r *Rows) Close() error { return nil }
+
+// Next returns the next row, or io.EOF once all rows have been read.
+func (r *Rows) Next(row *driver.Row) error {
+	if len(r.Rows) == 0 {
+		return io.EOF
+	}
+	*row, r.Rows = r.Rows[0], r.Rows[1:]
+	return nil
+}
+
+// BulkResults iterates over bulk update results built in memory.
+type BulkResults struct {
+	Results []driver.BulkResult
+}
+
+var _ driver.BulkResults = &BulkResults{}
+
+// Close does nothing.
+func (r *BulkResults) Close() error { return nil }
+
+// Next returns the next result, or io.EOF once all results have been read.
+func (r *BulkResults) Next(result *driver.BulkResult) error {
+	if len(r.Results) == 0 {
+		return io.EOF
+	}
+	*result, r.Results = r.Results[0], r.Results[1:]
+	return nil
+}
@@ -0,0 +1,31 @@
+package couchdb
+
+import (
+	"context"
+
+	"github.com/flimzy/kivik"
+	"github.com/flimzy/kivik/driver"
+)
+
+// activeTask decodes sequence IDs, which are integers in CouchDB 1.x, and
+// strings in 2.x.
+type activeTask struct {
+	driver.ActiveTask
+	SourceSeq             driver.SequenceID `json:"source_seq"`
+	CheckpointedSourceSeq driver.SequenceID `json:"checkpointed_source_seq"`
+}
+
+// ActiveTasksContext returns the tasks running on the server.
+func (c *client) ActiveTasksContext(ctx context.Context) ([]driver.ActiveTask, error) {
+	var result []activeTask
+	if _, err := c.DoJSON(ctx, kivik.MethodGet, "/_active_tasks", nil, &result); err != nil {
+		return nil, err
+	}
+	tasks := make([]driver.ActiveTask, len(result))
+	for i, task := range result {
+		tasks[i] = task.ActiveTask
+		tasks[i].SourceSeq = string(task.SourceSeq)
+		tasks[i].CheckpointedSourceSeq = string(task.CheckpointedSourceSeq)
+	}
+	return tasks, nil
+}
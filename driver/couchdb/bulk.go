@@ -18,6 +18,7 @@ type bulkResults struct {
 }
 
 var _ driver.BulkResults = &bulkResults{}
+var _ driver.BulkDocsOptioner = &db{}
 
 func (r *bulkResults) Next(update *driver.BulkResult) error {
 	if !r.dec.More() {
@@ -59,12 +60,23 @@ func (r *bulkResults) Close() error {
 }
 
 func (d *db) BulkDocsContext(ctx context.Context, docs ...interface{}) (driver.BulkResults, error) {
+	return d.BulkDocsOptsContext(ctx, nil, docs...)
+}
+
+// BulkDocsOptsContext performs a bulk update, passing options, such as
+// new_edits, in the request body.
+func (d *db) BulkDocsOptsContext(ctx context.Context, options map[string]interface{}, docs ...interface{}) (driver.BulkResults, error) {
+	body := make(map[string]interface{}, len(options)+1)
+	for key, value := range options {
+		body[key] = value
+	}
+	body["docs"] = docs
 	var jsonErr error
 	var cancel context.CancelFunc
 	ctx, cancel = context.WithCancel(ctx)
 	defer cancel()
 	opts := &chttp.Options{
-		Body:        chttp.EncodeBody(map[string]interface{}{"docs": docs}, &jsonErr, cancel),
+		Body:        chttp.EncodeBody(body, &jsonErr, cancel),
 		ForceCommit: d.forceCommit,
 	}
 	resp, err := d.Client.DoReq(ctx, kivik.MethodPost, d.path("_bulk_docs", nil), opts)
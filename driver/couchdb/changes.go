@@ -10,14 +10,20 @@ import (
 	"github.com/flimzy/kivik/driver/couchdb/chttp"
 )
 
-// ChangesContext returns the changes stream for the database.
+// ChangesContext returns the changes stream for the database. By default,
+// the continuous feed is followed from the current update sequence, but the
+// feed and since options may be set to override this. The normal and longpoll
+// feeds return once caught up.
 func (d *db) ChangesContext(ctx context.Context, opts map[string]interface{}) (driver.Rows, error) {
-	overrideOpts := map[string]interface{}{
+	allOpts := map[string]interface{}{
 		"feed":      "continuous",
 		"since":     "now",
 		"heartbeat": 6000,
 	}
-	options, err := optionsToParams(opts, overrideOpts)
+	for key, value := range opts {
+		allOpts[key] = value
+	}
+	options, err := optionsToParams(allOpts)
 	if err != nil {
 		return nil, err
 	}
@@ -28,13 +34,17 @@ func (d *db) ChangesContext(ctx context.Context, opts map[string]interface{}) (d
 	if err = chttp.ResponseError(resp); err != nil {
 		return nil, err
 	}
+	if feed := options.Get("feed"); feed == "normal" || feed == "longpoll" {
+		return newRows(resp.Body), nil
+	}
 	return newChangesRows(resp.Body), nil
 }
 
 type changesRows struct {
-	body   io.ReadCloser
-	dec    *json.Decoder
-	closed bool
+	body    io.ReadCloser
+	dec     *json.Decoder
+	closed  bool
+	lastSeq string
 }
 
 func newChangesRows(r io.ReadCloser) *changesRows {
@@ -59,10 +69,23 @@ func (r *changesRows) Next(row *driver.Row) error {
 	if !r.dec.More() {
 		return io.EOF
 	}
-
-	return r.dec.Decode(row)
+	var change struct {
+		driver.Row
+		LastSeq *driver.SequenceID `json:"last_seq"`
+	}
+	if err := r.dec.Decode(&change); err != nil {
+		return err
+	}
+	if change.LastSeq != nil {
+		// The feed has ended, due to a timeout or limit.
+		r.closed = true
+		r.lastSeq = string(*change.LastSeq)
+		return io.EOF
+	}
+	*row = change.Row
+	return nil
 }
 
 func (r *changesRows) Offset() int64     { return 0 }
 func (r *changesRows) TotalRows() int64  { return 0 }
-func (r *changesRows) UpdateSeq() string { return "" }
+func (r *changesRows) UpdateSeq() string { return r.lastSeq }
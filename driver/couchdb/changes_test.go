@@ -0,0 +1,102 @@
+package couchdb
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/kivik/driver"
+	"github.com/flimzy/kivik/test/kt"
+)
+
+func readChanges(t *testing.T, rows driver.Rows) []string {
+	var ids []string
+	for {
+		row := &driver.Row{}
+		err := rows.Next(row)
+		if err == io.EOF {
+			return ids
+		}
+		if err != nil {
+			t.Fatalf("Next() failed: %s", err)
+		}
+		ids = append(ids, row.ID+"@"+string(row.Seq))
+	}
+}
+
+func TestChangesRowsContinuous(t *testing.T) {
+	input := `{"seq":1,"id":"foo","changes":[{"rev":"1-a"}]}
+{"seq":2,"id":"bar","changes":[{"rev":"1-b"}],"deleted":true}
+{"last_seq":2}
+`
+	rows := newChangesRows(ioutil.NopCloser(strings.NewReader(input)))
+	ids := readChanges(t, rows)
+	if strings.Join(ids, ",") != "foo@1,bar@2" {
+		t.Errorf("Unexpected changes: %v", ids)
+	}
+	if seq := rows.UpdateSeq(); seq != "2" {
+		t.Errorf("Unexpected last seq %s", seq)
+	}
+}
+
+func TestChangesRowsNormal(t *testing.T) {
+	input := `{"results":[
+{"seq":"1-x","id":"foo","changes":[{"rev":"1-a"}]},
+{"seq":"2-x","id":"bar","changes":[{"rev":"1-b"}]}
+],
+"last_seq":"2-x","pending":0}`
+	rows := newRows(ioutil.NopCloser(strings.NewReader(input)))
+	ids := readChanges(t, rows)
+	if strings.Join(ids, ",") != "foo@1-x,bar@2-x" {
+		t.Errorf("Unexpected changes: %v", ids)
+	}
+	if seq := rows.UpdateSeq(); seq != "2-x" {
+		t.Errorf("Unexpected last seq %s", seq)
+	}
+}
+
+func TestChangesOptions(t *testing.T) {
+	type coTest struct {
+		Name     string
+		Options  map[string]interface{}
+		Expected url.Values
+	}
+	tests := []coTest{
+		{
+			Name:     "Defaults",
+			Expected: url.Values{"feed": {"continuous"}, "since": {"now"}, "heartbeat": {"6000"}},
+		},
+		{
+			Name:     "Override",
+			Options:  map[string]interface{}{"feed": "normal", "since": "0"},
+			Expected: url.Values{"feed": {"normal"}, "since": {"0"}, "heartbeat": {"6000"}},
+		},
+	}
+	for _, test := range tests {
+		func(test coTest) {
+			t.Run(test.Name, func(t *testing.T) {
+				var query url.Values
+				s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Path == "/foo/_changes" {
+						query = r.URL.Query()
+					}
+					_, _ = w.Write([]byte(`{"results":[],"last_seq":"0"}`))
+				}))
+				defer s.Close()
+				d := &db{client: connect(s.URL, t), dbName: "foo"}
+				rows, err := d.ChangesContext(kt.CTX, test.Options)
+				if err != nil {
+					t.Fatal(err)
+				}
+				_ = rows.Close()
+				if query.Encode() != test.Expected.Encode() {
+					t.Errorf("Unexpected query. Expected %s, got %s", test.Expected.Encode(), query.Encode())
+				}
+			})
+		}(test)
+	}
+}
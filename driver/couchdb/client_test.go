@@ -71,3 +71,10 @@ func TestCreateAndDestroyDB(t *testing.T) {
 		t.Errorf("Destroy failed: %s", err)
 	}
 }
+
+func TestActiveTasks(t *testing.T) {
+	client := getClient(t)
+	if _, err := client.ActiveTasksContext(kt.CTX); err != nil {
+		t.Errorf("Failed: %s", err)
+	}
+}
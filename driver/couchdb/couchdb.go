@@ -4,6 +4,7 @@ package couchdb
 import (
 	"context"
 	"strings"
+	"sync"
 
 	"github.com/flimzy/kivik"
 	"github.com/flimzy/kivik/driver"
@@ -45,6 +46,11 @@ type client struct {
 	*chttp.Client
 	Compat      CompatMode
 	forceCommit bool
+
+	// scheduler records whether the server provides the /_scheduler
+	// endpoints, once known.
+	schedulerMu sync.Mutex
+	scheduler   *bool
 }
 
 var _ driver.Client = &client{}
@@ -0,0 +1,579 @@
+package couchdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flimzy/kivik"
+	"github.com/flimzy/kivik/driver"
+	"github.com/flimzy/kivik/driver/couchdb/chttp"
+	"github.com/flimzy/kivik/errors"
+)
+
+const replicatorDB = "_replicator"
+
+// OptionTransient, set to true in the options passed to ReplicateContext,
+// starts the replication with a POST to /_replicate, instead of by creating a
+// document in the _replicator database. A transient replication is not
+// resumed if the server restarts and, unless it is continuous, the call does
+// not return until the replication has ended.
+const OptionTransient = "transient"
+
+var _ driver.Replicator = &client{}
+
+// replication is a replication managed by the server. Replications with a
+// docID are defined by a document in the _replicator database. Others were
+// started by a POST to /_replicate.
+type replication struct {
+	docID  string
+	source string
+	target string
+	c      *client
+
+	mu            sync.RWMutex
+	replicationID string
+	startTime     time.Time
+	endTime       time.Time
+	state         string
+	err           error
+	// final holds the statistics of a transient replication which has ended,
+	// and is no longer known to the server.
+	final *driver.ReplicationInfo
+}
+
+var _ driver.Replication = &replication{}
+
+func (r *replication) DocID() string  { return r.docID }
+func (r *replication) Source() string { return r.source }
+func (r *replication) Target() string { return r.target }
+
+func (r *replication) ReplicationID() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.replicationID
+}
+
+func (r *replication) StartTime() time.Time {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.startTime
+}
+
+func (r *replication) EndTime() time.Time {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.endTime
+}
+
+func (r *replication) State() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.state
+}
+
+func (r *replication) Err() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.err
+}
+
+// ReplicateContext starts a replication by creating a document in the
+// _replicator database, or by a POST to /_replicate if OptionTransient is set.
+// Other options are added to the document or request.
+func (c *client) ReplicateContext(ctx context.Context, target, source string, options map[string]interface{}) (driver.Replication, error) {
+	doc := make(map[string]interface{}, len(options)+2)
+	transient := false
+	for key, value := range options {
+		if key == OptionTransient {
+			var ok bool
+			if transient, ok = value.(bool); !ok {
+				return nil, errors.Statusf(kivik.StatusBadRequest, "invalid type %T for option %s", value, OptionTransient)
+			}
+			continue
+		}
+		doc[key] = value
+	}
+	doc["source"] = source
+	doc["target"] = target
+	if transient {
+		return c.replicateTransient(ctx, target, source, doc)
+	}
+	d := &db{client: c, dbName: replicatorDB}
+	docID, _, err := d.CreateDocContext(ctx, doc)
+	if err != nil {
+		return nil, err
+	}
+	return &replication{
+		docID:  docID,
+		source: source,
+		target: target,
+		c:      c,
+	}, nil
+}
+
+// replicateResponse is the response to a POST to /_replicate. Continuous
+// replications respond once started, with their replication ID. Others respond
+// once they have ended, with the replication history.
+type replicateResponse struct {
+	LocalID   string `json:"_local_id"`
+	SessionID string `json:"session_id"`
+	History   []struct {
+		SessionID        string `json:"session_id"`
+		StartTime        string `json:"start_time"`
+		EndTime          string `json:"end_time"`
+		DocsRead         int64  `json:"docs_read"`
+		DocsWritten      int64  `json:"docs_written"`
+		DocWriteFailures int64  `json:"doc_write_failures"`
+		MissingChecked   int64  `json:"missing_checked"`
+		MissingFound     int64  `json:"missing_found"`
+	} `json:"history"`
+}
+
+// replicateTransient starts a replication with a POST to /_replicate.
+func (c *client) replicateTransient(ctx context.Context, target, source string, body map[string]interface{}) (driver.Replication, error) {
+	var jsonErr error
+	var cancel context.CancelFunc
+	ctx, cancel = context.WithCancel(ctx)
+	defer cancel()
+	opts := &chttp.Options{
+		Body: chttp.EncodeBody(body, &jsonErr, cancel),
+	}
+	var result replicateResponse
+	_, err := c.DoJSON(ctx, kivik.MethodPost, "/_replicate", opts, &result)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	r := &replication{
+		source: source,
+		target: target,
+		c:      c,
+	}
+	if result.LocalID != "" {
+		r.replicationID = result.LocalID
+		r.state = string(kivik.ReplicationStarted)
+		return r, nil
+	}
+	r.state = string(kivik.ReplicationComplete)
+	r.endTime = time.Now()
+	r.final = &driver.ReplicationInfo{}
+	if len(result.History) > 0 && result.History[0].SessionID == result.SessionID {
+		h := result.History[0]
+		if t, err := time.Parse(time.RFC1123, h.StartTime); err == nil {
+			r.startTime = t
+		}
+		if t, err := time.Parse(time.RFC1123, h.EndTime); err == nil {
+			r.endTime = t
+		}
+		r.final.DocsRead = h.DocsRead
+		r.final.DocsWritten = h.DocsWritten
+		r.final.DocWriteFailures = h.DocWriteFailures
+		r.final.RevisionsChecked = h.MissingChecked
+		r.final.MissingRevisionsFound = h.MissingFound
+	}
+	return r, nil
+}
+
+// noScheduler returns true if err indicates that the server does not provide
+// the /_scheduler endpoints, introduced in CouchDB 2.1. CouchDB 1.x treats
+// _scheduler as an illegal database name.
+func noScheduler(err error) bool {
+	switch errors.StatusCode(err) {
+	case kivik.StatusNotFound, kivik.StatusBadRequest:
+		return true
+	}
+	return false
+}
+
+// hasScheduler returns true if the server provides the /_scheduler endpoints.
+// The result is remembered once known, as a 404 from one of the more specific
+// endpoints may only mean that the replication in question is unknown.
+func (c *client) hasScheduler(ctx context.Context) (bool, error) {
+	c.schedulerMu.Lock()
+	defer c.schedulerMu.Unlock()
+	if c.scheduler != nil {
+		return *c.scheduler, nil
+	}
+	_, err := c.DoError(ctx, kivik.MethodGet, "/_scheduler/jobs?limit=0", nil)
+	if err != nil && !noScheduler(err) {
+		return false, err
+	}
+	scheduler := err == nil
+	c.scheduler = &scheduler
+	return scheduler, nil
+}
+
+// UpdateContext fetches the replication's state from /_scheduler, or, for
+// servers without the scheduler, from the replication document or
+// /_active_tasks.
+func (r *replication) UpdateContext(ctx context.Context, info *driver.ReplicationInfo) error {
+	if r.docID == "" {
+		return r.updateJob(ctx, info)
+	}
+	scheduler, err := r.c.hasScheduler(ctx)
+	if err != nil {
+		return err
+	}
+	if !scheduler {
+		return r.updateFromDoc(ctx, info)
+	}
+	var doc schedulerDoc
+	if _, err := r.c.DoJSON(ctx, kivik.MethodGet, "/_scheduler/docs/"+replicatorDB+"/"+encodeDocID(r.docID), nil, &doc); err != nil {
+		return err
+	}
+	r.setFromSchedulerDoc(&doc, info)
+	return nil
+}
+
+// updateFromDoc reads the state recorded in the replication document by
+// CouchDB 1.x.
+func (r *replication) updateFromDoc(ctx context.Context, info *driver.ReplicationInfo) error {
+	var doc replicatorDoc
+	d := &db{client: r.c, dbName: replicatorDB}
+	if err := d.GetContext(ctx, r.docID, &doc, nil); err != nil {
+		return err
+	}
+	r.setFromDoc(&doc, info)
+	return nil
+}
+
+// updateJob reads the state of a replication without a document. The server
+// forgets such replications once they end, whether they completed, crashed
+// or were cancelled, so a replication which is no longer known has ended with
+// an unknown outcome. Those which complete while ReplicateContext waits are
+// recorded in r.final.
+func (r *replication) updateJob(ctx context.Context, info *driver.ReplicationInfo) error {
+	r.mu.RLock()
+	id, final := r.replicationID, r.final
+	r.mu.RUnlock()
+	if final != nil {
+		*info = *final
+		return nil
+	}
+	scheduler, err := r.c.hasScheduler(ctx)
+	if err != nil {
+		return err
+	}
+	if scheduler {
+		var job schedulerJob
+		_, err = r.c.DoJSON(ctx, kivik.MethodGet, "/_scheduler/jobs/"+replaceSlash(id), nil, &job)
+		if err == nil {
+			r.setFromJob(&job, info)
+			return nil
+		}
+		if errors.StatusCode(err) != kivik.StatusNotFound {
+			return err
+		}
+		r.setEnded()
+		return nil
+	}
+	tasks, err := r.c.ActiveTasksContext(ctx)
+	if err != nil {
+		return err
+	}
+	for _, task := range tasks {
+		if task.Type == "replication" && task.ReplicationID == id {
+			r.setFromTask(&task, info)
+			return nil
+		}
+	}
+	r.setEnded()
+	return nil
+}
+
+// setEnded records that a replication without a document is no longer known
+// to the server.
+func (r *replication) setEnded() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.endTime.IsZero() {
+		return
+	}
+	r.state = string(kivik.ReplicationError)
+	r.endTime = time.Now()
+	r.err = errors.Status(kivik.StatusNotFound, "replication is no longer known to the server, so its outcome is unknown")
+}
+
+// DeleteContext cancels the replication, by deleting its document or, for
+// replications without one, by a cancel request to /_replicate.
+func (r *replication) DeleteContext(ctx context.Context) error {
+	if r.docID == "" {
+		var jsonErr error
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		opts := &chttp.Options{
+			Body: chttp.EncodeBody(map[string]interface{}{
+				"replication_id": r.ReplicationID(),
+				"cancel":         true,
+			}, &jsonErr, cancel),
+		}
+		_, err := r.c.DoError(ctx, kivik.MethodPost, "/_replicate", opts)
+		if jsonErr != nil {
+			return jsonErr
+		}
+		return err
+	}
+	d := &db{client: r.c, dbName: replicatorDB}
+	rev, err := d.RevContext(ctx, r.docID)
+	if err != nil {
+		return err
+	}
+	_, err = d.DeleteContext(ctx, r.docID, rev)
+	return err
+}
+
+// GetReplicationsContext returns the replications known to the server: those
+// defined in the _replicator database, and those started with /_replicate.
+// options are passed to /_scheduler/docs, or to /_replicator/_all_docs for
+// servers without the scheduler.
+func (c *client) GetReplicationsContext(ctx context.Context, options map[string]interface{}) ([]driver.Replication, error) {
+	params, err := optionsToParams(options)
+	if err != nil {
+		return nil, err
+	}
+	scheduler, err := c.hasScheduler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !scheduler {
+		return c.legacyReplications(ctx, params)
+	}
+	var docs struct {
+		Docs []schedulerDoc `json:"docs"`
+	}
+	if _, err = c.DoJSON(ctx, kivik.MethodGet, "/_scheduler/docs?"+params.Encode(), nil, &docs); err != nil {
+		return nil, err
+	}
+	var jobs struct {
+		Jobs []schedulerJob `json:"jobs"`
+	}
+	if _, err := c.DoJSON(ctx, kivik.MethodGet, "/_scheduler/jobs", nil, &jobs); err != nil {
+		return nil, err
+	}
+	reps := make([]driver.Replication, 0, len(docs.Docs))
+	for i := range docs.Docs {
+		doc := &docs.Docs[i]
+		rep := &replication{docID: doc.DocID, source: doc.Source, target: doc.Target, c: c}
+		rep.setFromSchedulerDoc(doc, &driver.ReplicationInfo{})
+		reps = append(reps, rep)
+	}
+	for i := range jobs.Jobs {
+		job := &jobs.Jobs[i]
+		if job.DocID != "" {
+			continue
+		}
+		rep := &replication{source: job.Source, target: job.Target, c: c}
+		rep.setFromJob(job, &driver.ReplicationInfo{})
+		reps = append(reps, rep)
+	}
+	return reps, nil
+}
+
+// legacyReplications returns the replications of servers without the
+// scheduler, from the _replicator database and /_active_tasks.
+func (c *client) legacyReplications(ctx context.Context, params url.Values) ([]driver.Replication, error) {
+	params.Set("include_docs", "true")
+	var allDocs struct {
+		Rows []struct {
+			Doc replicatorDoc `json:"doc"`
+		} `json:"rows"`
+	}
+	if _, err := c.DoJSON(ctx, kivik.MethodGet, "/"+replicatorDB+"/_all_docs?"+params.Encode(), nil, &allDocs); err != nil {
+		return nil, err
+	}
+	reps := make([]driver.Replication, 0, len(allDocs.Rows))
+	for i := range allDocs.Rows {
+		doc := &allDocs.Rows[i].Doc
+		if strings.HasPrefix(doc.ID, prefixDesign) {
+			continue
+		}
+		rep := &replication{docID: doc.ID, source: endpointURL(doc.Source), target: endpointURL(doc.Target), c: c}
+		rep.setFromDoc(doc, &driver.ReplicationInfo{})
+		reps = append(reps, rep)
+	}
+	tasks, err := c.ActiveTasksContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range tasks {
+		task := &tasks[i]
+		if task.Type != "replication" || task.DocID != "" {
+			continue
+		}
+		rep := &replication{source: task.Source, target: task.Target, c: c}
+		rep.setFromTask(task, &driver.ReplicationInfo{})
+		reps = append(reps, rep)
+	}
+	return reps, nil
+}
+
+// schedulerDoc is a replication document's state, as reported by
+// /_scheduler/docs.
+type schedulerDoc struct {
+	DocID       string     `json:"doc_id"`
+	ID          string     `json:"id"`
+	Source      string     `json:"source"`
+	Target      string     `json:"target"`
+	State       string     `json:"state"`
+	StartTime   *time.Time `json:"start_time"`
+	LastUpdated *time.Time `json:"last_updated"`
+	Info        struct {
+		DocWriteFailures      int64       `json:"doc_write_failures"`
+		DocsRead              int64       `json:"docs_read"`
+		DocsWritten           int64       `json:"docs_written"`
+		MissingRevisionsFound int64       `json:"missing_revisions_found"`
+		RevisionsChecked      int64       `json:"revisions_checked"`
+		Error                 interface{} `json:"error"`
+	} `json:"info"`
+}
+
+func (r *replication) setFromSchedulerDoc(doc *schedulerDoc, info *driver.ReplicationInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.replicationID = doc.ID
+	r.state = doc.State
+	if doc.StartTime != nil {
+		r.startTime = *doc.StartTime
+	}
+	if doc.LastUpdated != nil && (doc.State == string(kivik.ReplicationComplete) || doc.State == string(kivik.ReplicationFailed)) {
+		r.endTime = *doc.LastUpdated
+	}
+	r.err = nil
+	if doc.Info.Error != nil {
+		r.err = errors.Status(kivik.StatusInternalServerError, fmt.Sprint(doc.Info.Error))
+	}
+	info.DocWriteFailures = doc.Info.DocWriteFailures
+	info.DocsRead = doc.Info.DocsRead
+	info.DocsWritten = doc.Info.DocsWritten
+	info.MissingRevisionsFound = doc.Info.MissingRevisionsFound
+	info.RevisionsChecked = doc.Info.RevisionsChecked
+}
+
+// schedulerJob is a running replication, as reported by /_scheduler/jobs.
+type schedulerJob struct {
+	DocID     string     `json:"doc_id"`
+	ID        string     `json:"id"`
+	Source    string     `json:"source"`
+	Target    string     `json:"target"`
+	StartTime *time.Time `json:"start_time"`
+	History   []struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"history"`
+	Info struct {
+		DocWriteFailures      int64 `json:"doc_write_failures"`
+		DocsRead              int64 `json:"docs_read"`
+		DocsWritten           int64 `json:"docs_written"`
+		MissingRevisionsFound int64 `json:"missing_revisions_found"`
+		RevisionsChecked      int64 `json:"revisions_checked"`
+	} `json:"info"`
+}
+
+func (r *replication) setFromJob(job *schedulerJob, info *driver.ReplicationInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.replicationID = job.ID
+	if job.StartTime != nil {
+		r.startTime = *job.StartTime
+	}
+	// History is ordered most recent first.
+	r.state = string(kivik.ReplicationRunning)
+	r.err = nil
+	if len(job.History) > 0 && job.History[0].Type == "crashed" {
+		r.state = string(kivik.ReplicationCrashing)
+		r.err = errors.Status(kivik.StatusInternalServerError, job.History[0].Reason)
+	}
+	info.DocWriteFailures = job.Info.DocWriteFailures
+	info.DocsRead = job.Info.DocsRead
+	info.DocsWritten = job.Info.DocsWritten
+	info.MissingRevisionsFound = job.Info.MissingRevisionsFound
+	info.RevisionsChecked = job.Info.RevisionsChecked
+}
+
+// replicatorDoc is a replication document, with the state recorded by
+// CouchDB 1.x.
+type replicatorDoc struct {
+	ID            string          `json:"_id"`
+	Source        interface{}     `json:"source"`
+	Target        interface{}     `json:"target"`
+	ReplicationID string          `json:"_replication_id"`
+	State         string          `json:"_replication_state"`
+	StateTime     json.RawMessage `json:"_replication_state_time"`
+	StateReason   string          `json:"_replication_state_reason"`
+	Stats         struct {
+		DocWriteFailures      int64 `json:"doc_write_failures"`
+		DocsRead              int64 `json:"docs_read"`
+		DocsWritten           int64 `json:"docs_written"`
+		MissingRevisionsFound int64 `json:"missing_revisions_found"`
+		RevisionsChecked      int64 `json:"revisions_checked"`
+	} `json:"_replication_stats"`
+}
+
+func (r *replication) setFromDoc(doc *replicatorDoc, info *driver.ReplicationInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.replicationID = doc.ReplicationID
+	r.state = doc.State
+	t := parseStateTime(doc.StateTime)
+	switch kivik.ReplicationState(doc.State) {
+	case kivik.ReplicationStarted:
+		r.startTime = t
+	case kivik.ReplicationComplete, kivik.ReplicationError:
+		r.endTime = t
+	}
+	r.err = nil
+	if doc.State == string(kivik.ReplicationError) {
+		r.err = errors.Status(kivik.StatusInternalServerError, doc.StateReason)
+	}
+	info.DocWriteFailures = doc.Stats.DocWriteFailures
+	info.DocsRead = doc.Stats.DocsRead
+	info.DocsWritten = doc.Stats.DocsWritten
+	info.MissingRevisionsFound = doc.Stats.MissingRevisionsFound
+	info.RevisionsChecked = doc.Stats.RevisionsChecked
+}
+
+func (r *replication) setFromTask(task *driver.ActiveTask, info *driver.ReplicationInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.replicationID = task.ReplicationID
+	r.state = string(kivik.ReplicationStarted)
+	r.startTime = time.Unix(task.StartedOn, 0)
+	info.DocWriteFailures = task.DocWriteFailures
+	info.DocsRead = task.DocsRead
+	info.DocsWritten = task.DocsWritten
+	info.MissingRevisionsFound = task.MissingRevisionsFound
+	info.RevisionsChecked = task.RevisionsChecked
+}
+
+// parseStateTime parses a _replication_state_time, which is a Unix timestamp
+// in CouchDB 1.x, and an RFC3339 string in later versions.
+func parseStateTime(raw json.RawMessage) time.Time {
+	s := strings.Trim(string(raw), `"`)
+	if sec, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(sec, 0)
+	}
+	t, _ := time.Parse(time.RFC3339, s)
+	return t
+}
+
+// endpointURL returns the URL of a replication source or target, which may
+// be a string, or an object with a url field.
+func endpointURL(endpoint interface{}) string {
+	switch e := endpoint.(type) {
+	case string:
+		return e
+	case map[string]interface{}:
+		u, _ := e["url"].(string)
+		return u
+	}
+	return ""
+}
@@ -0,0 +1,198 @@
+package couchdb
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/flimzy/kivik"
+	"github.com/flimzy/kivik/driver"
+	"github.com/flimzy/kivik/errors"
+	"github.com/flimzy/kivik/test/kt"
+)
+
+func TestSetFromSchedulerDoc(t *testing.T) {
+	input := `{"database":"_replicator","doc_id":"foo","id":"abc+continuous",
+"source":"http://localhost:5984/a/","target":"http://localhost:5984/b/",
+"state":"completed","error_count":0,"start_time":"2017-10-01T10:00:00Z",
+"last_updated":"2017-10-01T10:05:00Z",
+"info":{"docs_read":5,"docs_written":4,"doc_write_failures":1,"missing_revisions_found":5,"revisions_checked":6}}`
+	var doc schedulerDoc
+	if err := json.Unmarshal([]byte(input), &doc); err != nil {
+		t.Fatal(err)
+	}
+	r := &replication{}
+	info := &driver.ReplicationInfo{}
+	r.setFromSchedulerDoc(&doc, info)
+	if r.ReplicationID() != "abc+continuous" || r.State() != string(kivik.ReplicationComplete) || r.Err() != nil {
+		t.Errorf("Unexpected replication: %+v", r)
+	}
+	if !r.StartTime().Equal(time.Date(2017, 10, 1, 10, 0, 0, 0, time.UTC)) || !r.EndTime().Equal(time.Date(2017, 10, 1, 10, 5, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected times: %s - %s", r.StartTime(), r.EndTime())
+	}
+	if info.DocsRead != 5 || info.DocsWritten != 4 || info.DocWriteFailures != 1 {
+		t.Errorf("Unexpected info: %+v", info)
+	}
+}
+
+func TestSetFromDoc(t *testing.T) {
+	input := `{"_id":"foo","source":{"url":"http://localhost:5984/a"},"target":"b",
+"_replication_id":"abc","_replication_state":"error",
+"_replication_state_time":1506852000,"_replication_state_reason":"db_not_found"}`
+	var doc replicatorDoc
+	if err := json.Unmarshal([]byte(input), &doc); err != nil {
+		t.Fatal(err)
+	}
+	r := &replication{}
+	r.setFromDoc(&doc, &driver.ReplicationInfo{})
+	if r.State() != string(kivik.ReplicationError) || r.ReplicationID() != "abc" {
+		t.Errorf("Unexpected replication: %+v", r)
+	}
+	if err := r.Err(); errors.Reason(err) != "db_not_found" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if r.EndTime().Unix() != 1506852000 {
+		t.Errorf("Unexpected end time: %s", r.EndTime())
+	}
+	if src := endpointURL(doc.Source); src != "http://localhost:5984/a" {
+		t.Errorf("Unexpected source: %s", src)
+	}
+	if tgt := endpointURL(doc.Target); tgt != "b" {
+		t.Errorf("Unexpected target: %s", tgt)
+	}
+}
+
+func TestParseStateTime(t *testing.T) {
+	expected := time.Unix(1506852000, 0)
+	for _, input := range []string{`1506852000`, `"1506852000"`, `"2017-10-01T10:00:00Z"`} {
+		if st := parseStateTime(json.RawMessage(input)); !st.Equal(expected) {
+			t.Errorf("%s: unexpected time %s", input, st)
+		}
+	}
+}
+
+func TestReplicateTransient(t *testing.T) {
+	var body map[string]interface{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != kivik.MethodPost || r.URL.Path != "/_replicate" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["continuous"] == true {
+			_, _ = w.Write([]byte(`{"ok":true,"_local_id":"abc+continuous"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"ok":true,"session_id":"s1","history":[{"session_id":"s1",
+"start_time":"Sun, 01 Oct 2017 10:00:00 GMT","end_time":"Sun, 01 Oct 2017 10:05:00 GMT",
+"docs_read":5,"docs_written":4,"doc_write_failures":1,"missing_checked":6,"missing_found":5}]}`))
+	}))
+	defer s.Close()
+	c := connect(s.URL, t)
+
+	rep, err := c.ReplicateContext(kt.CTX, "b", "a", map[string]interface{}{OptionTransient: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := body[OptionTransient]; ok || body["source"] != "a" || body["target"] != "b" {
+		t.Errorf("Unexpected request body: %v", body)
+	}
+	info := &driver.ReplicationInfo{}
+	if err = rep.UpdateContext(kt.CTX, info); err != nil {
+		t.Fatal(err)
+	}
+	if rep.DocID() != "" || rep.State() != string(kivik.ReplicationComplete) {
+		t.Errorf("Unexpected replication: %+v", rep)
+	}
+	if !rep.EndTime().Equal(time.Date(2017, 10, 1, 10, 5, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected end time: %s", rep.EndTime())
+	}
+	if info.DocsRead != 5 || info.DocsWritten != 4 || info.RevisionsChecked != 6 {
+		t.Errorf("Unexpected info: %+v", info)
+	}
+
+	rep, err = c.ReplicateContext(kt.CTX, "b", "a", map[string]interface{}{OptionTransient: true, "continuous": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.ReplicationID() != "abc+continuous" || rep.State() != string(kivik.ReplicationStarted) {
+		t.Errorf("Unexpected continuous replication: %+v", rep)
+	}
+
+	if _, err = c.ReplicateContext(kt.CTX, "b", "a", map[string]interface{}{OptionTransient: "yes"}); errors.StatusCode(err) != kivik.StatusBadRequest {
+		t.Errorf("Expected Bad Request for an invalid transient option, got %v", err)
+	}
+}
+
+func TestUpdateJob(t *testing.T) {
+	type ujTest struct {
+		Name      string
+		Scheduler bool
+	}
+	tests := []ujTest{
+		{Name: "Scheduler", Scheduler: true},
+		{Name: "ActiveTasks", Scheduler: false},
+	}
+	for _, test := range tests {
+		func(test ujTest) {
+			t.Run(test.Name, func(t *testing.T) {
+				var gone bool
+				var detected int
+				s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					switch {
+					case r.URL.Path == "/_scheduler/jobs":
+						detected++
+						if !test.Scheduler {
+							w.WriteHeader(http.StatusBadRequest)
+							_, _ = w.Write([]byte(`{"error":"illegal_database_name"}`))
+							return
+						}
+						_, _ = w.Write([]byte(`{"total_rows":1,"offset":0,"jobs":[]}`))
+					case r.URL.Path == "/_scheduler/jobs/abc+continuous" && test.Scheduler:
+						if gone {
+							w.WriteHeader(http.StatusNotFound)
+							_, _ = w.Write([]byte(`{"error":"not_found","reason":"unknown"}`))
+							return
+						}
+						_, _ = w.Write([]byte(`{"id":"abc+continuous","source":"a","target":"b",
+"start_time":"2017-10-01T10:00:00Z","history":[{"type":"started"}],
+"info":{"docs_read":5,"docs_written":4,"doc_write_failures":1,"missing_revisions_found":5,"revisions_checked":6}}`))
+					case r.URL.Path == "/_active_tasks" && !test.Scheduler:
+						if gone {
+							_, _ = w.Write([]byte(`[]`))
+							return
+						}
+						_, _ = w.Write([]byte(`[{"type":"replication","replication_id":"abc+continuous","started_on":1506852000,
+"docs_read":5,"docs_written":4,"doc_write_failures":1,"missing_revisions_found":5,"revisions_checked":6}]`))
+					default:
+						w.WriteHeader(http.StatusNotFound)
+					}
+				}))
+				defer s.Close()
+				r := &replication{c: connect(s.URL, t), replicationID: "abc+continuous"}
+				info := &driver.ReplicationInfo{}
+				if err := r.UpdateContext(kt.CTX, info); err != nil {
+					t.Fatal(err)
+				}
+				if !r.EndTime().IsZero() || r.Err() != nil {
+					t.Errorf("Unexpected running replication: %+v", r)
+				}
+				if info.DocsRead != 5 || info.DocsWritten != 4 || info.RevisionsChecked != 6 {
+					t.Errorf("Unexpected info: %+v", info)
+				}
+				gone = true
+				if err := r.UpdateContext(kt.CTX, info); err != nil {
+					t.Fatal(err)
+				}
+				if r.State() != string(kivik.ReplicationError) || r.EndTime().IsZero() || errors.StatusCode(r.Err()) != kivik.StatusNotFound {
+					t.Errorf("Expected an ended replication with an unknown outcome, got %+v", r)
+				}
+				if detected != 1 {
+					t.Errorf("Expected the scheduler to be detected once, got %d requests", detected)
+				}
+			})
+		}(test)
+	}
+}
@@ -0,0 +1,28 @@
+package couchdb
+
+import (
+	"context"
+
+	"github.com/flimzy/kivik"
+	"github.com/flimzy/kivik/driver"
+	"github.com/flimzy/kivik/driver/couchdb/chttp"
+)
+
+var _ driver.RevsDiffer = &db{}
+
+// RevsDiffContext returns the revisions in revMap missing from the database.
+func (d *db) RevsDiffContext(ctx context.Context, revMap map[string][]string) (map[string]driver.RevDiff, error) {
+	var jsonErr error
+	var cancel context.CancelFunc
+	ctx, cancel = context.WithCancel(ctx)
+	defer cancel()
+	opts := &chttp.Options{
+		Body: chttp.EncodeBody(revMap, &jsonErr, cancel),
+	}
+	var result map[string]driver.RevDiff
+	_, err := d.Client.DoJSON(ctx, kivik.MethodPost, d.path("_revs_diff", nil), opts, &result)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	return result, err
+}
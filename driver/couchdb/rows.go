@@ -84,7 +84,8 @@ func (r *rows) begin() error {
 			// The JSON parser should never permit this
 			return fmt.Errorf("Unexpected token: (%T) %v", t, t)
 		}
-		if key == "rows" || key == "docs" {
+		// Normal and longpoll changes feeds return their rows as "results"
+		if key == "rows" || key == "docs" || key == "results" {
 			r.isFindRows = key == "docs"
 			// Consume the first '['
 			return consumeDelim(r.dec, json.Delim('['))
@@ -122,8 +123,11 @@ func (r *rows) finish() error {
 // parseMeta parses result metadata
 func (r *rows) parseMeta(key string) error {
 	switch key {
-	case "update_seq":
+	case "update_seq", "last_seq":
 		return r.readUpdateSeq()
+	case "pending":
+		var pending int64
+		return r.dec.Decode(&pending)
 	case "offset":
 		return r.dec.Decode(&r.offset)
 	case "total_rows":
@@ -75,6 +75,88 @@ type Cluster interface {
 	MembershipContext(ctx context.Context) (allNodes []string, clusterNodes []string, err error)
 }
 
+// ActiveTask describes a long-running task, such as compaction, view indexing
+// or replication, in the format of CouchDB's /_active_tasks endpoint. Fields
+// which do not apply to a task's type are left empty.
+type ActiveTask struct {
+	Node           string `json:"node,omitempty"`
+	PID            string `json:"pid,omitempty"`
+	Type           string `json:"type"`
+	Database       string `json:"database,omitempty"`
+	DesignDocument string `json:"design_document,omitempty"`
+	DocID          string `json:"doc_id,omitempty"`
+	Phase          string `json:"phase,omitempty"`
+	Progress       int    `json:"progress"`
+	ChangesDone    int64  `json:"changes_done"`
+	TotalChanges   int64  `json:"total_changes"`
+	// StartedOn and UpdatedOn are Unix timestamps, in seconds.
+	StartedOn int64 `json:"started_on"`
+	UpdatedOn int64 `json:"updated_on"`
+
+	// The following fields apply to replication tasks.
+	ReplicationID         string `json:"replication_id,omitempty"`
+	Source                string `json:"source,omitempty"`
+	Target                string `json:"target,omitempty"`
+	Continuous            bool   `json:"continuous,omitempty"`
+	DocsRead              int64  `json:"docs_read,omitempty"`
+	DocsWritten           int64  `json:"docs_written,omitempty"`
+	DocWriteFailures      int64  `json:"doc_write_failures,omitempty"`
+	MissingRevisionsFound int64  `json:"missing_revisions_found,omitempty"`
+	RevisionsChecked      int64  `json:"revisions_checked,omitempty"`
+	SourceSeq             string `json:"source_seq,omitempty"`
+	CheckpointedSourceSeq string `json:"checkpointed_source_seq,omitempty"`
+}
+
+// ActiveTasker is an optional interface that may be implemented by a Client
+// which can report its running tasks.
+type ActiveTasker interface {
+	ActiveTasksContext(ctx context.Context) ([]ActiveTask, error)
+}
+
+// ReplicationInfo reports the progress of a replication.
+type ReplicationInfo struct {
+	DocWriteFailures      int64
+	DocsRead              int64
+	DocsWritten           int64
+	MissingRevisionsFound int64
+	RevisionsChecked      int64
+}
+
+// Replication represents a replication managed by the server.
+type Replication interface {
+	// DocID returns the ID of the replication document, or an empty string
+	// for replications which have none.
+	DocID() string
+	// ReplicationID returns the server's ID for the replication, if known.
+	ReplicationID() string
+	Source() string
+	Target() string
+	// StartTime and EndTime return the times at which the replication
+	// started and ended, or the zero time if not known.
+	StartTime() time.Time
+	EndTime() time.Time
+	// State returns the state of the replication, as last fetched, such as
+	// "triggered", "completed" or "error".
+	State() string
+	// Err returns the error which caused the replication to fail, if any.
+	Err() error
+	// UpdateContext fetches the replication's current state, and populates
+	// info with its progress.
+	UpdateContext(ctx context.Context, info *ReplicationInfo) error
+	// DeleteContext cancels the replication.
+	DeleteContext(ctx context.Context) error
+}
+
+// Replicator is an optional interface that may be implemented by a Client
+// whose server can manage replications.
+type Replicator interface {
+	// ReplicateContext starts a replication from source to target, which are
+	// database names or URLs, as understood by the server.
+	ReplicateContext(ctx context.Context, target, source string, options map[string]interface{}) (Replication, error)
+	// GetReplicationsContext returns the replications known to the server.
+	GetReplicationsContext(ctx context.Context, options map[string]interface{}) ([]Replication, error)
+}
+
 // DBInfo provides statistics about a database.
 type DBInfo struct {
 	Name           string `json:"db_name"`
@@ -243,6 +325,31 @@ type Copier interface {
 	CopyContext(ctx context.Context, targetID, sourceID string, options map[string]interface{}) (targetRev string, err error)
 }
 
+// RevDiff lists the revisions of a document missing from a database, and
+// those present which may be ancestors of the missing revisions.
+type RevDiff struct {
+	Missing           []string `json:"missing,omitempty"`
+	PossibleAncestors []string `json:"possible_ancestors,omitempty"`
+}
+
+// RevsDiffer is an optional interface that may be implemented by a DB. It is
+// used by replication to find the revisions missing from the target. If not
+// implemented, a GetContext call for each revision is used instead.
+type RevsDiffer interface {
+	// RevsDiffContext returns, for each document ID in revMap with missing
+	// revisions, the missing revisions. Documents with no missing revisions
+	// are omitted.
+	RevsDiffContext(ctx context.Context, revMap map[string][]string) (map[string]RevDiff, error)
+}
+
+// BulkDocsOptioner is an optional interface that may be implemented by a DB
+// whose bulk updates accept options. Replication requires the new_edits
+// option, which, when false, stores documents with the revisions given,
+// rather than assigning new ones.
+type BulkDocsOptioner interface {
+	BulkDocsOptsContext(ctx context.Context, options map[string]interface{}, docs ...interface{}) (BulkResults, error)
+}
+
 // Configer is an optional interface that may be implemented by a Client.
 //
 // If a Client does implement Configer, it allows backend configuration
@@ -275,3 +382,10 @@ type ConfigSection interface {
 type ConfigItem interface {
 	GetContext(ctx context.Context, secName, key string) (value string, err error)
 }
+
+// ConfigOriginer is an optional interface that may be implemented by a Config
+// backend which combines several sources, such as a chain of INI files. It
+// reports the source (for example a file name) of each configuration value.
+type ConfigOriginer interface {
+	OriginsContext(ctx context.Context) (origins map[string]map[string]string, err error)
+}
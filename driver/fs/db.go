@@ -2,10 +2,22 @@ package fs
 
 import (
 	"context"
-	"errors"
+	"encoding/json"
 	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/pborman/uuid"
+
+	"github.com/flimzy/kivik"
 	"github.com/flimzy/kivik/driver"
+	"github.com/flimzy/kivik/driver/common"
+	"github.com/flimzy/kivik/errors"
 )
 
 type db struct {
@@ -13,6 +25,137 @@ type db struct {
 	dbName string
 }
 
+var _ driver.DB = &db{}
+var _ driver.BulkDocsOptioner = &db{}
+var _ driver.RevsDiffer = &db{}
+
+const (
+	localPrefix = "_local/"
+	// docExt is the extension of document files. Each document is stored in
+	// a file named for its escaped ID.
+	docExt = ".json"
+	// metaFile stores the database's update sequence, revision limit and
+	// security object. It cannot clash with a document file.
+	metaFile = ".kivik-db"
+
+	defaultRevsLimit = 1000
+)
+
+// pollInterval is the interval at which continuous changes feeds check for
+// new changes.
+var pollInterval = 100 * time.Millisecond
+
+// meta is the content of the metaFile.
+type meta struct {
+	UpdateSeq int64            `json:"update_seq"`
+	RevsLimit int              `json:"revs_limit"`
+	Security  *driver.Security `json:"security"`
+}
+
+func newDocID() string {
+	return strings.Replace(uuid.New(), "-", "", -1)
+}
+
+func (d *db) dir() string {
+	return filepath.Join(d.root, d.dbName)
+}
+
+func docFile(docID string) string {
+	return url.QueryEscape(docID) + docExt
+}
+
+// checkDB returns a 404 error if the database does not exist.
+func (d *db) checkDB() error {
+	if _, err := os.Stat(d.dir()); err != nil {
+		if os.IsNotExist(err) {
+			return errors.Status(kivik.StatusNotFound, "database does not exist")
+		}
+		return errors.WrapStatus(kivik.StatusInternalServerError, err)
+	}
+	return nil
+}
+
+// readJSON reads the named file in the database directory into v. It
+// returns false if the file does not exist.
+func (d *db) readJSON(name string, v interface{}) (bool, error) {
+	data, err := ioutil.ReadFile(filepath.Join(d.dir(), name))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.WrapStatus(kivik.StatusInternalServerError, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, errors.WrapStatus(kivik.StatusInternalServerError, err)
+	}
+	return true, nil
+}
+
+// writeJSON replaces the named file in the database directory with v. The
+// file is written to a temporary file first, so it is never left incomplete.
+func (d *db) writeJSON(name string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(d.dir(), ".tmp-")
+	if err != nil {
+		return errors.WrapStatus(kivik.StatusInternalServerError, err)
+	}
+	_, err = tmp.Write(data)
+	if e := tmp.Close(); err == nil {
+		err = e
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), fileMode)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(d.dir(), name))
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return errors.WrapStatus(kivik.StatusInternalServerError, err)
+	}
+	return nil
+}
+
+func (d *db) readMeta() (*meta, error) {
+	m := &meta{RevsLimit: defaultRevsLimit, Security: &driver.Security{}}
+	if _, err := d.readJSON(metaFile, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// readDoc returns the revision tree of a document, or nil if it does not
+// exist.
+func (d *db) readDoc(docID string) (*common.Document, error) {
+	doc := &common.Document{}
+	ok, err := d.readJSON(docFile(docID), doc)
+	if !ok {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// store adds rev to the document's revision tree, with the next update
+// sequence. The caller must hold the write lock.
+func (d *db) store(docID string, doc *common.Document, rev *common.Revision) error {
+	m, err := d.readMeta()
+	if err != nil {
+		return err
+	}
+	if doc == nil {
+		doc = &common.Document{}
+	}
+	m.UpdateSeq++
+	doc.Add(rev, m.RevsLimit, m.UpdateSeq)
+	if err := d.writeJSON(metaFile, m); err != nil {
+		return err
+	}
+	return d.writeJSON(docFile(docID), doc)
+}
+
 func (d *db) SetOption(_ string, _ interface{}) error {
 	return errors.New("no options supported")
 }
@@ -27,29 +170,150 @@ func (d *db) QueryContext(ctx context.Context, ddoc, view string, opts map[strin
 	return nil, nil
 }
 
+// GetContext fetches a document. The rev, revs and open_revs options are
+// supported.
 func (d *db) GetContext(_ context.Context, docID string, doc interface{}, opts map[string]interface{}) error {
-	// FIXME: Unimplemented
-	return nil
+	if err := d.checkDB(); err != nil {
+		return err
+	}
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	if strings.HasPrefix(docID, localPrefix) {
+		var local map[string]interface{}
+		ok, err := d.readJSON(docFile(docID), &local)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.Status(kivik.StatusNotFound, "missing")
+		}
+		return common.Decode(local, doc)
+	}
+	stored, err := d.readDoc(docID)
+	if err != nil {
+		return err
+	}
+	if stored == nil {
+		return errors.Status(kivik.StatusNotFound, "missing")
+	}
+	result, err := stored.Get(opts)
+	if err != nil {
+		return err
+	}
+	return common.Decode(result, doc)
 }
 
-func (d *db) CreateDocContext(_ context.Context, doc interface{}) (docID, rev string, err error) {
-	// FIXME: Unimplemented
-	return "", "", nil
+func (d *db) CreateDocContext(ctx context.Context, doc interface{}) (docID, rev string, err error) {
+	body, err := common.ToMap(doc)
+	if err != nil {
+		return "", "", err
+	}
+	docID, _ = body["_id"].(string)
+	if docID == "" {
+		docID = newDocID()
+	}
+	rev, err = d.PutContext(ctx, docID, body)
+	return docID, rev, err
 }
 
 func (d *db) PutContext(_ context.Context, docID string, doc interface{}) (rev string, err error) {
-	// FIXME: Unimplemented
-	return "", nil
+	if err := d.checkDB(); err != nil {
+		return "", err
+	}
+	body, err := common.ToMap(doc)
+	if err != nil {
+		return "", err
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if strings.HasPrefix(docID, localPrefix) {
+		return d.putLocal(docID, body)
+	}
+	existing, err := d.readDoc(docID)
+	if err != nil {
+		return "", err
+	}
+	newRev, err := common.NewEdit(existing, docID, body)
+	if err != nil {
+		return "", err
+	}
+	return newRev.Rev, d.store(docID, existing, newRev)
 }
 
-func (d *db) DeleteContext(_ context.Context, docID, rev string) (newRev string, err error) {
-	// FIXME: Unimplemented
-	return "", nil
+// putLocal stores a _local document. The caller must hold the write lock.
+func (d *db) putLocal(docID string, body map[string]interface{}) (string, error) {
+	var old map[string]interface{}
+	if _, err := d.readJSON(docFile(docID), &old); err != nil {
+		return "", err
+	}
+	local, rev, err := common.PutLocal(old, docID, body)
+	if err != nil {
+		return "", err
+	}
+	if local == nil {
+		if err := os.Remove(filepath.Join(d.dir(), docFile(docID))); err != nil && !os.IsNotExist(err) {
+			return "", errors.WrapStatus(kivik.StatusInternalServerError, err)
+		}
+		return rev, nil
+	}
+	return rev, d.writeJSON(docFile(docID), local)
+}
+
+func (d *db) DeleteContext(ctx context.Context, docID, rev string) (newRev string, err error) {
+	return d.PutContext(ctx, docID, map[string]interface{}{
+		"_rev":     rev,
+		"_deleted": true,
+	})
+}
+
+// docs calls fn for each document in the database, other than _local
+// documents. The caller must hold the read lock.
+func (d *db) docs(fn func(docID string, doc *common.Document)) error {
+	files, err := ioutil.ReadDir(d.dir())
+	if err != nil {
+		return errors.WrapStatus(kivik.StatusInternalServerError, err)
+	}
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), docExt) {
+			continue
+		}
+		docID, err := url.QueryUnescape(strings.TrimSuffix(file.Name(), docExt))
+		if err != nil || strings.HasPrefix(docID, localPrefix) {
+			continue
+		}
+		doc, err := d.readDoc(docID)
+		if err != nil {
+			return err
+		}
+		if doc != nil {
+			fn(docID, doc)
+		}
+	}
+	return nil
 }
 
 func (d *db) InfoContext(_ context.Context) (*driver.DBInfo, error) {
-	// FIXME: Unimplemented
-	return nil, nil
+	if err := d.checkDB(); err != nil {
+		return nil, err
+	}
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	m, err := d.readMeta()
+	if err != nil {
+		return nil, err
+	}
+	info := &driver.DBInfo{
+		Name:      d.dbName,
+		UpdateSeq: strconv.FormatInt(m.UpdateSeq, 10),
+	}
+	err = d.docs(func(_ string, doc *common.Document) {
+		if doc.Winner().Deleted {
+			info.DeletedCount++
+		} else {
+			info.DocCount++
+		}
+	})
+	return info, err
 }
 
 func (d *db) CompactContext(_ context.Context) error {
@@ -68,33 +332,195 @@ func (d *db) ViewCleanupContext(_ context.Context) error {
 }
 
 func (d *db) SecurityContext(_ context.Context) (*driver.Security, error) {
-	// FIXME: Unimplemented
-	return nil, nil
+	if err := d.checkDB(); err != nil {
+		return nil, err
+	}
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	m, err := d.readMeta()
+	if err != nil {
+		return nil, err
+	}
+	return m.Security, nil
 }
 
-func (d *db) SetSecurityContext(_ context.Context, _ *driver.Security) error {
-	// FIXME: Unimplemented
-	return nil
+func (d *db) SetSecurityContext(_ context.Context, security *driver.Security) error {
+	if err := d.checkDB(); err != nil {
+		return err
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	m, err := d.readMeta()
+	if err != nil {
+		return err
+	}
+	m.Security = security
+	return d.writeJSON(metaFile, m)
 }
 
 func (d *db) RevsLimitContext(_ context.Context) (limit int, err error) {
-	// FIXME: Unimplemented
-	return 0, nil
+	if err := d.checkDB(); err != nil {
+		return 0, err
+	}
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	m, err := d.readMeta()
+	if err != nil {
+		return 0, err
+	}
+	return m.RevsLimit, nil
 }
 
 func (d *db) SetRevsLimitContext(_ context.Context, limit int) error {
-	// FIXME: Unimplemented
-	return nil
+	if limit < 1 {
+		return errors.Status(kivik.StatusBadRequest, "revs_limit must be positive")
+	}
+	if err := d.checkDB(); err != nil {
+		return err
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	m, err := d.readMeta()
+	if err != nil {
+		return err
+	}
+	m.RevsLimit = limit
+	return d.writeJSON(metaFile, m)
 }
 
-func (d *db) ChangesContext(_ context.Context, _ map[string]interface{}) (driver.Rows, error) {
-	// FIXME: Unimplemented
-	return nil, nil
+// ChangesContext returns the changes since the since option. The normal and
+// longpoll feeds return immediately, and the continuous feed checks for
+// further changes every pollInterval, until it is closed, or ctx is
+// cancelled. With style=all_docs, all leaf revisions are reported, rather
+// than only the winning revision. Filter functions are not supported.
+func (d *db) ChangesContext(ctx context.Context, opts map[string]interface{}) (driver.Rows, error) {
+	if filter, _ := opts["filter"].(string); filter != "" {
+		return nil, errors.Status(kivik.StatusNotImplemented, "filter functions not supported")
+	}
+	if err := d.checkDB(); err != nil {
+		return nil, err
+	}
+	d.mutex.RLock()
+	m, err := d.readMeta()
+	d.mutex.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	since, err := common.SinceOption(opts["since"], m.UpdateSeq)
+	if err != nil {
+		return nil, err
+	}
+	allDocs := opts["style"] == "all_docs"
+	poll := func(since int64) ([]driver.Row, error) {
+		d.mutex.RLock()
+		defer d.mutex.RUnlock()
+		var changes []driver.Row
+		err := d.docs(func(docID string, doc *common.Document) {
+			if doc.Seq > since {
+				changes = append(changes, doc.Change(docID, allDocs))
+			}
+		})
+		common.SortChanges(changes)
+		return changes, err
+	}
+	if feed, _ := opts["feed"].(string); feed == "continuous" {
+		return common.ContinuousChanges(ctx, since, poll, func(ctx context.Context, _ int64) {
+			select {
+			case <-time.After(pollInterval):
+			case <-ctx.Done():
+			}
+		}), nil
+	}
+	changes, err := poll(since)
+	if err != nil {
+		return nil, err
+	}
+	return &common.Rows{Rows: changes, Seq: strconv.FormatInt(m.UpdateSeq, 10)}, nil
 }
 
-func (d *db) BulkDocsContext(_ context.Context, _ ...interface{}) (driver.BulkResults, error) {
-	// FIXME: Unimplemented
-	return nil, nil
+func (d *db) BulkDocsContext(ctx context.Context, docs ...interface{}) (driver.BulkResults, error) {
+	results := make([]driver.BulkResult, len(docs))
+	for i, doc := range docs {
+		body, err := common.ToMap(doc)
+		if err != nil {
+			return nil, err
+		}
+		docID, _ := body["_id"].(string)
+		if docID == "" {
+			docID = newDocID()
+		}
+		rev, err := d.PutContext(ctx, docID, body)
+		results[i] = driver.BulkResult{ID: docID, Rev: rev, Error: err}
+	}
+	return &common.BulkResults{Results: results}, nil
+}
+
+// BulkDocsOptsContext supports the new_edits option. When false, documents
+// are stored with the revisions and history given in their _rev and
+// _revisions fields, as by replication.
+func (d *db) BulkDocsOptsContext(ctx context.Context, options map[string]interface{}, docs ...interface{}) (driver.BulkResults, error) {
+	if newEdits, ok := options["new_edits"]; !ok || newEdits == true || newEdits == "true" {
+		return d.BulkDocsContext(ctx, docs...)
+	}
+	if err := d.checkDB(); err != nil {
+		return nil, err
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	results := make([]driver.BulkResult, 0, len(docs))
+	for _, doc := range docs {
+		body, err := common.ToMap(doc)
+		if err != nil {
+			return nil, err
+		}
+		docID, _ := body["_id"].(string)
+		if err = d.replicate(docID, body); err != nil {
+			results = append(results, driver.BulkResult{ID: docID, Error: err})
+		}
+	}
+	// As CouchDB, only failures are reported with new_edits=false.
+	return &common.BulkResults{Results: results}, nil
+}
+
+// replicate stores a revision as given, with its history from _revisions.
+// The caller must hold the write lock.
+func (d *db) replicate(docID string, body map[string]interface{}) error {
+	existing, err := d.readDoc(docID)
+	if err != nil {
+		return err
+	}
+	rev, err := common.Replicated(existing, body)
+	if err != nil || rev == nil {
+		return err
+	}
+	return d.store(docID, existing, rev)
+}
+
+// RevsDiffContext returns the revisions in revMap which are not in the
+// database.
+func (d *db) RevsDiffContext(_ context.Context, revMap map[string][]string) (map[string]driver.RevDiff, error) {
+	if err := d.checkDB(); err != nil {
+		return nil, err
+	}
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	diff := make(map[string]driver.RevDiff)
+	for docID, revs := range revMap {
+		doc, err := d.readDoc(docID)
+		if err != nil {
+			return nil, err
+		}
+		var missing []string
+		for _, rev := range revs {
+			if doc == nil || !doc.Known(rev) {
+				missing = append(missing, rev)
+			}
+		}
+		if len(missing) > 0 {
+			diff[docID] = driver.RevDiff{Missing: missing}
+		}
+	}
+	return diff, nil
 }
 
 func (d *db) PutAttachmentContext(_ context.Context, _, _, _, _ string, _ io.Reader) (string, error) {
@@ -0,0 +1,112 @@
+package fs
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/flimzy/kivik"
+	"github.com/flimzy/kivik/driver"
+	"github.com/flimzy/kivik/errors"
+)
+
+// tempDir returns a temporary directory, in which a root directory that does
+// not yet exist can be initialized as a kivik data store.
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "kivik-fs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func newTestDB(t *testing.T, root string) *db {
+	c, err := (&fsDriver{}).NewClientContext(context.Background(), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exists, _ := c.DBExistsContext(context.Background(), "foo"); !exists {
+		if err := c.CreateDBContext(context.Background(), "foo"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d, err := c.DBContext(context.Background(), "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d.(*db)
+}
+
+func TestPutGetDelete(t *testing.T) {
+	ctx := context.Background()
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	root := dir + "/root"
+	d := newTestDB(t, root)
+	rev, err := d.PutContext(ctx, "some/doc", map[string]interface{}{"value": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = d.PutContext(ctx, "some/doc", map[string]interface{}{"value": 2}); errors.StatusCode(err) != kivik.StatusConflict {
+		t.Errorf("Expected conflict without _rev, got %v", err)
+	}
+	rev2, err := d.PutContext(ctx, "some/doc", map[string]interface{}{"_rev": rev, "value": 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Documents persist between clients.
+	d = newTestDB(t, root)
+	var doc map[string]interface{}
+	if err = d.GetContext(ctx, "some/doc", &doc, nil); err != nil {
+		t.Fatal(err)
+	}
+	if doc["_rev"] != rev2 || doc["value"] != 2.0 {
+		t.Errorf("Unexpected document: %v", doc)
+	}
+	if err = d.GetContext(ctx, "some/doc", &doc, map[string]interface{}{"rev": rev}); err != nil || doc["value"] != 1.0 {
+		t.Errorf("Unexpected old revision: %v, %v", doc, err)
+	}
+	if _, err = d.DeleteContext(ctx, "some/doc", rev2); err != nil {
+		t.Fatal(err)
+	}
+	if err = d.GetContext(ctx, "some/doc", &doc, nil); errors.StatusCode(err) != kivik.StatusNotFound {
+		t.Errorf("Expected deleted document to be missing, got %v", err)
+	}
+	if _, err = d.PutContext(ctx, "_local/check", map[string]interface{}{"seq": 1}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := d.InfoContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.DocCount != 0 || info.DeletedCount != 1 || info.UpdateSeq != "3" {
+		t.Errorf("Unexpected info: %+v", info)
+	}
+	rows, err := d.ChangesContext(ctx, map[string]interface{}{"since": "0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var row driver.Row
+	if err = rows.Next(&row); err != nil || row.ID != "some/doc" || !row.Deleted || row.Seq != "3" {
+		t.Errorf("Unexpected change %+v, error %v", row, err)
+	}
+	if err = rows.Next(&row); err == nil {
+		t.Errorf("Unexpected change for a _local document: %+v", row)
+	}
+}
+
+func TestMissingDB(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	root := dir + "/root"
+	c, err := (&fsDriver{}).NewClientContext(context.Background(), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, _ := c.DBContext(context.Background(), "missing")
+	if _, err := d.PutContext(context.Background(), "foo", map[string]interface{}{}); errors.StatusCode(err) != kivik.StatusNotFound {
+		t.Errorf("Expected Not Found for a missing database, got %v", err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"os"
 	"regexp"
+	"sync"
 
 	"github.com/flimzy/kivik"
 	"github.com/flimzy/kivik/driver"
@@ -74,6 +75,8 @@ func validateRootDir(dir string) error {
 type client struct {
 	*common.Client
 	root string
+	// mutex guards the documents of all databases under root.
+	mutex sync.RWMutex
 }
 
 var _ driver.Client = &client{}
@@ -0,0 +1,98 @@
+package fs
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/flimzy/kivik"
+	_ "github.com/flimzy/kivik/driver/memory"
+)
+
+func TestReplicateMemory(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	root := dir + "/root"
+	fsClient, err := kivik.New("fs", root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	memClient, err := kivik.New("memory", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = fsClient.CreateDB("source"); err != nil {
+		t.Fatal(err)
+	}
+	if err = memClient.CreateDB("target"); err != nil {
+		t.Fatal(err)
+	}
+	source, _ := fsClient.DB("source")
+	target, _ := memClient.DB("target")
+	if _, err = source.Put("foo", map[string]interface{}{"value": "foo"}); err != nil {
+		t.Fatal(err)
+	}
+
+	progress := make(chan kivik.ReplicationInfo, 10)
+	result := make(chan error, 1)
+	go func() {
+		_, err := kivik.Replicate(ctx, target, source, &kivik.ReplicationOptions{
+			Continuous: true,
+			Progress:   func(info kivik.ReplicationInfo) { progress <- info },
+		})
+		result <- err
+	}()
+	waitWritten := func(n int64) {
+		for {
+			select {
+			case info := <-progress:
+				if info.DocsWritten >= n {
+					return
+				}
+			case err := <-result:
+				t.Fatalf("Replication ended early: %v", err)
+			case <-time.After(5 * time.Second):
+				t.Fatalf("Timed out waiting for %d documents to be written", n)
+			}
+		}
+	}
+	waitWritten(1)
+	rev, err := source.Put("bar", map[string]interface{}{"value": "bar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitWritten(2)
+	cancel()
+	if err := <-result; err != context.Canceled {
+		t.Errorf("Expected replication to end with the context, got %v", err)
+	}
+	var doc map[string]interface{}
+	if err = target.Get("bar", &doc, nil); err != nil {
+		t.Fatal(err)
+	}
+	if doc["_rev"] != rev || doc["value"] != "bar" {
+		t.Errorf("Unexpected replicated document: %v", doc)
+	}
+
+	// And back again, into a new fs database.
+	if err = fsClient.CreateDB("copy"); err != nil {
+		t.Fatal(err)
+	}
+	dest, _ := fsClient.DB("copy")
+	info, err := kivik.Replicate(context.Background(), dest, target, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.DocsWritten != 2 {
+		t.Errorf("Unexpected result: %+v", info)
+	}
+	if err = dest.Get("bar", &doc, nil); err != nil {
+		t.Fatal(err)
+	}
+	if doc["_rev"] != rev {
+		t.Errorf("Unexpected replicated revision %s", doc["_rev"])
+	}
+}
@@ -0,0 +1,159 @@
+package instrument
+
+import (
+	"context"
+	"io"
+
+	"github.com/flimzy/kivik/driver"
+)
+
+type client struct {
+	driver.Client
+	t *tracer
+}
+
+var _ driver.Client = &client{}
+
+// newClient wraps c, preserving its optional interfaces.
+func newClient(c driver.Client, t *tracer) driver.Client {
+	return (&client{Client: c, t: t}).withOptional()
+}
+
+func (c *client) ServerInfoContext(ctx context.Context) (driver.ServerInfo, error) {
+	ctx, sp := c.t.begin(ctx, "ServerInfoContext", "", "")
+	info, err := c.Client.ServerInfoContext(ctx)
+	sp.end(err)
+	return info, err
+}
+
+func (c *client) AllDBsContext(ctx context.Context) ([]string, error) {
+	ctx, sp := c.t.begin(ctx, "AllDBsContext", "", "")
+	dbs, err := c.Client.AllDBsContext(ctx)
+	sp.end(err)
+	return dbs, err
+}
+
+func (c *client) DBExistsContext(ctx context.Context, dbName string) (bool, error) {
+	ctx, sp := c.t.begin(ctx, "DBExistsContext", dbName, "")
+	exists, err := c.Client.DBExistsContext(ctx, dbName)
+	sp.end(err)
+	return exists, err
+}
+
+func (c *client) CreateDBContext(ctx context.Context, dbName string) error {
+	ctx, sp := c.t.begin(ctx, "CreateDBContext", dbName, "")
+	err := c.Client.CreateDBContext(ctx, dbName)
+	sp.end(err)
+	return err
+}
+
+func (c *client) DestroyDBContext(ctx context.Context, dbName string) error {
+	ctx, sp := c.t.begin(ctx, "DestroyDBContext", dbName, "")
+	err := c.Client.DestroyDBContext(ctx, dbName)
+	sp.end(err)
+	return err
+}
+
+func (c *client) DBContext(ctx context.Context, dbName string) (driver.DB, error) {
+	ctx, sp := c.t.begin(ctx, "DBContext", dbName, "")
+	db, err := c.Client.DBContext(ctx, dbName)
+	sp.end(err)
+	if err != nil {
+		return db, err
+	}
+	return newDB(db, dbName, c.t), nil
+}
+
+func (c *client) SetDefault(key string, value interface{}) error {
+	_, sp := c.t.begin(context.Background(), "SetDefault", "", "")
+	err := c.Client.SetDefault(key, value)
+	sp.end(err)
+	return err
+}
+
+type authenticator struct{ *client }
+
+func (c authenticator) AuthenticateContext(ctx context.Context, a interface{}) error {
+	ctx, sp := c.t.begin(ctx, "AuthenticateContext", "", "")
+	err := c.Client.(driver.Authenticator).AuthenticateContext(ctx, a)
+	sp.end(err)
+	return err
+}
+
+type uuider struct{ *client }
+
+func (c uuider) UUIDsContext(ctx context.Context, count int) ([]string, error) {
+	ctx, sp := c.t.begin(ctx, "UUIDsContext", "", "")
+	uuids, err := c.Client.(driver.UUIDer).UUIDsContext(ctx, count)
+	sp.end(err)
+	return uuids, err
+}
+
+type logReader struct{ *client }
+
+func (c logReader) LogContext(ctx context.Context, length, offset int64) (io.ReadCloser, error) {
+	ctx, sp := c.t.begin(ctx, "LogContext", "", "")
+	body, err := c.Client.(driver.LogReader).LogContext(ctx, length, offset)
+	if err != nil {
+		sp.end(err)
+		return nil, err
+	}
+	return &readCloser{ReadCloser: body, sp: sp}, nil
+}
+
+type cluster struct{ *client }
+
+func (c cluster) MembershipContext(ctx context.Context) ([]string, []string, error) {
+	ctx, sp := c.t.begin(ctx, "MembershipContext", "", "")
+	all, members, err := c.Client.(driver.Cluster).MembershipContext(ctx)
+	sp.end(err)
+	return all, members, err
+}
+
+type configer struct{ *client }
+
+func (c configer) ConfigContext(ctx context.Context) (driver.Config, error) {
+	ctx, sp := c.t.begin(ctx, "ConfigContext", "", "")
+	conf, err := c.Client.(driver.Configer).ConfigContext(ctx)
+	sp.end(err)
+	return conf, err
+}
+
+type dbUpdater struct{ *client }
+
+func (c dbUpdater) DBUpdates() (driver.DBUpdates, error) {
+	_, sp := c.t.begin(context.Background(), "DBUpdates", "", "")
+	updates, err := c.Client.(driver.DBUpdater).DBUpdates()
+	if err != nil {
+		sp.end(err)
+		return nil, err
+	}
+	return &dbUpdates{DBUpdates: updates, sp: sp}, nil
+}
+
+type activeTasker struct{ *client }
+
+func (c activeTasker) ActiveTasksContext(ctx context.Context) ([]driver.ActiveTask, error) {
+	ctx, sp := c.t.begin(ctx, "ActiveTasksContext", "", "")
+	tasks, err := c.Client.(driver.ActiveTasker).ActiveTasksContext(ctx)
+	sp.call.Rows = int64(len(tasks))
+	sp.end(err)
+	return tasks, err
+}
+
+type replicator struct{ *client }
+
+func (c replicator) ReplicateContext(ctx context.Context, target, source string, options map[string]interface{}) (driver.Replication, error) {
+	ctx, sp := c.t.begin(ctx, "ReplicateContext", "", "")
+	rep, err := c.Client.(driver.Replicator).ReplicateContext(ctx, target, source, options)
+	sp.end(err)
+	return rep, err
+}
+
+func (c replicator) GetReplicationsContext(ctx context.Context, options map[string]interface{}) ([]driver.Replication, error) {
+	ctx, sp := c.t.begin(ctx, "GetReplicationsContext", "", "")
+	reps, err := c.Client.(driver.Replicator).GetReplicationsContext(ctx, options)
+	sp.call.Rows = int64(len(reps))
+	sp.end(err)
+	return reps, err
+}
@@ -0,0 +1,268 @@
+package instrument
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/flimzy/kivik/driver"
+)
+
+type db struct {
+	driver.DB
+	name string
+	t    *tracer
+}
+
+var _ driver.DB = &db{}
+
+// newDB wraps d, preserving its optional interfaces.
+func newDB(d driver.DB, name string, t *tracer) driver.DB {
+	return (&db{DB: d, name: name, t: t}).withOptional()
+}
+
+func (d *db) begin(ctx context.Context, method, docID string) (context.Context, *span) {
+	return d.t.begin(ctx, method, d.name, docID)
+}
+
+// rows wraps a Rows result, ending sp when it is closed.
+func (d *db) rows(r driver.Rows, err error, sp *span) (driver.Rows, error) {
+	if err != nil {
+		sp.end(err)
+		return nil, err
+	}
+	return &rows{Rows: r, sp: sp}, nil
+}
+
+func (d *db) SetOption(key string, value interface{}) error {
+	_, sp := d.begin(context.Background(), "SetOption", "")
+	err := d.DB.SetOption(key, value)
+	sp.end(err)
+	return err
+}
+
+func (d *db) AllDocsContext(ctx context.Context, options map[string]interface{}) (driver.Rows, error) {
+	ctx, sp := d.begin(ctx, "AllDocsContext", "")
+	r, err := d.DB.AllDocsContext(ctx, options)
+	return d.rows(r, err, sp)
+}
+
+func (d *db) GetContext(ctx context.Context, docID string, doc interface{}, options map[string]interface{}) error {
+	ctx, sp := d.begin(ctx, "GetContext", docID)
+	err := d.DB.GetContext(ctx, docID, doc, options)
+	sp.end(err)
+	return err
+}
+
+func (d *db) CreateDocContext(ctx context.Context, doc interface{}) (string, string, error) {
+	ctx, sp := d.begin(ctx, "CreateDocContext", "")
+	docID, rev, err := d.DB.CreateDocContext(ctx, doc)
+	sp.call.DocID = docID
+	sp.end(err)
+	return docID, rev, err
+}
+
+func (d *db) PutContext(ctx context.Context, docID string, doc interface{}) (string, error) {
+	ctx, sp := d.begin(ctx, "PutContext", docID)
+	rev, err := d.DB.PutContext(ctx, docID, doc)
+	sp.end(err)
+	return rev, err
+}
+
+func (d *db) DeleteContext(ctx context.Context, docID, rev string) (string, error) {
+	ctx, sp := d.begin(ctx, "DeleteContext", docID)
+	newRev, err := d.DB.DeleteContext(ctx, docID, rev)
+	sp.end(err)
+	return newRev, err
+}
+
+func (d *db) InfoContext(ctx context.Context) (*driver.DBInfo, error) {
+	ctx, sp := d.begin(ctx, "InfoContext", "")
+	info, err := d.DB.InfoContext(ctx)
+	sp.end(err)
+	return info, err
+}
+
+func (d *db) CompactContext(ctx context.Context) error {
+	ctx, sp := d.begin(ctx, "CompactContext", "")
+	err := d.DB.CompactContext(ctx)
+	sp.end(err)
+	return err
+}
+
+func (d *db) CompactViewContext(ctx context.Context, ddocID string) error {
+	ctx, sp := d.begin(ctx, "CompactViewContext", ddocID)
+	err := d.DB.CompactViewContext(ctx, ddocID)
+	sp.end(err)
+	return err
+}
+
+func (d *db) ViewCleanupContext(ctx context.Context) error {
+	ctx, sp := d.begin(ctx, "ViewCleanupContext", "")
+	err := d.DB.ViewCleanupContext(ctx)
+	sp.end(err)
+	return err
+}
+
+func (d *db) SecurityContext(ctx context.Context) (*driver.Security, error) {
+	ctx, sp := d.begin(ctx, "SecurityContext", "")
+	sec, err := d.DB.SecurityContext(ctx)
+	sp.end(err)
+	return sec, err
+}
+
+func (d *db) SetSecurityContext(ctx context.Context, security *driver.Security) error {
+	ctx, sp := d.begin(ctx, "SetSecurityContext", "")
+	err := d.DB.SetSecurityContext(ctx, security)
+	sp.end(err)
+	return err
+}
+
+func (d *db) RevsLimitContext(ctx context.Context) (int, error) {
+	ctx, sp := d.begin(ctx, "RevsLimitContext", "")
+	limit, err := d.DB.RevsLimitContext(ctx)
+	sp.end(err)
+	return limit, err
+}
+
+func (d *db) SetRevsLimitContext(ctx context.Context, limit int) error {
+	ctx, sp := d.begin(ctx, "SetRevsLimitContext", "")
+	err := d.DB.SetRevsLimitContext(ctx, limit)
+	sp.end(err)
+	return err
+}
+
+func (d *db) ChangesContext(ctx context.Context, options map[string]interface{}) (driver.Rows, error) {
+	ctx, sp := d.begin(ctx, "ChangesContext", "")
+	r, err := d.DB.ChangesContext(ctx, options)
+	return d.rows(r, err, sp)
+}
+
+func (d *db) BulkDocsContext(ctx context.Context, docs ...interface{}) (driver.BulkResults, error) {
+	ctx, sp := d.begin(ctx, "BulkDocsContext", "")
+	results, err := d.DB.BulkDocsContext(ctx, docs...)
+	if err != nil {
+		sp.end(err)
+		return nil, err
+	}
+	return &bulkResults{BulkResults: results, sp: sp}, nil
+}
+
+func (d *db) PutAttachmentContext(ctx context.Context, docID, rev, filename, contentType string, body io.Reader) (string, error) {
+	ctx, sp := d.begin(ctx, "PutAttachmentContext", docID)
+	r := &reader{Reader: body}
+	newRev, err := d.DB.PutAttachmentContext(ctx, docID, rev, filename, contentType, r)
+	sp.call.Bytes = r.n
+	sp.end(err)
+	return newRev, err
+}
+
+func (d *db) GetAttachmentContext(ctx context.Context, docID, rev, filename string) (string, driver.Checksum, io.ReadCloser, error) {
+	ctx, sp := d.begin(ctx, "GetAttachmentContext", docID)
+	contentType, md5sum, body, err := d.DB.GetAttachmentContext(ctx, docID, rev, filename)
+	if err != nil {
+		sp.end(err)
+		return contentType, md5sum, body, err
+	}
+	return contentType, md5sum, &readCloser{ReadCloser: body, sp: sp}, nil
+}
+
+func (d *db) DeleteAttachmentContext(ctx context.Context, docID, rev, filename string) (string, error) {
+	ctx, sp := d.begin(ctx, "DeleteAttachmentContext", docID)
+	newRev, err := d.DB.DeleteAttachmentContext(ctx, docID, rev, filename)
+	sp.end(err)
+	return newRev, err
+}
+
+func (d *db) QueryContext(ctx context.Context, ddoc, view string, options map[string]interface{}) (driver.Rows, error) {
+	ctx, sp := d.begin(ctx, "QueryContext", ddoc)
+	r, err := d.DB.QueryContext(ctx, ddoc, view, options)
+	return d.rows(r, err, sp)
+}
+
+type finder struct{ *db }
+
+func (d finder) FindContext(ctx context.Context, query interface{}) (driver.Rows, error) {
+	ctx, sp := d.begin(ctx, "FindContext", "")
+	r, err := d.DB.(driver.Finder).FindContext(ctx, query)
+	return d.rows(r, err, sp)
+}
+
+func (d finder) CreateIndexContext(ctx context.Context, ddoc, name string, index interface{}) error {
+	ctx, sp := d.begin(ctx, "CreateIndexContext", ddoc)
+	err := d.DB.(driver.Finder).CreateIndexContext(ctx, ddoc, name, index)
+	sp.end(err)
+	return err
+}
+
+func (d finder) GetIndexesContext(ctx context.Context) ([]driver.Index, error) {
+	ctx, sp := d.begin(ctx, "GetIndexesContext", "")
+	indexes, err := d.DB.(driver.Finder).GetIndexesContext(ctx)
+	sp.end(err)
+	return indexes, err
+}
+
+func (d finder) DeleteIndexContext(ctx context.Context, ddoc, name string) error {
+	ctx, sp := d.begin(ctx, "DeleteIndexContext", ddoc)
+	err := d.DB.(driver.Finder).DeleteIndexContext(ctx, ddoc, name)
+	sp.end(err)
+	return err
+}
+
+type attachmentMetaer struct{ *db }
+
+func (d attachmentMetaer) GetAttachmentMetaContext(ctx context.Context, docID, rev, filename string) (string, driver.Checksum, error) {
+	ctx, sp := d.begin(ctx, "GetAttachmentMetaContext", docID)
+	contentType, md5sum, err := d.DB.(driver.AttachmentMetaer).GetAttachmentMetaContext(ctx, docID, rev, filename)
+	sp.end(err)
+	return contentType, md5sum, err
+}
+
+type rever struct{ *db }
+
+func (d rever) RevContext(ctx context.Context, docID string) (string, error) {
+	ctx, sp := d.begin(ctx, "RevContext", docID)
+	rev, err := d.DB.(driver.Rever).RevContext(ctx, docID)
+	sp.end(err)
+	return rev, err
+}
+
+type dbFlusher struct{ *db }
+
+func (d dbFlusher) FlushContext(ctx context.Context) (time.Time, error) {
+	ctx, sp := d.begin(ctx, "FlushContext", "")
+	t, err := d.DB.(driver.DBFlusher).FlushContext(ctx)
+	sp.end(err)
+	return t, err
+}
+
+type copier struct{ *db }
+
+func (d copier) CopyContext(ctx context.Context, targetID, sourceID string, options map[string]interface{}) (string, error) {
+	ctx, sp := d.begin(ctx, "CopyContext", sourceID)
+	rev, err := d.DB.(driver.Copier).CopyContext(ctx, targetID, sourceID, options)
+	sp.end(err)
+	return rev, err
+}
+
+type revsDiffer struct{ *db }
+
+func (d revsDiffer) RevsDiffContext(ctx context.Context, revMap map[string][]string) (map[string]driver.RevDiff, error) {
+	ctx, sp := d.begin(ctx, "RevsDiffContext", "")
+	diff, err := d.DB.(driver.RevsDiffer).RevsDiffContext(ctx, revMap)
+	sp.call.Rows = int64(len(diff))
+	sp.end(err)
+	return diff, err
+}
+
+type bulkDocsOptioner struct{ *db }
+
+func (d bulkDocsOptioner) BulkDocsOptsContext(ctx context.Context, options map[string]interface{}, docs ...interface{}) (driver.BulkResults, error) {
+	ctx, sp := d.begin(ctx, "BulkDocsOptsContext", "")
+	results, err := d.DB.(driver.BulkDocsOptioner).BulkDocsOptsContext(ctx, options, docs...)
+	if err != nil {
+		sp.end(err)
+		return nil, err
+	}
+	return &bulkResults{BulkResults: results, sp: sp}, nil
+}
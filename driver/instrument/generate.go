@@ -0,0 +1,101 @@
+// +build ignore
+
+// This program generates optional.go, which combines the mixins for each
+// optional interface into a single value, for every combination of optional
+// interfaces a wrapped client or database may implement. Run it with
+// go generate.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"strings"
+)
+
+type iface struct {
+	name  string // The driver interface name
+	mixin string // The mixin type name
+}
+
+type wrapper struct {
+	recv  string // The receiver name
+	typ   string // The wrapper type name
+	iface string // The driver interface returned
+	opts  []iface
+}
+
+var wrappers = []wrapper{
+	{
+		recv:  "c",
+		typ:   "client",
+		iface: "Client",
+		opts: []iface{
+			{"Authenticator", "authenticator"},
+			{"UUIDer", "uuider"},
+			{"LogReader", "logReader"},
+			{"Cluster", "cluster"},
+			{"Configer", "configer"},
+			{"DBUpdater", "dbUpdater"},
+			{"ActiveTasker", "activeTasker"},
+			{"Replicator", "replicator"},
+		},
+	},
+	{
+		recv:  "d",
+		typ:   "db",
+		iface: "DB",
+		opts: []iface{
+			{"Finder", "finder"},
+			{"AttachmentMetaer", "attachmentMetaer"},
+			{"Rever", "rever"},
+			{"DBFlusher", "dbFlusher"},
+			{"Copier", "copier"},
+			{"RevsDiffer", "revsDiffer"},
+			{"BulkDocsOptioner", "bulkDocsOptioner"},
+		},
+	},
+}
+
+func main() {
+	buf := &bytes.Buffer{}
+	fmt.Fprint(buf, "// Code generated by generate.go. DO NOT EDIT.\n\n")
+	fmt.Fprint(buf, "package instrument\n\n")
+	fmt.Fprint(buf, "import \"github.com/flimzy/kivik/driver\"\n")
+	for _, w := range wrappers {
+		writeWrapper(buf, w)
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile("optional.go", src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func writeWrapper(buf *bytes.Buffer, w wrapper) {
+	r := w.recv
+	fmt.Fprintf(buf, "\n// withOptional returns %s, extended with each optional interface implemented\n", r)
+	fmt.Fprintf(buf, "// by the wrapped %s.\n", w.iface)
+	fmt.Fprintf(buf, "func (%s *%s) withOptional() driver.%s {\n", r, w.typ, w.iface)
+	fmt.Fprint(buf, "\tvar mask uint\n")
+	for i, opt := range w.opts {
+		fmt.Fprintf(buf, "\tif _, ok := %s.%s.(driver.%s); ok {\n\t\tmask |= 1 << %d\n\t}\n", r, w.iface, opt.name, i)
+	}
+	fmt.Fprint(buf, "\tswitch mask {\n")
+	for mask := 1; mask < 1<<uint(len(w.opts)); mask++ {
+		types := []string{"*" + w.typ}
+		values := []string{r}
+		for i, opt := range w.opts {
+			if mask&(1<<uint(i)) != 0 {
+				types = append(types, opt.mixin)
+				values = append(values, opt.mixin+"{"+r+"}")
+			}
+		}
+		fmt.Fprintf(buf, "\tcase %d:\n\t\treturn struct {\n\t\t\t%s\n\t\t}{%s}\n", mask, strings.Join(types, "\n\t\t\t"), strings.Join(values, ", "))
+	}
+	fmt.Fprintf(buf, "\t}\n\treturn %s\n}\n", r)
+}
@@ -0,0 +1,141 @@
+// Package instrument provides a driver wrapper which reports each driver call
+// to pluggable hooks, for tracing and metrics.
+//
+// The wrapped Client and DB implement exactly the optional interfaces (such
+// as driver.Finder or driver.Rever) implemented by the underlying driver, so
+// kivik's behavior is unchanged by instrumentation.
+//
+// Example:
+//
+//	kivik.Register("traced-couch", instrument.Wrap(&couchdb.Couch{}, "couch", myHook))
+//	client, err := kivik.New("traced-couch", dsn)
+package instrument
+
+//go:generate go run generate.go
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/flimzy/kivik/driver"
+	"github.com/flimzy/kivik/errors"
+)
+
+// Call describes a single driver call.
+type Call struct {
+	// Driver is the name given to Wrap.
+	Driver string
+	// Method is the name of the driver method, such as "GetContext".
+	Method string
+	// DB is the database name, for calls against a database.
+	DB string
+	// DocID is the document ID, for calls against a single document.
+	DocID string
+	// Start is the time the call began.
+	Start time.Time
+	// Duration is the duration of the call. For calls which return an
+	// iterator or stream, it includes the time until the result is closed.
+	Duration time.Duration
+	// Err is the error returned by the call, or encountered while iterating
+	// over its results.
+	Err error
+	// Status is the HTTP status code of Err, or 0 if Err is nil.
+	Status int
+	// Rows is the number of rows, results or updates read from an iterator.
+	Rows int64
+	// Bytes is the number of bytes streamed, for attachments and logs.
+	Bytes int64
+}
+
+// Hook receives notifications of driver calls.
+type Hook interface {
+	// Start is called before each call. The returned context is passed to the
+	// driver, and to End, so may carry values such as a tracing span.
+	Start(ctx context.Context, call *Call) context.Context
+	// End is called once the call, and any streamed results, are complete.
+	End(ctx context.Context, call *Call)
+}
+
+// Wrap returns a driver which instruments all clients it creates. name is
+// reported as Call.Driver.
+func Wrap(d driver.Driver, name string, hooks ...Hook) driver.Driver {
+	return &instrumentedDriver{Driver: d, t: &tracer{driver: name, hooks: hooks}}
+}
+
+// WrapClient instruments an existing client.
+func WrapClient(c driver.Client, name string, hooks ...Hook) driver.Client {
+	return newClient(c, &tracer{driver: name, hooks: hooks})
+}
+
+// WrapDB instruments an existing database handle. dbName is reported as
+// Call.DB.
+func WrapDB(db driver.DB, name, dbName string, hooks ...Hook) driver.DB {
+	return newDB(db, dbName, &tracer{driver: name, hooks: hooks})
+}
+
+type instrumentedDriver struct {
+	driver.Driver
+	t *tracer
+}
+
+var _ driver.Driver = &instrumentedDriver{}
+
+func (d *instrumentedDriver) NewClientContext(ctx context.Context, dsn string) (driver.Client, error) {
+	ctx, sp := d.t.begin(ctx, "NewClientContext", "", "")
+	c, err := d.Driver.NewClientContext(ctx, dsn)
+	sp.end(err)
+	if err != nil {
+		return nil, err
+	}
+	return newClient(c, d.t), nil
+}
+
+type tracer struct {
+	driver string
+	hooks  []Hook
+}
+
+var now = time.Now
+
+// span tracks a call in progress.
+type span struct {
+	t    *tracer
+	ctx  context.Context
+	call *Call
+
+	mu   sync.Mutex
+	done bool
+}
+
+// begin starts a call, returning the context to pass to the driver.
+func (t *tracer) begin(ctx context.Context, method, dbName, docID string) (context.Context, *span) {
+	call := &Call{
+		Driver: t.driver,
+		Method: method,
+		DB:     dbName,
+		DocID:  docID,
+		Start:  now(),
+	}
+	for _, h := range t.hooks {
+		ctx = h.Start(ctx, call)
+	}
+	return ctx, &span{t: t, ctx: ctx, call: call}
+}
+
+// end completes the call, if not already completed. Hooks are called in the
+// reverse of the order in which they were started.
+func (s *span) end(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done {
+		return
+	}
+	s.done = true
+	s.call.Duration = now().Sub(s.call.Start)
+	s.call.Err = err
+	s.call.Status = errors.StatusCode(err)
+	for i := len(s.t.hooks) - 1; i >= 0; i-- {
+		s.t.hooks[i].End(s.ctx, s.call)
+	}
+}
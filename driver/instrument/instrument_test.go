@@ -0,0 +1,277 @@
+package instrument
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flimzy/diff"
+
+	"github.com/flimzy/kivik/driver"
+	"github.com/flimzy/kivik/errors"
+	"github.com/flimzy/kivik/metrics"
+)
+
+type fakeClient struct {
+	driver.Client
+	db driver.DB
+}
+
+func (c *fakeClient) DBContext(_ context.Context, _ string) (driver.DB, error) {
+	return c.db, nil
+}
+
+type fakeUUIDer struct{ *fakeClient }
+
+func (c fakeUUIDer) UUIDsContext(_ context.Context, count int) ([]string, error) {
+	return make([]string, count), nil
+}
+
+type fakeLogReader struct{ *fakeClient }
+
+func (c fakeLogReader) LogContext(_ context.Context, _, _ int64) (io.ReadCloser, error) {
+	return ioutil.NopCloser(strings.NewReader("log data")), nil
+}
+
+type fakeDB struct {
+	driver.DB
+}
+
+func (d *fakeDB) GetContext(_ context.Context, docID string, _ interface{}, _ map[string]interface{}) error {
+	if docID == "missing" {
+		return errors.Status(http.StatusNotFound, "missing")
+	}
+	return nil
+}
+
+func (d *fakeDB) AllDocsContext(_ context.Context, _ map[string]interface{}) (driver.Rows, error) {
+	return &fakeRows{remaining: 3}, nil
+}
+
+type fakeRever struct{ *fakeDB }
+
+func (d fakeRever) RevContext(_ context.Context, _ string) (string, error) {
+	return "1-xxx", nil
+}
+
+type fakeCopier struct{ *fakeDB }
+
+func (d fakeCopier) CopyContext(_ context.Context, _, _ string, _ map[string]interface{}) (string, error) {
+	return "1-xxx", nil
+}
+
+type fakeRows struct {
+	driver.Rows
+	remaining int
+}
+
+func (r *fakeRows) Next(_ *driver.Row) error {
+	if r.remaining == 0 {
+		return io.EOF
+	}
+	r.remaining--
+	return nil
+}
+
+func (r *fakeRows) Close() error { return nil }
+
+type recorder struct {
+	started []string
+	calls   []Call
+}
+
+func (h *recorder) Start(ctx context.Context, call *Call) context.Context {
+	h.started = append(h.started, call.Method)
+	return ctx
+}
+
+func (h *recorder) End(_ context.Context, call *Call) {
+	c := *call
+	c.Start = time.Time{}
+	h.calls = append(h.calls, c)
+}
+
+// implements returns the names of the optional interfaces implemented by x.
+func implements(x interface{}) []string {
+	var names []string
+	if _, ok := x.(driver.Authenticator); ok {
+		names = append(names, "Authenticator")
+	}
+	if _, ok := x.(driver.UUIDer); ok {
+		names = append(names, "UUIDer")
+	}
+	if _, ok := x.(driver.LogReader); ok {
+		names = append(names, "LogReader")
+	}
+	if _, ok := x.(driver.Cluster); ok {
+		names = append(names, "Cluster")
+	}
+	if _, ok := x.(driver.Configer); ok {
+		names = append(names, "Configer")
+	}
+	if _, ok := x.(driver.DBUpdater); ok {
+		names = append(names, "DBUpdater")
+	}
+	if _, ok := x.(driver.ActiveTasker); ok {
+		names = append(names, "ActiveTasker")
+	}
+	if _, ok := x.(driver.Replicator); ok {
+		names = append(names, "Replicator")
+	}
+	if _, ok := x.(driver.Finder); ok {
+		names = append(names, "Finder")
+	}
+	if _, ok := x.(driver.AttachmentMetaer); ok {
+		names = append(names, "AttachmentMetaer")
+	}
+	if _, ok := x.(driver.Rever); ok {
+		names = append(names, "Rever")
+	}
+	if _, ok := x.(driver.DBFlusher); ok {
+		names = append(names, "DBFlusher")
+	}
+	if _, ok := x.(driver.Copier); ok {
+		names = append(names, "Copier")
+	}
+	if _, ok := x.(driver.RevsDiffer); ok {
+		names = append(names, "RevsDiffer")
+	}
+	if _, ok := x.(driver.BulkDocsOptioner); ok {
+		names = append(names, "BulkDocsOptioner")
+	}
+	return names
+}
+
+func TestOptionalInterfaces(t *testing.T) {
+	type oiTest struct {
+		Name     string
+		Wrapped  interface{}
+		Expected []string
+	}
+	c := &fakeClient{}
+	d := &fakeDB{}
+	tests := []oiTest{
+		{
+			Name:    "PlainClient",
+			Wrapped: WrapClient(c, "fake"),
+		},
+		{
+			Name:     "UUIDer",
+			Wrapped:  WrapClient(fakeUUIDer{c}, "fake"),
+			Expected: []string{"UUIDer"},
+		},
+		{
+			Name: "UUIDerLogReader",
+			Wrapped: WrapClient(struct {
+				*fakeClient
+				fakeUUIDer
+				fakeLogReader
+			}{c, fakeUUIDer{c}, fakeLogReader{c}}, "fake"),
+			Expected: []string{"UUIDer", "LogReader"},
+		},
+		{
+			Name:    "PlainDB",
+			Wrapped: WrapDB(d, "fake", "foo"),
+		},
+		{
+			Name: "ReverCopier",
+			Wrapped: WrapDB(struct {
+				*fakeDB
+				fakeRever
+				fakeCopier
+			}{d, fakeRever{d}, fakeCopier{d}}, "fake", "foo"),
+			Expected: []string{"Rever", "Copier"},
+		},
+	}
+	for _, test := range tests {
+		func(test oiTest) {
+			t.Run(test.Name, func(t *testing.T) {
+				if d := diff.Interface(test.Expected, implements(test.Wrapped)); d != "" {
+					t.Error(d)
+				}
+			})
+		}(test)
+	}
+}
+
+func TestHooks(t *testing.T) {
+	defer func(n func() time.Time) { now = n }(now)
+	var tick time.Duration
+	now = func() time.Time {
+		tick += time.Second
+		return time.Time{}.Add(tick)
+	}
+	h := &recorder{}
+	db := WrapDB(struct {
+		*fakeDB
+		fakeRever
+	}{&fakeDB{}, fakeRever{}}, "fake", "foo", h)
+	_ = db.GetContext(context.Background(), "bar", nil, nil)
+	_ = db.GetContext(context.Background(), "missing", nil, nil)
+	_, _ = db.(driver.Rever).RevContext(context.Background(), "baz")
+	expected := []Call{
+		{Driver: "fake", Method: "GetContext", DB: "foo", DocID: "bar", Duration: time.Second},
+		{Driver: "fake", Method: "GetContext", DB: "foo", DocID: "missing", Duration: time.Second,
+			Err: errors.Status(http.StatusNotFound, "missing"), Status: http.StatusNotFound},
+		{Driver: "fake", Method: "RevContext", DB: "foo", DocID: "baz", Duration: time.Second},
+	}
+	if d := diff.Interface(expected, h.calls); d != "" {
+		t.Error(d)
+	}
+}
+
+func TestRows(t *testing.T) {
+	h := &recorder{}
+	client := WrapClient(&fakeClient{db: &fakeDB{}}, "fake", h)
+	db, err := client.DBContext(context.Background(), "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := db.AllDocsContext(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h.calls) != 1 {
+		t.Fatalf("Expected only DBContext to have ended, got %d calls", len(h.calls))
+	}
+	row := &driver.Row{}
+	for rows.Next(row) == nil {
+	}
+	_ = rows.Close()
+	if len(h.calls) != 2 {
+		t.Fatalf("Expected 2 calls, got %d", len(h.calls))
+	}
+	call := h.calls[1]
+	if call.Method != "AllDocsContext" || call.DB != "foo" {
+		t.Errorf("Unexpected call %s on %s", call.Method, call.DB)
+	}
+	if call.Rows != 3 {
+		t.Errorf("Expected 3 rows, got %d", call.Rows)
+	}
+	if call.Err != nil {
+		t.Errorf("Unexpected error: %s", call.Err)
+	}
+}
+
+func TestMetricsHook(t *testing.T) {
+	r := metrics.NewRegistry()
+	c := WrapClient(fakeLogReader{&fakeClient{}}, "fake", MetricsHook(r))
+	body, err := c.(driver.LogReader).LogContext(context.Background(), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ioutil.ReadAll(body); err != nil {
+		t.Fatal(err)
+	}
+	_ = body.Close()
+	if n := r.Histogram(MetricCallTime, "", nil, "driver", "method", "status").Count("fake", "LogContext", "0"); n != 1 {
+		t.Errorf("Expected 1 call, got %d", n)
+	}
+	if n := r.Counter(MetricBytes, "", "driver", "method").Value("fake", "LogContext"); n != 8 {
+		t.Errorf("Expected 8 bytes, got %v", n)
+	}
+}
@@ -0,0 +1,115 @@
+package instrument
+
+import (
+	"io"
+
+	"github.com/flimzy/kivik/driver"
+)
+
+// iterErr returns the error to report for an iterator's Next method. io.EOF
+// is the normal end of iteration, so is not reported.
+func iterErr(err error) error {
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+// rows counts rows, and ends the call when the iterator is exhausted or
+// closed.
+type rows struct {
+	driver.Rows
+	sp *span
+}
+
+func (r *rows) Next(row *driver.Row) error {
+	err := r.Rows.Next(row)
+	if err == nil {
+		r.sp.call.Rows++
+		return nil
+	}
+	r.sp.end(iterErr(err))
+	return err
+}
+
+func (r *rows) Close() error {
+	err := r.Rows.Close()
+	r.sp.end(err)
+	return err
+}
+
+type bulkResults struct {
+	driver.BulkResults
+	sp *span
+}
+
+func (r *bulkResults) Next(result *driver.BulkResult) error {
+	err := r.BulkResults.Next(result)
+	if err == nil {
+		r.sp.call.Rows++
+		return nil
+	}
+	r.sp.end(iterErr(err))
+	return err
+}
+
+func (r *bulkResults) Close() error {
+	err := r.BulkResults.Close()
+	r.sp.end(err)
+	return err
+}
+
+type dbUpdates struct {
+	driver.DBUpdates
+	sp *span
+}
+
+func (u *dbUpdates) Next(update *driver.DBUpdate) error {
+	err := u.DBUpdates.Next(update)
+	if err == nil {
+		u.sp.call.Rows++
+		return nil
+	}
+	u.sp.end(iterErr(err))
+	return err
+}
+
+func (u *dbUpdates) Close() error {
+	err := u.DBUpdates.Close()
+	u.sp.end(err)
+	return err
+}
+
+// readCloser counts bytes read, and ends the call when the stream is
+// exhausted or closed.
+type readCloser struct {
+	io.ReadCloser
+	sp *span
+}
+
+func (r *readCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.sp.call.Bytes += int64(n)
+	if err != nil {
+		r.sp.end(iterErr(err))
+	}
+	return n, err
+}
+
+func (r *readCloser) Close() error {
+	err := r.ReadCloser.Close()
+	r.sp.end(err)
+	return err
+}
+
+// reader counts bytes read.
+type reader struct {
+	io.Reader
+	n int64
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
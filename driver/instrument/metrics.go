@@ -0,0 +1,48 @@
+package instrument
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/flimzy/kivik/metrics"
+)
+
+// Metric names.
+const (
+	MetricCallTime = "couchdb.driver.call_time_seconds"
+	MetricRows     = "couchdb.driver.rows"
+	MetricBytes    = "couchdb.driver.bytes"
+)
+
+type metricsHook struct {
+	callTime *metrics.Histogram
+	rows     *metrics.Counter
+	bytes    *metrics.Counter
+}
+
+var _ Hook = &metricsHook{}
+
+// MetricsHook returns a hook which records the duration of each call, and the
+// rows and bytes streamed, in r. Calls are labeled by driver, method and
+// status, where status is the HTTP status code of the error, or 0 on success.
+func MetricsHook(r *metrics.Registry) Hook {
+	return &metricsHook{
+		callTime: r.Histogram(MetricCallTime, "length of a driver call", nil, "driver", "method", "status"),
+		rows:     r.Counter(MetricRows, "number of rows read from driver iterators", "driver", "method"),
+		bytes:    r.Counter(MetricBytes, "number of bytes streamed by the driver", "driver", "method"),
+	}
+}
+
+func (h *metricsHook) Start(ctx context.Context, _ *Call) context.Context {
+	return ctx
+}
+
+func (h *metricsHook) End(_ context.Context, call *Call) {
+	h.callTime.Observe(call.Duration.Seconds(), call.Driver, call.Method, strconv.Itoa(call.Status))
+	if call.Rows > 0 {
+		h.rows.Add(float64(call.Rows), call.Driver, call.Method)
+	}
+	if call.Bytes > 0 {
+		h.bytes.Add(float64(call.Bytes), call.Driver, call.Method)
+	}
+}
@@ -0,0 +1,3068 @@
+// Code generated by generate.go. DO NOT EDIT.
+
+package instrument
+
+import "github.com/flimzy/kivik/driver"
+
+// withOptional returns c, extended with each optional interface implemented
+// by the wrapped Client.
+func (c *client) withOptional() driver.Client {
+	var mask uint
+	if _, ok := c.Client.(driver.Authenticator); ok {
+		mask |= 1 << 0
+	}
+	if _, ok := c.Client.(driver.UUIDer); ok {
+		mask |= 1 << 1
+	}
+	if _, ok := c.Client.(driver.LogReader); ok {
+		mask |= 1 << 2
+	}
+	if _, ok := c.Client.(driver.Cluster); ok {
+		mask |= 1 << 3
+	}
+	if _, ok := c.Client.(driver.Configer); ok {
+		mask |= 1 << 4
+	}
+	if _, ok := c.Client.(driver.DBUpdater); ok {
+		mask |= 1 << 5
+	}
+	if _, ok := c.Client.(driver.ActiveTasker); ok {
+		mask |= 1 << 6
+	}
+	if _, ok := c.Client.(driver.Replicator); ok {
+		mask |= 1 << 7
+	}
+	switch mask {
+	case 1:
+		return struct {
+			*client
+			authenticator
+		}{c, authenticator{c}}
+	case 2:
+		return struct {
+			*client
+			uuider
+		}{c, uuider{c}}
+	case 3:
+		return struct {
+			*client
+			authenticator
+			uuider
+		}{c, authenticator{c}, uuider{c}}
+	case 4:
+		return struct {
+			*client
+			logReader
+		}{c, logReader{c}}
+	case 5:
+		return struct {
+			*client
+			authenticator
+			logReader
+		}{c, authenticator{c}, logReader{c}}
+	case 6:
+		return struct {
+			*client
+			uuider
+			logReader
+		}{c, uuider{c}, logReader{c}}
+	case 7:
+		return struct {
+			*client
+			authenticator
+			uuider
+			logReader
+		}{c, authenticator{c}, uuider{c}, logReader{c}}
+	case 8:
+		return struct {
+			*client
+			cluster
+		}{c, cluster{c}}
+	case 9:
+		return struct {
+			*client
+			authenticator
+			cluster
+		}{c, authenticator{c}, cluster{c}}
+	case 10:
+		return struct {
+			*client
+			uuider
+			cluster
+		}{c, uuider{c}, cluster{c}}
+	case 11:
+		return struct {
+			*client
+			authenticator
+			uuider
+			cluster
+		}{c, authenticator{c}, uuider{c}, cluster{c}}
+	case 12:
+		return struct {
+			*client
+			logReader
+			cluster
+		}{c, logReader{c}, cluster{c}}
+	case 13:
+		return struct {
+			*client
+			authenticator
+			logReader
+			cluster
+		}{c, authenticator{c}, logReader{c}, cluster{c}}
+	case 14:
+		return struct {
+			*client
+			uuider
+			logReader
+			cluster
+		}{c, uuider{c}, logReader{c}, cluster{c}}
+	case 15:
+		return struct {
+			*client
+			authenticator
+			uuider
+			logReader
+			cluster
+		}{c, authenticator{c}, uuider{c}, logReader{c}, cluster{c}}
+	case 16:
+		return struct {
+			*client
+			configer
+		}{c, configer{c}}
+	case 17:
+		return struct {
+			*client
+			authenticator
+			configer
+		}{c, authenticator{c}, configer{c}}
+	case 18:
+		return struct {
+			*client
+			uuider
+			configer
+		}{c, uuider{c}, configer{c}}
+	case 19:
+		return struct {
+			*client
+			authenticator
+			uuider
+			configer
+		}{c, authenticator{c}, uuider{c}, configer{c}}
+	case 20:
+		return struct {
+			*client
+			logReader
+			configer
+		}{c, logReader{c}, configer{c}}
+	case 21:
+		return struct {
+			*client
+			authenticator
+			logReader
+			configer
+		}{c, authenticator{c}, logReader{c}, configer{c}}
+	case 22:
+		return struct {
+			*client
+			uuider
+			logReader
+			configer
+		}{c, uuider{c}, logReader{c}, configer{c}}
+	case 23:
+		return struct {
+			*client
+			authenticator
+			uuider
+			logReader
+			configer
+		}{c, authenticator{c}, uuider{c}, logReader{c}, configer{c}}
+	case 24:
+		return struct {
+			*client
+			cluster
+			configer
+		}{c, cluster{c}, configer{c}}
+	case 25:
+		return struct {
+			*client
+			authenticator
+			cluster
+			configer
+		}{c, authenticator{c}, cluster{c}, configer{c}}
+	case 26:
+		return struct {
+			*client
+			uuider
+			cluster
+			configer
+		}{c, uuider{c}, cluster{c}, configer{c}}
+	case 27:
+		return struct {
+			*client
+			authenticator
+			uuider
+			cluster
+			configer
+		}{c, authenticator{c}, uuider{c}, cluster{c}, configer{c}}
+	case 28:
+		return struct {
+			*client
+			logReader
+			cluster
+			configer
+		}{c, logReader{c}, cluster{c}, configer{c}}
+	case 29:
+		return struct {
+			*client
+			authenticator
+			logReader
+			cluster
+			configer
+		}{c, authenticator{c}, logReader{c}, cluster{c}, configer{c}}
+	case 30:
+		return struct {
+			*client
+			uuider
+			logReader
+			cluster
+			configer
+		}{c, uuider{c}, logReader{c}, cluster{c}, configer{c}}
+	case 31:
+		return struct {
+			*client
+			authenticator
+			uuider
+			logReader
+			cluster
+			configer
+		}{c, authenticator{c}, uuider{c}, logReader{c}, cluster{c}, configer{c}}
+	case 32:
+		return struct {
+			*client
+			dbUpdater
+		}{c, dbUpdater{c}}
+	case 33:
+		return struct {
+			*client
+			authenticator
+			dbUpdater
+		}{c, authenticator{c}, dbUpdater{c}}
+	case 34:
+		return struct {
+			*client
+			uuider
+			dbUpdater
+		}{c, uuider{c}, dbUpdater{c}}
+	case 35:
+		return struct {
+			*client
+			authenticator
+			uuider
+			dbUpdater
+		}{c, authenticator{c}, uuider{c}, dbUpdater{c}}
+	case 36:
+		return struct {
+			*client
+			logReader
+			dbUpdater
+		}{c, logReader{c}, dbUpdater{c}}
+	case 37:
+		return struct {
+			*client
+			authenticator
+			logReader
+			dbUpdater
+		}{c, authenticator{c}, logReader{c}, dbUpdater{c}}
+	case 38:
+		return struct {
+			*client
+			uuider
+			logReader
+			dbUpdater
+		}{c, uuider{c}, logReader{c}, dbUpdater{c}}
+	case 39:
+		return struct {
+			*client
+			authenticator
+			uuider
+			logReader
+			dbUpdater
+		}{c, authenticator{c}, uuider{c}, logReader{c}, dbUpdater{c}}
+	case 40:
+		return struct {
+			*client
+			cluster
+			dbUpdater
+		}{c, cluster{c}, dbUpdater{c}}
+	case 41:
+		return struct {
+			*client
+			authenticator
+			cluster
+			dbUpdater
+		}{c, authenticator{c}, cluster{c}, dbUpdater{c}}
+	case 42:
+		return struct {
+			*client
+			uuider
+			cluster
+			dbUpdater
+		}{c, uuider{c}, cluster{c}, dbUpdater{c}}
+	case 43:
+		return struct {
+			*client
+			authenticator
+			uuider
+			cluster
+			dbUpdater
+		}{c, authenticator{c}, uuider{c}, cluster{c}, dbUpdater{c}}
+	case 44:
+		return struct {
+			*client
+			logReader
+			cluster
+			dbUpdater
+		}{c, logReader{c}, cluster{c}, dbUpdater{c}}
+	case 45:
+		return struct {
+			*client
+			authenticator
+			logReader
+			cluster
+			dbUpdater
+		}{c, authenticator{c}, logReader{c}, cluster{c}, dbUpdater{c}}
+	case 46:
+		return struct {
+			*client
+			uuider
+			logReader
+			cluster
+			dbUpdater
+		}{c, uuider{c}, logReader{c}, cluster{c}, dbUpdater{c}}
+	case 47:
+		return struct {
+			*client
+			authenticator
+			uuider
+			logReader
+			cluster
+			dbUpdater
+		}{c, authenticator{c}, uuider{c}, logReader{c}, cluster{c}, dbUpdater{c}}
+	case 48:
+		return struct {
+			*client
+			configer
+			dbUpdater
+		}{c, configer{c}, dbUpdater{c}}
+	case 49:
+		return struct {
+			*client
+			authenticator
+			configer
+			dbUpdater
+		}{c, authenticator{c}, configer{c}, dbUpdater{c}}
+	case 50:
+		return struct {
+			*client
+			uuider
+			configer
+			dbUpdater
+		}{c, uuider{c}, configer{c}, dbUpdater{c}}
+	case 51:
+		return struct {
+			*client
+			authenticator
+			uuider
+			configer
+			dbUpdater
+		}{c, authenticator{c}, uuider{c}, configer{c}, dbUpdater{c}}
+	case 52:
+		return struct {
+			*client
+			logReader
+			configer
+			dbUpdater
+		}{c, logReader{c}, configer{c}, dbUpdater{c}}
+	case 53:
+		return struct {
+			*client
+			authenticator
+			logReader
+			configer
+			dbUpdater
+		}{c, authenticator{c}, logReader{c}, configer{c}, dbUpdater{c}}
+	case 54:
+		return struct {
+			*client
+			uuider
+			logReader
+			configer
+			dbUpdater
+		}{c, uuider{c}, logReader{c}, configer{c}, dbUpdater{c}}
+	case 55:
+		return struct {
+			*client
+			authenticator
+			uuider
+			logReader
+			configer
+			dbUpdater
+		}{c, authenticator{c}, uuider{c}, logReader{c}, configer{c}, dbUpdater{c}}
+	case 56:
+		return struct {
+			*client
+			cluster
+			configer
+			dbUpdater
+		}{c, cluster{c}, configer{c}, dbUpdater{c}}
+	case 57:
+		return struct {
+			*client
+			authenticator
+			cluster
+			configer
+			dbUpdater
+		}{c, authenticator{c}, cluster{c}, configer{c}, dbUpdater{c}}
+	case 58:
+		return struct {
+			*client
+			uuider
+			cluster
+			configer
+			dbUpdater
+		}{c, uuider{c}, cluster{c}, configer{c}, dbUpdater{c}}
+	case 59:
+		return struct {
+			*client
+			authenticator
+			uuider
+			cluster
+			configer
+			dbUpdater
+		}{c, authenticator{c}, uuider{c}, cluster{c}, configer{c}, dbUpdater{c}}
+	case 60:
+		return struct {
+			*client
+			logReader
+			cluster
+			configer
+			dbUpdater
+		}{c, logReader{c}, cluster{c}, configer{c}, dbUpdater{c}}
+	case 61:
+		return struct {
+			*client
+			authenticator
+			logReader
+			cluster
+			configer
+			dbUpdater
+		}{c, authenticator{c}, logReader{c}, cluster{c}, configer{c}, dbUpdater{c}}
+	case 62:
+		return struct {
+			*client
+			uuider
+			logReader
+			cluster
+			configer
+			dbUpdater
+		}{c, uuider{c}, logReader{c}, cluster{c}, configer{c}, dbUpdater{c}}
+	case 63:
+		return struct {
+			*client
+			authenticator
+			uuider
+			logReader
+			cluster
+			configer
+			dbUpdater
+		}{c, authenticator{c}, uuider{c}, logReader{c}, cluster{c}, configer{c}, dbUpdater{c}}
+	case 64:
+		return struct {
+			*client
+			activeTasker
+		}{c, activeTasker{c}}
+	case 65:
+		return struct {
+			*client
+			authenticator
+			activeTasker
+		}{c, authenticator{c}, activeTasker{c}}
+	case 66:
+		return struct {
+			*client
+			uuider
+			activeTasker
+		}{c, uuider{c}, activeTasker{c}}
+	case 67:
+		return struct {
+			*client
+			authenticator
+			uuider
+			activeTasker
+		}{c, authenticator{c}, uuider{c}, activeTasker{c}}
+	case 68:
+		return struct {
+			*client
+			logReader
+			activeTasker
+		}{c, logReader{c}, activeTasker{c}}
+	case 69:
+		return struct {
+			*client
+			authenticator
+			logReader
+			activeTasker
+		}{c, authenticator{c}, logReader{c}, activeTasker{c}}
+	case 70:
+		return struct {
+			*client
+			uuider
+			logReader
+			activeTasker
+		}{c, uuider{c}, logReader{c}, activeTasker{c}}
+	case 71:
+		return struct {
+			*client
+			authenticator
+			uuider
+			logReader
+			activeTasker
+		}{c, authenticator{c}, uuider{c}, logReader{c}, activeTasker{c}}
+	case 72:
+		return struct {
+			*client
+			cluster
+			activeTasker
+		}{c, cluster{c}, activeTasker{c}}
+	case 73:
+		return struct {
+			*client
+			authenticator
+			cluster
+			activeTasker
+		}{c, authenticator{c}, cluster{c}, activeTasker{c}}
+	case 74:
+		return struct {
+			*client
+			uuider
+			cluster
+			activeTasker
+		}{c, uuider{c}, cluster{c}, activeTasker{c}}
+	case 75:
+		return struct {
+			*client
+			authenticator
+			uuider
+			cluster
+			activeTasker
+		}{c, authenticator{c}, uuider{c}, cluster{c}, activeTasker{c}}
+	case 76:
+		return struct {
+			*client
+			logReader
+			cluster
+			activeTasker
+		}{c, logReader{c}, cluster{c}, activeTasker{c}}
+	case 77:
+		return struct {
+			*client
+			authenticator
+			logReader
+			cluster
+			activeTasker
+		}{c, authenticator{c}, logReader{c}, cluster{c}, activeTasker{c}}
+	case 78:
+		return struct {
+			*client
+			uuider
+			logReader
+			cluster
+			activeTasker
+		}{c, uuider{c}, logReader{c}, cluster{c}, activeTasker{c}}
+	case 79:
+		return struct {
+			*client
+			authenticator
+			uuider
+			logReader
+			cluster
+			activeTasker
+		}{c, authenticator{c}, uuider{c}, logReader{c}, cluster{c}, activeTasker{c}}
+	case 80:
+		return struct {
+			*client
+			configer
+			activeTasker
+		}{c, configer{c}, activeTasker{c}}
+	case 81:
+		return struct {
+			*client
+			authenticator
+			configer
+			activeTasker
+		}{c, authenticator{c}, configer{c}, activeTasker{c}}
+	case 82:
+		return struct {
+			*client
+			uuider
+			configer
+			activeTasker
+		}{c, uuider{c}, configer{c}, activeTasker{c}}
+	case 83:
+		return struct {
+			*client
+			authenticator
+			uuider
+			configer
+			activeTasker
+		}{c, authenticator{c}, uuider{c}, configer{c}, activeTasker{c}}
+	case 84:
+		return struct {
+			*client
+			logReader
+			configer
+			activeTasker
+		}{c, logReader{c}, configer{c}, activeTasker{c}}
+	case 85:
+		return struct {
+			*client
+			authenticator
+			logReader
+			configer
+			activeTasker
+		}{c, authenticator{c}, logReader{c}, configer{c}, activeTasker{c}}
+	case 86:
+		return struct {
+			*client
+			uuider
+			logReader
+			configer
+			activeTasker
+		}{c, uuider{c}, logReader{c}, configer{c}, activeTasker{c}}
+	case 87:
+		return struct {
+			*client
+			authenticator
+			uuider
+			logReader
+			configer
+			activeTasker
+		}{c, authenticator{c}, uuider{c}, logReader{c}, configer{c}, activeTasker{c}}
+	case 88:
+		return struct {
+			*client
+			cluster
+			configer
+			activeTasker
+		}{c, cluster{c}, configer{c}, activeTasker{c}}
+	case 89:
+		return struct {
+			*client
+			authenticator
+			cluster
+			configer
+			activeTasker
+		}{c, authenticator{c}, cluster{c}, configer{c}, activeTasker{c}}
+	case 90:
+		return struct {
+			*client
+			uuider
+			cluster
+			configer
+			activeTasker
+		}{c, uuider{c}, cluster{c}, configer{c}, activeTasker{c}}
+	case 91:
+		return struct {
+			*client
+			authenticator
+			uuider
+			cluster
+			configer
+			activeTasker
+		}{c, authenticator{c}, uuider{c}, cluster{c}, configer{c}, activeTasker{c}}
+	case 92:
+		return struct {
+			*client
+			logReader
+			cluster
+			configer
+			activeTasker
+		}{c, logReader{c}, cluster{c}, configer{c}, activeTasker{c}}
+	case 93:
+		return struct {
+			*client
+			authenticator
+			logReader
+			cluster
+			configer
+			activeTasker
+		}{c, authenticator{c}, logReader{c}, cluster{c}, configer{c}, activeTasker{c}}
+	case 94:
+		return struct {
+			*client
+			uuider
+			logReader
+			cluster
+			configer
+			activeTasker
+		}{c, uuider{c}, logReader{c}, cluster{c}, configer{c}, activeTasker{c}}
+	case 95:
+		return struct {
+			*client
+			authenticator
+			uuider
+			logReader
+			cluster
+			configer
+			activeTasker
+		}{c, authenticator{c}, uuider{c}, logReader{c}, cluster{c}, configer{c}, activeTasker{c}}
+	case 96:
+		return struct {
+			*client
+			dbUpdater
+			activeTasker
+		}{c, dbUpdater{c}, activeTasker{c}}
+	case 97:
+		return struct {
+			*client
+			authenticator
+			dbUpdater
+			activeTasker
+		}{c, authenticator{c}, dbUpdater{c}, activeTasker{c}}
+	case 98:
+		return struct {
+			*client
+			uuider
+			dbUpdater
+			activeTasker
+		}{c, uuider{c}, dbUpdater{c}, activeTasker{c}}
+	case 99:
+		return struct {
+			*client
+			authenticator
+			uuider
+			dbUpdater
+			activeTasker
+		}{c, authenticator{c}, uuider{c}, dbUpdater{c}, activeTasker{c}}
+	case 100:
+		return struct {
+			*client
+			logReader
+			dbUpdater
+			activeTasker
+		}{c, logReader{c}, dbUpdater{c}, activeTasker{c}}
+	case 101:
+		return struct {
+			*client
+			authenticator
+			logReader
+			dbUpdater
+			activeTasker
+		}{c, authenticator{c}, logReader{c}, dbUpdater{c}, activeTasker{c}}
+	case 102:
+		return struct {
+			*client
+			uuider
+			logReader
+			dbUpdater
+			activeTasker
+		}{c, uuider{c}, logReader{c}, dbUpdater{c}, activeTasker{c}}
+	case 103:
+		return struct {
+			*client
+			authenticator
+			uuider
+			logReader
+			dbUpdater
+			activeTasker
+		}{c, authenticator{c}, uuider{c}, logReader{c}, dbUpdater{c}, activeTasker{c}}
+	case 104:
+		return struct {
+			*client
+			cluster
+			dbUpdater
+			activeTasker
+		}{c, cluster{c}, dbUpdater{c}, activeTasker{c}}
+	case 105:
+		return struct {
+			*client
+			authenticator
+			cluster
+			dbUpdater
+			activeTasker
+		}{c, authenticator{c}, cluster{c}, dbUpdater{c}, activeTasker{c}}
+	case 106:
+		return struct {
+			*client
+			uuider
+			cluster
+			dbUpdater
+			activeTasker
+		}{c, uuider{c}, cluster{c}, dbUpdater{c}, activeTasker{c}}
+	case 107:
+		return struct {
+			*client
+			authenticator
+			uuider
+			cluster
+			dbUpdater
+			activeTasker
+		}{c, authenticator{c}, uuider{c}, cluster{c}, dbUpdater{c}, activeTasker{c}}
+	case 108:
+		return struct {
+			*client
+			logReader
+			cluster
+			dbUpdater
+			activeTasker
+		}{c, logReader{c}, cluster{c}, dbUpdater{c}, activeTasker{c}}
+	case 109:
+		return struct {
+			*client
+			authenticator
+			logReader
+			cluster
+			dbUpdater
+			activeTasker
+		}{c, authenticator{c}, logReader{c}, cluster{c}, dbUpdater{c}, activeTasker{c}}
+	case 110:
+		return struct {
+			*client
+			uuider
+			logReader
+			cluster
+			dbUpdater
+			activeTasker
+		}{c, uuider{c}, logReader{c}, cluster{c}, dbUpdater{c}, activeTasker{c}}
+	case 111:
+		return struct {
+			*client
+			authenticator
+			uuider
+			logReader
+			cluster
+			dbUpdater
+			activeTasker
+		}{c, authenticator{c}, uuider{c}, logReader{c}, cluster{c}, dbUpdater{c}, activeTasker{c}}
+	case 112:
+		return struct {
+			*client
+			configer
+			dbUpdater
+			activeTasker
+		}{c, configer{c}, dbUpdater{c}, activeTasker{c}}
+	case 113:
+		return struct {
+			*client
+			authenticator
+			configer
+			dbUpdater
+			activeTasker
+		}{c, authenticator{c}, configer{c}, dbUpdater{c}, activeTasker{c}}
+	case 114:
+		return struct {
+			*client
+			uuider
+			configer
+			dbUpdater
+			activeTasker
+		}{c, uuider{c}, configer{c}, dbUpdater{c}, activeTasker{c}}
+	case 115:
+		return struct {
+			*client
+			authenticator
+			uuider
+			configer
+			dbUpdater
+			activeTasker
+		}{c, authenticator{c}, uuider{c}, configer{c}, dbUpdater{c}, activeTasker{c}}
+	case 116:
+		return struct {
+			*client
+			logReader
+			configer
+			dbUpdater
+			activeTasker
+		}{c, logReader{c}, configer{c}, dbUpdater{c}, activeTasker{c}}
+	case 117:
+		return struct {
+			*client
+			authenticator
+			logReader
+			configer
+			dbUpdater
+			activeTasker
+		}{c, authenticator{c}, logReader{c}, configer{c}, dbUpdater{c}, activeTasker{c}}
+	case 118:
+		return struct {
+			*client
+			uuider
+			logReader
+			configer
+			dbUpdater
+			activeTasker
+		}{c, uuider{c}, logReader{c}, configer{c}, dbUpdater{c}, activeTasker{c}}
+	case 119:
+		return struct {
+			*client
+			authenticator
+			uuider
+			logReader
+			configer
+			dbUpdater
+			activeTasker
+		}{c, authenticator{c}, uuider{c}, logReader{c}, configer{c}, dbUpdater{c}, activeTasker{c}}
+	case 120:
+		return struct {
+			*client
+			cluster
+			configer
+			dbUpdater
+			activeTasker
+		}{c, cluster{c}, configer{c}, dbUpdater{c}, activeTasker{c}}
+	case 121:
+		return struct {
+			*client
+			authenticator
+			cluster
+			configer
+			dbUpdater
+			activeTasker
+		}{c, authenticator{c}, cluster{c}, configer{c}, dbUpdater{c}, activeTasker{c}}
+	case 122:
+		return struct {
+			*client
+			uuider
+			cluster
+			configer
+			dbUpdater
+			activeTasker
+		}{c, uuider{c}, cluster{c}, configer{c}, dbUpdater{c}, activeTasker{c}}
+	case 123:
+		return struct {
+			*client
+			authenticator
+			uuider
+			cluster
+			configer
+			dbUpdater
+			activeTasker
+		}{c, authenticator{c}, uuider{c}, cluster{c}, configer{c}, dbUpdater{c}, activeTasker{c}}
+	case 124:
+		return struct {
+			*client
+			logReader
+			cluster
+			configer
+			dbUpdater
+			activeTasker
+		}{c, logReader{c}, cluster{c}, configer{c}, dbUpdater{c}, activeTasker{c}}
+	case 125:
+		return struct {
+			*client
+			authenticator
+			logReader
+			cluster
+			configer
+			dbUpdater
+			activeTasker
+		}{c, authenticator{c}, logReader{c}, cluster{c}, configer{c}, dbUpdater{c}, activeTasker{c}}
+	case 126:
+		return struct {
+			*client
+			uuider
+			logReader
+			cluster
+			configer
+			dbUpdater
+			activeTasker
+		}{c, uuider{c}, logReader{c}, cluster{c}, configer{c}, dbUpdater{c}, activeTasker{c}}
+	case 127:
+		return struct {
+			*client
+			authenticator
+			uuider
+			logReader
+			cluster
+			configer
+			dbUpdater
+			activeTasker
+		}{c, authenticator{c}, uuider{c}, logReader{c}, cluster{c}, configer{c}, dbUpdater{c}, activeTasker{c}}
+	case 128:
+		return struct {
+			*client
+			replicator
+		}{c, replicator{c}}
+	case 129:
+		return struct {
+			*client
+			authenticator
+			replicator
+		}{c, authenticator{c}, replicator{c}}
+	case 130:
+		return struct {
+			*client
+			uuider
+			replicator
+		}{c, uuider{c}, replicator{c}}
+	case 131:
+		return struct {
+			*client
+			authenticator
+			uuider
+			replicator
+		}{c, authenticator{c}, uuider{c}, replicator{c}}
+	case 132:
+		return struct {
+			*client
+			logReader
+			replicator
+		}{c, logReader{c}, replicator{c}}
+	case 133:
+		return struct {
+			*client
+			authenticator
+			logReader
+			replicator
+		}{c, authenticator{c}, logReader{c}, replicator{c}}
+	case 134:
+		return struct {
+			*client
+			uuider
+			logReader
+			replicator
+		}{c, uuider{c}, logReader{c}, replicator{c}}
+	case 135:
+		return struct {
+			*client
+			authenticator
+			uuider
+			logReader
+			replicator
+		}{c, authenticator{c}, uuider{c}, logReader{c}, replicator{c}}
+	case 136:
+		return struct {
+			*client
+			cluster
+			replicator
+		}{c, cluster{c}, replicator{c}}
+	case 137:
+		return struct {
+			*client
+			authenticator
+			cluster
+			replicator
+		}{c, authenticator{c}, cluster{c}, replicator{c}}
+	case 138:
+		return struct {
+			*client
+			uuider
+			cluster
+			replicator
+		}{c, uuider{c}, cluster{c}, replicator{c}}
+	case 139:
+		return struct {
+			*client
+			authenticator
+			uuider
+			cluster
+			replicator
+		}{c, authenticator{c}, uuider{c}, cluster{c}, replicator{c}}
+	case 140:
+		return struct {
+			*client
+			logReader
+			cluster
+			replicator
+		}{c, logReader{c}, cluster{c}, replicator{c}}
+	case 141:
+		return struct {
+			*client
+			authenticator
+			logReader
+			cluster
+			replicator
+		}{c, authenticator{c}, logReader{c}, cluster{c}, replicator{c}}
+	case 142:
+		return struct {
+			*client
+			uuider
+			logReader
+			cluster
+			replicator
+		}{c, uuider{c}, logReader{c}, cluster{c}, replicator{c}}
+	case 143:
+		return struct {
+			*client
+			authenticator
+			uuider
+			logReader
+			cluster
+			replicator
+		}{c, authenticator{c}, uuider{c}, logReader{c}, cluster{c}, replicator{c}}
+	case 144:
+		return struct {
+			*client
+			configer
+			replicator
+		}{c, configer{c}, replicator{c}}
+	case 145:
+		return struct {
+			*client
+			authenticator
+			configer
+			replicator
+		}{c, authenticator{c}, configer{c}, replicator{c}}
+	case 146:
+		return struct {
+			*client
+			uuider
+			configer
+			replicator
+		}{c, uuider{c}, configer{c}, replicator{c}}
+	case 147:
+		return struct {
+			*client
+			authenticator
+			uuider
+			configer
+			replicator
+		}{c, authenticator{c}, uuider{c}, configer{c}, replicator{c}}
+	case 148:
+		return struct {
+			*client
+			logReader
+			configer
+			replicator
+		}{c, logReader{c}, configer{c}, replicator{c}}
+	case 149:
+		return struct {
+			*client
+			authenticator
+			logReader
+			configer
+			replicator
+		}{c, authenticator{c}, logReader{c}, configer{c}, replicator{c}}
+	case 150:
+		return struct {
+			*client
+			uuider
+			logReader
+			configer
+			replicator
+		}{c, uuider{c}, logReader{c}, configer{c}, replicator{c}}
+	case 151:
+		return struct {
+			*client
+			authenticator
+			uuider
+			logReader
+			configer
+			replicator
+		}{c, authenticator{c}, uuider{c}, logReader{c}, configer{c}, replicator{c}}
+	case 152:
+		return struct {
+			*client
+			cluster
+			configer
+			replicator
+		}{c, cluster{c}, configer{c}, replicator{c}}
+	case 153:
+		return struct {
+			*client
+			authenticator
+			cluster
+			configer
+			replicator
+		}{c, authenticator{c}, cluster{c}, configer{c}, replicator{c}}
+	case 154:
+		return struct {
+			*client
+			uuider
+			cluster
+			configer
+			replicator
+		}{c, uuider{c}, cluster{c}, configer{c}, replicator{c}}
+	case 155:
+		return struct {
+			*client
+			authenticator
+			uuider
+			cluster
+			configer
+			replicator
+		}{c, authenticator{c}, uuider{c}, cluster{c}, configer{c}, replicator{c}}
+	case 156:
+		return struct {
+			*client
+			logReader
+			cluster
+			configer
+			replicator
+		}{c, logReader{c}, cluster{c}, configer{c}, replicator{c}}
+	case 157:
+		return struct {
+			*client
+			authenticator
+			logReader
+			cluster
+			configer
+			replicator
+		}{c, authenticator{c}, logReader{c}, cluster{c}, configer{c}, replicator{c}}
+	case 158:
+		return struct {
+			*client
+			uuider
+			logReader
+			cluster
+			configer
+			replicator
+		}{c, uuider{c}, logReader{c}, cluster{c}, configer{c}, replicator{c}}
+	case 159:
+		return struct {
+			*client
+			authenticator
+			uuider
+			logReader
+			cluster
+			configer
+			replicator
+		}{c, authenticator{c}, uuider{c}, logReader{c}, cluster{c}, configer{c}, replicator{c}}
+	case 160:
+		return struct {
+			*client
+			dbUpdater
+			replicator
+		}{c, dbUpdater{c}, replicator{c}}
+	case 161:
+		return struct {
+			*client
+			authenticator
+			dbUpdater
+			replicator
+		}{c, authenticator{c}, dbUpdater{c}, replicator{c}}
+	case 162:
+		return struct {
+			*client
+			uuider
+			dbUpdater
+			replicator
+		}{c, uuider{c}, dbUpdater{c}, replicator{c}}
+	case 163:
+		return struct {
+			*client
+			authenticator
+			uuider
+			dbUpdater
+			replicator
+		}{c, authenticator{c}, uuider{c}, dbUpdater{c}, replicator{c}}
+	case 164:
+		return struct {
+			*client
+			logReader
+			dbUpdater
+			replicator
+		}{c, logReader{c}, dbUpdater{c}, replicator{c}}
+	case 165:
+		return struct {
+			*client
+			authenticator
+			logReader
+			dbUpdater
+			replicator
+		}{c, authenticator{c}, logReader{c}, dbUpdater{c}, replicator{c}}
+	case 166:
+		return struct {
+			*client
+			uuider
+			logReader
+			dbUpdater
+			replicator
+		}{c, uuider{c}, logReader{c}, dbUpdater{c}, replicator{c}}
+	case 167:
+		return struct {
+			*client
+			authenticator
+			uuider
+			logReader
+			dbUpdater
+			replicator
+		}{c, authenticator{c}, uuider{c}, logReader{c}, dbUpdater{c}, replicator{c}}
+	case 168:
+		return struct {
+			*client
+			cluster
+			dbUpdater
+			replicator
+		}{c, cluster{c}, dbUpdater{c}, replicator{c}}
+	case 169:
+		return struct {
+			*client
+			authenticator
+			cluster
+			dbUpdater
+			replicator
+		}{c, authenticator{c}, cluster{c}, dbUpdater{c}, replicator{c}}
+	case 170:
+		return struct {
+			*client
+			uuider
+			cluster
+			dbUpdater
+			replicator
+		}{c, uuider{c}, cluster{c}, dbUpdater{c}, replicator{c}}
+	case 171:
+		return struct {
+			*client
+			authenticator
+			uuider
+			cluster
+			dbUpdater
+			replicator
+		}{c, authenticator{c}, uuider{c}, cluster{c}, dbUpdater{c}, replicator{c}}
+	case 172:
+		return struct {
+			*client
+			logReader
+			cluster
+			dbUpdater
+			replicator
+		}{c, logReader{c}, cluster{c}, dbUpdater{c}, replicator{c}}
+	case 173:
+		return struct {
+			*client
+			authenticator
+			logReader
+			cluster
+			dbUpdater
+			replicator
+		}{c, authenticator{c}, logReader{c}, cluster{c}, dbUpdater{c}, replicator{c}}
+	case 174:
+		return struct {
+			*client
+			uuider
+			logReader
+			cluster
+			dbUpdater
+			replicator
+		}{c, uuider{c}, logReader{c}, cluster{c}, dbUpdater{c}, replicator{c}}
+	case 175:
+		return struct {
+			*client
+			authenticator
+			uuider
+			logReader
+			cluster
+			dbUpdater
+			replicator
+		}{c, authenticator{c}, uuider{c}, logReader{c}, cluster{c}, dbUpdater{c}, replicator{c}}
+	case 176:
+		return struct {
+			*client
+			configer
+			dbUpdater
+			replicator
+		}{c, configer{c}, dbUpdater{c}, replicator{c}}
+	case 177:
+		return struct {
+			*client
+			authenticator
+			configer
+			dbUpdater
+			replicator
+		}{c, authenticator{c}, configer{c}, dbUpdater{c}, replicator{c}}
+	case 178:
+		return struct {
+			*client
+			uuider
+			configer
+			dbUpdater
+			replicator
+		}{c, uuider{c}, configer{c}, dbUpdater{c}, replicator{c}}
+	case 179:
+		return struct {
+			*client
+			authenticator
+			uuider
+			configer
+			dbUpdater
+			replicator
+		}{c, authenticator{c}, uuider{c}, configer{c}, dbUpdater{c}, replicator{c}}
+	case 180:
+		return struct {
+			*client
+			logReader
+			configer
+			dbUpdater
+			replicator
+		}{c, logReader{c}, configer{c}, dbUpdater{c}, replicator{c}}
+	case 181:
+		return struct {
+			*client
+			authenticator
+			logReader
+			configer
+			dbUpdater
+			replicator
+		}{c, authenticator{c}, logReader{c}, configer{c}, dbUpdater{c}, replicator{c}}
+	case 182:
+		return struct {
+			*client
+			uuider
+			logReader
+			configer
+			dbUpdater
+			replicator
+		}{c, uuider{c}, logReader{c}, configer{c}, dbUpdater{c}, replicator{c}}
+	case 183:
+		return struct {
+			*client
+			authenticator
+			uuider
+			logReader
+			configer
+			dbUpdater
+			replicator
+		}{c, authenticator{c}, uuider{c}, logReader{c}, configer{c}, dbUpdater{c}, replicator{c}}
+	case 184:
+		return struct {
+			*client
+			cluster
+			configer
+			dbUpdater
+			replicator
+		}{c, cluster{c}, configer{c}, dbUpdater{c}, replicator{c}}
+	case 185:
+		return struct {
+			*client
+			authenticator
+			cluster
+			configer
+			dbUpdater
+			replicator
+		}{c, authenticator{c}, cluster{c}, configer{c}, dbUpdater{c}, replicator{c}}
+	case 186:
+		return struct {
+			*client
+			uuider
+			cluster
+			configer
+			dbUpdater
+			replicator
+		}{c, uuider{c}, cluster{c}, configer{c}, dbUpdater{c}, replicator{c}}
+	case 187:
+		return struct {
+			*client
+			authenticator
+			uuider
+			cluster
+			configer
+			dbUpdater
+			replicator
+		}{c, authenticator{c}, uuider{c}, cluster{c}, configer{c}, dbUpdater{c}, replicator{c}}
+	case 188:
+		return struct {
+			*client
+			logReader
+			cluster
+			configer
+			dbUpdater
+			replicator
+		}{c, logReader{c}, cluster{c}, configer{c}, dbUpdater{c}, replicator{c}}
+	case 189:
+		return struct {
+			*client
+			authenticator
+			logReader
+			cluster
+			configer
+			dbUpdater
+			replicator
+		}{c, authenticator{c}, logReader{c}, cluster{c}, configer{c}, dbUpdater{c}, replicator{c}}
+	case 190:
+		return struct {
+			*client
+			uuider
+			logReader
+			cluster
+			configer
+			dbUpdater
+			replicator
+		}{c, uuider{c}, logReader{c}, cluster{c}, configer{c}, dbUpdater{c}, replicator{c}}
+	case 191:
+		return struct {
+			*client
+			authenticator
+			uuider
+			logReader
+			cluster
+			configer
+			dbUpdater
+			replicator
+		}{c, authenticator{c}, uuider{c}, logReader{c}, cluster{c}, configer{c}, dbUpdater{c}, replicator{c}}
+	case 192:
+		return struct {
+			*client
+			activeTasker
+			replicator
+		}{c, activeTasker{c}, replicator{c}}
+	case 193:
+		return struct {
+			*client
+			authenticator
+			activeTasker
+			replicator
+		}{c, authenticator{c}, activeTasker{c}, replicator{c}}
+	case 194:
+		return struct {
+			*client
+			uuider
+			activeTasker
+			replicator
+		}{c, uuider{c}, activeTasker{c}, replicator{c}}
+	case 195:
+		return struct {
+			*client
+			authenticator
+			uuider
+			activeTasker
+			replicator
+		}{c, authenticator{c}, uuider{c}, activeTasker{c}, replicator{c}}
+	case 196:
+		return struct {
+			*client
+			logReader
+			activeTasker
+			replicator
+		}{c, logReader{c}, activeTasker{c}, replicator{c}}
+	case 197:
+		return struct {
+			*client
+			authenticator
+			logReader
+			activeTasker
+			replicator
+		}{c, authenticator{c}, logReader{c}, activeTasker{c}, replicator{c}}
+	case 198:
+		return struct {
+			*client
+			uuider
+			logReader
+			activeTasker
+			replicator
+		}{c, uuider{c}, logReader{c}, activeTasker{c}, replicator{c}}
+	case 199:
+		return struct {
+			*client
+			authenticator
+			uuider
+			logReader
+			activeTasker
+			replicator
+		}{c, authenticator{c}, uuider{c}, logReader{c}, activeTasker{c}, replicator{c}}
+	case 200:
+		return struct {
+			*client
+			cluster
+			activeTasker
+			replicator
+		}{c, cluster{c}, activeTasker{c}, replicator{c}}
+	case 201:
+		return struct {
+			*client
+			authenticator
+			cluster
+			activeTasker
+			replicator
+		}{c, authenticator{c}, cluster{c}, activeTasker{c}, replicator{c}}
+	case 202:
+		return struct {
+			*client
+			uuider
+			cluster
+			activeTasker
+			replicator
+		}{c, uuider{c}, cluster{c}, activeTasker{c}, replicator{c}}
+	case 203:
+		return struct {
+			*client
+			authenticator
+			uuider
+			cluster
+			activeTasker
+			replicator
+		}{c, authenticator{c}, uuider{c}, cluster{c}, activeTasker{c}, replicator{c}}
+	case 204:
+		return struct {
+			*client
+			logReader
+			cluster
+			activeTasker
+			replicator
+		}{c, logReader{c}, cluster{c}, activeTasker{c}, replicator{c}}
+	case 205:
+		return struct {
+			*client
+			authenticator
+			logReader
+			cluster
+			activeTasker
+			replicator
+		}{c, authenticator{c}, logReader{c}, cluster{c}, activeTasker{c}, replicator{c}}
+	case 206:
+		return struct {
+			*client
+			uuider
+			logReader
+			cluster
+			activeTasker
+			replicator
+		}{c, uuider{c}, logReader{c}, cluster{c}, activeTasker{c}, replicator{c}}
+	case 207:
+		return struct {
+			*client
+			authenticator
+			uuider
+			logReader
+			cluster
+			activeTasker
+			replicator
+		}{c, authenticator{c}, uuider{c}, logReader{c}, cluster{c}, activeTasker{c}, replicator{c}}
+	case 208:
+		return struct {
+			*client
+			configer
+			activeTasker
+			replicator
+		}{c, configer{c}, activeTasker{c}, replicator{c}}
+	case 209:
+		return struct {
+			*client
+			authenticator
+			configer
+			activeTasker
+			replicator
+		}{c, authenticator{c}, configer{c}, activeTasker{c}, replicator{c}}
+	case 210:
+		return struct {
+			*client
+			uuider
+			configer
+			activeTasker
+			replicator
+		}{c, uuider{c}, configer{c}, activeTasker{c}, replicator{c}}
+	case 211:
+		return struct {
+			*client
+			authenticator
+			uuider
+			configer
+			activeTasker
+			replicator
+		}{c, authenticator{c}, uuider{c}, configer{c}, activeTasker{c}, replicator{c}}
+	case 212:
+		return struct {
+			*client
+			logReader
+			configer
+			activeTasker
+			replicator
+		}{c, logReader{c}, configer{c}, activeTasker{c}, replicator{c}}
+	case 213:
+		return struct {
+			*client
+			authenticator
+			logReader
+			configer
+			activeTasker
+			replicator
+		}{c, authenticator{c}, logReader{c}, configer{c}, activeTasker{c}, replicator{c}}
+	case 214:
+		return struct {
+			*client
+			uuider
+			logReader
+			configer
+			activeTasker
+			replicator
+		}{c, uuider{c}, logReader{c}, configer{c}, activeTasker{c}, replicator{c}}
+	case 215:
+		return struct {
+			*client
+			authenticator
+			uuider
+			logReader
+			configer
+			activeTasker
+			replicator
+		}{c, authenticator{c}, uuider{c}, logReader{c}, configer{c}, activeTasker{c}, replicator{c}}
+	case 216:
+		return struct {
+			*client
+			cluster
+			configer
+			activeTasker
+			replicator
+		}{c, cluster{c}, configer{c}, activeTasker{c}, replicator{c}}
+	case 217:
+		return struct {
+			*client
+			authenticator
+			cluster
+			configer
+			activeTasker
+			replicator
+		}{c, authenticator{c}, cluster{c}, configer{c}, activeTasker{c}, replicator{c}}
+	case 218:
+		return struct {
+			*client
+			uuider
+			cluster
+			configer
+			activeTasker
+			replicator
+		}{c, uuider{c}, cluster{c}, configer{c}, activeTasker{c}, replicator{c}}
+	case 219:
+		return struct {
+			*client
+			authenticator
+			uuider
+			cluster
+			configer
+			activeTasker
+			replicator
+		}{c, authenticator{c}, uuider{c}, cluster{c}, configer{c}, activeTasker{c}, replicator{c}}
+	case 220:
+		return struct {
+			*client
+			logReader
+			cluster
+			configer
+			activeTasker
+			replicator
+		}{c, logReader{c}, cluster{c}, configer{c}, activeTasker{c}, replicator{c}}
+	case 221:
+		return struct {
+			*client
+			authenticator
+			logReader
+			cluster
+			configer
+			activeTasker
+			replicator
+		}{c, authenticator{c}, logReader{c}, cluster{c}, configer{c}, activeTasker{c}, replicator{c}}
+	case 222:
+		return struct {
+			*client
+			uuider
+			logReader
+			cluster
+			configer
+			activeTasker
+			replicator
+		}{c, uuider{c}, logReader{c}, cluster{c}, configer{c}, activeTasker{c}, replicator{c}}
+	case 223:
+		return struct {
+			*client
+			authenticator
+			uuider
+			logReader
+			cluster
+			configer
+			activeTasker
+			replicator
+		}{c, authenticator{c}, uuider{c}, logReader{c}, cluster{c}, configer{c}, activeTasker{c}, replicator{c}}
+	case 224:
+		return struct {
+			*client
+			dbUpdater
+			activeTasker
+			replicator
+		}{c, dbUpdater{c}, activeTasker{c}, replicator{c}}
+	case 225:
+		return struct {
+			*client
+			authenticator
+			dbUpdater
+			activeTasker
+			replicator
+		}{c, authenticator{c}, dbUpdater{c}, activeTasker{c}, replicator{c}}
+	case 226:
+		return struct {
+			*client
+			uuider
+			dbUpdater
+			activeTasker
+			replicator
+		}{c, uuider{c}, dbUpdater{c}, activeTasker{c}, replicator{c}}
+	case 227:
+		return struct {
+			*client
+			authenticator
+			uuider
+			dbUpdater
+			activeTasker
+			replicator
+		}{c, authenticator{c}, uuider{c}, dbUpdater{c}, activeTasker{c}, replicator{c}}
+	case 228:
+		return struct {
+			*client
+			logReader
+			dbUpdater
+			activeTasker
+			replicator
+		}{c, logReader{c}, dbUpdater{c}, activeTasker{c}, replicator{c}}
+	case 229:
+		return struct {
+			*client
+			authenticator
+			logReader
+			dbUpdater
+			activeTasker
+			replicator
+		}{c, authenticator{c}, logReader{c}, dbUpdater{c}, activeTasker{c}, replicator{c}}
+	case 230:
+		return struct {
+			*client
+			uuider
+			logReader
+			dbUpdater
+			activeTasker
+			replicator
+		}{c, uuider{c}, logReader{c}, dbUpdater{c}, activeTasker{c}, replicator{c}}
+	case 231:
+		return struct {
+			*client
+			authenticator
+			uuider
+			logReader
+			dbUpdater
+			activeTasker
+			replicator
+		}{c, authenticator{c}, uuider{c}, logReader{c}, dbUpdater{c}, activeTasker{c}, replicator{c}}
+	case 232:
+		return struct {
+			*client
+			cluster
+			dbUpdater
+			activeTasker
+			replicator
+		}{c, cluster{c}, dbUpdater{c}, activeTasker{c}, replicator{c}}
+	case 233:
+		return struct {
+			*client
+			authenticator
+			cluster
+			dbUpdater
+			activeTasker
+			replicator
+		}{c, authenticator{c}, cluster{c}, dbUpdater{c}, activeTasker{c}, replicator{c}}
+	case 234:
+		return struct {
+			*client
+			uuider
+			cluster
+			dbUpdater
+			activeTasker
+			replicator
+		}{c, uuider{c}, cluster{c}, dbUpdater{c}, activeTasker{c}, replicator{c}}
+	case 235:
+		return struct {
+			*client
+			authenticator
+			uuider
+			cluster
+			dbUpdater
+			activeTasker
+			replicator
+		}{c, authenticator{c}, uuider{c}, cluster{c}, dbUpdater{c}, activeTasker{c}, replicator{c}}
+	case 236:
+		return struct {
+			*client
+			logReader
+			cluster
+			dbUpdater
+			activeTasker
+			replicator
+		}{c, logReader{c}, cluster{c}, dbUpdater{c}, activeTasker{c}, replicator{c}}
+	case 237:
+		return struct {
+			*client
+			authenticator
+			logReader
+			cluster
+			dbUpdater
+			activeTasker
+			replicator
+		}{c, authenticator{c}, logReader{c}, cluster{c}, dbUpdater{c}, activeTasker{c}, replicator{c}}
+	case 238:
+		return struct {
+			*client
+			uuider
+			logReader
+			cluster
+			dbUpdater
+			activeTasker
+			replicator
+		}{c, uuider{c}, logReader{c}, cluster{c}, dbUpdater{c}, activeTasker{c}, replicator{c}}
+	case 239:
+		return struct {
+			*client
+			authenticator
+			uuider
+			logReader
+			cluster
+			dbUpdater
+			activeTasker
+			replicator
+		}{c, authenticator{c}, uuider{c}, logReader{c}, cluster{c}, dbUpdater{c}, activeTasker{c}, replicator{c}}
+	case 240:
+		return struct {
+			*client
+			configer
+			dbUpdater
+			activeTasker
+			replicator
+		}{c, configer{c}, dbUpdater{c}, activeTasker{c}, replicator{c}}
+	case 241:
+		return struct {
+			*client
+			authenticator
+			configer
+			dbUpdater
+			activeTasker
+			replicator
+		}{c, authenticator{c}, configer{c}, dbUpdater{c}, activeTasker{c}, replicator{c}}
+	case 242:
+		return struct {
+			*client
+			uuider
+			configer
+			dbUpdater
+			activeTasker
+			replicator
+		}{c, uuider{c}, configer{c}, dbUpdater{c}, activeTasker{c}, replicator{c}}
+	case 243:
+		return struct {
+			*client
+			authenticator
+			uuider
+			configer
+			dbUpdater
+			activeTasker
+			replicator
+		}{c, authenticator{c}, uuider{c}, configer{c}, dbUpdater{c}, activeTasker{c}, replicator{c}}
+	case 244:
+		return struct {
+			*client
+			logReader
+			configer
+			dbUpdater
+			activeTasker
+			replicator
+		}{c, logReader{c}, configer{c}, dbUpdater{c}, activeTasker{c}, replicator{c}}
+	case 245:
+		return struct {
+			*client
+			authenticator
+			logReader
+			configer
+			dbUpdater
+			activeTasker
+			replicator
+		}{c, authenticator{c}, logReader{c}, configer{c}, dbUpdater{c}, activeTasker{c}, replicator{c}}
+	case 246:
+		return struct {
+			*client
+			uuider
+			logReader
+			configer
+			dbUpdater
+			activeTasker
+			replicator
+		}{c, uuider{c}, logReader{c}, configer{c}, dbUpdater{c}, activeTasker{c}, replicator{c}}
+	case 247:
+		return struct {
+			*client
+			authenticator
+			uuider
+			logReader
+			configer
+			dbUpdater
+			activeTasker
+			replicator
+		}{c, authenticator{c}, uuider{c}, logReader{c}, configer{c}, dbUpdater{c}, activeTasker{c}, replicator{c}}
+	case 248:
+		return struct {
+			*client
+			cluster
+			configer
+			dbUpdater
+			activeTasker
+			replicator
+		}{c, cluster{c}, configer{c}, dbUpdater{c}, activeTasker{c}, replicator{c}}
+	case 249:
+		return struct {
+			*client
+			authenticator
+			cluster
+			configer
+			dbUpdater
+			activeTasker
+			replicator
+		}{c, authenticator{c}, cluster{c}, configer{c}, dbUpdater{c}, activeTasker{c}, replicator{c}}
+	case 250:
+		return struct {
+			*client
+			uuider
+			cluster
+			configer
+			dbUpdater
+			activeTasker
+			replicator
+		}{c, uuider{c}, cluster{c}, configer{c}, dbUpdater{c}, activeTasker{c}, replicator{c}}
+	case 251:
+		return struct {
+			*client
+			authenticator
+			uuider
+			cluster
+			configer
+			dbUpdater
+			activeTasker
+			replicator
+		}{c, authenticator{c}, uuider{c}, cluster{c}, configer{c}, dbUpdater{c}, activeTasker{c}, replicator{c}}
+	case 252:
+		return struct {
+			*client
+			logReader
+			cluster
+			configer
+			dbUpdater
+			activeTasker
+			replicator
+		}{c, logReader{c}, cluster{c}, configer{c}, dbUpdater{c}, activeTasker{c}, replicator{c}}
+	case 253:
+		return struct {
+			*client
+			authenticator
+			logReader
+			cluster
+			configer
+			dbUpdater
+			activeTasker
+			replicator
+		}{c, authenticator{c}, logReader{c}, cluster{c}, configer{c}, dbUpdater{c}, activeTasker{c}, replicator{c}}
+	case 254:
+		return struct {
+			*client
+			uuider
+			logReader
+			cluster
+			configer
+			dbUpdater
+			activeTasker
+			replicator
+		}{c, uuider{c}, logReader{c}, cluster{c}, configer{c}, dbUpdater{c}, activeTasker{c}, replicator{c}}
+	case 255:
+		return struct {
+			*client
+			authenticator
+			uuider
+			logReader
+			cluster
+			configer
+			dbUpdater
+			activeTasker
+			replicator
+		}{c, authenticator{c}, uuider{c}, logReader{c}, cluster{c}, configer{c}, dbUpdater{c}, activeTasker{c}, replicator{c}}
+	}
+	return c
+}
+
+// withOptional returns d, extended with each optional interface implemented
+// by the wrapped DB.
+func (d *db) withOptional() driver.DB {
+	var mask uint
+	if _, ok := d.DB.(driver.Finder); ok {
+		mask |= 1 << 0
+	}
+	if _, ok := d.DB.(driver.AttachmentMetaer); ok {
+		mask |= 1 << 1
+	}
+	if _, ok := d.DB.(driver.Rever); ok {
+		mask |= 1 << 2
+	}
+	if _, ok := d.DB.(driver.DBFlusher); ok {
+		mask |= 1 << 3
+	}
+	if _, ok := d.DB.(driver.Copier); ok {
+		mask |= 1 << 4
+	}
+	if _, ok := d.DB.(driver.RevsDiffer); ok {
+		mask |= 1 << 5
+	}
+	if _, ok := d.DB.(driver.BulkDocsOptioner); ok {
+		mask |= 1 << 6
+	}
+	switch mask {
+	case 1:
+		return struct {
+			*db
+			finder
+		}{d, finder{d}}
+	case 2:
+		return struct {
+			*db
+			attachmentMetaer
+		}{d, attachmentMetaer{d}}
+	case 3:
+		return struct {
+			*db
+			finder
+			attachmentMetaer
+		}{d, finder{d}, attachmentMetaer{d}}
+	case 4:
+		return struct {
+			*db
+			rever
+		}{d, rever{d}}
+	case 5:
+		return struct {
+			*db
+			finder
+			rever
+		}{d, finder{d}, rever{d}}
+	case 6:
+		return struct {
+			*db
+			attachmentMetaer
+			rever
+		}{d, attachmentMetaer{d}, rever{d}}
+	case 7:
+		return struct {
+			*db
+			finder
+			attachmentMetaer
+			rever
+		}{d, finder{d}, attachmentMetaer{d}, rever{d}}
+	case 8:
+		return struct {
+			*db
+			dbFlusher
+		}{d, dbFlusher{d}}
+	case 9:
+		return struct {
+			*db
+			finder
+			dbFlusher
+		}{d, finder{d}, dbFlusher{d}}
+	case 10:
+		return struct {
+			*db
+			attachmentMetaer
+			dbFlusher
+		}{d, attachmentMetaer{d}, dbFlusher{d}}
+	case 11:
+		return struct {
+			*db
+			finder
+			attachmentMetaer
+			dbFlusher
+		}{d, finder{d}, attachmentMetaer{d}, dbFlusher{d}}
+	case 12:
+		return struct {
+			*db
+			rever
+			dbFlusher
+		}{d, rever{d}, dbFlusher{d}}
+	case 13:
+		return struct {
+			*db
+			finder
+			rever
+			dbFlusher
+		}{d, finder{d}, rever{d}, dbFlusher{d}}
+	case 14:
+		return struct {
+			*db
+			attachmentMetaer
+			rever
+			dbFlusher
+		}{d, attachmentMetaer{d}, rever{d}, dbFlusher{d}}
+	case 15:
+		return struct {
+			*db
+			finder
+			attachmentMetaer
+			rever
+			dbFlusher
+		}{d, finder{d}, attachmentMetaer{d}, rever{d}, dbFlusher{d}}
+	case 16:
+		return struct {
+			*db
+			copier
+		}{d, copier{d}}
+	case 17:
+		return struct {
+			*db
+			finder
+			copier
+		}{d, finder{d}, copier{d}}
+	case 18:
+		return struct {
+			*db
+			attachmentMetaer
+			copier
+		}{d, attachmentMetaer{d}, copier{d}}
+	case 19:
+		return struct {
+			*db
+			finder
+			attachmentMetaer
+			copier
+		}{d, finder{d}, attachmentMetaer{d}, copier{d}}
+	case 20:
+		return struct {
+			*db
+			rever
+			copier
+		}{d, rever{d}, copier{d}}
+	case 21:
+		return struct {
+			*db
+			finder
+			rever
+			copier
+		}{d, finder{d}, rever{d}, copier{d}}
+	case 22:
+		return struct {
+			*db
+			attachmentMetaer
+			rever
+			copier
+		}{d, attachmentMetaer{d}, rever{d}, copier{d}}
+	case 23:
+		return struct {
+			*db
+			finder
+			attachmentMetaer
+			rever
+			copier
+		}{d, finder{d}, attachmentMetaer{d}, rever{d}, copier{d}}
+	case 24:
+		return struct {
+			*db
+			dbFlusher
+			copier
+		}{d, dbFlusher{d}, copier{d}}
+	case 25:
+		return struct {
+			*db
+			finder
+			dbFlusher
+			copier
+		}{d, finder{d}, dbFlusher{d}, copier{d}}
+	case 26:
+		return struct {
+			*db
+			attachmentMetaer
+			dbFlusher
+			copier
+		}{d, attachmentMetaer{d}, dbFlusher{d}, copier{d}}
+	case 27:
+		return struct {
+			*db
+			finder
+			attachmentMetaer
+			dbFlusher
+			copier
+		}{d, finder{d}, attachmentMetaer{d}, dbFlusher{d}, copier{d}}
+	case 28:
+		return struct {
+			*db
+			rever
+			dbFlusher
+			copier
+		}{d, rever{d}, dbFlusher{d}, copier{d}}
+	case 29:
+		return struct {
+			*db
+			finder
+			rever
+			dbFlusher
+			copier
+		}{d, finder{d}, rever{d}, dbFlusher{d}, copier{d}}
+	case 30:
+		return struct {
+			*db
+			attachmentMetaer
+			rever
+			dbFlusher
+			copier
+		}{d, attachmentMetaer{d}, rever{d}, dbFlusher{d}, copier{d}}
+	case 31:
+		return struct {
+			*db
+			finder
+			attachmentMetaer
+			rever
+			dbFlusher
+			copier
+		}{d, finder{d}, attachmentMetaer{d}, rever{d}, dbFlusher{d}, copier{d}}
+	case 32:
+		return struct {
+			*db
+			revsDiffer
+		}{d, revsDiffer{d}}
+	case 33:
+		return struct {
+			*db
+			finder
+			revsDiffer
+		}{d, finder{d}, revsDiffer{d}}
+	case 34:
+		return struct {
+			*db
+			attachmentMetaer
+			revsDiffer
+		}{d, attachmentMetaer{d}, revsDiffer{d}}
+	case 35:
+		return struct {
+			*db
+			finder
+			attachmentMetaer
+			revsDiffer
+		}{d, finder{d}, attachmentMetaer{d}, revsDiffer{d}}
+	case 36:
+		return struct {
+			*db
+			rever
+			revsDiffer
+		}{d, rever{d}, revsDiffer{d}}
+	case 37:
+		return struct {
+			*db
+			finder
+			rever
+			revsDiffer
+		}{d, finder{d}, rever{d}, revsDiffer{d}}
+	case 38:
+		return struct {
+			*db
+			attachmentMetaer
+			rever
+			revsDiffer
+		}{d, attachmentMetaer{d}, rever{d}, revsDiffer{d}}
+	case 39:
+		return struct {
+			*db
+			finder
+			attachmentMetaer
+			rever
+			revsDiffer
+		}{d, finder{d}, attachmentMetaer{d}, rever{d}, revsDiffer{d}}
+	case 40:
+		return struct {
+			*db
+			dbFlusher
+			revsDiffer
+		}{d, dbFlusher{d}, revsDiffer{d}}
+	case 41:
+		return struct {
+			*db
+			finder
+			dbFlusher
+			revsDiffer
+		}{d, finder{d}, dbFlusher{d}, revsDiffer{d}}
+	case 42:
+		return struct {
+			*db
+			attachmentMetaer
+			dbFlusher
+			revsDiffer
+		}{d, attachmentMetaer{d}, dbFlusher{d}, revsDiffer{d}}
+	case 43:
+		return struct {
+			*db
+			finder
+			attachmentMetaer
+			dbFlusher
+			revsDiffer
+		}{d, finder{d}, attachmentMetaer{d}, dbFlusher{d}, revsDiffer{d}}
+	case 44:
+		return struct {
+			*db
+			rever
+			dbFlusher
+			revsDiffer
+		}{d, rever{d}, dbFlusher{d}, revsDiffer{d}}
+	case 45:
+		return struct {
+			*db
+			finder
+			rever
+			dbFlusher
+			revsDiffer
+		}{d, finder{d}, rever{d}, dbFlusher{d}, revsDiffer{d}}
+	case 46:
+		return struct {
+			*db
+			attachmentMetaer
+			rever
+			dbFlusher
+			revsDiffer
+		}{d, attachmentMetaer{d}, rever{d}, dbFlusher{d}, revsDiffer{d}}
+	case 47:
+		return struct {
+			*db
+			finder
+			attachmentMetaer
+			rever
+			dbFlusher
+			revsDiffer
+		}{d, finder{d}, attachmentMetaer{d}, rever{d}, dbFlusher{d}, revsDiffer{d}}
+	case 48:
+		return struct {
+			*db
+			copier
+			revsDiffer
+		}{d, copier{d}, revsDiffer{d}}
+	case 49:
+		return struct {
+			*db
+			finder
+			copier
+			revsDiffer
+		}{d, finder{d}, copier{d}, revsDiffer{d}}
+	case 50:
+		return struct {
+			*db
+			attachmentMetaer
+			copier
+			revsDiffer
+		}{d, attachmentMetaer{d}, copier{d}, revsDiffer{d}}
+	case 51:
+		return struct {
+			*db
+			finder
+			attachmentMetaer
+			copier
+			revsDiffer
+		}{d, finder{d}, attachmentMetaer{d}, copier{d}, revsDiffer{d}}
+	case 52:
+		return struct {
+			*db
+			rever
+			copier
+			revsDiffer
+		}{d, rever{d}, copier{d}, revsDiffer{d}}
+	case 53:
+		return struct {
+			*db
+			finder
+			rever
+			copier
+			revsDiffer
+		}{d, finder{d}, rever{d}, copier{d}, revsDiffer{d}}
+	case 54:
+		return struct {
+			*db
+			attachmentMetaer
+			rever
+			copier
+			revsDiffer
+		}{d, attachmentMetaer{d}, rever{d}, copier{d}, revsDiffer{d}}
+	case 55:
+		return struct {
+			*db
+			finder
+			attachmentMetaer
+			rever
+			copier
+			revsDiffer
+		}{d, finder{d}, attachmentMetaer{d}, rever{d}, copier{d}, revsDiffer{d}}
+	case 56:
+		return struct {
+			*db
+			dbFlusher
+			copier
+			revsDiffer
+		}{d, dbFlusher{d}, copier{d}, revsDiffer{d}}
+	case 57:
+		return struct {
+			*db
+			finder
+			dbFlusher
+			copier
+			revsDiffer
+		}{d, finder{d}, dbFlusher{d}, copier{d}, revsDiffer{d}}
+	case 58:
+		return struct {
+			*db
+			attachmentMetaer
+			dbFlusher
+			copier
+			revsDiffer
+		}{d, attachmentMetaer{d}, dbFlusher{d}, copier{d}, revsDiffer{d}}
+	case 59:
+		return struct {
+			*db
+			finder
+			attachmentMetaer
+			dbFlusher
+			copier
+			revsDiffer
+		}{d, finder{d}, attachmentMetaer{d}, dbFlusher{d}, copier{d}, revsDiffer{d}}
+	case 60:
+		return struct {
+			*db
+			rever
+			dbFlusher
+			copier
+			revsDiffer
+		}{d, rever{d}, dbFlusher{d}, copier{d}, revsDiffer{d}}
+	case 61:
+		return struct {
+			*db
+			finder
+			rever
+			dbFlusher
+			copier
+			revsDiffer
+		}{d, finder{d}, rever{d}, dbFlusher{d}, copier{d}, revsDiffer{d}}
+	case 62:
+		return struct {
+			*db
+			attachmentMetaer
+			rever
+			dbFlusher
+			copier
+			revsDiffer
+		}{d, attachmentMetaer{d}, rever{d}, dbFlusher{d}, copier{d}, revsDiffer{d}}
+	case 63:
+		return struct {
+			*db
+			finder
+			attachmentMetaer
+			rever
+			dbFlusher
+			copier
+			revsDiffer
+		}{d, finder{d}, attachmentMetaer{d}, rever{d}, dbFlusher{d}, copier{d}, revsDiffer{d}}
+	case 64:
+		return struct {
+			*db
+			bulkDocsOptioner
+		}{d, bulkDocsOptioner{d}}
+	case 65:
+		return struct {
+			*db
+			finder
+			bulkDocsOptioner
+		}{d, finder{d}, bulkDocsOptioner{d}}
+	case 66:
+		return struct {
+			*db
+			attachmentMetaer
+			bulkDocsOptioner
+		}{d, attachmentMetaer{d}, bulkDocsOptioner{d}}
+	case 67:
+		return struct {
+			*db
+			finder
+			attachmentMetaer
+			bulkDocsOptioner
+		}{d, finder{d}, attachmentMetaer{d}, bulkDocsOptioner{d}}
+	case 68:
+		return struct {
+			*db
+			rever
+			bulkDocsOptioner
+		}{d, rever{d}, bulkDocsOptioner{d}}
+	case 69:
+		return struct {
+			*db
+			finder
+			rever
+			bulkDocsOptioner
+		}{d, finder{d}, rever{d}, bulkDocsOptioner{d}}
+	case 70:
+		return struct {
+			*db
+			attachmentMetaer
+			rever
+			bulkDocsOptioner
+		}{d, attachmentMetaer{d}, rever{d}, bulkDocsOptioner{d}}
+	case 71:
+		return struct {
+			*db
+			finder
+			attachmentMetaer
+			rever
+			bulkDocsOptioner
+		}{d, finder{d}, attachmentMetaer{d}, rever{d}, bulkDocsOptioner{d}}
+	case 72:
+		return struct {
+			*db
+			dbFlusher
+			bulkDocsOptioner
+		}{d, dbFlusher{d}, bulkDocsOptioner{d}}
+	case 73:
+		return struct {
+			*db
+			finder
+			dbFlusher
+			bulkDocsOptioner
+		}{d, finder{d}, dbFlusher{d}, bulkDocsOptioner{d}}
+	case 74:
+		return struct {
+			*db
+			attachmentMetaer
+			dbFlusher
+			bulkDocsOptioner
+		}{d, attachmentMetaer{d}, dbFlusher{d}, bulkDocsOptioner{d}}
+	case 75:
+		return struct {
+			*db
+			finder
+			attachmentMetaer
+			dbFlusher
+			bulkDocsOptioner
+		}{d, finder{d}, attachmentMetaer{d}, dbFlusher{d}, bulkDocsOptioner{d}}
+	case 76:
+		return struct {
+			*db
+			rever
+			dbFlusher
+			bulkDocsOptioner
+		}{d, rever{d}, dbFlusher{d}, bulkDocsOptioner{d}}
+	case 77:
+		return struct {
+			*db
+			finder
+			rever
+			dbFlusher
+			bulkDocsOptioner
+		}{d, finder{d}, rever{d}, dbFlusher{d}, bulkDocsOptioner{d}}
+	case 78:
+		return struct {
+			*db
+			attachmentMetaer
+			rever
+			dbFlusher
+			bulkDocsOptioner
+		}{d, attachmentMetaer{d}, rever{d}, dbFlusher{d}, bulkDocsOptioner{d}}
+	case 79:
+		return struct {
+			*db
+			finder
+			attachmentMetaer
+			rever
+			dbFlusher
+			bulkDocsOptioner
+		}{d, finder{d}, attachmentMetaer{d}, rever{d}, dbFlusher{d}, bulkDocsOptioner{d}}
+	case 80:
+		return struct {
+			*db
+			copier
+			bulkDocsOptioner
+		}{d, copier{d}, bulkDocsOptioner{d}}
+	case 81:
+		return struct {
+			*db
+			finder
+			copier
+			bulkDocsOptioner
+		}{d, finder{d}, copier{d}, bulkDocsOptioner{d}}
+	case 82:
+		return struct {
+			*db
+			attachmentMetaer
+			copier
+			bulkDocsOptioner
+		}{d, attachmentMetaer{d}, copier{d}, bulkDocsOptioner{d}}
+	case 83:
+		return struct {
+			*db
+			finder
+			attachmentMetaer
+			copier
+			bulkDocsOptioner
+		}{d, finder{d}, attachmentMetaer{d}, copier{d}, bulkDocsOptioner{d}}
+	case 84:
+		return struct {
+			*db
+			rever
+			copier
+			bulkDocsOptioner
+		}{d, rever{d}, copier{d}, bulkDocsOptioner{d}}
+	case 85:
+		return struct {
+			*db
+			finder
+			rever
+			copier
+			bulkDocsOptioner
+		}{d, finder{d}, rever{d}, copier{d}, bulkDocsOptioner{d}}
+	case 86:
+		return struct {
+			*db
+			attachmentMetaer
+			rever
+			copier
+			bulkDocsOptioner
+		}{d, attachmentMetaer{d}, rever{d}, copier{d}, bulkDocsOptioner{d}}
+	case 87:
+		return struct {
+			*db
+			finder
+			attachmentMetaer
+			rever
+			copier
+			bulkDocsOptioner
+		}{d, finder{d}, attachmentMetaer{d}, rever{d}, copier{d}, bulkDocsOptioner{d}}
+	case 88:
+		return struct {
+			*db
+			dbFlusher
+			copier
+			bulkDocsOptioner
+		}{d, dbFlusher{d}, copier{d}, bulkDocsOptioner{d}}
+	case 89:
+		return struct {
+			*db
+			finder
+			dbFlusher
+			copier
+			bulkDocsOptioner
+		}{d, finder{d}, dbFlusher{d}, copier{d}, bulkDocsOptioner{d}}
+	case 90:
+		return struct {
+			*db
+			attachmentMetaer
+			dbFlusher
+			copier
+			bulkDocsOptioner
+		}{d, attachmentMetaer{d}, dbFlusher{d}, copier{d}, bulkDocsOptioner{d}}
+	case 91:
+		return struct {
+			*db
+			finder
+			attachmentMetaer
+			dbFlusher
+			copier
+			bulkDocsOptioner
+		}{d, finder{d}, attachmentMetaer{d}, dbFlusher{d}, copier{d}, bulkDocsOptioner{d}}
+	case 92:
+		return struct {
+			*db
+			rever
+			dbFlusher
+			copier
+			bulkDocsOptioner
+		}{d, rever{d}, dbFlusher{d}, copier{d}, bulkDocsOptioner{d}}
+	case 93:
+		return struct {
+			*db
+			finder
+			rever
+			dbFlusher
+			copier
+			bulkDocsOptioner
+		}{d, finder{d}, rever{d}, dbFlusher{d}, copier{d}, bulkDocsOptioner{d}}
+	case 94:
+		return struct {
+			*db
+			attachmentMetaer
+			rever
+			dbFlusher
+			copier
+			bulkDocsOptioner
+		}{d, attachmentMetaer{d}, rever{d}, dbFlusher{d}, copier{d}, bulkDocsOptioner{d}}
+	case 95:
+		return struct {
+			*db
+			finder
+			attachmentMetaer
+			rever
+			dbFlusher
+			copier
+			bulkDocsOptioner
+		}{d, finder{d}, attachmentMetaer{d}, rever{d}, dbFlusher{d}, copier{d}, bulkDocsOptioner{d}}
+	case 96:
+		return struct {
+			*db
+			revsDiffer
+			bulkDocsOptioner
+		}{d, revsDiffer{d}, bulkDocsOptioner{d}}
+	case 97:
+		return struct {
+			*db
+			finder
+			revsDiffer
+			bulkDocsOptioner
+		}{d, finder{d}, revsDiffer{d}, bulkDocsOptioner{d}}
+	case 98:
+		return struct {
+			*db
+			attachmentMetaer
+			revsDiffer
+			bulkDocsOptioner
+		}{d, attachmentMetaer{d}, revsDiffer{d}, bulkDocsOptioner{d}}
+	case 99:
+		return struct {
+			*db
+			finder
+			attachmentMetaer
+			revsDiffer
+			bulkDocsOptioner
+		}{d, finder{d}, attachmentMetaer{d}, revsDiffer{d}, bulkDocsOptioner{d}}
+	case 100:
+		return struct {
+			*db
+			rever
+			revsDiffer
+			bulkDocsOptioner
+		}{d, rever{d}, revsDiffer{d}, bulkDocsOptioner{d}}
+	case 101:
+		return struct {
+			*db
+			finder
+			rever
+			revsDiffer
+			bulkDocsOptioner
+		}{d, finder{d}, rever{d}, revsDiffer{d}, bulkDocsOptioner{d}}
+	case 102:
+		return struct {
+			*db
+			attachmentMetaer
+			rever
+			revsDiffer
+			bulkDocsOptioner
+		}{d, attachmentMetaer{d}, rever{d}, revsDiffer{d}, bulkDocsOptioner{d}}
+	case 103:
+		return struct {
+			*db
+			finder
+			attachmentMetaer
+			rever
+			revsDiffer
+			bulkDocsOptioner
+		}{d, finder{d}, attachmentMetaer{d}, rever{d}, revsDiffer{d}, bulkDocsOptioner{d}}
+	case 104:
+		return struct {
+			*db
+			dbFlusher
+			revsDiffer
+			bulkDocsOptioner
+		}{d, dbFlusher{d}, revsDiffer{d}, bulkDocsOptioner{d}}
+	case 105:
+		return struct {
+			*db
+			finder
+			dbFlusher
+			revsDiffer
+			bulkDocsOptioner
+		}{d, finder{d}, dbFlusher{d}, revsDiffer{d}, bulkDocsOptioner{d}}
+	case 106:
+		return struct {
+			*db
+			attachmentMetaer
+			dbFlusher
+			revsDiffer
+			bulkDocsOptioner
+		}{d, attachmentMetaer{d}, dbFlusher{d}, revsDiffer{d}, bulkDocsOptioner{d}}
+	case 107:
+		return struct {
+			*db
+			finder
+			attachmentMetaer
+			dbFlusher
+			revsDiffer
+			bulkDocsOptioner
+		}{d, finder{d}, attachmentMetaer{d}, dbFlusher{d}, revsDiffer{d}, bulkDocsOptioner{d}}
+	case 108:
+		return struct {
+			*db
+			rever
+			dbFlusher
+			revsDiffer
+			bulkDocsOptioner
+		}{d, rever{d}, dbFlusher{d}, revsDiffer{d}, bulkDocsOptioner{d}}
+	case 109:
+		return struct {
+			*db
+			finder
+			rever
+			dbFlusher
+			revsDiffer
+			bulkDocsOptioner
+		}{d, finder{d}, rever{d}, dbFlusher{d}, revsDiffer{d}, bulkDocsOptioner{d}}
+	case 110:
+		return struct {
+			*db
+			attachmentMetaer
+			rever
+			dbFlusher
+			revsDiffer
+			bulkDocsOptioner
+		}{d, attachmentMetaer{d}, rever{d}, dbFlusher{d}, revsDiffer{d}, bulkDocsOptioner{d}}
+	case 111:
+		return struct {
+			*db
+			finder
+			attachmentMetaer
+			rever
+			dbFlusher
+			revsDiffer
+			bulkDocsOptioner
+		}{d, finder{d}, attachmentMetaer{d}, rever{d}, dbFlusher{d}, revsDiffer{d}, bulkDocsOptioner{d}}
+	case 112:
+		return struct {
+			*db
+			copier
+			revsDiffer
+			bulkDocsOptioner
+		}{d, copier{d}, revsDiffer{d}, bulkDocsOptioner{d}}
+	case 113:
+		return struct {
+			*db
+			finder
+			copier
+			revsDiffer
+			bulkDocsOptioner
+		}{d, finder{d}, copier{d}, revsDiffer{d}, bulkDocsOptioner{d}}
+	case 114:
+		return struct {
+			*db
+			attachmentMetaer
+			copier
+			revsDiffer
+			bulkDocsOptioner
+		}{d, attachmentMetaer{d}, copier{d}, revsDiffer{d}, bulkDocsOptioner{d}}
+	case 115:
+		return struct {
+			*db
+			finder
+			attachmentMetaer
+			copier
+			revsDiffer
+			bulkDocsOptioner
+		}{d, finder{d}, attachmentMetaer{d}, copier{d}, revsDiffer{d}, bulkDocsOptioner{d}}
+	case 116:
+		return struct {
+			*db
+			rever
+			copier
+			revsDiffer
+			bulkDocsOptioner
+		}{d, rever{d}, copier{d}, revsDiffer{d}, bulkDocsOptioner{d}}
+	case 117:
+		return struct {
+			*db
+			finder
+			rever
+			copier
+			revsDiffer
+			bulkDocsOptioner
+		}{d, finder{d}, rever{d}, copier{d}, revsDiffer{d}, bulkDocsOptioner{d}}
+	case 118:
+		return struct {
+			*db
+			attachmentMetaer
+			rever
+			copier
+			revsDiffer
+			bulkDocsOptioner
+		}{d, attachmentMetaer{d}, rever{d}, copier{d}, revsDiffer{d}, bulkDocsOptioner{d}}
+	case 119:
+		return struct {
+			*db
+			finder
+			attachmentMetaer
+			rever
+			copier
+			revsDiffer
+			bulkDocsOptioner
+		}{d, finder{d}, attachmentMetaer{d}, rever{d}, copier{d}, revsDiffer{d}, bulkDocsOptioner{d}}
+	case 120:
+		return struct {
+			*db
+			dbFlusher
+			copier
+			revsDiffer
+			bulkDocsOptioner
+		}{d, dbFlusher{d}, copier{d}, revsDiffer{d}, bulkDocsOptioner{d}}
+	case 121:
+		return struct {
+			*db
+			finder
+			dbFlusher
+			copier
+			revsDiffer
+			bulkDocsOptioner
+		}{d, finder{d}, dbFlusher{d}, copier{d}, revsDiffer{d}, bulkDocsOptioner{d}}
+	case 122:
+		return struct {
+			*db
+			attachmentMetaer
+			dbFlusher
+			copier
+			revsDiffer
+			bulkDocsOptioner
+		}{d, attachmentMetaer{d}, dbFlusher{d}, copier{d}, revsDiffer{d}, bulkDocsOptioner{d}}
+	case 123:
+		return struct {
+			*db
+			finder
+			attachmentMetaer
+			dbFlusher
+			copier
+			revsDiffer
+			bulkDocsOptioner
+		}{d, finder{d}, attachmentMetaer{d}, dbFlusher{d}, copier{d}, revsDiffer{d}, bulkDocsOptioner{d}}
+	case 124:
+		return struct {
+			*db
+			rever
+			dbFlusher
+			copier
+			revsDiffer
+			bulkDocsOptioner
+		}{d, rever{d}, dbFlusher{d}, copier{d}, revsDiffer{d}, bulkDocsOptioner{d}}
+	case 125:
+		return struct {
+			*db
+			finder
+			rever
+			dbFlusher
+			copier
+			revsDiffer
+			bulkDocsOptioner
+		}{d, finder{d}, rever{d}, dbFlusher{d}, copier{d}, revsDiffer{d}, bulkDocsOptioner{d}}
+	case 126:
+		return struct {
+			*db
+			attachmentMetaer
+			rever
+			dbFlusher
+			copier
+			revsDiffer
+			bulkDocsOptioner
+		}{d, attachmentMetaer{d}, rever{d}, dbFlusher{d}, copier{d}, revsDiffer{d}, bulkDocsOptioner{d}}
+	case 127:
+		return struct {
+			*db
+			finder
+			attachmentMetaer
+			rever
+			dbFlusher
+			copier
+			revsDiffer
+			bulkDocsOptioner
+		}{d, finder{d}, attachmentMetaer{d}, rever{d}, dbFlusher{d}, copier{d}, revsDiffer{d}, bulkDocsOptioner{d}}
+	}
+	return d
+}
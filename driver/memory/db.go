@@ -3,8 +3,14 @@ package memory
 import (
 	"context"
 	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pborman/uuid"
 
 	"github.com/flimzy/kivik/driver"
+	"github.com/flimzy/kivik/driver/common"
 	"github.com/flimzy/kivik/errors"
 )
 
@@ -14,6 +20,10 @@ type db struct {
 	dbName string
 }
 
+var _ driver.DB = &db{}
+var _ driver.BulkDocsOptioner = &db{}
+var _ driver.RevsDiffer = &db{}
+
 type indexDoc struct {
 	ID    string        `json:"id"`
 	Key   string        `json:"key"`
@@ -24,6 +34,12 @@ type indexDocValue struct {
 	Rev string `json:"rev"`
 }
 
+const localPrefix = "_local/"
+
+func newDocID() string {
+	return strings.Replace(uuid.New(), "-", "", -1)
+}
+
 func (d *db) SetOption(_ string, _ interface{}) error {
 	return errors.New("no options supported")
 }
@@ -38,29 +54,89 @@ func (d *db) QueryContext(ctx context.Context, ddoc, view string, opts map[strin
 	return nil, nil
 }
 
+// GetContext fetches a document. The rev, revs and open_revs options are
+// supported.
 func (d *db) GetContext(_ context.Context, docID string, doc interface{}, opts map[string]interface{}) error {
-	// FIXME: Unimplemented
-	return nil
+	database, err := d.database()
+	if err != nil {
+		return err
+	}
+	database.mutex.RLock()
+	defer database.mutex.RUnlock()
+	if strings.HasPrefix(docID, localPrefix) {
+		local, ok := database.local[docID]
+		if !ok {
+			return errors.Status(http.StatusNotFound, "missing")
+		}
+		return common.Decode(local, doc)
+	}
+	stored, ok := database.docs[docID]
+	if !ok {
+		return errors.Status(http.StatusNotFound, "missing")
+	}
+	result, err := stored.Get(opts)
+	if err != nil {
+		return err
+	}
+	return common.Decode(result, doc)
 }
 
-func (d *db) CreateDocContext(_ context.Context, doc interface{}) (docID, rev string, err error) {
-	// FIXME: Unimplemented
-	return "", "", nil
+func (d *db) CreateDocContext(ctx context.Context, doc interface{}) (docID, rev string, err error) {
+	body, err := common.ToMap(doc)
+	if err != nil {
+		return "", "", err
+	}
+	docID, _ = body["_id"].(string)
+	if docID == "" {
+		docID = newDocID()
+	}
+	rev, err = d.PutContext(ctx, docID, body)
+	return docID, rev, err
 }
 
 func (d *db) PutContext(_ context.Context, docID string, doc interface{}) (rev string, err error) {
-	// FIXME: Unimplemented
-	return "", nil
+	database, err := d.database()
+	if err != nil {
+		return "", err
+	}
+	body, err := common.ToMap(doc)
+	if err != nil {
+		return "", err
+	}
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+	if strings.HasPrefix(docID, localPrefix) {
+		return database.putLocal(docID, body)
+	}
+	return database.update(docID, body)
 }
 
-func (d *db) DeleteContext(_ context.Context, docID, rev string) (newRev string, err error) {
-	// FIXME: Unimplemented
-	return "", nil
+func (d *db) DeleteContext(ctx context.Context, docID, rev string) (newRev string, err error) {
+	return d.PutContext(ctx, docID, map[string]interface{}{
+		"_rev":     rev,
+		"_deleted": true,
+	})
 }
 
 func (d *db) InfoContext(_ context.Context) (*driver.DBInfo, error) {
-	// FIXME: Unimplemented
-	return nil, nil
+	database, err := d.database()
+	if err != nil {
+		return nil, err
+	}
+	database.mutex.RLock()
+	defer database.mutex.RUnlock()
+	info := &driver.DBInfo{
+		Name:      d.dbName,
+		UpdateSeq: strconv.FormatInt(database.updateSeq, 10),
+	}
+	for _, doc := range database.docs {
+		if doc.Winner().Deleted {
+			info.DeletedCount++
+		} else {
+			info.DocCount++
+		}
+	}
+	return info, nil
 }
 
 func (c *client) CompactContext(_ context.Context) error {
@@ -79,33 +155,152 @@ func (d *db) ViewCleanupContext(_ context.Context) error {
 }
 
 func (d *db) SecurityContext(_ context.Context) (*driver.Security, error) {
-	// FIXME: Unimplemented
-	return nil, nil
+	database, err := d.database()
+	if err != nil {
+		return nil, err
+	}
+	database.mutex.RLock()
+	defer database.mutex.RUnlock()
+	sec := *database.security
+	return &sec, nil
 }
 
-func (d *db) SetSecurityContext(_ context.Context, _ *driver.Security) error {
-	// FIXME: Unimplemented
+func (d *db) SetSecurityContext(_ context.Context, security *driver.Security) error {
+	database, err := d.database()
+	if err != nil {
+		return err
+	}
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+	sec := *security
+	database.security = &sec
 	return nil
 }
 
 func (d *db) RevsLimitContext(_ context.Context) (limit int, err error) {
-	// FIXME: Unimplemented
-	return 0, nil
+	database, err := d.database()
+	if err != nil {
+		return 0, err
+	}
+	database.mutex.RLock()
+	defer database.mutex.RUnlock()
+	return database.revsLimit, nil
 }
 
 func (d *db) SetRevsLimitContext(_ context.Context, limit int) error {
-	// FIXME: Unimplemented
+	if limit < 1 {
+		return errors.Status(http.StatusBadRequest, "revs_limit must be positive")
+	}
+	database, err := d.database()
+	if err != nil {
+		return err
+	}
+	database.mutex.Lock()
+	defer database.mutex.Unlock()
+	database.revsLimit = limit
 	return nil
 }
 
+// ChangesContext returns the changes since the since option. The normal and
+// longpoll feeds return immediately, and the continuous feed waits for
+// further changes until it is closed, or ctx is cancelled. With
+// style=all_docs, all leaf revisions are reported, rather than only the
+// winning revision. Filter functions are not supported.
 func (d *db) ChangesContext(ctx context.Context, opts map[string]interface{}) (driver.Rows, error) {
-	// FIXME: Unimplemented
-	return nil, nil
+	if filter, _ := opts["filter"].(string); filter != "" {
+		return nil, errors.Status(http.StatusNotImplemented, "filter functions not supported")
+	}
+	database, err := d.database()
+	if err != nil {
+		return nil, err
+	}
+	database.mutex.RLock()
+	updateSeq := database.updateSeq
+	database.mutex.RUnlock()
+	since, err := common.SinceOption(opts["since"], updateSeq)
+	if err != nil {
+		return nil, err
+	}
+	allDocs := opts["style"] == "all_docs"
+	poll := func(since int64) ([]driver.Row, error) {
+		return database.changes(since, allDocs), nil
+	}
+	if feed, _ := opts["feed"].(string); feed == "continuous" {
+		return common.ContinuousChanges(ctx, since, poll, database.wait), nil
+	}
+	changes, _ := poll(since)
+	return &common.Rows{Rows: changes, Seq: strconv.FormatInt(updateSeq, 10)}, nil
 }
 
-func (d *db) BulkDocsContext(_ context.Context, _ ...interface{}) (driver.BulkResults, error) {
-	// FIXME: Unimplemented
-	return nil, nil
+func (d *db) BulkDocsContext(ctx context.Context, docs ...interface{}) (driver.BulkResults, error) {
+	results := make([]driver.BulkResult, len(docs))
+	for i, doc := range docs {
+		body, err := common.ToMap(doc)
+		if err != nil {
+			return nil, err
+		}
+		docID, _ := body["_id"].(string)
+		if docID == "" {
+			docID = newDocID()
+		}
+		rev, err := d.PutContext(ctx, docID, body)
+		results[i] = driver.BulkResult{ID: docID, Rev: rev, Error: err}
+	}
+	return &common.BulkResults{Results: results}, nil
+}
+
+// BulkDocsOptsContext supports the new_edits option. When false, documents
+// are stored with the revisions and history given in their _rev and
+// _revisions fields, as by replication.
+func (d *db) BulkDocsOptsContext(ctx context.Context, options map[string]interface{}, docs ...interface{}) (driver.BulkResults, error) {
+	if newEdits, ok := options["new_edits"]; !ok || newEdits == true || newEdits == "true" {
+		return d.BulkDocsContext(ctx, docs...)
+	}
+	database, err := d.database()
+	if err != nil {
+		return nil, err
+	}
+	results := make([]driver.BulkResult, 0, len(docs))
+	for _, doc := range docs {
+		body, err := common.ToMap(doc)
+		if err != nil {
+			return nil, err
+		}
+		database.mutex.Lock()
+		err = database.replicate(body)
+		database.mutex.Unlock()
+		if err != nil {
+			docID, _ := body["_id"].(string)
+			results = append(results, driver.BulkResult{ID: docID, Error: err})
+		}
+	}
+	// As CouchDB, only failures are reported with new_edits=false.
+	return &common.BulkResults{Results: results}, nil
+}
+
+// RevsDiffContext returns the revisions in revMap which are not in the
+// database.
+func (d *db) RevsDiffContext(_ context.Context, revMap map[string][]string) (map[string]driver.RevDiff, error) {
+	database, err := d.database()
+	if err != nil {
+		return nil, err
+	}
+	database.mutex.RLock()
+	defer database.mutex.RUnlock()
+	diff := make(map[string]driver.RevDiff)
+	for docID, revs := range revMap {
+		doc := database.docs[docID]
+		var missing []string
+		for _, rev := range revs {
+			if doc == nil || !doc.Known(rev) {
+				missing = append(missing, rev)
+			}
+		}
+		if len(missing) > 0 {
+			diff[docID] = driver.RevDiff{Missing: missing}
+		}
+	}
+	return diff, nil
 }
 
 func (d *db) PutAttachmentContext(_ context.Context, _, _, _, _ string, _ io.Reader) (string, error) {
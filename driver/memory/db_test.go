@@ -0,0 +1,177 @@
+package memory
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/flimzy/kivik"
+	"github.com/flimzy/kivik/driver"
+	"github.com/flimzy/kivik/driver/common"
+	"github.com/flimzy/kivik/errors"
+)
+
+func newTestDB(t *testing.T) *db {
+	c, err := (&memDriver{}).NewClientContext(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CreateDBContext(context.Background(), "foo"); err != nil {
+		t.Fatal(err)
+	}
+	d, err := c.DBContext(context.Background(), "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d.(*db)
+}
+
+func TestPutGetDelete(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	rev, err := d.PutContext(ctx, "doc", map[string]interface{}{"value": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = d.PutContext(ctx, "doc", map[string]interface{}{"value": 2}); errors.StatusCode(err) != kivik.StatusConflict {
+		t.Errorf("Expected conflict without _rev, got %v", err)
+	}
+	rev2, err := d.PutContext(ctx, "doc", map[string]interface{}{"_rev": rev, "value": 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if common.RevGeneration(rev2) != 2 {
+		t.Errorf("Unexpected revision %s", rev2)
+	}
+	var doc map[string]interface{}
+	if err = d.GetContext(ctx, "doc", &doc, nil); err != nil {
+		t.Fatal(err)
+	}
+	if doc["_rev"] != rev2 || doc["value"] != 2.0 {
+		t.Errorf("Unexpected document: %v", doc)
+	}
+	if err = d.GetContext(ctx, "doc", &doc, map[string]interface{}{"rev": rev}); err != nil || doc["value"] != 1.0 {
+		t.Errorf("Unexpected old revision: %v, %v", doc, err)
+	}
+	if _, err = d.DeleteContext(ctx, "doc", rev2); err != nil {
+		t.Fatal(err)
+	}
+	if err = d.GetContext(ctx, "doc", &doc, nil); errors.StatusCode(err) != kivik.StatusNotFound {
+		t.Errorf("Expected deleted document to be missing, got %v", err)
+	}
+	info, err := d.InfoContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.DocCount != 0 || info.DeletedCount != 1 || info.UpdateSeq != "3" {
+		t.Errorf("Unexpected info: %+v", info)
+	}
+}
+
+func TestNewEditsFalse(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	results, err := d.BulkDocsOptsContext(ctx, map[string]interface{}{"new_edits": false},
+		map[string]interface{}{
+			"_id":        "doc",
+			"_rev":       "2-b",
+			"_revisions": map[string]interface{}{"start": 2, "ids": []string{"b", "a"}},
+		},
+		map[string]interface{}{"_id": "doc", "_rev": "2-c"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result driver.BulkResult
+	if err = results.Next(&result); err == nil {
+		t.Errorf("Unexpected failure: %+v", result)
+	}
+	diff, err := d.RevsDiffContext(ctx, map[string][]string{"doc": {"1-a", "2-b", "3-x"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if missing := diff["doc"].Missing; len(missing) != 1 || missing[0] != "3-x" {
+		t.Errorf("Unexpected diff: %v", diff)
+	}
+	var doc map[string]interface{}
+	if err = d.GetContext(ctx, "doc", &doc, nil); err != nil {
+		t.Fatal(err)
+	}
+	if doc["_rev"] != "2-c" {
+		t.Errorf("Expected winning revision 2-c, got %s", doc["_rev"])
+	}
+	var open []map[string]interface{}
+	if err = d.GetContext(ctx, "doc", &open, map[string]interface{}{"open_revs": `["2-b","1-a"]`, "revs": true}); err != nil {
+		t.Fatal(err)
+	}
+	if len(open) != 2 || open[0]["ok"] == nil || open[1]["missing"] != "1-a" {
+		t.Errorf("Unexpected open_revs result: %v", open)
+	}
+	rows, err := d.ChangesContext(ctx, map[string]interface{}{"since": "0", "style": "all_docs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var row driver.Row
+	if err = rows.Next(&row); err != nil {
+		t.Fatal(err)
+	}
+	if row.ID != "doc" || len(row.Changes) != 2 {
+		t.Errorf("Unexpected change: %+v", row)
+	}
+}
+
+func TestChangesSince(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	for _, id := range []string{"a", "b", "c"} {
+		if _, err := d.PutContext(ctx, id, map[string]interface{}{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rows, err := d.ChangesContext(ctx, map[string]interface{}{"since": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	var row driver.Row
+	for rows.Next(&row) == nil {
+		ids = append(ids, row.ID+"@"+string(row.Seq))
+	}
+	if len(ids) != 2 || ids[0] != "b@2" || ids[1] != "c@3" {
+		t.Errorf("Unexpected changes: %v", ids)
+	}
+	if _, err := d.ChangesContext(ctx, map[string]interface{}{"filter": "ddoc/filter"}); errors.StatusCode(err) != kivik.StatusNotImplemented {
+		t.Errorf("Expected filters to be unsupported, got %v", err)
+	}
+}
+
+func TestChangesContinuous(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	if _, err := d.PutContext(ctx, "a", map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := d.ChangesContext(ctx, map[string]interface{}{"feed": "continuous", "since": "0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var row driver.Row
+	if err = rows.Next(&row); err != nil || row.ID != "a" {
+		t.Fatalf("Unexpected change %+v, error %v", row, err)
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_, _ = d.PutContext(ctx, "b", map[string]interface{}{})
+	}()
+	if err = rows.Next(&row); err != nil || row.ID != "b" || row.Seq != "2" {
+		t.Fatalf("Unexpected change %+v, error %v", row, err)
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_ = rows.Close()
+	}()
+	if err = rows.Next(&row); err != io.EOF {
+		t.Errorf("Expected EOF once the feed is closed, got %v", err)
+	}
+}
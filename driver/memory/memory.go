@@ -76,7 +76,7 @@ func (c *client) CreateDBContext(ctx context.Context, dbName string) error {
 	}
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
-	c.dbs[dbName] = &database{}
+	c.dbs[dbName] = newDatabase()
 	return nil
 }
 
@@ -0,0 +1,161 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/flimzy/kivik"
+	"github.com/flimzy/kivik/errors"
+)
+
+func TestReplicate(t *testing.T) {
+	ctx := context.Background()
+	client, err := kivik.New("memory", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"source", "target"} {
+		if err = client.CreateDB(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	source, _ := client.DB("source")
+	target, _ := client.DB("target")
+	rev, err := source.Put("foo", map[string]interface{}{"value": "foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = source.Put("bar", map[string]interface{}{"value": "bar"}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := kivik.Replicate(ctx, target, source, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.DocsWritten != 2 {
+		t.Errorf("Unexpected result: %+v", info)
+	}
+	var doc map[string]interface{}
+	if err = target.Get("foo", &doc, nil); err != nil {
+		t.Fatal(err)
+	}
+	if doc["_rev"] != rev || doc["value"] != "foo" {
+		t.Errorf("Unexpected replicated document: %v", doc)
+	}
+
+	// Edits on both sides are replicated as a conflict, resolved the same
+	// way in both databases.
+	if _, err = source.Put("foo", map[string]interface{}{"_rev": rev, "value": "source"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = target.Put("foo", map[string]interface{}{"_rev": rev, "value": "target"}); err != nil {
+		t.Fatal(err)
+	}
+	if info, err = kivik.Replicate(ctx, target, source, nil); err != nil {
+		t.Fatal(err)
+	}
+	if info.StartSeq != "2" || info.DocsWritten != 1 {
+		t.Errorf("Unexpected result: %+v", info)
+	}
+	if _, err = kivik.Replicate(ctx, source, target, nil); err != nil {
+		t.Fatal(err)
+	}
+	var fromSource, fromTarget map[string]interface{}
+	if err = source.Get("foo", &fromSource, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err = target.Get("foo", &fromTarget, nil); err != nil {
+		t.Fatal(err)
+	}
+	if fromSource["_rev"] != fromTarget["_rev"] {
+		t.Errorf("Winning revisions differ: %s != %s", fromSource["_rev"], fromTarget["_rev"])
+	}
+}
+
+func TestReplicateFilter(t *testing.T) {
+	ctx := context.Background()
+	client, err := kivik.New("memory", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"source", "target"} {
+		if err = client.CreateDB(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	source, _ := client.DB("source")
+	target, _ := client.DB("target")
+	if _, err = source.Put("foo", map[string]interface{}{"type": "foo"}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = kivik.Replicate(ctx, target, source, &kivik.ReplicationOptions{
+		Filter:      "ddoc/type",
+		QueryParams: map[string]interface{}{"type": "bar"},
+	})
+	if errors.StatusCode(err) != kivik.StatusNotImplemented {
+		t.Errorf("Expected Not Implemented for a filtered replication, got %v", err)
+	}
+	info, err := target.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.DocCount != 0 {
+		t.Errorf("Expected no documents to be replicated, found %d", info.DocCount)
+	}
+}
+
+func TestReplicateContinuous(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client, err := kivik.New("memory", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"source", "target"} {
+		if err = client.CreateDB(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	source, _ := client.DB("source")
+	target, _ := client.DB("target")
+	if _, err = source.Put("foo", map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	progress := make(chan kivik.ReplicationInfo, 10)
+	result := make(chan error, 1)
+	go func() {
+		_, err := kivik.Replicate(ctx, target, source, &kivik.ReplicationOptions{
+			Continuous: true,
+			Progress:   func(info kivik.ReplicationInfo) { progress <- info },
+		})
+		result <- err
+	}()
+	waitWritten := func(n int64) {
+		for {
+			select {
+			case info := <-progress:
+				if info.DocsWritten >= n {
+					return
+				}
+			case err := <-result:
+				t.Fatalf("Replication ended early: %v", err)
+			case <-time.After(5 * time.Second):
+				t.Fatalf("Timed out waiting for %d documents to be written", n)
+			}
+		}
+	}
+	waitWritten(1)
+	if _, err = source.Put("bar", map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	waitWritten(2)
+	var doc map[string]interface{}
+	if err = target.Get("bar", &doc, nil); err != nil {
+		t.Errorf("Change made during continuous replication was not replicated: %s", err)
+	}
+	cancel()
+	if err := <-result; err != context.Canceled {
+		t.Errorf("Expected replication to end with the context, got %v", err)
+	}
+}
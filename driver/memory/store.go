@@ -1,27 +1,37 @@
 package memory
 
-import "sync"
+import (
+	"context"
+	"net/http"
+	"sync"
 
-type file struct {
-	ContentType string
-	Data        []byte
-}
-
-type document struct {
-	revs []*revision
-}
-
-type revision struct {
-	data        map[string]interface{}
-	ID          string
-	Rev         string
-	Attachments map[string]file
-}
+	"github.com/flimzy/kivik/driver"
+	"github.com/flimzy/kivik/driver/common"
+	"github.com/flimzy/kivik/errors"
+)
 
 type database struct {
 	mutex     sync.RWMutex
-	docs      map[string]*document
+	docs      map[string]*common.Document
+	local     map[string]map[string]interface{}
+	security  *driver.Security
+	revsLimit int
 	updateSeq int64
+	// changed is closed, and replaced, whenever a document is stored, to
+	// wake continuous changes feeds.
+	changed chan struct{}
+}
+
+const defaultRevsLimit = 1000
+
+func newDatabase() *database {
+	return &database{
+		docs:      make(map[string]*common.Document),
+		local:     make(map[string]map[string]interface{}),
+		security:  &driver.Security{},
+		revsLimit: defaultRevsLimit,
+		changed:   make(chan struct{}),
+	}
 }
 
 func (d *db) getDB() *database {
@@ -31,3 +41,90 @@ func (d *db) getDB() *database {
 	database, _ := c.dbs[d.dbName]
 	return database
 }
+
+// database returns the database, or a 404 error if it does not exist.
+func (d *db) database() (*database, error) {
+	if database := d.getDB(); database != nil {
+		return database, nil
+	}
+	return nil, errors.Status(http.StatusNotFound, "database does not exist")
+}
+
+// update stores a new revision of a document, as a child of the leaf
+// revision given in doc's _rev.
+func (d *database) update(docID string, doc map[string]interface{}) (string, error) {
+	rev, err := common.NewEdit(d.docs[docID], docID, doc)
+	if err != nil {
+		return "", err
+	}
+	d.store(docID, rev)
+	return rev.Rev, nil
+}
+
+// replicate stores a revision of a document as given, with its history from
+// _revisions, as CouchDB does with new_edits=false.
+func (d *database) replicate(doc map[string]interface{}) error {
+	docID, _ := doc["_id"].(string)
+	rev, err := common.Replicated(d.docs[docID], doc)
+	if err != nil || rev == nil {
+		return err
+	}
+	d.store(docID, rev)
+	return nil
+}
+
+func (d *database) store(docID string, rev *common.Revision) {
+	doc := d.docs[docID]
+	if doc == nil {
+		doc = &common.Document{}
+		d.docs[docID] = doc
+	}
+	d.updateSeq++
+	doc.Add(rev, d.revsLimit, d.updateSeq)
+	close(d.changed)
+	d.changed = make(chan struct{})
+}
+
+// putLocal stores a _local document, which is not replicated, and has no
+// revision history.
+func (d *database) putLocal(docID string, doc map[string]interface{}) (string, error) {
+	old, _ := d.local[docID]
+	local, rev, err := common.PutLocal(old, docID, doc)
+	if err != nil {
+		return "", err
+	}
+	if local == nil {
+		delete(d.local, docID)
+	} else {
+		d.local[docID] = local
+	}
+	return rev, nil
+}
+
+// changes returns the changes after since, in order.
+func (d *database) changes(since int64, allDocs bool) []driver.Row {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	var changes []driver.Row
+	for id, doc := range d.docs {
+		if doc.Seq > since {
+			changes = append(changes, doc.Change(id, allDocs))
+		}
+	}
+	common.SortChanges(changes)
+	return changes
+}
+
+// wait blocks until a document is stored after since, or ctx is cancelled.
+func (d *database) wait(ctx context.Context, since int64) {
+	d.mutex.RLock()
+	changed, seq := d.changed, d.updateSeq
+	d.mutex.RUnlock()
+	if seq > since {
+		return
+	}
+	select {
+	case <-changed:
+	case <-ctx.Done():
+	}
+}
@@ -10,17 +10,15 @@ import (
 	"github.com/flimzy/kivik/driver"
 	"github.com/flimzy/kivik/driver/pouchdb/bindings"
 	"github.com/flimzy/kivik/errors"
+	"github.com/flimzy/kivik/tasks"
 	"github.com/gopherjs/gopherjs/js"
 )
 
 type db struct {
-	db *bindings.DB
+	db   *bindings.DB
+	name string
 
 	client *client
-
-	// compacting is set true when compaction begins, and unset when the
-	// callback returns.
-	compacting bool
 }
 
 // SetOption sets a connection-time option by replacing the underling DB
@@ -98,16 +96,25 @@ func (d *db) InfoContext(ctx context.Context) (*driver.DBInfo, error) {
 	i, err := d.db.Info(ctx)
 	return &driver.DBInfo{
 		Name:           i.Name,
-		CompactRunning: d.compacting,
+		CompactRunning: d.compacting(),
 		DocCount:       i.DocCount,
 		UpdateSeq:      i.UpdateSeq,
 	}, err
 }
 
+// compacting returns true while a compaction or view cleanup is running.
+func (d *db) compacting() bool {
+	return d.client.tasks.Running(tasks.TypeDatabaseCompaction, d.name) ||
+		d.client.tasks.Running(tasks.TypeViewCompaction, d.name)
+}
+
 func (d *db) CompactContext(_ context.Context) error {
-	d.compacting = true
+	task := d.client.tasks.Start(driver.ActiveTask{
+		Type:     tasks.TypeDatabaseCompaction,
+		Database: d.name,
+	})
 	go func() {
-		defer func() { d.compacting = false }()
+		defer task.Done()
 		if err := d.db.Compact(); err != nil {
 			fmt.Fprintf(os.Stderr, "compaction failed: %s", err)
 		}
@@ -121,9 +128,12 @@ func (d *db) CompactViewContext(_ context.Context, _ string) error {
 }
 
 func (d *db) ViewCleanupContext(_ context.Context) error {
-	d.compacting = true
+	task := d.client.tasks.Start(driver.ActiveTask{
+		Type:     tasks.TypeViewCompaction,
+		Database: d.name,
+	})
 	go func() {
-		defer func() { d.compacting = false }()
+		defer task.Done()
 		if err := d.db.ViewCleanup(); err != nil {
 			fmt.Fprintf(os.Stderr, "view cleanup failed: %s", err)
 		}
@@ -12,6 +12,7 @@ import (
 	"github.com/flimzy/kivik/driver"
 	"github.com/flimzy/kivik/driver/pouchdb/bindings"
 	"github.com/flimzy/kivik/errors"
+	"github.com/flimzy/kivik/tasks"
 	"github.com/imdario/mergo"
 )
 
@@ -70,6 +71,7 @@ func (d *Driver) NewClientContext(_ context.Context, dsn string) (driver.Client,
 		dsn:   u,
 		pouch: pouch,
 		opts:  make(map[string]Options),
+		tasks: tasks.NewRegistry(),
 	}
 	if user != nil {
 		pass, _ := user.Password()
@@ -88,9 +90,16 @@ type client struct {
 	dsn   *url.URL
 	opts  map[string]Options
 	pouch *bindings.PouchDB
+	// tasks tracks compactions and view cleanups, which run in the background.
+	tasks *tasks.Registry
 }
 
 var _ driver.Client = &client{}
+var _ driver.ActiveTasker = &client{}
+
+func (c *client) ActiveTasksContext(ctx context.Context) ([]driver.ActiveTask, error) {
+	return c.tasks.ActiveTasksContext(ctx)
+}
 
 const optionsDefaultKey = "defaults"
 
@@ -231,6 +240,7 @@ func (c *client) DBContext(ctx context.Context, dbName string) (driver.DB, error
 	}
 	return &db{
 		db:     c.pouch.New(c.dbURL(dbName), opts),
+		name:   dbName,
 		client: c,
 	}, nil
 }
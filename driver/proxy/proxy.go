@@ -17,6 +17,7 @@ type CompleteClient interface {
 	driver.LogReader
 	driver.Cluster
 	driver.Configer
+	driver.ActiveTasker
 }
 
 // NewClient wraps an existing *kivik.Client connection, allowing it to be used
@@ -40,6 +41,18 @@ func (c *client) ConfigContext(ctx context.Context) (driver.Config, error) {
 	return c.ConfigContext(ctx)
 }
 
+func (c *client) ActiveTasksContext(ctx context.Context) ([]driver.ActiveTask, error) {
+	kivikTasks, err := c.Client.ActiveTasksContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tasks := make([]driver.ActiveTask, len(kivikTasks))
+	for i, task := range kivikTasks {
+		tasks[i] = driver.ActiveTask(task)
+	}
+	return tasks, nil
+}
+
 type db struct {
 	*kivik.DB
 }
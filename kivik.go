@@ -52,6 +52,17 @@ func (c *Client) SetDefault(key string, value interface{}) error {
 	return c.driverClient.SetDefault(key, value)
 }
 
+// Wrap returns a new client, sharing c's connection, whose driver client is
+// replaced by the result of wrap. This allows middleware, such as
+// instrumentation, to be inserted between kivik and the driver.
+func (c *Client) Wrap(wrap func(driver.Client) driver.Client) *Client {
+	return &Client{
+		dsn:          c.dsn,
+		driverName:   c.driverName,
+		driverClient: wrap(c.driverClient),
+	}
+}
+
 // Driver returns the name of the driver string used to connect this client.
 func (c *Client) Driver() string {
 	return c.driverName
@@ -0,0 +1,75 @@
+// Package jsonlog provides a file logger which writes one JSON object per
+// line, for consumption by log pipelines.
+//
+// Each line contains the timestamp (RFC 3339, UTC), level and message, along
+// with any structured fields, such as those defined in the logger package:
+//
+//	{"level":"info","message":"GET /foo 200","method":"GET","path":"/foo","status":200,"timestamp":"2017-05-01T12:00:00Z"}
+package jsonlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/flimzy/kivik/driver"
+	"github.com/flimzy/kivik/errors"
+	"github.com/flimzy/kivik/logger"
+	"github.com/flimzy/kivik/logger/logfile"
+)
+
+// Reserved keys. Fields with these names are overwritten.
+const (
+	KeyTimestamp = "timestamp"
+	KeyLevel     = "level"
+	KeyMessage   = "message"
+)
+
+// Logger is a JSON file logger instance. It is configured in the same way as
+// logfile.Logger, and reading the log returns the raw JSON lines.
+type Logger struct {
+	logfile.Logger
+}
+
+var _ logger.LogWriter = &Logger{}
+var _ logger.FieldWriter = &Logger{}
+var _ driver.LogReader = &Logger{}
+
+var now = time.Now
+
+// WriteLog writes a log entry with no additional fields.
+func (l *Logger) WriteLog(level logger.LogLevel, message string) error {
+	return l.WriteLogFields(level, message, nil)
+}
+
+// WriteLogFields writes a log entry, including fields.
+func (l *Logger) WriteLogFields(level logger.LogLevel, message string, fields logger.Fields) error {
+	entry := make(map[string]interface{}, len(fields)+3)
+	for k, v := range fields {
+		entry[k] = fieldValue(v)
+	}
+	entry[KeyTimestamp] = now().UTC().Format(time.RFC3339Nano)
+	entry[KeyLevel] = level.String()
+	entry[KeyMessage] = message
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode log entry")
+	}
+	_, err = l.Write(append(line, '\n'))
+	return err
+}
+
+// fieldValue converts values which don't encode usefully as JSON.
+func fieldValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case error:
+		return t.Error()
+	case time.Duration:
+		return float64(t) / float64(time.Millisecond)
+	case json.Marshaler:
+		return v
+	case fmt.Stringer:
+		return t.String()
+	}
+	return v
+}
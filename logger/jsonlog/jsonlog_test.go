@@ -0,0 +1,59 @@
+package jsonlog
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/flimzy/kivik/errors"
+	"github.com/flimzy/kivik/logger"
+)
+
+func TestWriteLog(t *testing.T) {
+	now = func() time.Time {
+		return time.Date(2017, 5, 1, 12, 0, 0, 0, time.FixedZone("EST", -5*3600))
+	}
+	defer func() { now = time.Now }()
+	f, err := ioutil.TempFile("", "kivik-jsonlog-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+	defer os.Remove(f.Name())
+	log := &Logger{}
+	if err = log.Init(map[string]string{"file": f.Name()}); err != nil {
+		t.Fatal(err)
+	}
+	if err = log.WriteLog(logger.LogLevelWarn, "plain"); err != nil {
+		t.Fatal(err)
+	}
+	err = log.WriteLogFields(logger.LogLevelInfo, "GET /foo 200", logger.Fields{
+		logger.FieldMethod:   "GET",
+		logger.FieldPath:     "/foo",
+		logger.FieldStatus:   200,
+		logger.FieldDuration: 1500 * time.Microsecond,
+		"error":              errors.New("oops"),
+		KeyMessage:           "ignored",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"level":"warning","message":"plain","timestamp":"2017-05-01T17:00:00Z"}
+{"duration_ms":1.5,"error":"oops","level":"info","message":"GET /foo 200","method":"GET","path":"/foo","status":200,"timestamp":"2017-05-01T17:00:00Z"}
+`
+	r, err := log.LogContext(context.Background(), 1000, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	buf := &bytes.Buffer{}
+	if _, err := buf.ReadFrom(r); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != expected {
+		t.Errorf("Unexpected log:\nExpected: %s\n  Actual: %s\n", expected, buf.String())
+	}
+}
@@ -2,25 +2,58 @@ package logfile
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"os/signal"
+	"strconv"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/flimzy/kivik"
+	"github.com/flimzy/kivik/config"
 	"github.com/flimzy/kivik/driver"
 	"github.com/flimzy/kivik/errors"
 	"github.com/flimzy/kivik/logger"
 )
 
+func init() {
+	config.Register(
+		&config.Key{Section: "log", Name: "file",
+			Description: "File to which logs are written"},
+		&config.Key{Section: "log", Name: "max_size", Type: config.TypeInt, Min: 0, Max: 1 << 53,
+			Description: "Rotate the log file when it would exceed this many bytes. 0 disables size-based rotation"},
+		&config.Key{Section: "log", Name: "rotate_interval",
+			Description: "Rotate the log file at this interval, e.g. 24h. Empty disables time-based rotation"},
+		&config.Key{Section: "log", Name: "max_files", Type: config.TypeInt, Min: 0, Max: 1 << 31,
+			Description: "Number of rotated log files to keep. 0 keeps all of them"},
+		&config.Key{Section: "log", Name: "compress", Type: config.TypeBool,
+			Description: "Compress rotated log files with gzip"},
+	)
+}
+
 // Logger is a file logger instance.
 type Logger struct {
 	mutex    sync.RWMutex
 	filename string
 	f        *os.File
+	size     int64
+	opened   time.Time
+
+	maxSize  int64
+	interval time.Duration
+	maxFiles int
+	compress bool
+
+	hup chan os.Signal
+
+	// compressMu is held while a rotated file is compressed, so that it
+	// isn't moved by a concurrent rotation.
+	compressMu sync.Mutex
 }
 
 var _ logger.LogWriter = &Logger{}
@@ -28,42 +61,290 @@ var _ driver.LogReader = &Logger{}
 
 var now = time.Now
 
+var rename = os.Rename
+
 // Init initializes the logger. It looks for the following configuration
 // parameters:
 //
 //  - file: The file to which logs are written. (required)
+//  - max_size: Rotate the file before it exceeds this many bytes.
+//  - rotate_interval: Rotate the file at this interval, e.g. 24h.
+//  - max_files: The number of rotated files to keep. (default: all)
+//  - compress: If true, rotated files are compressed with gzip.
+//
+// Rotated files are named file.1, file.2, and so on, with file.1 the most
+// recent, and a .gz suffix when compressed. The file is also reopened when
+// the process receives SIGHUP, for use with external tools such as logrotate.
 func (l *Logger) Init(conf map[string]string) error {
-	if l.f != nil {
-		l.f.Close()
-		l.filename = ""
-	}
 	filename, ok := conf["file"]
 	if !ok {
 		return errors.New("log.file must be configured")
 	}
+	maxSize, err := getInt(conf, "max_size")
+	if err != nil {
+		return err
+	}
+	maxFiles, err := getInt(conf, "max_files")
+	if err != nil {
+		return err
+	}
+	var interval time.Duration
+	if value := conf["rotate_interval"]; value != "" {
+		if interval, err = time.ParseDuration(value); err != nil {
+			return errors.Wrapf(err, "invalid rotate_interval '%s'", value)
+		}
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.f != nil {
+		_ = l.f.Close()
+		l.f = nil
+		l.filename = ""
+	}
+	l.maxSize = maxSize
+	l.maxFiles = int(maxFiles)
+	l.interval = interval
+	l.compress = conf["compress"] == "true"
+	if err := l.open(filename); err != nil {
+		return err
+	}
+	if l.hup == nil {
+		l.hup = make(chan os.Signal, 1)
+		signal.Notify(l.hup, syscall.SIGHUP)
+		go func(hup <-chan os.Signal) {
+			for range hup {
+				_ = l.Reopen()
+			}
+		}(l.hup)
+	}
+	return nil
+}
+
+func getInt(conf map[string]string, key string) (int64, error) {
+	value, ok := conf[key]
+	if !ok || value == "" {
+		return 0, nil
+	}
+	i, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || i < 0 {
+		return 0, errors.Errorf("invalid %s '%s'", key, value)
+	}
+	return i, nil
+}
+
+// open opens filename for appending. The caller must hold the write lock.
+func (l *Logger) open(filename string) error {
 	f, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
 	if err != nil {
 		return errors.Wrap(err, "failed to open log file")
 	}
+	st, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return errors.Wrap(err, "failed to open log file")
+	}
 	l.filename = filename
 	l.f = f
+	l.size = st.Size()
+	l.opened = now()
 	return nil
 }
 
-// WriteLog writes a log to the opened log file.
-func (l *Logger) WriteLog(level logger.LogLevel, message string) error {
+// Reopen closes and reopens the log file. This is done automatically on
+// SIGHUP, after the file has been moved by an external tool.
+func (l *Logger) Reopen() error {
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
-	_, err := fmt.Fprintf(l.f, "[%s] [%s] [--] %s\n", now().Format(logger.TimeFormat), level, message)
+	if l.f == nil {
+		return errors.New("log file not open")
+	}
+	_ = l.f.Close()
+	l.f = nil
+	return l.open(l.filename)
+}
+
+// Close closes the log file, and stops listening for SIGHUP.
+func (l *Logger) Close() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.hup != nil {
+		signal.Stop(l.hup)
+		close(l.hup)
+		l.hup = nil
+	}
+	if l.f == nil {
+		return nil
+	}
+	err := l.f.Close()
+	l.f = nil
+	return err
+}
+
+// WriteLog writes a log to the opened log file.
+func (l *Logger) WriteLog(level logger.LogLevel, message string) error {
+	_, err := fmt.Fprintf(l, "[%s] [%s] [--] %s\n", now().Format(logger.TimeFormat), level, message)
 	return err
 }
 
+// Write writes p to the log file, unmodified. This allows other log formats
+// to be written to the file. The file is rotated first, if required. Rotated
+// files are compressed after the write, without blocking other writers.
+func (l *Logger) Write(p []byte) (int, error) {
+	l.mutex.Lock()
+	if l.f == nil {
+		l.mutex.Unlock()
+		return 0, errors.New("log file not open")
+	}
+	var compress string
+	if l.needsRotate(int64(len(p))) {
+		var err error
+		if compress, err = l.rotate(); err != nil {
+			l.mutex.Unlock()
+			return 0, err
+		}
+	}
+	n, err := l.f.Write(p)
+	l.size += int64(n)
+	l.mutex.Unlock()
+	if compress != "" {
+		defer l.compressMu.Unlock()
+		if e := compressFile(compress); err == nil {
+			err = e
+		}
+	}
+	return n, err
+}
+
+func (l *Logger) needsRotate(n int64) bool {
+	if l.size == 0 {
+		return false
+	}
+	if l.maxSize > 0 && l.size+n > l.maxSize {
+		return true
+	}
+	return l.interval > 0 && now().Sub(l.opened) >= l.interval
+}
+
+// rotatedName returns the name of the nth rotated file.
+func (l *Logger) rotatedName(n int, compressed bool) string {
+	name := l.filename + "." + strconv.Itoa(n)
+	if compressed {
+		name += ".gz"
+	}
+	return name
+}
+
+// rotated returns the name of the nth rotated file, and whether it is
+// compressed, or an empty string if it does not exist.
+func (l *Logger) rotated(n int) (string, bool) {
+	for _, compressed := range []bool{false, true} {
+		name := l.rotatedName(n, compressed)
+		if _, err := os.Stat(name); err == nil {
+			return name, compressed
+		}
+	}
+	return "", false
+}
+
+// rotate moves the current file to file.1, shifting older files up and
+// removing those beyond the retention count, then reopens the file. The file
+// is reopened even if rotation fails, so that logging can continue. The
+// caller must hold the write lock.
+//
+// If the rotated file is to be compressed, its name is returned, and
+// compressMu is left locked. The caller must compress the file, and then
+// unlock compressMu.
+func (l *Logger) rotate() (compress string, err error) {
+	l.compressMu.Lock()
+	defer func() {
+		if compress == "" {
+			l.compressMu.Unlock()
+		}
+	}()
+	count := 0
+	for {
+		if name, _ := l.rotated(count + 1); name == "" {
+			break
+		}
+		count++
+	}
+	for n := count; n > 0; n-- {
+		name, compressed := l.rotated(n)
+		if l.maxFiles > 0 && n >= l.maxFiles {
+			if err := os.Remove(name); err != nil {
+				return "", errors.Wrap(err, "failed to remove old log file")
+			}
+			continue
+		}
+		if err := rename(name, l.rotatedName(n+1, compressed)); err != nil {
+			return "", errors.Wrap(err, "failed to rotate log file")
+		}
+	}
+	_ = l.f.Close()
+	l.f = nil
+	defer func() {
+		if e := l.open(l.filename); e != nil {
+			compress, err = "", e
+		}
+	}()
+	first := l.rotatedName(1, false)
+	if err := rename(l.filename, first); err != nil {
+		return "", errors.Wrap(err, "failed to rotate log file")
+	}
+	if l.compress {
+		return first, nil
+	}
+	return "", nil
+}
+
+// compressFile replaces filename with filename.gz.
+func compressFile(filename string) error {
+	in, err := os.Open(filename)
+	if err != nil {
+		return errors.Wrap(err, "failed to compress log file")
+	}
+	defer in.Close()
+	out, err := os.OpenFile(filename+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return errors.Wrap(err, "failed to compress log file")
+	}
+	gz := gzip.NewWriter(out)
+	if _, err = io.Copy(gz, in); err == nil {
+		err = gz.Close()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(filename + ".gz")
+		return errors.Wrap(err, "failed to compress log file")
+	}
+	return os.Remove(filename)
+}
+
 type logReadCloser struct {
 	io.Reader
-	io.Closer
+	closers []io.Closer
+}
+
+func (r *logReadCloser) Close() error {
+	var err error
+	for _, c := range r.closers {
+		if e := c.Close(); err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// segment is a section of the log: the current file, or a rotated one.
+type segment struct {
+	io.ReadSeeker
+	size int64
 }
 
-// LogContext reads the log file.
+// LogContext reads the log file. If the current file holds fewer than
+// offset+length bytes, reading continues into the rotated files.
 func (l *Logger) LogContext(_ context.Context, length, offset int64) (io.ReadCloser, error) {
 	if length < 0 {
 		return nil, errors.Status(kivik.StatusBadRequest, "invalid length specified")
@@ -74,23 +355,97 @@ func (l *Logger) LogContext(_ context.Context, length, offset int64) (io.ReadClo
 	if length == 0 {
 		return ioutil.NopCloser(&bytes.Buffer{}), nil
 	}
-	l.f.Sync()
-	f, err := os.Open(l.filename)
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	if l.f == nil {
+		return nil, errors.New("log file not open")
+	}
+	_ = l.f.Sync()
+	rc := &logReadCloser{}
+	segments, total, err := l.segments(offset+length, rc)
 	if err != nil {
+		_ = rc.Close()
 		return nil, err
 	}
+	// segments are newest first; read them oldest first.
+	readers := make([]io.Reader, 0, len(segments))
+	skip := total - (offset + length)
+	for i := len(segments) - 1; i >= 0; i-- {
+		seg := segments[i]
+		if skip >= seg.size {
+			skip -= seg.size
+			continue
+		}
+		if skip > 0 {
+			if _, err := seg.Seek(skip, io.SeekStart); err != nil {
+				_ = rc.Close()
+				return nil, err
+			}
+			skip = 0
+		}
+		readers = append(readers, seg)
+	}
+	n := length
+	if total-offset < n {
+		n = total - offset
+	}
+	if n < 0 {
+		n = 0
+	}
+	rc.Reader = &io.LimitedReader{R: io.MultiReader(readers...), N: n}
+	return rc, nil
+}
+
+// segments opens the current and rotated files, newest first, until at least
+// need bytes are available or there are no more files. Open files are added
+// to rc's closers.
+func (l *Logger) segments(need int64, rc *logReadCloser) ([]segment, int64, error) {
+	f, err := os.Open(l.filename)
+	if err != nil {
+		return nil, 0, err
+	}
+	rc.closers = append(rc.closers, f)
 	st, err := f.Stat()
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	if st.Size() > length {
-		_, err := f.Seek(-(offset + length), os.SEEK_END)
+	segments := []segment{{ReadSeeker: f, size: st.Size()}}
+	total := st.Size()
+	for n := 1; total < need; n++ {
+		name, compressed := l.rotated(n)
+		if name == "" {
+			break
+		}
+		seg, err := openSegment(name, compressed, rc)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
+		segments = append(segments, seg)
+		total += seg.size
+	}
+	return segments, total, nil
+}
+
+func openSegment(name string, compressed bool, rc *logReadCloser) (segment, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return segment{}, err
+	}
+	rc.closers = append(rc.closers, f)
+	if !compressed {
+		st, err := f.Stat()
+		if err != nil {
+			return segment{}, err
+		}
+		return segment{ReadSeeker: f, size: st.Size()}, nil
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return segment{}, errors.Wrapf(err, "failed to read %s", name)
+	}
+	content, err := ioutil.ReadAll(gz)
+	if err != nil {
+		return segment{}, errors.Wrapf(err, "failed to read %s", name)
 	}
-	return &logReadCloser{
-		Reader: &io.LimitedReader{R: f, N: length},
-		Closer: f,
-	}, nil
+	return segment{ReadSeeker: bytes.NewReader(content), size: int64(len(content))}, nil
 }
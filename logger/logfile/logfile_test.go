@@ -5,9 +5,12 @@ import (
 	"context"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/flimzy/diff"
+	"github.com/flimzy/kivik/errors"
 	"github.com/flimzy/kivik/logger"
 )
 
@@ -111,3 +114,138 @@ func TestLog(t *testing.T) {
 		})
 	}
 }
+
+func readLog(t *testing.T, l *Logger, length, offset int64) string {
+	r, err := l.LogContext(CTX, length, offset)
+	if err != nil {
+		t.Fatalf("Unexpected error reading log: %s", err)
+	}
+	defer r.Close()
+	buf := &bytes.Buffer{}
+	if _, err := buf.ReadFrom(r); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kivik-log-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "kivik.log")
+	log := &Logger{}
+	if err = log.Init(map[string]string{"file": filename, "max_size": "10", "max_files": "2", "compress": "true"}); err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+	for _, line := range []string{"aaaaaaa\n", "bbbbbbb\n", "ccccccc\n", "ddddddd\n"} {
+		if _, err := log.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var files []string
+	infos, _ := ioutil.ReadDir(dir)
+	for _, info := range infos {
+		files = append(files, info.Name())
+	}
+	expectedFiles := []string{"kivik.log", "kivik.log.1.gz", "kivik.log.2.gz"}
+	if d := diff.TextSlices(expectedFiles, files); d != "" {
+		t.Errorf("Unexpected files:\n%s\n", d)
+	}
+	if content := readLog(t, log, 4, 0); content != "ddd\n" {
+		t.Errorf("Unexpected tail: %q", content)
+	}
+	if content := readLog(t, log, 20, 0); content != "bbb\nccccccc\nddddddd\n" {
+		t.Errorf("Failed to read across rotation: %q", content)
+	}
+	if content := readLog(t, log, 10, 4); content != "ccccc\ndddd" {
+		t.Errorf("Failed to read across rotation with offset: %q", content)
+	}
+	if content := readLog(t, log, 100, 0); content != "bbbbbbb\nccccccc\nddddddd\n" {
+		t.Errorf("Unexpected full log: %q", content)
+	}
+}
+
+func TestRotateInterval(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kivik-log-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	current := time.Date(2017, 5, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return current }
+	defer func() { now = time.Now }()
+	filename := filepath.Join(dir, "kivik.log")
+	log := &Logger{}
+	if err = log.Init(map[string]string{"file": filename, "rotate_interval": "24h"}); err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+	_, _ = log.Write([]byte("day 1\n"))
+	current = current.Add(23 * time.Hour)
+	_, _ = log.Write([]byte("day 1 again\n"))
+	current = current.Add(time.Hour)
+	_, _ = log.Write([]byte("day 2\n"))
+	if content, _ := ioutil.ReadFile(filename + ".1"); string(content) != "day 1\nday 1 again\n" {
+		t.Errorf("Unexpected rotated file: %q", content)
+	}
+	if content, _ := ioutil.ReadFile(filename); string(content) != "day 2\n" {
+		t.Errorf("Unexpected current file: %q", content)
+	}
+}
+
+func TestReopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kivik-log-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "kivik.log")
+	log := &Logger{}
+	if err = log.Init(map[string]string{"file": filename}); err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+	_, _ = log.Write([]byte("before\n"))
+	if err := os.Rename(filename, filename+".old"); err != nil {
+		t.Fatal(err)
+	}
+	if err := log.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = log.Write([]byte("after\n"))
+	if content, _ := ioutil.ReadFile(filename); string(content) != "after\n" {
+		t.Errorf("Unexpected content after reopen: %q", content)
+	}
+}
+
+func TestRotateFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kivik-log-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "kivik.log")
+	log := &Logger{}
+	if err = log.Init(map[string]string{"file": filename, "max_size": "10"}); err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+	_, _ = log.Write([]byte("aaaaaaa\n"))
+	rename = func(_, _ string) error { return errors.New("rename failed") }
+	if _, err := log.Write([]byte("bbbbbbb\n")); err == nil {
+		t.Errorf("Expected rotation failure")
+	}
+	rename = os.Rename
+	if _, err := log.Write([]byte("ccccccc\n")); err != nil {
+		t.Fatalf("Log file was not reopened after a failed rotation: %s", err)
+	}
+	if content, _ := ioutil.ReadFile(filename + ".1"); string(content) != "aaaaaaa\n" {
+		t.Errorf("Unexpected rotated file: %q", content)
+	}
+	if content, _ := ioutil.ReadFile(filename); string(content) != "ccccccc\n" {
+		t.Errorf("Unexpected current file: %q", content)
+	}
+}
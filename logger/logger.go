@@ -4,6 +4,8 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/flimzy/kivik/errors"
 )
 
 // TimeFormat is the default time format used for CouchDB logs.
@@ -56,6 +58,20 @@ func StringToLogLevel(str string) (level LogLevel, ok bool) {
 	}
 }
 
+// ConfigLevel returns the log level set by the level key of conf, or def if
+// the key is not set. An error is returned if the level is unknown.
+func ConfigLevel(conf map[string]string, def LogLevel) (LogLevel, error) {
+	str, ok := conf["level"]
+	if !ok || str == "" {
+		return def, nil
+	}
+	level, ok := StringToLogLevel(str)
+	if !ok {
+		return 0, errors.Errorf("unknown log level '%s'", str)
+	}
+	return level, nil
+}
+
 // LogWriter is an interface for a logging backend.
 type LogWriter interface {
 	// Init is used to (re)start the logger. When called, any log files should
@@ -68,6 +84,29 @@ type LogWriter interface {
 	WriteLog(level LogLevel, message string) error
 }
 
+// Fields are structured data attached to a log message.
+type Fields map[string]interface{}
+
+// Standard field names.
+const (
+	FieldRequestID  = "request_id"
+	FieldUser       = "user"
+	FieldRemoteAddr = "remote_addr"
+	FieldMethod     = "method"
+	FieldPath       = "path"
+	FieldStatus     = "status"
+	FieldBytes      = "bytes"
+	// FieldDuration is the request duration, in milliseconds.
+	FieldDuration = "duration_ms"
+)
+
+// FieldWriter is an optional interface which may be implemented by a
+// LogWriter which supports structured fields. When available, it is used in
+// place of WriteLog.
+type FieldWriter interface {
+	WriteLogFields(level LogLevel, message string, fields Fields) error
+}
+
 type statusWriter struct {
 	http.ResponseWriter
 	status int
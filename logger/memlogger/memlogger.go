@@ -9,6 +9,7 @@ import (
 	"io"
 	"io/ioutil"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/flimzy/kivik"
@@ -22,7 +23,9 @@ var now = time.Now
 // Logger is an in-memory logger instance. It fulfills both the logger.Logger
 // and driver.Logger interfaces
 type Logger struct {
-	ring *ring.Ring
+	mutex sync.RWMutex
+	ring  *ring.Ring
+	level logger.LogLevel
 }
 
 var _ logger.LogWriter = &Logger{}
@@ -31,15 +34,21 @@ var _ driver.LogReader = &Logger{}
 // Init initializes the memory logger. It considers the following configuration
 // parameters:
 //
-// - capacity: The number of log entries to keep in memory. Defaults to 100.
-//  - level: The minimum log level to log to the file. (default: info)
+//  - capacity: The number of log entries to keep in memory. Defaults to 100.
+//  - level: The minimum level of log entries to keep. (default: info)
 func (l *Logger) Init(conf map[string]string) error {
-	l.ring = nil
 	cap, err := getCapacity(conf)
 	if err != nil {
 		return err
 	}
+	level, err := logger.ConfigLevel(conf, logger.LogLevelInfo)
+	if err != nil {
+		return err
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
 	l.ring = ring.New(cap)
+	l.level = level
 	return nil
 }
 
@@ -57,6 +66,11 @@ func getCapacity(conf map[string]string) (int, error) {
 
 // WriteLog logs the message at the designated level.
 func (l *Logger) WriteLog(level logger.LogLevel, message string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if level < l.level {
+		return nil
+	}
 	msg := fmt.Sprintf("[%s] [%s] [--] %s\n", now().Format(logger.TimeFormat), level, message)
 	l.ring.Value = &msg
 	l.ring = l.ring.Next()
@@ -94,6 +108,8 @@ func (l *Logger) LogContext(_ context.Context, length, offset int64) (io.ReadClo
 	if length == 0 {
 		return ioutil.NopCloser(&bytes.Buffer{}), nil
 	}
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
 	cur := l.ring.Prev()
 	list := make([]*string, 0, length/100)
 	remain := length
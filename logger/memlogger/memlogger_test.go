@@ -77,3 +77,50 @@ func TestLog(t *testing.T) {
 		})
 	}
 }
+
+func TestLevel(t *testing.T) {
+	log := &Logger{}
+	if err := log.Init(map[string]string{"level": "warning"}); err != nil {
+		t.Fatal(err)
+	}
+	_ = log.WriteLog(logger.LogLevelInfo, "ignored")
+	_ = log.WriteLog(logger.LogLevelError, "kept")
+	r, err := log.LogContext(CTX, 1000, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	_, _ = buf.ReadFrom(r)
+	if bytes.Contains(buf.Bytes(), []byte("ignored")) || !bytes.Contains(buf.Bytes(), []byte("kept")) {
+		t.Errorf("Unexpected log: %s", buf.String())
+	}
+	if err := log.Init(map[string]string{"level": "loud"}); err == nil {
+		t.Errorf("Expected error for unknown level")
+	}
+}
+
+func TestConcurrentInit(t *testing.T) {
+	log := &Logger{}
+	if err := log.Init(map[string]string{"capacity": "10"}); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			_ = log.WriteLog(logger.LogLevelError, "message")
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		if err := log.Init(map[string]string{"capacity": "5", "level": "warning"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-done
+	if err := log.Init(map[string]string{"capacity": "bogus"}); err == nil {
+		t.Errorf("Expected error for invalid capacity")
+	}
+	if err := log.WriteLog(logger.LogLevelError, "still works"); err != nil {
+		t.Errorf("Failed to write after invalid configuration: %s", err)
+	}
+}
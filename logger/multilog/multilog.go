@@ -0,0 +1,145 @@
+// Package multilog provides a logger which sends each message to several
+// backends, each with its own minimum log level.
+//
+// Each backend is initialized with the full log configuration section, with
+// keys prefixed by the backend's name and a dot overriding the unprefixed
+// keys. For example, with backends named "file" and "memory":
+//
+//	[log]
+//	level = debug
+//	file = /var/log/kivik.log
+//	file.level = warning
+//	memory.capacity = 1000
+//
+// the file backend receives level=warning, and the memory backend
+// level=debug. Note that messages below log.level are discarded before they
+// reach any backend.
+package multilog
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/flimzy/kivik"
+	"github.com/flimzy/kivik/driver"
+	"github.com/flimzy/kivik/errors"
+	"github.com/flimzy/kivik/logger"
+)
+
+// Backend is a named log backend.
+type Backend struct {
+	// Name identifies the backend in the configuration.
+	Name string
+	logger.LogWriter
+}
+
+// Logger is a multiplexing logger instance.
+type Logger struct {
+	backends []Backend
+	reader   string
+
+	// mu guards levels, which holds the minimum level of each backend. It
+	// is replaced, rather than modified, by Init.
+	mu     sync.RWMutex
+	levels []logger.LogLevel
+}
+
+var _ logger.LogWriter = &Logger{}
+var _ logger.FieldWriter = &Logger{}
+var _ driver.LogReader = &Logger{}
+
+// New returns a logger which writes to each of backends. reader is the name
+// of the backend which answers log reads. It must implement driver.LogReader.
+func New(reader string, backends ...Backend) *Logger {
+	return &Logger{
+		backends: backends,
+		reader:   reader,
+		levels:   make([]logger.LogLevel, len(backends)),
+	}
+}
+
+// Init initializes each backend, with the configuration described in the
+// package documentation.
+func (l *Logger) Init(conf map[string]string) error {
+	levels := make([]logger.LogLevel, len(l.backends))
+	for i, b := range l.backends {
+		bConf := backendConfig(conf, b.Name)
+		level, err := logger.ConfigLevel(bConf, logger.LogLevelDebug)
+		if err != nil {
+			return errors.Wrapf(err, "log backend '%s'", b.Name)
+		}
+		if err := b.Init(bConf); err != nil {
+			return errors.Wrapf(err, "log backend '%s'", b.Name)
+		}
+		levels[i] = level
+	}
+	l.mu.Lock()
+	l.levels = levels
+	l.mu.Unlock()
+	return nil
+}
+
+// backendConfig returns the configuration for the named backend.
+func backendConfig(conf map[string]string, name string) map[string]string {
+	prefix := name + "."
+	bConf := make(map[string]string, len(conf))
+	for key, value := range conf {
+		if !strings.Contains(key, ".") {
+			bConf[key] = value
+		}
+	}
+	for key, value := range conf {
+		if strings.HasPrefix(key, prefix) {
+			bConf[strings.TrimPrefix(key, prefix)] = value
+		}
+	}
+	return bConf
+}
+
+// WriteLog writes the message to each backend whose level permits it. All
+// backends are written to, even if one fails, and the first error is
+// returned.
+func (l *Logger) WriteLog(level logger.LogLevel, message string) error {
+	return l.WriteLogFields(level, message, nil)
+}
+
+// WriteLogFields writes the message and fields to each backend whose level
+// permits it. Fields are dropped for backends which do not implement
+// logger.FieldWriter.
+func (l *Logger) WriteLogFields(level logger.LogLevel, message string, fields logger.Fields) error {
+	l.mu.RLock()
+	levels := l.levels
+	l.mu.RUnlock()
+	var err error
+	for i, b := range l.backends {
+		if level < levels[i] {
+			continue
+		}
+		var e error
+		if fw, ok := b.LogWriter.(logger.FieldWriter); ok && len(fields) > 0 {
+			e = fw.WriteLogFields(level, message, fields)
+		} else {
+			e = b.LogWriter.WriteLog(level, message)
+		}
+		if err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// LogContext reads the log from the designated reader backend.
+func (l *Logger) LogContext(ctx context.Context, length, offset int64) (io.ReadCloser, error) {
+	for _, b := range l.backends {
+		if b.Name != l.reader {
+			continue
+		}
+		if reader, ok := b.LogWriter.(driver.LogReader); ok {
+			return reader.LogContext(ctx, length, offset)
+		}
+		break
+	}
+	return nil, errors.Status(kivik.StatusNotImplemented, "no log backend supports reading")
+}
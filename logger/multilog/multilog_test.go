@@ -0,0 +1,112 @@
+package multilog
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/kivik/errors"
+	"github.com/flimzy/kivik/logger"
+	"github.com/flimzy/kivik/logger/memlogger"
+)
+
+type recorder struct {
+	conf     map[string]string
+	messages []string
+}
+
+func (r *recorder) Init(conf map[string]string) error {
+	r.conf = conf
+	return nil
+}
+
+func (r *recorder) WriteLog(level logger.LogLevel, message string) error {
+	r.messages = append(r.messages, level.String()+": "+message)
+	return nil
+}
+
+func TestBackendConfig(t *testing.T) {
+	conf := map[string]string{
+		"level":        "info",
+		"file":         "/var/log/kivik.log",
+		"file.level":   "error",
+		"memory.level": "debug",
+	}
+	expected := map[string]string{
+		"level": "error",
+		"file":  "/var/log/kivik.log",
+	}
+	if d := diff.AsJSON(expected, backendConfig(conf, "file")); d != "" {
+		t.Error(d)
+	}
+}
+
+func TestWriteLog(t *testing.T) {
+	a, b := &recorder{}, &recorder{}
+	l := New("a", Backend{Name: "a", LogWriter: a}, Backend{Name: "b", LogWriter: b})
+	if err := l.Init(map[string]string{"level": "debug", "b.level": "warning"}); err != nil {
+		t.Fatal(err)
+	}
+	_ = l.WriteLog(logger.LogLevelDebug, "one")
+	_ = l.WriteLog(logger.LogLevelError, "two")
+	if d := diff.TextSlices([]string{"debug: one", "error: two"}, a.messages); d != "" {
+		t.Errorf("Backend a:\n%s", d)
+	}
+	if d := diff.TextSlices([]string{"error: two"}, b.messages); d != "" {
+		t.Errorf("Backend b:\n%s", d)
+	}
+	if _, err := l.LogContext(context.Background(), 100, 0); errors.StatusCode(err) != 501 {
+		t.Errorf("Expected Not Implemented for non-reader backend, got %v", err)
+	}
+}
+
+func TestInitInvalidLevel(t *testing.T) {
+	l := New("", Backend{Name: "a", LogWriter: &recorder{}})
+	err := l.Init(map[string]string{"a.level": "loud"})
+	expected := "log backend 'a': unknown log level 'loud'"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error '%s', got %v", expected, err)
+	}
+}
+
+func TestLogContext(t *testing.T) {
+	mem := &memlogger.Logger{}
+	l := New("memory", Backend{Name: "other", LogWriter: &recorder{}}, Backend{Name: "memory", LogWriter: mem})
+	if err := l.Init(map[string]string{"memory.level": "warning"}); err != nil {
+		t.Fatal(err)
+	}
+	_ = l.WriteLog(logger.LogLevelInfo, "ignored")
+	_ = l.WriteLog(logger.LogLevelWarn, "kept")
+	r, err := l.LogContext(context.Background(), 1000, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	buf := &bytes.Buffer{}
+	_, _ = buf.ReadFrom(r)
+	if !bytes.HasSuffix(buf.Bytes(), []byte("[warning] [--] kept\n")) || bytes.Contains(buf.Bytes(), []byte("ignored")) {
+		t.Errorf("Unexpected log: %s", buf.String())
+	}
+}
+
+func TestConcurrentInit(t *testing.T) {
+	mem := &memlogger.Logger{}
+	l := New("memory", Backend{Name: "memory", LogWriter: mem})
+	if err := l.Init(map[string]string{"level": "debug"}); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			_ = l.WriteLog(logger.LogLevelInfo, "message")
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		if err := l.Init(map[string]string{"memory.level": "warning"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-done
+}
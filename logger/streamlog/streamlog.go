@@ -0,0 +1,40 @@
+// Package streamlog provides a logger which writes CouchDB-style log lines to
+// an io.Writer, such as standard error.
+package streamlog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/flimzy/kivik/logger"
+)
+
+// Logger is a stream logger instance.
+type Logger struct {
+	// W is the stream to which logs are written. If nil, os.Stderr is used.
+	W io.Writer
+
+	mutex sync.Mutex
+}
+
+var _ logger.LogWriter = &Logger{}
+
+var now = time.Now
+
+// Init does nothing, as the stream logger takes no configuration.
+func (l *Logger) Init(_ map[string]string) error { return nil }
+
+// WriteLog writes the message to the stream.
+func (l *Logger) WriteLog(level logger.LogLevel, message string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	w := l.W
+	if w == nil {
+		w = os.Stderr
+	}
+	_, err := fmt.Fprintf(w, "[%s] [%s] [--] %s\n", now().Format(logger.TimeFormat), level, message)
+	return err
+}
@@ -0,0 +1,143 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// PrometheusContentType is the content type of the Prometheus text format.
+const PrometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// PrometheusName returns the Prometheus name of a metric.
+func PrometheusName(name, typ string) string {
+	name = strings.Replace(name, ".", "_", -1)
+	if typ == TypeCounter && !strings.HasSuffix(name, "_total") {
+		name += "_total"
+	}
+	return name
+}
+
+// WritePrometheus writes all metrics to w, in the Prometheus text exposition
+// format.
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range r.sortedFamilies() {
+		name := PrometheusName(f.name, f.typ)
+		if f.help != "" {
+			fmt.Fprintf(bw, "# HELP %s %s\n", name, escapeHelp(f.help))
+		}
+		fmt.Fprintf(bw, "# TYPE %s %s\n", name, f.typ)
+		for _, s := range f.snapshot() {
+			if f.typ != TypeHistogram {
+				fmt.Fprintf(bw, "%s%s %s\n", name, formatLabels(f.labels, s.labelValues, ""), formatValue(s.value))
+				continue
+			}
+			var cumulative uint64
+			for i, upper := range f.buckets {
+				cumulative += s.counts[i]
+				fmt.Fprintf(bw, "%s_bucket%s %d\n", name, formatLabels(f.labels, s.labelValues, formatValue(upper)), cumulative)
+			}
+			fmt.Fprintf(bw, "%s_bucket%s %d\n", name, formatLabels(f.labels, s.labelValues, "+Inf"), s.count)
+			fmt.Fprintf(bw, "%s_sum%s %s\n", name, formatLabels(f.labels, s.labelValues, ""), formatValue(s.value))
+			fmt.Fprintf(bw, "%s_count%s %d\n", name, formatLabels(f.labels, s.labelValues, ""), s.count)
+		}
+	}
+	return bw.Flush()
+}
+
+// formatLabels formats label pairs, adding the le label if le is not empty.
+func formatLabels(labels, values []string, le string) string {
+	pairs := make([]string, 0, len(labels)+1)
+	for i, label := range labels {
+		pairs = append(pairs, label+`="`+escapeLabel(values[i])+`"`)
+	}
+	if le != "" {
+		pairs = append(pairs, `le="`+le+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// Stats returns all metrics as a nested map, in the style of CouchDB's
+// /_node/_local/_stats endpoint. Each metric is an object with value, type
+// and desc keys. The value of a histogram is an object with n, min, max,
+// arithmetic_mean and histogram keys, where histogram is a list of
+// [upper bound, count] pairs.
+func (r *Registry) Stats() map[string]interface{} {
+	stats := make(map[string]interface{})
+	for _, f := range r.sortedFamilies() {
+		for _, s := range f.snapshot() {
+			path := append(strings.Split(f.name, "."), s.labelValues...)
+			setPath(stats, path, map[string]interface{}{
+				"value": statValue(f, s),
+				"type":  f.typ,
+				"desc":  f.help,
+			})
+		}
+	}
+	return stats
+}
+
+func statValue(f *family, s series) interface{} {
+	if f.typ != TypeHistogram {
+		return s.value
+	}
+	value := map[string]interface{}{
+		"n":               s.count,
+		"min":             0.0,
+		"max":             0.0,
+		"arithmetic_mean": 0.0,
+	}
+	if s.count > 0 {
+		value["min"] = s.min
+		value["max"] = s.max
+		value["arithmetic_mean"] = s.value / float64(s.count)
+	}
+	histogram := make([][]interface{}, 0, len(f.buckets)+1)
+	var counted uint64
+	for i, upper := range f.buckets {
+		histogram = append(histogram, []interface{}{upper, s.counts[i]})
+		counted += s.counts[i]
+	}
+	histogram = append(histogram, []interface{}{"+Inf", s.count - counted})
+	value["histogram"] = histogram
+	return value
+}
+
+// setPath sets the value at path within m, creating intermediate maps as
+// required.
+func setPath(m map[string]interface{}, path []string, value interface{}) {
+	for _, key := range path[:len(path)-1] {
+		next, ok := m[key].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			m[key] = next
+		}
+		m = next
+	}
+	m[path[len(path)-1]] = value
+}
@@ -0,0 +1,265 @@
+// Package metrics provides simple counters, gauges and histograms, which may
+// be exported in the Prometheus text format, or as CouchDB-style statistics.
+//
+// Metric names are dot-separated paths, such as "couchdb.httpd.requests".
+// In the Prometheus format, dots are replaced by underscores, and counters
+// are given a "_total" suffix. In the statistics format, the name describes
+// the position of the metric in a nested JSON object, below which each label
+// value adds a further level of nesting.
+package metrics
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Metric types.
+const (
+	TypeCounter   = "counter"
+	TypeGauge     = "gauge"
+	TypeHistogram = "histogram"
+)
+
+// DefaultBuckets are the default histogram bucket upper bounds, in seconds.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Registry is a collection of metrics.
+type Registry struct {
+	mu       sync.RWMutex
+	families map[string]*family
+}
+
+// NewRegistry returns a new, empty registry.
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+// family is a metric and its series, one for each combination of label
+// values.
+type family struct {
+	name    string
+	typ     string
+	help    string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	value       float64
+	// Histograms only
+	counts   []uint64
+	count    uint64
+	min, max float64
+}
+
+// register returns the named family, creating it if necessary. It panics if
+// a metric of the same name, but a different type or labels, already exists.
+func (r *Registry) register(name, typ, help string, buckets []float64, labels []string) *family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if f, ok := r.families[name]; ok {
+		if f.typ != typ || strings.Join(f.labels, ",") != strings.Join(labels, ",") {
+			panic("metrics: conflicting registration for " + name)
+		}
+		return f
+	}
+	f := &family{
+		name:    name,
+		typ:     typ,
+		help:    help,
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*series),
+	}
+	r.families[name] = f
+	return f
+}
+
+// sortedFamilies returns the registered families, sorted by name.
+func (r *Registry) sortedFamilies() []*family {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	families := make([]*family, len(names))
+	for i, name := range names {
+		families[i] = r.families[name]
+	}
+	return families
+}
+
+// key returns the label values, padded or truncated to the number of
+// labels, and the corresponding series key.
+func (f *family) key(labelValues []string) ([]string, string) {
+	values := make([]string, len(f.labels))
+	copy(values, labelValues)
+	return values, strings.Join(values, "\xff")
+}
+
+// find returns the series for labelValues, or an empty series if it does not
+// exist. The caller must hold f.mu.
+func (f *family) find(labelValues []string) *series {
+	_, key := f.key(labelValues)
+	if s, ok := f.series[key]; ok {
+		return s
+	}
+	return &series{}
+}
+
+// get returns the series for labelValues, creating it if necessary. The
+// caller must hold f.mu. Missing label values are treated as empty, and
+// extras are ignored.
+func (f *family) get(labelValues []string) *series {
+	values, key := f.key(labelValues)
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labelValues: values}
+		if f.typ == TypeHistogram {
+			s.counts = make([]uint64, len(f.buckets))
+			s.min = math.Inf(1)
+			s.max = math.Inf(-1)
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+// snapshot returns copies of the family's series, sorted by label values.
+func (f *family) snapshot() []series {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := make([]series, len(keys))
+	for i, key := range keys {
+		s := *f.series[key]
+		s.counts = append([]uint64(nil), s.counts...)
+		result[i] = s
+	}
+	return result
+}
+
+// Counter is a monotonically increasing value.
+type Counter struct {
+	f *family
+}
+
+// Counter returns the named counter, registering it if necessary.
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	return &Counter{f: r.register(name, TypeCounter, help, nil, labels)}
+}
+
+// Inc increments the counter for the given label values by one.
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds delta, which must not be negative, to the counter for the given
+// label values.
+func (c *Counter) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		panic("metrics: counter cannot decrease")
+	}
+	c.f.mu.Lock()
+	c.f.get(labelValues).value += delta
+	c.f.mu.Unlock()
+}
+
+// Value returns the current value of the counter for the given label values.
+func (c *Counter) Value(labelValues ...string) float64 {
+	c.f.mu.Lock()
+	defer c.f.mu.Unlock()
+	return c.f.find(labelValues).value
+}
+
+// Gauge is a value which may go up or down.
+type Gauge struct {
+	f *family
+}
+
+// Gauge returns the named gauge, registering it if necessary.
+func (r *Registry) Gauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{f: r.register(name, TypeGauge, help, nil, labels)}
+}
+
+// Set sets the gauge for the given label values.
+func (g *Gauge) Set(value float64, labelValues ...string) {
+	g.f.mu.Lock()
+	g.f.get(labelValues).value = value
+	g.f.mu.Unlock()
+}
+
+// Add adds delta to the gauge for the given label values.
+func (g *Gauge) Add(delta float64, labelValues ...string) {
+	g.f.mu.Lock()
+	g.f.get(labelValues).value += delta
+	g.f.mu.Unlock()
+}
+
+// Inc increments the gauge by one.
+func (g *Gauge) Inc(labelValues ...string) { g.Add(1, labelValues...) }
+
+// Dec decrements the gauge by one.
+func (g *Gauge) Dec(labelValues ...string) { g.Add(-1, labelValues...) }
+
+// Value returns the current value of the gauge for the given label values.
+func (g *Gauge) Value(labelValues ...string) float64 {
+	g.f.mu.Lock()
+	defer g.f.mu.Unlock()
+	return g.f.find(labelValues).value
+}
+
+// Histogram counts observations in buckets.
+type Histogram struct {
+	f *family
+}
+
+// Histogram returns the named histogram, registering it if necessary.
+// buckets are the bucket upper bounds, in increasing order. If nil,
+// DefaultBuckets is used.
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	return &Histogram{f: r.register(name, TypeHistogram, help, buckets, labels)}
+}
+
+// Observe records a value for the given label values.
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	h.f.mu.Lock()
+	defer h.f.mu.Unlock()
+	s := h.f.get(labelValues)
+	for i, upper := range h.f.buckets {
+		if value <= upper {
+			s.counts[i]++
+			break
+		}
+	}
+	s.count++
+	s.value += value
+	if value < s.min {
+		s.min = value
+	}
+	if value > s.max {
+		s.max = value
+	}
+}
+
+// Count returns the number of observations for the given label values.
+func (h *Histogram) Count(labelValues ...string) uint64 {
+	h.f.mu.Lock()
+	defer h.f.mu.Unlock()
+	return h.f.find(labelValues).count
+}
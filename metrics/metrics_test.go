@@ -0,0 +1,103 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/flimzy/diff"
+)
+
+func testRegistry() *Registry {
+	r := NewRegistry()
+	c := r.Counter("couchdb.httpd.requests", "number of HTTP requests", "method", "status")
+	c.Inc("GET", "200")
+	c.Inc("GET", "200")
+	c.Inc("PUT", "201")
+	r.Gauge("couchdb.httpd.clients_requesting_changes", "number of clients for continuous _changes").Set(3)
+	h := r.Histogram("couchdb.request_time_seconds", "request time", []float64{0.1, 1})
+	h.Observe(0.05)
+	h.Observe(0.5)
+	h.Observe(2)
+	return r
+}
+
+func TestWritePrometheus(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := testRegistry().WritePrometheus(buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := `# HELP couchdb_httpd_clients_requesting_changes number of clients for continuous _changes
+# TYPE couchdb_httpd_clients_requesting_changes gauge
+couchdb_httpd_clients_requesting_changes 3
+# HELP couchdb_httpd_requests_total number of HTTP requests
+# TYPE couchdb_httpd_requests_total counter
+couchdb_httpd_requests_total{method="GET",status="200"} 2
+couchdb_httpd_requests_total{method="PUT",status="201"} 1
+# HELP couchdb_request_time_seconds request time
+# TYPE couchdb_request_time_seconds histogram
+couchdb_request_time_seconds_bucket{le="0.1"} 1
+couchdb_request_time_seconds_bucket{le="1"} 2
+couchdb_request_time_seconds_bucket{le="+Inf"} 3
+couchdb_request_time_seconds_sum 2.55
+couchdb_request_time_seconds_count 3
+`
+	if d := diff.Text(expected, buf.String()); d != "" {
+		t.Error(d)
+	}
+}
+
+func TestStats(t *testing.T) {
+	expected := map[string]interface{}{
+		"couchdb": map[string]interface{}{
+			"httpd": map[string]interface{}{
+				"requests": map[string]interface{}{
+					"GET": map[string]interface{}{
+						"200": map[string]interface{}{"value": 2, "type": "counter", "desc": "number of HTTP requests"},
+					},
+					"PUT": map[string]interface{}{
+						"201": map[string]interface{}{"value": 1, "type": "counter", "desc": "number of HTTP requests"},
+					},
+				},
+				"clients_requesting_changes": map[string]interface{}{"value": 3, "type": "gauge", "desc": "number of clients for continuous _changes"},
+			},
+			"request_time_seconds": map[string]interface{}{
+				"type": "histogram",
+				"desc": "request time",
+				"value": map[string]interface{}{
+					"n":               3,
+					"min":             0.05,
+					"max":             2,
+					"arithmetic_mean": 0.85,
+					"histogram":       []interface{}{[]interface{}{0.1, 1}, []interface{}{1, 1}, []interface{}{"+Inf", 1}},
+				},
+			},
+		},
+	}
+	if d := diff.AsJSON(expected, testRegistry().Stats()); d != "" {
+		t.Error(d)
+	}
+}
+
+func TestValueDoesNotCreateSeries(t *testing.T) {
+	r := NewRegistry()
+	c := r.Counter("foo", "", "label")
+	if v := c.Value("bar"); v != 0 {
+		t.Errorf("Unexpected value %v", v)
+	}
+	buf := &bytes.Buffer{}
+	_ = r.WritePrometheus(buf)
+	if buf.String() != "# TYPE foo_total counter\n" {
+		t.Errorf("Unexpected output: %s", buf.String())
+	}
+}
+
+func TestConflictingRegistration(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("foo", "")
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected panic")
+		}
+	}()
+	r.Gauge("foo", "")
+}
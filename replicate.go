@@ -0,0 +1,565 @@
+package kivik
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/flimzy/kivik/driver"
+	"github.com/flimzy/kivik/errors"
+	"github.com/flimzy/kivik/tasks"
+)
+
+// ReplicationOptions configures a replication performed by Replicate.
+type ReplicationOptions struct {
+	// ID identifies the replication, and names the checkpoint documents
+	// stored in the source and target. If empty, an ID is derived from the
+	// database names and the filter options.
+	ID string
+	// Continuous causes replication to continue until the context is
+	// cancelled, rather than ending once the target is up to date.
+	Continuous bool
+	// Filter is the name of a filter function on the source, in the form
+	// "ddoc/filter", which selects the changes to replicate. QueryParams are
+	// passed to the filter function.
+	Filter      string
+	QueryParams map[string]interface{}
+	// DocIDs, if set, restricts replication to the listed documents.
+	DocIDs []string
+	// BatchSize is the maximum number of changes processed, and checkpointed,
+	// at once. Defaults to 100.
+	BatchSize int
+	// Progress, if set, is called after each batch is checkpointed.
+	Progress func(ReplicationInfo)
+	// Tasks, if set, is used to report the replication as an active task.
+	Tasks *tasks.Registry
+}
+
+// ReplicationInfo reports the progress of a replication.
+type ReplicationInfo struct {
+	// DocsRead is the number of document revisions read from the source.
+	DocsRead int64
+	// DocsWritten is the number of document revisions written to the target.
+	DocsWritten int64
+	// DocWriteFailures is the number of document revisions rejected by the
+	// target.
+	DocWriteFailures int64
+	// MissingRevisionsFound is the number of revisions found to be missing
+	// from the target.
+	MissingRevisionsFound int64
+	// RevisionsChecked is the number of revisions compared against the target.
+	RevisionsChecked int64
+	// StartSeq is the source sequence from which replication began.
+	StartSeq string
+	// SourceSeq is the last source sequence checkpointed.
+	SourceSeq string
+	// StartTime is the time replication began.
+	StartTime time.Time
+}
+
+const (
+	defaultBatchSize = 100
+	// maxHistory is the number of sessions recorded in a checkpoint.
+	maxHistory = 50
+	// replicationIDVersion is recorded in checkpoints, as by CouchDB.
+	replicationIDVersion = 3
+)
+
+// Replicate copies changes from source to target, using the CouchDB
+// replication protocol. It may be used between databases of any drivers,
+// provided that the target supports bulk updates with the new_edits option
+// (driver.BulkDocsOptioner), and that the source provides a changes feed, and
+// supports the open_revs, revs and attachments options to GetContext. All of
+// the bundled drivers qualify, though the memory and fs drivers do not
+// support filter functions, so reject filtered replications.
+//
+// Progress is checkpointed in _local documents in both databases, so that an
+// interrupted replication resumes where it left off. Unless opts.Continuous
+// is set, Replicate returns once all changes have been replicated. opts may
+// be nil.
+func Replicate(ctx context.Context, target, source *DB, opts *ReplicationOptions) (*ReplicationInfo, error) {
+	if opts == nil {
+		opts = &ReplicationOptions{}
+	}
+	r, err := newReplicator(ctx, target.driverDB, source.driverDB, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = r.run(ctx)
+	info := r.info
+	return &info, err
+}
+
+type replicator struct {
+	target, source driver.DB
+	writer         driver.BulkDocsOptioner
+	opts           *ReplicationOptions
+	batchSize      int
+	docIDs         map[string]bool
+
+	id                     string
+	sessionID              string
+	sourceName, targetName string
+	sourceCP, targetCP     *checkpoint
+
+	info ReplicationInfo
+	task *tasks.Task
+}
+
+func newReplicator(ctx context.Context, target, source driver.DB, opts *ReplicationOptions) (*replicator, error) {
+	writer, ok := target.(driver.BulkDocsOptioner)
+	if !ok {
+		return nil, errors.Status(StatusNotImplemented, "kivik: target does not support new_edits=false")
+	}
+	sourceInfo, err := source.InfoContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	targetInfo, err := target.InfoContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sessionID, err := newSessionID()
+	if err != nil {
+		return nil, err
+	}
+	r := &replicator{
+		target:     target,
+		source:     source,
+		writer:     writer,
+		opts:       opts,
+		batchSize:  opts.BatchSize,
+		id:         opts.ID,
+		sessionID:  sessionID,
+		sourceName: sourceInfo.Name,
+		targetName: targetInfo.Name,
+	}
+	if r.batchSize <= 0 {
+		r.batchSize = defaultBatchSize
+	}
+	if len(opts.DocIDs) > 0 {
+		r.docIDs = make(map[string]bool, len(opts.DocIDs))
+		for _, id := range opts.DocIDs {
+			r.docIDs[id] = true
+		}
+	}
+	if r.id == "" {
+		r.id, err = replicationID(r.sourceName, r.targetName, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// replicationID derives a replication ID from the parameters which determine
+// which changes are replicated.
+func replicationID(source, target string, opts *ReplicationOptions) (string, error) {
+	docIDs := append([]string{}, opts.DocIDs...)
+	sort.Strings(docIDs)
+	params, err := json.Marshal([]interface{}{source, target, opts.Filter, opts.QueryParams, docIDs})
+	if err != nil {
+		return "", err
+	}
+	sum := md5.Sum(params)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (r *replicator) run(ctx context.Context) error {
+	var err error
+	if r.sourceCP, err = r.readCheckpoint(ctx, r.source); err != nil {
+		return err
+	}
+	if r.targetCP, err = r.readCheckpoint(ctx, r.target); err != nil {
+		return err
+	}
+	r.info.StartSeq = startSeq(r.sourceCP, r.targetCP)
+	r.info.SourceSeq = r.info.StartSeq
+	r.info.StartTime = time.Now()
+	if r.opts.Tasks != nil {
+		r.task = r.opts.Tasks.Start(driver.ActiveTask{
+			Type:          tasks.TypeReplication,
+			ReplicationID: r.id,
+			Source:        r.sourceName,
+			Target:        r.targetName,
+			Continuous:    r.opts.Continuous,
+		})
+		defer r.task.Done()
+	}
+
+	rows, err := r.source.ChangesContext(ctx, r.changesOptions())
+	if err != nil {
+		return err
+	}
+	if rows == nil {
+		return errors.Status(StatusNotImplemented, "kivik: source does not support the changes feed")
+	}
+	defer rows.Close()
+	done := make(chan struct{})
+	defer close(done)
+	changes := make(chan driver.Row, r.batchSize)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(changes)
+		for {
+			var row driver.Row
+			if err := rows.Next(&row); err != nil {
+				if err != io.EOF {
+					errc <- err
+				}
+				return
+			}
+			select {
+			case changes <- row:
+			case <-done:
+				return
+			}
+		}
+	}()
+	for {
+		batch, more, err := r.nextBatch(ctx, changes)
+		if err != nil {
+			return err
+		}
+		if len(batch) > 0 {
+			if err := r.processBatch(ctx, batch); err != nil {
+				return err
+			}
+		}
+		if !more {
+			break
+		}
+	}
+	select {
+	case err := <-errc:
+		return err
+	default:
+		return nil
+	}
+}
+
+func (r *replicator) changesOptions() map[string]interface{} {
+	since := r.info.StartSeq
+	if since == "" {
+		since = "0"
+	}
+	opts := map[string]interface{}{
+		"feed":  "normal",
+		"since": since,
+		"style": "all_docs",
+	}
+	if r.opts.Continuous {
+		opts["feed"] = "continuous"
+	}
+	if r.opts.Filter != "" {
+		for key, value := range r.opts.QueryParams {
+			opts[key] = value
+		}
+		opts["filter"] = r.opts.Filter
+	}
+	return opts
+}
+
+// nextBatch waits for a change, then returns it along with any further
+// changes immediately available, up to the batch size. more is false once the
+// feed has ended.
+func (r *replicator) nextBatch(ctx context.Context, changes <-chan driver.Row) (batch []driver.Row, more bool, err error) {
+	select {
+	case row, ok := <-changes:
+		if !ok {
+			return nil, false, nil
+		}
+		batch = append(batch, row)
+	case <-ctx.Done():
+		return nil, false, ctx.Err()
+	}
+	for len(batch) < r.batchSize {
+		select {
+		case row, ok := <-changes:
+			if !ok {
+				return batch, false, nil
+			}
+			batch = append(batch, row)
+		default:
+			return batch, true, nil
+		}
+	}
+	return batch, true, nil
+}
+
+// processBatch replicates the revisions in batch which are missing from the
+// target, then records a checkpoint.
+func (r *replicator) processBatch(ctx context.Context, batch []driver.Row) error {
+	revMap := make(map[string][]string)
+	var order []string
+	for _, row := range batch {
+		if row.ID == "" || (r.docIDs != nil && !r.docIDs[row.ID]) {
+			continue
+		}
+		if _, ok := revMap[row.ID]; !ok {
+			order = append(order, row.ID)
+		}
+		revMap[row.ID] = append(revMap[row.ID], row.Changes...)
+		r.info.RevisionsChecked += int64(len(row.Changes))
+	}
+	if len(revMap) > 0 {
+		diff, err := r.revsDiff(ctx, revMap)
+		if err != nil {
+			return err
+		}
+		var docs []interface{}
+		for _, docID := range order {
+			missing := diff[docID].Missing
+			if len(missing) == 0 {
+				continue
+			}
+			r.info.MissingRevisionsFound += int64(len(missing))
+			revs, err := r.fetch(ctx, docID, missing)
+			if err != nil {
+				return err
+			}
+			r.info.DocsRead += int64(len(revs))
+			docs = append(docs, revs...)
+		}
+		if err := r.write(ctx, docs); err != nil {
+			return err
+		}
+	}
+	return r.checkpoint(ctx, string(batch[len(batch)-1].Seq))
+}
+
+// revsDiff returns the revisions in revMap which are missing from the target.
+// If the target does not implement driver.RevsDiffer, each revision is
+// requested in turn.
+func (r *replicator) revsDiff(ctx context.Context, revMap map[string][]string) (map[string]driver.RevDiff, error) {
+	if differ, ok := r.target.(driver.RevsDiffer); ok {
+		return differ.RevsDiffContext(ctx, revMap)
+	}
+	diff := make(map[string]driver.RevDiff)
+	for docID, revs := range revMap {
+		var missing []string
+		for _, rev := range revs {
+			var doc json.RawMessage
+			err := r.target.GetContext(ctx, docID, &doc, map[string]interface{}{"rev": rev})
+			if errors.StatusCode(err) == StatusNotFound {
+				missing = append(missing, rev)
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		if len(missing) > 0 {
+			diff[docID] = driver.RevDiff{Missing: missing}
+		}
+	}
+	return diff, nil
+}
+
+// fetch reads the requested revisions of a document from the source, with
+// their revision histories and attachments.
+func (r *replicator) fetch(ctx context.Context, docID string, revs []string) ([]interface{}, error) {
+	openRevs, err := json.Marshal(revs)
+	if err != nil {
+		return nil, err
+	}
+	var results []struct {
+		OK      map[string]interface{} `json:"ok"`
+		Missing string                 `json:"missing"`
+	}
+	err = r.source.GetContext(ctx, docID, &results, map[string]interface{}{
+		"open_revs":   string(openRevs),
+		"revs":        true,
+		"attachments": true,
+		"latest":      true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	docs := make([]interface{}, 0, len(results))
+	for _, result := range results {
+		if result.OK != nil {
+			docs = append(docs, result.OK)
+		}
+	}
+	return docs, nil
+}
+
+// write stores docs in the target, with their existing revisions.
+func (r *replicator) write(ctx context.Context, docs []interface{}) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	results, err := r.writer.BulkDocsOptsContext(ctx, map[string]interface{}{"new_edits": false}, docs...)
+	if err != nil {
+		return err
+	}
+	var failures int64
+	var result driver.BulkResult
+	for {
+		err := results.Next(&result)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			_ = results.Close()
+			return err
+		}
+		if result.Error != nil {
+			failures++
+		}
+	}
+	if err := results.Close(); err != nil {
+		return err
+	}
+	r.info.DocWriteFailures += failures
+	r.info.DocsWritten += int64(len(docs)) - failures
+	return nil
+}
+
+// checkpoint records seq as replicated, in the source and target.
+func (r *replicator) checkpoint(ctx context.Context, seq string) error {
+	if flusher, ok := r.target.(driver.DBFlusher); ok {
+		if _, err := flusher.FlushContext(ctx); err != nil && errors.StatusCode(err) != StatusNotImplemented {
+			return err
+		}
+	}
+	history := checkpointHistory{
+		SessionID:        r.sessionID,
+		StartTime:        r.info.StartTime.UTC().Format(time.RFC1123),
+		EndTime:          time.Now().UTC().Format(time.RFC1123),
+		StartLastSeq:     driver.SequenceID(r.info.StartSeq),
+		EndLastSeq:       driver.SequenceID(seq),
+		RecordedSeq:      driver.SequenceID(seq),
+		DocsRead:         r.info.DocsRead,
+		DocsWritten:      r.info.DocsWritten,
+		DocWriteFailures: r.info.DocWriteFailures,
+		MissingChecked:   r.info.RevisionsChecked,
+		MissingFound:     r.info.MissingRevisionsFound,
+	}
+	var err error
+	if r.sourceCP, err = r.writeCheckpoint(ctx, r.source, r.sourceCP, history); err != nil {
+		return err
+	}
+	if r.targetCP, err = r.writeCheckpoint(ctx, r.target, r.targetCP, history); err != nil {
+		return err
+	}
+	r.info.SourceSeq = seq
+	if r.task != nil {
+		r.task.Update(func(task *driver.ActiveTask) {
+			task.DocsRead = r.info.DocsRead
+			task.DocsWritten = r.info.DocsWritten
+			task.DocWriteFailures = r.info.DocWriteFailures
+			task.MissingRevisionsFound = r.info.MissingRevisionsFound
+			task.RevisionsChecked = r.info.RevisionsChecked
+			task.SourceSeq = seq
+			task.CheckpointedSourceSeq = seq
+		})
+	}
+	if r.opts.Progress != nil {
+		r.opts.Progress(r.info)
+	}
+	return nil
+}
+
+// checkpoint is a replication checkpoint document, in the format used by
+// CouchDB, so that either may resume the other's replications.
+type checkpoint struct {
+	ID                   string              `json:"_id"`
+	Rev                  string              `json:"_rev,omitempty"`
+	SessionID            string              `json:"session_id"`
+	SourceLastSeq        driver.SequenceID   `json:"source_last_seq"`
+	ReplicationIDVersion int                 `json:"replication_id_version"`
+	History              []checkpointHistory `json:"history"`
+}
+
+type checkpointHistory struct {
+	SessionID        string            `json:"session_id"`
+	StartTime        string            `json:"start_time"`
+	EndTime          string            `json:"end_time"`
+	StartLastSeq     driver.SequenceID `json:"start_last_seq"`
+	EndLastSeq       driver.SequenceID `json:"end_last_seq"`
+	RecordedSeq      driver.SequenceID `json:"recorded_seq"`
+	DocsRead         int64             `json:"docs_read"`
+	DocsWritten      int64             `json:"docs_written"`
+	DocWriteFailures int64             `json:"doc_write_failures"`
+	MissingChecked   int64             `json:"missing_checked"`
+	MissingFound     int64             `json:"missing_found"`
+}
+
+func (r *replicator) checkpointID() string {
+	return "_local/" + r.id
+}
+
+// readCheckpoint returns the checkpoint stored in db, or nil if there is none.
+func (r *replicator) readCheckpoint(ctx context.Context, db driver.DB) (*checkpoint, error) {
+	cp := &checkpoint{}
+	err := db.GetContext(ctx, r.checkpointID(), cp, nil)
+	if errors.StatusCode(err) == StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// writeCheckpoint stores a checkpoint in db, replacing cp, which may be nil.
+// The history entry of the current session is replaced by history.
+func (r *replicator) writeCheckpoint(ctx context.Context, db driver.DB, cp *checkpoint, history checkpointHistory) (*checkpoint, error) {
+	if cp == nil {
+		cp = &checkpoint{ID: r.checkpointID()}
+	}
+	if len(cp.History) > 0 && cp.History[0].SessionID == r.sessionID {
+		cp.History = cp.History[1:]
+	}
+	cp.History = append([]checkpointHistory{history}, cp.History...)
+	if len(cp.History) > maxHistory {
+		cp.History = cp.History[:maxHistory]
+	}
+	cp.SessionID = r.sessionID
+	cp.SourceLastSeq = history.RecordedSeq
+	cp.ReplicationIDVersion = replicationIDVersion
+	rev, err := db.PutContext(ctx, cp.ID, cp)
+	if err != nil {
+		return nil, err
+	}
+	cp.Rev = rev
+	return cp, nil
+}
+
+// startSeq returns the sequence from which to resume replication, given the
+// source and target checkpoints. If the checkpoints disagree, the most recent
+// session recorded in both is used. If there is none, replication starts
+// from the beginning, indicated by an empty string.
+func startSeq(source, target *checkpoint) string {
+	if source == nil || target == nil {
+		return ""
+	}
+	if source.SessionID == target.SessionID {
+		return string(source.SourceLastSeq)
+	}
+	targetSessions := make(map[string]bool, len(target.History))
+	for _, h := range target.History {
+		targetSessions[h.SessionID] = true
+	}
+	for _, h := range source.History {
+		if targetSessions[h.SessionID] {
+			return string(h.RecordedSeq)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,306 @@
+package kivik
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/flimzy/kivik/driver"
+	"github.com/flimzy/kivik/errors"
+	"github.com/flimzy/kivik/tasks"
+)
+
+// replDB is a minimal in-memory database, supporting the driver methods used
+// by replication.
+type replDB struct {
+	driver.DB
+	name string
+
+	mu      sync.Mutex
+	revs    map[string]map[string]map[string]interface{}
+	changes []driver.Row
+	local   map[string]map[string]interface{}
+}
+
+var _ driver.BulkDocsOptioner = &replDB{}
+
+func newReplDB(name string) *replDB {
+	return &replDB{
+		name:  name,
+		revs:  make(map[string]map[string]map[string]interface{}),
+		local: make(map[string]map[string]interface{}),
+	}
+}
+
+// store saves a revision of a document, which must include _id, _rev and
+// _revisions.
+func (d *replDB) store(doc map[string]interface{}) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	id, rev := doc["_id"].(string), doc["_rev"].(string)
+	if d.revs[id] == nil {
+		d.revs[id] = make(map[string]map[string]interface{})
+	}
+	d.revs[id][rev] = doc
+	d.changes = append(d.changes, driver.Row{
+		ID:      id,
+		Seq:     driver.SequenceID(strconv.Itoa(len(d.changes) + 1)),
+		Changes: driver.Changes{rev},
+	})
+}
+
+func (d *replDB) put(id, rev string) {
+	d.store(map[string]interface{}{
+		"_id":  id,
+		"_rev": rev,
+		"_revisions": map[string]interface{}{
+			"start": 1,
+			"ids":   []string{strings.TrimPrefix(rev, "1-")},
+		},
+		"value": id,
+	})
+}
+
+func (d *replDB) has(id, rev string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_, ok := d.revs[id][rev]
+	return ok
+}
+
+// decode copies src into dst via JSON, as a driver would.
+func decode(src, dst interface{}) error {
+	buf, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(buf, dst)
+}
+
+func (d *replDB) InfoContext(_ context.Context) (*driver.DBInfo, error) {
+	return &driver.DBInfo{Name: d.name}, nil
+}
+
+func (d *replDB) GetContext(_ context.Context, docID string, doc interface{}, opts map[string]interface{}) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if strings.HasPrefix(docID, "_local/") {
+		local, ok := d.local[docID]
+		if !ok {
+			return errors.Status(StatusNotFound, "missing")
+		}
+		return decode(local, doc)
+	}
+	if openRevs, ok := opts["open_revs"].(string); ok {
+		var revs []string
+		if err := json.Unmarshal([]byte(openRevs), &revs); err != nil {
+			return err
+		}
+		results := make([]map[string]interface{}, len(revs))
+		for i, rev := range revs {
+			if revDoc, ok := d.revs[docID][rev]; ok {
+				results[i] = map[string]interface{}{"ok": revDoc}
+			} else {
+				results[i] = map[string]interface{}{"missing": rev}
+			}
+		}
+		return decode(results, doc)
+	}
+	revDoc, ok := d.revs[docID][opts["rev"].(string)]
+	if !ok {
+		return errors.Status(StatusNotFound, "missing")
+	}
+	return decode(revDoc, doc)
+}
+
+func (d *replDB) PutContext(_ context.Context, docID string, doc interface{}) (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	local := make(map[string]interface{})
+	if err := decode(doc, &local); err != nil {
+		return "", err
+	}
+	rev := "0-1"
+	if old, ok := d.local[docID]; ok {
+		n, _ := strconv.Atoi(strings.TrimPrefix(old["_rev"].(string), "0-"))
+		rev = "0-" + strconv.Itoa(n+1)
+	}
+	local["_rev"] = rev
+	d.local[docID] = local
+	return rev, nil
+}
+
+func (d *replDB) ChangesContext(_ context.Context, opts map[string]interface{}) (driver.Rows, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	since, err := strconv.Atoi(opts["since"].(string))
+	if err != nil {
+		return nil, err
+	}
+	return &replRows{rows: append([]driver.Row{}, d.changes[since:]...)}, nil
+}
+
+func (d *replDB) BulkDocsOptsContext(_ context.Context, opts map[string]interface{}, docs ...interface{}) (driver.BulkResults, error) {
+	if opts["new_edits"] != false {
+		return nil, errors.New("expected new_edits=false")
+	}
+	for _, doc := range docs {
+		var stored map[string]interface{}
+		if err := decode(doc, &stored); err != nil {
+			return nil, err
+		}
+		d.store(stored)
+	}
+	return &replBulkResults{}, nil
+}
+
+type replRows struct {
+	driver.Rows
+	rows []driver.Row
+}
+
+func (r *replRows) Next(row *driver.Row) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	*row, r.rows = r.rows[0], r.rows[1:]
+	return nil
+}
+
+func (r *replRows) Close() error { return nil }
+
+// replBulkResults reports no errors, as CouchDB does for new_edits=false.
+type replBulkResults struct{}
+
+func (r *replBulkResults) Next(_ *driver.BulkResult) error { return io.EOF }
+func (r *replBulkResults) Close() error                    { return nil }
+
+func TestReplicate(t *testing.T) {
+	source, target := newReplDB("source"), newReplDB("target")
+	source.put("foo", "1-a")
+	source.put("bar", "1-b")
+	source.put("baz", "1-c")
+	target.put("bar", "1-b")
+	registry := tasks.NewRegistry()
+	var progress []ReplicationInfo
+	opts := &ReplicationOptions{
+		BatchSize: 2,
+		Tasks:     registry,
+		Progress: func(info ReplicationInfo) {
+			if len(registry.Tasks()) != 1 {
+				t.Errorf("Expected replication task to be running")
+			}
+			progress = append(progress, info)
+		},
+	}
+	info, err := Replicate(context.Background(), &DB{driverDB: target}, &DB{driverDB: source}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.DocsWritten != 2 || info.RevisionsChecked != 3 || info.MissingRevisionsFound != 2 || info.SourceSeq != "3" {
+		t.Errorf("Unexpected result: %+v", info)
+	}
+	if len(progress) != 2 {
+		t.Errorf("Expected 2 progress reports, got %d", len(progress))
+	}
+	if len(registry.Tasks()) != 0 {
+		t.Errorf("Expected replication task to be done")
+	}
+	if !target.has("foo", "1-a") || !target.has("baz", "1-c") {
+		t.Error("Expected foo and baz to be replicated")
+	}
+
+	// A second replication resumes from the checkpoint
+	source.put("qux", "1-d")
+	info, err = Replicate(context.Background(), &DB{driverDB: target}, &DB{driverDB: source}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.StartSeq != "3" || info.RevisionsChecked != 1 || info.DocsWritten != 1 {
+		t.Errorf("Unexpected result: %+v", info)
+	}
+	if !target.has("qux", "1-d") {
+		t.Error("Expected qux to be replicated")
+	}
+}
+
+func TestReplicateDocIDs(t *testing.T) {
+	source, target := newReplDB("source"), newReplDB("target")
+	source.put("foo", "1-a")
+	source.put("bar", "1-b")
+	info, err := Replicate(context.Background(), &DB{driverDB: target}, &DB{driverDB: source}, &ReplicationOptions{
+		DocIDs: []string{"bar"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.DocsWritten != 1 || target.has("foo", "1-a") || !target.has("bar", "1-b") {
+		t.Errorf("Unexpected result: %+v", info)
+	}
+}
+
+func TestStartSeq(t *testing.T) {
+	type ssTest struct {
+		Name           string
+		Source, Target *checkpoint
+		Expected       string
+	}
+	tests := []ssTest{
+		{
+			Name: "NoCheckpoints",
+		},
+		{
+			Name:   "SourceOnly",
+			Source: &checkpoint{SessionID: "a", SourceLastSeq: "5"},
+		},
+		{
+			Name:     "SameSession",
+			Source:   &checkpoint{SessionID: "a", SourceLastSeq: "5"},
+			Target:   &checkpoint{SessionID: "a", SourceLastSeq: "5"},
+			Expected: "5",
+		},
+		{
+			Name: "CommonHistory",
+			Source: &checkpoint{SessionID: "c", SourceLastSeq: "9", History: []checkpointHistory{
+				{SessionID: "c", RecordedSeq: "9"},
+				{SessionID: "b", RecordedSeq: "7"},
+				{SessionID: "a", RecordedSeq: "5"},
+			}},
+			Target: &checkpoint{SessionID: "d", SourceLastSeq: "8", History: []checkpointHistory{
+				{SessionID: "d", RecordedSeq: "8"},
+				{SessionID: "b", RecordedSeq: "7"},
+			}},
+			Expected: "7",
+		},
+	}
+	for _, test := range tests {
+		func(test ssTest) {
+			t.Run(test.Name, func(t *testing.T) {
+				if seq := startSeq(test.Source, test.Target); seq != test.Expected {
+					t.Errorf("Expected %q, got %q", test.Expected, seq)
+				}
+			})
+		}(test)
+	}
+}
+
+// noChangesDB has no changes feed, as the fs driver.
+type noChangesDB struct {
+	*replDB
+}
+
+func (d noChangesDB) ChangesContext(_ context.Context, _ map[string]interface{}) (driver.Rows, error) {
+	return nil, nil
+}
+
+func TestReplicateNoChanges(t *testing.T) {
+	source, target := noChangesDB{newReplDB("source")}, newReplDB("target")
+	_, err := Replicate(context.Background(), &DB{driverDB: target}, &DB{driverDB: source}, nil)
+	if errors.StatusCode(err) != StatusNotImplemented {
+		t.Errorf("Expected 501, got %v", err)
+	}
+}
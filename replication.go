@@ -0,0 +1,224 @@
+package kivik
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/flimzy/kivik/driver"
+	"github.com/flimzy/kivik/errors"
+)
+
+// ReplicationState is the state of a server-managed replication.
+type ReplicationState string
+
+// The possible states of a replication.
+const (
+	ReplicationNotStarted ReplicationState = ""
+	ReplicationStarted    ReplicationState = "triggered"
+	ReplicationError      ReplicationState = "error"
+	ReplicationComplete   ReplicationState = "completed"
+
+	// The following states are reported by the replication scheduler of
+	// CouchDB 2.1 and later.
+	ReplicationPending  ReplicationState = "pending"
+	ReplicationRunning  ReplicationState = "running"
+	ReplicationCrashing ReplicationState = "crashing"
+	ReplicationFailed   ReplicationState = "failed"
+)
+
+// replicationPollInterval is the interval at which Wait polls for the state
+// of a replication.
+var replicationPollInterval = time.Second
+
+// Replication is a handle to a replication managed by the server. Its state
+// is as of the last call to Update.
+type Replication struct {
+	Source string
+	Target string
+
+	irep driver.Replication
+
+	mu        sync.RWMutex
+	info      driver.ReplicationInfo
+	statusErr error
+}
+
+func newReplication(rep driver.Replication) *Replication {
+	return &Replication{
+		Source: rep.Source(),
+		Target: rep.Target(),
+		irep:   rep,
+	}
+}
+
+// DocID returns the ID of the replication document in the _replicator
+// database, or an empty string for replications which have none.
+func (r *Replication) DocID() string {
+	return r.irep.DocID()
+}
+
+// ReplicationID returns the server's ID for the replication, once known.
+func (r *Replication) ReplicationID() string {
+	return r.irep.ReplicationID()
+}
+
+// StartTime returns the time the replication started, if known.
+func (r *Replication) StartTime() time.Time {
+	return r.irep.StartTime()
+}
+
+// EndTime returns the time the replication ended, if known.
+func (r *Replication) EndTime() time.Time {
+	return r.irep.EndTime()
+}
+
+// State returns the state of the replication.
+func (r *Replication) State() ReplicationState {
+	return ReplicationState(r.irep.State())
+}
+
+// Err returns the error which caused the replication to fail, or the error
+// encountered by the last call to Update.
+func (r *Replication) Err() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.statusErr != nil {
+		return r.statusErr
+	}
+	return r.irep.Err()
+}
+
+// IsActive returns true if the replication has not yet completed or failed.
+// The replication scheduler of CouchDB 2.1 and later retries replications in
+// the error state, so these are only considered finished once the server has
+// recorded an end time, as CouchDB 1.x does.
+func (r *Replication) IsActive() bool {
+	switch r.State() {
+	case ReplicationComplete, ReplicationFailed:
+		return false
+	case ReplicationError:
+		return r.EndTime().IsZero()
+	}
+	return true
+}
+
+// Info returns the progress of the replication. The sequence and start time
+// fields are not reported for server-managed replications.
+func (r *Replication) Info() ReplicationInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return ReplicationInfo{
+		DocsRead:              r.info.DocsRead,
+		DocsWritten:           r.info.DocsWritten,
+		DocWriteFailures:      r.info.DocWriteFailures,
+		MissingRevisionsFound: r.info.MissingRevisionsFound,
+		RevisionsChecked:      r.info.RevisionsChecked,
+	}
+}
+
+// Update fetches the current state and progress of the replication.
+func (r *Replication) Update(ctx context.Context) error {
+	info := driver.ReplicationInfo{}
+	err := r.irep.UpdateContext(ctx, &info)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statusErr = err
+	if err == nil {
+		r.info = info
+	}
+	return err
+}
+
+// Wait polls the replication until it completes or fails, or ctx is
+// cancelled, and returns the error which caused it to fail, if any. A
+// continuous replication only ends if it fails, or is cancelled.
+func (r *Replication) Wait(ctx context.Context) error {
+	for {
+		if err := r.Update(ctx); err != nil {
+			return err
+		}
+		if !r.IsActive() {
+			return r.Err()
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(replicationPollInterval):
+		}
+	}
+}
+
+// Cancel stops the replication.
+func (r *Replication) Cancel(ctx context.Context) error {
+	return r.irep.DeleteContext(ctx)
+}
+
+// Replicate calls ReplicateContext with a background context.
+func (c *Client) Replicate(target, source string, options Options) (*Replication, error) {
+	return c.ReplicateContext(context.Background(), target, source, options)
+}
+
+// ReplicateContext asks the server to replicate source to target, which are
+// database names or URLs, as understood by the server. options, such as
+// continuous or filter, are passed to the server. The returned handle may be
+// used to wait for, or cancel, the replication. To replicate between
+// databases without involving a server, see Replicate.
+func (c *Client) ReplicateContext(ctx context.Context, target, source string, options Options) (*Replication, error) {
+	replicator, ok := c.driverClient.(driver.Replicator)
+	if !ok {
+		return nil, ErrNotImplemented
+	}
+	rep, err := replicator.ReplicateContext(ctx, target, source, options)
+	if err != nil {
+		return nil, err
+	}
+	return newReplication(rep), nil
+}
+
+// GetReplications calls GetReplicationsContext with a background context.
+func (c *Client) GetReplications(options Options) ([]*Replication, error) {
+	return c.GetReplicationsContext(context.Background(), options)
+}
+
+// GetReplicationsContext returns the replications known to the server,
+// whether started by Replicate, by documents in the _replicator database, or
+// by other clients.
+func (c *Client) GetReplicationsContext(ctx context.Context, options Options) ([]*Replication, error) {
+	replicator, ok := c.driverClient.(driver.Replicator)
+	if !ok {
+		return nil, ErrNotImplemented
+	}
+	reps, err := replicator.GetReplicationsContext(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	replications := make([]*Replication, len(reps))
+	for i, rep := range reps {
+		replications[i] = newReplication(rep)
+	}
+	return replications, nil
+}
+
+// CancelReplication calls CancelReplicationContext with a background context.
+func (c *Client) CancelReplication(replicationID string) error {
+	return c.CancelReplicationContext(context.Background(), replicationID)
+}
+
+// CancelReplicationContext cancels the replication with the given document ID
+// or replication ID.
+func (c *Client) CancelReplicationContext(ctx context.Context, replicationID string) error {
+	if replicationID == "" {
+		return errors.Status(StatusBadRequest, "kivik: replicationID required")
+	}
+	reps, err := c.GetReplicationsContext(ctx, nil)
+	if err != nil {
+		return err
+	}
+	for _, rep := range reps {
+		if rep.DocID() == replicationID || rep.ReplicationID() == replicationID {
+			return rep.Cancel(ctx)
+		}
+	}
+	return ErrNotFound
+}
@@ -0,0 +1,89 @@
+package kivik
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/flimzy/kivik/driver"
+)
+
+// fakeReplication completes after a number of updates.
+type fakeReplication struct {
+	driver.Replication
+	updates int
+	state   string
+	endTime time.Time
+}
+
+func (r *fakeReplication) Source() string { return "a" }
+func (r *fakeReplication) Target() string { return "b" }
+func (r *fakeReplication) State() string  { return r.state }
+func (r *fakeReplication) Err() error     { return nil }
+
+func (r *fakeReplication) EndTime() time.Time { return r.endTime }
+
+func (r *fakeReplication) UpdateContext(_ context.Context, info *driver.ReplicationInfo) error {
+	r.updates--
+	r.state = string(ReplicationRunning)
+	if r.updates == 0 {
+		r.state = string(ReplicationComplete)
+	}
+	info.DocsWritten = 3
+	return nil
+}
+
+func TestReplicationWait(t *testing.T) {
+	defer func(interval time.Duration) { replicationPollInterval = interval }(replicationPollInterval)
+	replicationPollInterval = time.Millisecond
+	rep := newReplication(&fakeReplication{updates: 3})
+	if !rep.IsActive() {
+		t.Error("Expected new replication to be active")
+	}
+	if err := rep.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if rep.IsActive() || rep.State() != ReplicationComplete {
+		t.Errorf("Unexpected state %q", rep.State())
+	}
+	if info := rep.Info(); info.DocsWritten != 3 {
+		t.Errorf("Unexpected info: %+v", info)
+	}
+}
+
+func TestReplicationWaitCancelled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	rep := newReplication(&fakeReplication{updates: -1})
+	if err := rep.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+type isActiveTest struct {
+	Name     string
+	State    ReplicationState
+	EndTime  time.Time
+	Expected bool
+}
+
+func TestReplicationIsActive(t *testing.T) {
+	tests := []isActiveTest{
+		{Name: "Running", State: ReplicationRunning, Expected: true},
+		{Name: "Crashing", State: ReplicationCrashing, Expected: true},
+		{Name: "Completed", State: ReplicationComplete, EndTime: time.Unix(1, 0), Expected: false},
+		{Name: "Failed", State: ReplicationFailed, Expected: false},
+		{Name: "SchedulerError", State: ReplicationError, Expected: true},
+		{Name: "FinalError", State: ReplicationError, EndTime: time.Unix(1, 0), Expected: false},
+	}
+	for _, test := range tests {
+		func(test isActiveTest) {
+			t.Run(test.Name, func(t *testing.T) {
+				rep := newReplication(&fakeReplication{state: string(test.State), endTime: test.EndTime})
+				if active := rep.IsActive(); active != test.Expected {
+					t.Errorf("Expected IsActive() = %t, got %t", test.Expected, active)
+				}
+			})
+		}(test)
+	}
+}
@@ -184,6 +184,15 @@ func (r *Rows) Changes() []string {
 	return nil
 }
 
+// Seq returns the update sequence of the last-read result. Only valid for the
+// changes feed.
+func (r *Rows) Seq() string {
+	if r.curRow != nil {
+		return string(r.curRow.Seq)
+	}
+	return ""
+}
+
 // Deleted returns true for the changes feed if the change relates to a deleted
 // document.
 func (r *Rows) Deleted() bool {
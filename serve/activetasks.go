@@ -0,0 +1,36 @@
+package serve
+
+import (
+	"net/http"
+
+	"github.com/flimzy/kivik"
+	"github.com/flimzy/kivik/errors"
+	"github.com/flimzy/kivik/tasks"
+)
+
+// Tasks returns the service's task registry. Long-running operations started
+// by the server, rather than by the backend driver, should be reported here,
+// to be listed by /_active_tasks alongside those of the driver.
+func (s *Service) Tasks() *tasks.Registry {
+	s.tasksOnce.Do(func() {
+		s.tasks = tasks.NewRegistry()
+	})
+	return s.tasks
+}
+
+// activeTasks serves the tasks of the backend driver, if it reports them,
+// followed by those of the service.
+func activeTasks(w http.ResponseWriter, r *http.Request) error {
+	s := GetService(r)
+	result, err := getClient(r).ActiveTasksContext(r.Context())
+	if err != nil && errors.StatusCode(err) != kivik.StatusNotImplemented {
+		return err
+	}
+	if result == nil {
+		result = []kivik.ActiveTask{}
+	}
+	for _, task := range s.Tasks().Tasks() {
+		result = append(result, kivik.ActiveTask(task))
+	}
+	return serveJSON(w, result)
+}
@@ -0,0 +1,52 @@
+package serve
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/flimzy/kivik"
+	"github.com/flimzy/kivik/driver"
+	_ "github.com/flimzy/kivik/driver/memory"
+	"github.com/flimzy/kivik/tasks"
+)
+
+func TestActiveTasks(t *testing.T) {
+	client, err := kivik.New("memory", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Service{Client: client, LogWriter: &initCounter{}}
+	handler, err := s.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	task := s.Tasks().Start(driver.ActiveTask{Type: tasks.TypeReplication, Source: "foo", Target: "bar"})
+	defer task.Done()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/_active_tasks", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Unexpected status %d: %s", w.Code, w.Body.String())
+	}
+	var result []kivik.ActiveTask
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 1 {
+		t.Fatalf("Expected 1 task, got %d", len(result))
+	}
+	if result[0].Type != tasks.TypeReplication || result[0].Source != "foo" || result[0].Target != "bar" {
+		t.Errorf("Unexpected task: %+v", result[0])
+	}
+	var raw []map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &raw); err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{"progress", "changes_done", "total_changes"} {
+		if _, ok := raw[0][field]; !ok {
+			t.Errorf("Expected %s to be reported before any progress is made", field)
+		}
+	}
+}
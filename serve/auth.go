@@ -2,22 +2,32 @@ package serve
 
 import (
 	"net/http"
+	"regexp"
+	"strings"
 
 	"github.com/flimzy/kivik/auth"
 	"github.com/flimzy/kivik/authdb"
+	"github.com/flimzy/kivik/errors"
 )
 
 type doneWriter struct {
 	http.ResponseWriter
-	done bool
+	done   bool
+	status int
 }
 
 func (w *doneWriter) WriteHeader(status int) {
+	if !w.done {
+		w.status = status
+	}
 	w.done = true
 	w.ResponseWriter.WriteHeader(status)
 }
 
 func (w *doneWriter) Write(b []byte) (int, error) {
+	if !w.done {
+		w.status = http.StatusOK
+	}
 	w.done = true
 	return w.ResponseWriter.Write(b)
 }
@@ -28,7 +38,7 @@ func authHandler(next http.Handler) http.Handler {
 		s := GetService(r)
 		session, err := s.validate(dw, r)
 		if err != nil {
-			reportError(w, err)
+			reportError(w, r, err)
 			return
 		}
 		sessionPtr := mustGetSessionPtr(r.Context())
@@ -37,6 +47,10 @@ func authHandler(next http.Handler) http.Handler {
 			// The auth handler already responded to the request
 			return
 		}
+		if session.User == nil && s.requireValidUser() {
+			reportError(w, r, errors.Status(http.StatusUnauthorized, "Authentication required."))
+			return
+		}
 		next.ServeHTTP(w, r)
 	})
 }
@@ -44,29 +58,109 @@ func authHandler(next http.Handler) http.Handler {
 // validate must return a 401 error if there is an authentication failure.
 // No error means the user is permitted.
 func (s *Service) validate(w http.ResponseWriter, r *http.Request) (*auth.Session, error) {
-	if s.authHandlers == nil {
+	if len(s.authHandlerNames) == 0 {
 		// Perpetual admin party
 		return s.createSession("", &authdb.UserContext{Roles: []string{"_admin"}}), nil
 	}
-	for methodName, handler := range s.authHandlers {
-		uCtx, err := handler.Authenticate(w, r)
+	m := s.serviceMetrics()
+	for _, methodName := range s.activeAuthHandlers() {
+		uCtx, err := s.authHandlers[methodName].Authenticate(w, r)
 		if err != nil {
+			if errors.StatusCode(err) == http.StatusUnauthorized {
+				m.authFailures.Inc(methodName)
+			}
 			return nil, err
 		}
 		if uCtx != nil {
+			m.authSuccesses.Inc(methodName)
 			return s.createSession(methodName, uCtx), nil
 		}
+		if dw, ok := w.(*doneWriter); ok && dw.done {
+			// The handler responded to the request itself, as the cookie
+			// handler does for POST /_session logins.
+			switch {
+			case dw.status == http.StatusUnauthorized:
+				m.authFailures.Inc(methodName)
+			case dw.status < http.StatusBadRequest:
+				m.authSuccesses.Inc(methodName)
+			}
+			return s.createSession("", nil), nil
+		}
 	}
 	// None of the auth methods succeeded, so return unauthorized
 	return s.createSession("", nil), nil
 }
 
+// activeAuthHandlers returns the names of the auth handlers to try, in order.
+// This is the order given by httpd.authentication_handlers, if set, or else
+// the order of the AuthHandlers slice. Handlers not named in
+// httpd.authentication_handlers are disabled.
+func (s *Service) activeAuthHandlers() []string {
+	value := s.Config().GetString("httpd", "authentication_handlers")
+	if value == "" {
+		return s.authHandlerNames
+	}
+	var names []string
+	for _, name := range parseAuthHandlers(value) {
+		if _, ok := s.authHandlers[name]; ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// authHandlerTuple matches an Erlang {module, function} tuple.
+var authHandlerTuple = regexp.MustCompile(`\{\s*[\w.]+\s*,\s*([\w.]+)\s*\}`)
+
+// parseAuthHandlers parses the httpd.authentication_handlers configuration
+// value. It may be given in CouchDB's format, i.e.
+//
+//	{chttpd_auth, cookie_authentication_handler}, {chttpd_auth, default_authentication_handler}
+//
+// in which case each handler's function name is converted to the equivalent
+// Kivik method name ("cookie", "default", etc), or as a simple comma-separated
+// list of method names.
+func parseAuthHandlers(value string) []string {
+	var names []string
+	if matches := authHandlerTuple.FindAllStringSubmatch(value, -1); len(matches) > 0 {
+		for _, match := range matches {
+			names = append(names, strings.TrimSuffix(match[1], "_authentication_handler"))
+		}
+		return names
+	}
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// requireValidUser returns true if anonymous requests are to be rejected.
+func (s *Service) requireValidUser() bool {
+	return s.Config().GetBool("couch_httpd_auth", "require_valid_user") ||
+		s.Config().GetBool("chttpd", "require_valid_user")
+}
+
+// wwwAuthenticate returns the WWW-Authenticate challenge to send with 401
+// responses, if any. This is the value of httpd.WWW-Authenticate, or a Basic
+// auth challenge if require_valid_user is set.
+func (s *Service) wwwAuthenticate() string {
+	if challenge := s.Config().GetString("httpd", "WWW-Authenticate"); challenge != "" {
+		return challenge
+	}
+	if s.requireValidUser() {
+		return `Basic realm="server"`
+	}
+	return ""
+}
+
 func (s *Service) createSession(method string, user *authdb.UserContext) *auth.Session {
-	userDB := s.Config().GetString("couch_httpd_auth", "authentication_db")
+	userDB := s.usersDBName()
 	return &auth.Session{
 		AuthMethod: method,
 		AuthDB:     userDB,
-		Handlers:   s.authHandlerNames,
+		Handlers:   s.activeAuthHandlers(),
 		User:       user,
 	}
 }
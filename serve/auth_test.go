@@ -0,0 +1,91 @@
+package serve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/kivik/authdb"
+)
+
+// orderHandler records the order in which handlers are called, and
+// authenticates the user if a header matching its name is set.
+type orderHandler struct {
+	name  string
+	calls *[]string
+}
+
+func (h *orderHandler) MethodName() string { return h.name }
+
+func (h *orderHandler) Authenticate(_ http.ResponseWriter, r *http.Request) (*authdb.UserContext, error) {
+	*h.calls = append(*h.calls, h.name)
+	if r.Header.Get("X-"+h.name) != "" {
+		return &authdb.UserContext{Name: h.name}, nil
+	}
+	return nil, nil
+}
+
+type authOrderTest struct {
+	Name      string
+	Conf      map[string]string
+	Header    string
+	Calls     []string
+	Status    int
+	Challenge string
+}
+
+func TestAuthHandlerOrder(t *testing.T) {
+	tests := []authOrderTest{
+		{Name: "SliceOrder", Calls: []string{"c", "a", "b"}, Status: http.StatusOK},
+		{Name: "StopOnSuccess", Header: "X-a", Calls: []string{"c", "a"}, Status: http.StatusOK},
+		{Name: "ConfiguredOrder", Conf: map[string]string{"authentication_handlers": "b, a"},
+			Calls: []string{"b", "a"}, Status: http.StatusOK},
+		{Name: "CouchDBFormat",
+			Conf:  map[string]string{"authentication_handlers": "{chttpd_auth, a_authentication_handler}, {chttpd_auth, c_authentication_handler}"},
+			Calls: []string{"a", "c"}, Status: http.StatusOK},
+		{Name: "RequireValidUser", Conf: map[string]string{"require_valid_user": "true"},
+			Calls: []string{"c", "a", "b"}, Status: http.StatusUnauthorized, Challenge: `Basic realm="server"`},
+		{Name: "RequireValidUserAuthenticated", Conf: map[string]string{"require_valid_user": "true"},
+			Header: "X-b", Calls: []string{"c", "a", "b"}, Status: http.StatusOK},
+		{Name: "CustomChallenge", Conf: map[string]string{"require_valid_user": "true", "WWW-Authenticate": `Basic realm="kivik"`},
+			Calls: []string{"c", "a", "b"}, Status: http.StatusUnauthorized, Challenge: `Basic realm="kivik"`},
+	}
+	for _, test := range tests {
+		func(test authOrderTest) {
+			t.Run(test.Name, func(t *testing.T) {
+				var calls []string
+				s := &Service{LogWriter: &initCounter{}}
+				for _, name := range []string{"c", "a", "b"} {
+					s.AuthHandlers = append(s.AuthHandlers, &orderHandler{name: name, calls: &calls})
+				}
+				for key, value := range test.Conf {
+					sec := "httpd"
+					if key == "require_valid_user" {
+						sec = "couch_httpd_auth"
+					}
+					_ = s.Config().Set(sec, key, value)
+				}
+				handler, err := s.Init()
+				if err != nil {
+					t.Fatal(err)
+				}
+				req := httptest.NewRequest("GET", "/", nil)
+				if test.Header != "" {
+					req.Header.Set(test.Header, "yes")
+				}
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, req)
+				if d := diff.TextSlices(test.Calls, calls); d != "" {
+					t.Errorf("Unexpected handler order:\n%s\n", d)
+				}
+				if w.Code != test.Status {
+					t.Errorf("Unexpected status. Expected %d, Actual %d", test.Status, w.Code)
+				}
+				if challenge := w.Header().Get("WWW-Authenticate"); challenge != test.Challenge {
+					t.Errorf("Unexpected challenge. Expected '%s', Actual '%s'", test.Challenge, challenge)
+				}
+			})
+		}(test)
+	}
+}
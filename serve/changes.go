@@ -0,0 +1,125 @@
+package serve
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/flimzy/kivik"
+	"github.com/flimzy/kivik/errors"
+)
+
+type changeRev struct {
+	Rev string `json:"rev"`
+}
+
+type change struct {
+	Seq     string      `json:"seq"`
+	ID      string      `json:"id"`
+	Changes []changeRev `json:"changes"`
+	Deleted bool        `json:"deleted,omitempty"`
+}
+
+type changesResponse struct {
+	Results []change `json:"results"`
+	LastSeq string   `json:"last_seq"`
+}
+
+// changes serves the changes feed. The normal (default) and longpoll feeds are
+// served as a single JSON object once the driver's feed ends. The continuous
+// feed is served as one change per line, and is terminated when the client
+// disconnects, or when the service is shut down.
+func changes(w http.ResponseWriter, r *http.Request) error {
+	s := GetService(r)
+	opts := queryOptions(r)
+	feed, _ := opts["feed"].(string)
+	switch feed {
+	case "":
+		feed = "normal"
+		opts["feed"] = feed
+	case "normal", "longpoll", "continuous":
+	default:
+		return errors.Status(kivik.StatusBadRequest, "supported feeds are normal, longpoll and continuous")
+	}
+	if _, ok := opts["since"]; !ok {
+		opts["since"] = "0"
+	}
+	db, err := getClient(r).DBContext(r.Context(), getParams(r)["db"])
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go func() {
+		select {
+		case <-s.shuttingDown():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	rows, err := db.ChangesContext(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if feed != "continuous" {
+		return serveChanges(w, rows, opts["since"].(string))
+	}
+	defer s.trackChangesClient()()
+	w.Header().Set("Content-Type", typeJSON)
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+	enc := json.NewEncoder(w)
+	for rows.Next() {
+		if err := enc.Encode(rowChange(rows)); err != nil {
+			// The client has gone away
+			return nil
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	if err := rows.Err(); err != nil && ctx.Err() == nil {
+		// The response has already begun, so the best we can do is log it.
+		GetLogger(r).Error("changes feed for %s failed: %s", getParams(r)["db"], err)
+	}
+	return nil
+}
+
+// serveChanges serves a normal or longpoll changes feed. since is reported as
+// the last sequence if the feed is empty, and the driver reports none.
+func serveChanges(w http.ResponseWriter, rows *kivik.Rows, since string) error {
+	resp := changesResponse{Results: []change{}}
+	for rows.Next() {
+		c := rowChange(rows)
+		resp.Results = append(resp.Results, c)
+		resp.LastSeq = c.Seq
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if seq := rows.UpdateSeq(); seq != "" {
+		resp.LastSeq = seq
+	}
+	if resp.LastSeq == "" {
+		resp.LastSeq = since
+	}
+	return serveJSON(w, resp)
+}
+
+// rowChange converts the current row of a changes feed.
+func rowChange(rows *kivik.Rows) change {
+	revs := make([]changeRev, len(rows.Changes()))
+	for i, rev := range rows.Changes() {
+		revs[i] = changeRev{Rev: rev}
+	}
+	return change{
+		Seq:     rows.Seq(),
+		ID:      rows.ID(),
+		Changes: revs,
+		Deleted: rows.Deleted(),
+	}
+}
@@ -0,0 +1,113 @@
+package serve
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/flimzy/kivik"
+	"github.com/flimzy/kivik/auth"
+)
+
+func TestChangesNormalFeed(t *testing.T) {
+	handler, db := newDocService(t)
+	if _, err := db.Put("foo", map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Put("bar", map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	type changesTest struct {
+		Name    string
+		Path    string
+		Status  int
+		Results int
+		LastSeq string
+	}
+	tests := []changesTest{
+		{Name: "Default", Path: "/db/_changes", Status: http.StatusOK, Results: 2, LastSeq: "2"},
+		{Name: "Normal", Path: "/db/_changes?feed=normal", Status: http.StatusOK, Results: 2, LastSeq: "2"},
+		{Name: "Longpoll", Path: "/db/_changes?feed=longpoll&since=1", Status: http.StatusOK, Results: 1, LastSeq: "2"},
+		{Name: "Empty", Path: "/db/_changes?since=2", Status: http.StatusOK, Results: 0, LastSeq: "2"},
+		{Name: "InvalidFeed", Path: "/db/_changes?feed=bogus", Status: http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		func(test changesTest) {
+			t.Run(test.Name, func(t *testing.T) {
+				req := httptest.NewRequest("GET", test.Path, nil)
+				req.Header.Set("X-User", "alice")
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, req)
+				if w.Code != test.Status {
+					t.Fatalf("Unexpected status. Expected %d, Actual %d: %s", test.Status, w.Code, w.Body.String())
+				}
+				if test.Status != http.StatusOK {
+					return
+				}
+				var result struct {
+					Results []change `json:"results"`
+					LastSeq *string  `json:"last_seq"`
+				}
+				if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+					t.Fatalf("Failed to decode response: %s\n%s", err, w.Body.String())
+				}
+				if len(result.Results) != test.Results {
+					t.Errorf("Expected %d results, got %d", test.Results, len(result.Results))
+				}
+				if result.LastSeq == nil || *result.LastSeq != test.LastSeq {
+					t.Errorf("Expected last_seq %s, got %v", test.LastSeq, result.LastSeq)
+				}
+			})
+		}(test)
+	}
+}
+
+func TestChangesClientsMetric(t *testing.T) {
+	client, err := kivik.New("memory", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = client.CreateDB("db"); err != nil {
+		t.Fatal(err)
+	}
+	s := &Service{
+		Client:       client,
+		LogWriter:    &initCounter{},
+		AuthHandlers: []auth.Handler{headerAuth{}},
+	}
+	handler, err := s.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	clients := s.serviceMetrics().changesClients
+	req := httptest.NewRequest("GET", "/db/_changes?feed=normal", nil)
+	req.Header.Set("X-User", "alice")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if v := clients.Value(); v != 0 {
+		t.Errorf("Expected no changes clients after a normal feed, got %v", v)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req = httptest.NewRequest("GET", "/db/_changes?feed=continuous", nil).WithContext(ctx)
+	req.Header.Set("X-User", "alice")
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		close(done)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for clients.Value() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the continuous feed to be counted")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+	if v := clients.Value(); v != 0 {
+		t.Errorf("Expected no changes clients once the feed ended, got %v", v)
+	}
+}
@@ -2,6 +2,7 @@ package serve
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 
 	"github.com/flimzy/kivik/config"
@@ -28,6 +29,16 @@ func getConfig(w http.ResponseWriter, r *http.Request) error {
 	return serveJSON(w, conf)
 }
 
+// getConfigOrigins reports the source of each configuration value, for
+// backends which support it.
+func getConfigOrigins(w http.ResponseWriter, r *http.Request) error {
+	origins, err := GetService(r).Config().OriginsContext(r.Context())
+	if err != nil {
+		return err
+	}
+	return serveJSON(w, origins)
+}
+
 func getConfigSection(w http.ResponseWriter, r *http.Request) error {
 	sec, ok := stringParam(r, "section")
 	if !ok {
@@ -55,3 +66,47 @@ func getConfigItem(w http.ResponseWriter, r *http.Request) error {
 	}
 	return serveJSON(w, conf)
 }
+
+func putConfigItem(w http.ResponseWriter, r *http.Request) error {
+	sec, ok := stringParam(r, "section")
+	if !ok {
+		return errors.Status(http.StatusBadRequest, "section required")
+	}
+	key, ok := stringParam(r, "key")
+	if !ok {
+		return errors.Status(http.StatusBadRequest, "key required")
+	}
+	var value string
+	if err := json.NewDecoder(r.Body).Decode(&value); err != nil {
+		return errors.Status(http.StatusBadRequest, "Request body must be a JSON string")
+	}
+	conf := GetService(r).Config()
+	old, err := conf.GetContext(r.Context(), sec, key)
+	if err != nil && errors.StatusCode(err) != http.StatusNotFound {
+		return err
+	}
+	if err := conf.SetContext(r.Context(), sec, key, value); err != nil {
+		return err
+	}
+	return serveJSON(w, old)
+}
+
+func deleteConfigItem(w http.ResponseWriter, r *http.Request) error {
+	sec, ok := stringParam(r, "section")
+	if !ok {
+		return errors.Status(http.StatusBadRequest, "section required")
+	}
+	key, ok := stringParam(r, "key")
+	if !ok {
+		return errors.Status(http.StatusBadRequest, "key required")
+	}
+	conf := GetService(r).Config()
+	old, err := conf.GetContext(r.Context(), sec, key)
+	if err != nil {
+		return err
+	}
+	if err := conf.DeleteContext(r.Context(), sec, key); err != nil {
+		return err
+	}
+	return serveJSON(w, old)
+}
@@ -0,0 +1,272 @@
+// Package fileconf provides a configuration backend which persists changes to
+// an INI file, in the same format as CouchDB's local.ini. Comments, blank lines
+// and the order of existing entries are preserved when the file is rewritten.
+package fileconf
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/flimzy/kivik/driver"
+	"github.com/flimzy/kivik/errors"
+)
+
+// line is a single line of the INI file. Lines which are neither section
+// headers nor key/value pairs (comments, blank lines) have an empty key, and
+// are preserved verbatim.
+type line struct {
+	raw     string
+	section string
+	key     string
+	value   string
+	header  bool
+}
+
+// Config is a file-backed configuration instance.
+type Config struct {
+	mu       sync.RWMutex
+	filename string
+	lines    []*line
+}
+
+var _ driver.Config = &Config{}
+var _ driver.ConfigSection = &Config{}
+var _ driver.ConfigItem = &Config{}
+
+// New reads the configuration from filename. If the file does not exist, an
+// empty configuration is returned, and the file will be created on the first
+// change.
+func New(filename string) (*Config, error) {
+	c := &Config{filename: filename}
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	lines, err := parse(f)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", filename)
+	}
+	c.lines = lines
+	return c, nil
+}
+
+// Filename returns the name of the backing file.
+func (c *Config) Filename() string {
+	return c.filename
+}
+
+func parse(r io.Reader) ([]*line, error) {
+	var lines []*line
+	var section string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		raw := scanner.Text()
+		l := &line{raw: raw, section: section}
+		trimmed := strings.TrimSpace(raw)
+		switch {
+		case trimmed == "", trimmed[0] == ';', trimmed[0] == '#':
+		case trimmed[0] == '[' && trimmed[len(trimmed)-1] == ']':
+			section = strings.TrimSpace(trimmed[1 : len(trimmed)-1])
+			l.section = section
+			l.header = true
+		default:
+			parts := strings.SplitN(trimmed, "=", 2)
+			if len(parts) == 2 && section != "" {
+				l.key = strings.TrimSpace(parts[0])
+				l.value = strings.TrimSpace(parts[1])
+			}
+		}
+		lines = append(lines, l)
+	}
+	return lines, scanner.Err()
+}
+
+// GetAllContext returns the full configuration tree.
+func (c *Config) GetAllContext(_ context.Context) (map[string]map[string]string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	conf := make(map[string]map[string]string)
+	for _, l := range c.lines {
+		if l.key == "" {
+			continue
+		}
+		if _, ok := conf[l.section]; !ok {
+			conf[l.section] = make(map[string]string)
+		}
+		conf[l.section][l.key] = l.value
+	}
+	return conf, nil
+}
+
+// GetSectionContext returns a single configuration section.
+func (c *Config) GetSectionContext(_ context.Context, secName string) (map[string]string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var sec map[string]string
+	for _, l := range c.lines {
+		if l.section != secName {
+			continue
+		}
+		if sec == nil {
+			sec = make(map[string]string)
+		}
+		if l.key != "" {
+			sec[l.key] = l.value
+		}
+	}
+	if sec == nil {
+		return nil, errors.Status(http.StatusNotFound, "configuration section not found")
+	}
+	return sec, nil
+}
+
+// GetContext returns a single configuration value.
+func (c *Config) GetContext(_ context.Context, secName, key string) (string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if i := c.find(secName, key); i >= 0 {
+		return c.lines[i].value, nil
+	}
+	return "", errors.Status(http.StatusNotFound, "configuration key not found")
+}
+
+// find returns the index of the last line defining key in secName, or -1.
+// As in CouchDB, later definitions override earlier ones.
+func (c *Config) find(secName, key string) int {
+	for i := len(c.lines) - 1; i >= 0; i-- {
+		if l := c.lines[i]; l.section == secName && l.key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// validate rejects section names, keys and values which can't be stored in
+// an INI file without changing its structure, such as a value containing a
+// line break followed by a section header.
+func validate(secName, key, value string) error {
+	if strings.ContainsAny(secName+key+value, "\r\n") {
+		return errors.Status(http.StatusBadRequest, "configuration may not contain line breaks")
+	}
+	if secName == "" || strings.ContainsAny(secName, "[]") {
+		return errors.Status(http.StatusBadRequest, "invalid configuration section name")
+	}
+	if trimmed := strings.TrimSpace(key); trimmed == "" || strings.ContainsRune(key, '=') || strings.ContainsAny(trimmed[:1], ";#[") {
+		return errors.Status(http.StatusBadRequest, "invalid configuration key")
+	}
+	return nil
+}
+
+// SetContext sets a configuration value, and writes the change to disk. The
+// configuration is unchanged if the write fails.
+func (c *Config) SetContext(_ context.Context, secName, key, value string) error {
+	if err := validate(secName, key, value); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	newLine := &line{
+		raw:     key + " = " + value,
+		section: secName,
+		key:     key,
+		value:   value,
+	}
+	lines := make([]*line, 0, len(c.lines)+3)
+	if i := c.find(secName, key); i >= 0 {
+		lines = append(lines, c.lines...)
+		lines[i] = newLine
+		return c.replace(lines)
+	}
+	// Insert after the last key of the section, to keep any trailing comments
+	// or blank lines where they were.
+	last := -1
+	for i, l := range c.lines {
+		if l.section == secName && (l.header || l.key != "") {
+			last = i
+		}
+	}
+	if last < 0 {
+		lines = append(lines, c.lines...)
+		if len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1].raw) != "" {
+			lines = append(lines, &line{section: lines[len(lines)-1].section})
+		}
+		lines = append(lines, &line{raw: "[" + secName + "]", section: secName, header: true}, newLine)
+		return c.replace(lines)
+	}
+	lines = append(lines, c.lines[:last+1]...)
+	lines = append(lines, newLine)
+	lines = append(lines, c.lines[last+1:]...)
+	return c.replace(lines)
+}
+
+// DeleteContext removes a configuration key, and writes the change to disk.
+// The configuration is unchanged if the write fails.
+func (c *Config) DeleteContext(_ context.Context, secName, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i := c.find(secName, key)
+	if i < 0 {
+		return errors.Status(http.StatusNotFound, "configuration key not found")
+	}
+	// Remove every definition, so that an earlier one isn't revealed.
+	lines := make([]*line, 0, len(c.lines))
+	for _, l := range c.lines {
+		if l.section == secName && l.key == key {
+			continue
+		}
+		lines = append(lines, l)
+	}
+	return c.replace(lines)
+}
+
+// replace writes lines to disk, and then makes them the current
+// configuration.
+func (c *Config) replace(lines []*line) error {
+	if err := write(c.filename, lines); err != nil {
+		return err
+	}
+	c.lines = lines
+	return nil
+}
+
+// write atomically replaces filename with lines.
+func write(filename string, lines []*line) error {
+	buf := &bytes.Buffer{}
+	for _, l := range lines {
+		buf.WriteString(l.raw)
+		buf.WriteByte('\n')
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), "."+filepath.Base(filename))
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if fi, err := os.Stat(filename); err == nil {
+		_ = tmp.Chmod(fi.Mode())
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		os.Remove(tmp.Name())
+		return errors.Wrapf(err, "failed to write %s", filename)
+	}
+	return nil
+}
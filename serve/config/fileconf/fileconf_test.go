@@ -0,0 +1,174 @@
+package fileconf
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/kivik/errors"
+)
+
+var CTX = context.Background()
+
+const localINI = `; CouchDB Configuration Settings
+
+[httpd]
+port = 5984
+;bind_address = 127.0.0.1
+
+[log]
+level = info
+`
+
+type setTest struct {
+	Name     string
+	Section  string
+	Key      string
+	Value    string
+	Delete   bool
+	Expected string
+	Status   int
+}
+
+func TestSetDelete(t *testing.T) {
+	tests := []setTest{
+		{Name: "Replace", Section: "httpd", Key: "port", Value: "6000",
+			Expected: "; CouchDB Configuration Settings\n\n[httpd]\nport = 6000\n;bind_address = 127.0.0.1\n\n[log]\nlevel = info\n"},
+		{Name: "NewKey", Section: "httpd", Key: "bind_address", Value: "0.0.0.0",
+			Expected: "; CouchDB Configuration Settings\n\n[httpd]\nport = 5984\nbind_address = 0.0.0.0\n;bind_address = 127.0.0.1\n\n[log]\nlevel = info\n"},
+		{Name: "NewSection", Section: "admins", Key: "bob", Value: "abc123",
+			Expected: localINI + "\n[admins]\nbob = abc123\n"},
+		{Name: "Delete", Section: "log", Key: "level", Delete: true,
+			Expected: "; CouchDB Configuration Settings\n\n[httpd]\nport = 5984\n;bind_address = 127.0.0.1\n\n[log]\n"},
+		{Name: "DeleteMissing", Section: "log", Key: "file", Delete: true,
+			Status: 404, Expected: localINI},
+		{Name: "ValueNewline", Section: "log", Key: "level", Value: "info\n[admins]\nevil = x",
+			Status: 400, Expected: localINI},
+		{Name: "ValueCarriageReturn", Section: "log", Key: "level", Value: "info\r",
+			Status: 400, Expected: localINI},
+		{Name: "KeyNewline", Section: "log", Key: "x\n[admins]\nevil", Value: "x",
+			Status: 400, Expected: localINI},
+		{Name: "KeyEquals", Section: "log", Key: "a=b", Value: "x",
+			Status: 400, Expected: localINI},
+		{Name: "SectionBracket", Section: "log]\n[admins", Key: "evil", Value: "x",
+			Status: 400, Expected: localINI},
+	}
+	for _, test := range tests {
+		func(test setTest) {
+			t.Run(test.Name, func(t *testing.T) {
+				dir, err := ioutil.TempDir("", "kivik-fileconf-")
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer os.RemoveAll(dir)
+				filename := filepath.Join(dir, "local.ini")
+				if err = ioutil.WriteFile(filename, []byte(localINI), 0644); err != nil {
+					t.Fatal(err)
+				}
+				c, err := New(filename)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if test.Delete {
+					err = c.DeleteContext(CTX, test.Section, test.Key)
+				} else {
+					err = c.SetContext(CTX, test.Section, test.Key, test.Value)
+				}
+				if status := errors.StatusCode(err); status != test.Status {
+					t.Errorf("Unexpected status. Expected %d, Actual %d (%s)", test.Status, status, err)
+				}
+				result, err := ioutil.ReadFile(filename)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(result) != test.Expected {
+					t.Errorf("Unexpected file content.\nExpected:\n%s\nActual:\n%s\n", test.Expected, string(result))
+				}
+				if test.Status != 0 && !test.Delete {
+					return
+				}
+				// Ensure the change survives a reload
+				reloaded, err := New(filename)
+				if err != nil {
+					t.Fatal(err)
+				}
+				value, err := reloaded.GetContext(CTX, test.Section, test.Key)
+				if test.Delete {
+					if errors.StatusCode(err) != 404 {
+						t.Errorf("Expected deleted key to be missing, got '%s'", value)
+					}
+				} else if value != test.Value {
+					t.Errorf("Unexpected value after reload. Expected '%s', Actual '%s'", test.Value, value)
+				}
+			})
+		}(test)
+	}
+}
+
+func TestGetSection(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kivik-fileconf-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	c, err := New(filepath.Join(dir, "missing.ini"))
+	if err != nil {
+		t.Fatalf("Missing file should not be an error: %s", err)
+	}
+	if _, err = c.GetSectionContext(CTX, "httpd"); errors.StatusCode(err) != 404 {
+		t.Errorf("Expected 404 for missing section, got %v", err)
+	}
+	if err = c.SetContext(CTX, "httpd", "port", "5984"); err != nil {
+		t.Fatal(err)
+	}
+	sec, err := c.GetSectionContext(CTX, "httpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sec["port"] != "5984" {
+		t.Errorf("Unexpected section content: %v", sec)
+	}
+}
+
+func TestWriteFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kivik-fileconf-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "local.ini")
+	if err = ioutil.WriteFile(filename, []byte(localINI), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := New(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// With the directory gone, every write fails.
+	if err = os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.SetContext(CTX, "httpd", "port", "6000"); err == nil {
+		t.Errorf("Expected Set to fail")
+	}
+	if err = c.SetContext(CTX, "admins", "bob", "abc123"); err == nil {
+		t.Errorf("Expected Set of a new section to fail")
+	}
+	if err = c.DeleteContext(CTX, "log", "level"); err == nil {
+		t.Errorf("Expected Delete to fail")
+	}
+	all, err := c.GetAllContext(CTX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]map[string]string{
+		"httpd": {"port": "5984"},
+		"log":   {"level": "info"},
+	}
+	if d := diff.AsJSON(expected, all); d != "" {
+		t.Errorf("Failed writes changed the configuration:\n%s\n", d)
+	}
+}
@@ -0,0 +1,348 @@
+// Package layered provides a configuration backend which combines several
+// other backends, in the manner of CouchDB's chain of INI files.
+//
+// Layers are consulted in order, with later layers overriding earlier ones.
+// Environment variables of the form KIVIK_<SECTION>_<KEY> override every
+// layer. Changes are written to the last writable layer, and are rejected if
+// they would be shadowed by a later read-only layer or an environment
+// variable.
+package layered
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/flimzy/kivik/config"
+	"github.com/flimzy/kivik/driver"
+	"github.com/flimzy/kivik/errors"
+	"github.com/flimzy/kivik/serve/config/fileconf"
+)
+
+// EnvPrefix is the prefix of environment variables which override
+// configuration values.
+const EnvPrefix = "KIVIK_"
+
+// EnvOrigin is the origin reported for values set by environment variables.
+const EnvOrigin = "env"
+
+// Layer is a single configuration source.
+type Layer struct {
+	// Name identifies the layer, and is reported as the origin of its
+	// values. For file-backed layers, it is the file name.
+	Name string
+	driver.Config
+	// ReadOnly layers are never written.
+	ReadOnly bool
+}
+
+// Config is a layered configuration backend.
+type Config struct {
+	layers []*Layer
+	// environ returns the environment. If nil, environment variables are
+	// ignored.
+	environ func() []string
+}
+
+var _ driver.Config = &Config{}
+var _ driver.ConfigItem = &Config{}
+var _ driver.ConfigOriginer = &Config{}
+
+// New returns a new layered configuration, with the layers given in order of
+// increasing priority. Environment variables are not consulted.
+func New(layers ...*Layer) *Config {
+	return &Config{layers: layers}
+}
+
+// WithEnv enables overriding configuration values with environment variables.
+func (c *Config) WithEnv() *Config {
+	c.environ = os.Environ
+	return c
+}
+
+// Load reads the CouchDB-style configuration chain from dir: default.ini and
+// default.d/*.ini, which are read-only, then local.ini, then local.d/*.ini,
+// and finally environment variables. The files in each .d directory are read
+// in lexical order. Missing files are treated as empty. Changes are written to
+// the last file in the chain.
+func Load(dir string) (*Config, error) {
+	defaultD, err := globINI(filepath.Join(dir, "default.d"))
+	if err != nil {
+		return nil, err
+	}
+	localD, err := globINI(filepath.Join(dir, "local.d"))
+	if err != nil {
+		return nil, err
+	}
+	files := append([]string{filepath.Join(dir, "default.ini")}, defaultD...)
+	readOnly := len(files)
+	files = append(files, filepath.Join(dir, "local.ini"))
+	files = append(files, localD...)
+	layers := make([]*Layer, 0, len(files))
+	for i, filename := range files {
+		conf, err := fileconf.New(filename)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, &Layer{
+			Name:     filename,
+			Config:   conf,
+			ReadOnly: i < readOnly,
+		})
+	}
+	return New(layers...).WithEnv(), nil
+}
+
+// globINI returns the .ini files in dir, in lexical order.
+func globINI(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.ini"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Layers returns the configuration layers, in order of increasing priority.
+func (c *Config) Layers() []*Layer {
+	return c.layers
+}
+
+// envName returns the environment variable name component for a section or
+// key name.
+func envName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// env returns the environment variables with EnvPrefix, with the prefix
+// removed.
+func (c *Config) env() map[string]string {
+	if c.environ == nil {
+		return nil
+	}
+	vars := make(map[string]string)
+	for _, kv := range c.environ() {
+		if !strings.HasPrefix(kv, EnvPrefix) {
+			continue
+		}
+		parts := strings.SplitN(kv[len(EnvPrefix):], "=", 2)
+		if len(parts) == 2 {
+			vars[parts[0]] = parts[1]
+		}
+	}
+	return vars
+}
+
+// each calls fn for every value, in order of increasing priority, with the
+// value's origin. Layers which report their own origins, such as nested
+// layered configurations, are trusted to do so.
+func (c *Config) each(ctx context.Context, fn func(secName, key, value, origin string)) error {
+	known := make(map[string]map[string]bool)
+	for _, k := range config.DefaultSchema.Keys() {
+		if _, ok := known[k.Section]; !ok {
+			known[k.Section] = make(map[string]bool)
+		}
+		known[k.Section][k.Name] = true
+	}
+	for _, layer := range c.layers {
+		conf, err := layer.GetAllContext(ctx)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read %s", layer.Name)
+		}
+		var origins map[string]map[string]string
+		if originer, ok := layer.Config.(driver.ConfigOriginer); ok {
+			if origins, err = originer.OriginsContext(ctx); err != nil {
+				return errors.Wrapf(err, "failed to read %s", layer.Name)
+			}
+		}
+		for secName, sec := range conf {
+			if _, ok := known[secName]; !ok {
+				known[secName] = make(map[string]bool)
+			}
+			for key, value := range sec {
+				known[secName][key] = true
+				origin, ok := origins[secName][key]
+				if !ok {
+					origin = layer.Name
+				}
+				fn(secName, key, value, origin)
+			}
+		}
+	}
+	for name, value := range c.env() {
+		secName, key := splitEnvName(name, known)
+		fn(secName, key, value, EnvOrigin)
+	}
+	return nil
+}
+
+// splitEnvName splits an environment variable name into a section and key.
+// As both may contain underscores, a known key whose variable name matches
+// exactly, as GetContext would match it, is preferred. Otherwise the longest
+// known section name which matches is used, and failing that, the name is
+// split at the first underscore, and lower-cased.
+func splitEnvName(name string, known map[string]map[string]bool) (secName, key string) {
+	for sec, keys := range known {
+		if len(sec) <= len(secName) {
+			continue
+		}
+		for k := range keys {
+			if envName(sec)+"_"+envName(k) == name {
+				secName, key = sec, k
+				break
+			}
+		}
+	}
+	if secName != "" {
+		return secName, key
+	}
+	for sec := range known {
+		prefix := envName(sec) + "_"
+		if strings.HasPrefix(name, prefix) && len(sec) > len(secName) {
+			secName, key = sec, strings.ToLower(name[len(prefix):])
+		}
+	}
+	if secName != "" {
+		return secName, key
+	}
+	parts := strings.SplitN(name, "_", 2)
+	if len(parts) != 2 {
+		return strings.ToLower(name), ""
+	}
+	return strings.ToLower(parts[0]), strings.ToLower(parts[1])
+}
+
+// GetAllContext returns the effective configuration.
+func (c *Config) GetAllContext(ctx context.Context) (map[string]map[string]string, error) {
+	conf := make(map[string]map[string]string)
+	err := c.each(ctx, func(secName, key, value, _ string) {
+		if _, ok := conf[secName]; !ok {
+			conf[secName] = make(map[string]string)
+		}
+		conf[secName][key] = value
+	})
+	return conf, err
+}
+
+// OriginsContext returns the name of the layer which defines each effective
+// value, or EnvOrigin for environment variables.
+func (c *Config) OriginsContext(ctx context.Context) (map[string]map[string]string, error) {
+	origins := make(map[string]map[string]string)
+	err := c.each(ctx, func(secName, key, _, origin string) {
+		if _, ok := origins[secName]; !ok {
+			origins[secName] = make(map[string]string)
+		}
+		origins[secName][key] = origin
+	})
+	return origins, err
+}
+
+// GetContext returns a single effective value.
+func (c *Config) GetContext(ctx context.Context, secName, key string) (string, error) {
+	if value, ok := c.env()[envName(secName)+"_"+envName(key)]; ok {
+		return value, nil
+	}
+	for i := len(c.layers) - 1; i >= 0; i-- {
+		value, err := getItem(ctx, c.layers[i].Config, secName, key)
+		if err == nil {
+			return value, nil
+		}
+		if errors.StatusCode(err) != http.StatusNotFound {
+			return "", err
+		}
+	}
+	return "", errors.Status(http.StatusNotFound, "configuration key not found")
+}
+
+func getItem(ctx context.Context, layer driver.Config, secName, key string) (string, error) {
+	if itemer, ok := layer.(driver.ConfigItem); ok {
+		return itemer.GetContext(ctx, secName, key)
+	}
+	conf, err := layer.GetAllContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	if value, ok := conf[secName][key]; ok {
+		return value, nil
+	}
+	return "", errors.Status(http.StatusNotFound, "configuration key not found")
+}
+
+// writable returns the index of the last writable layer.
+func (c *Config) writable() (int, error) {
+	for i := len(c.layers) - 1; i >= 0; i-- {
+		if !c.layers[i].ReadOnly {
+			return i, nil
+		}
+	}
+	return 0, errors.Status(http.StatusForbidden, "configuration is read-only")
+}
+
+// shadowed returns a 409 Conflict error if the key is set by an environment
+// variable, or by a layer after the i-th, as a change to the i-th layer would
+// have no effect.
+func (c *Config) shadowed(ctx context.Context, i int, secName, key string) error {
+	name := envName(secName) + "_" + envName(key)
+	if _, ok := c.env()[name]; ok {
+		return errors.Statusf(http.StatusConflict, "configuration key is overridden by the environment variable %s%s", EnvPrefix, name)
+	}
+	for _, layer := range c.layers[i+1:] {
+		_, err := getItem(ctx, layer.Config, secName, key)
+		if err == nil {
+			return errors.Statusf(http.StatusConflict, "configuration key is overridden by %s", layer.Name)
+		}
+		if errors.StatusCode(err) != http.StatusNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetContext writes a value to the last writable layer. A 409 Conflict error
+// is returned if the value would be overridden by an environment variable or
+// a later read-only layer.
+func (c *Config) SetContext(ctx context.Context, secName, key, value string) error {
+	i, err := c.writable()
+	if err != nil {
+		return err
+	}
+	if err := c.shadowed(ctx, i, secName, key); err != nil {
+		return err
+	}
+	return c.layers[i].SetContext(ctx, secName, key, value)
+}
+
+// DeleteContext deletes a value from the last writable layer. A value which
+// is only defined by read-only layers can't be deleted, and a 409 Conflict
+// error is returned if the value is overridden by an environment variable or
+// a later read-only layer.
+func (c *Config) DeleteContext(ctx context.Context, secName, key string) error {
+	i, err := c.writable()
+	if err != nil {
+		return err
+	}
+	layer := c.layers[i]
+	if _, err := getItem(ctx, layer.Config, secName, key); err != nil {
+		if errors.StatusCode(err) == http.StatusNotFound {
+			if _, e := c.GetContext(ctx, secName, key); e == nil {
+				return errors.Status(http.StatusForbidden, "configuration key is defined in a read-only layer")
+			}
+		}
+		return err
+	}
+	if err := c.shadowed(ctx, i, secName, key); err != nil {
+		return err
+	}
+	return layer.DeleteContext(ctx, secName, key)
+}
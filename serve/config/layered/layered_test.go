@@ -0,0 +1,199 @@
+package layered
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/kivik/config"
+	"github.com/flimzy/kivik/errors"
+	"github.com/flimzy/kivik/serve/config/memconf"
+)
+
+func writeFile(t *testing.T, filename, content string) {
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filename, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "layered")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defaultIni := filepath.Join(dir, "default.ini")
+	localIni := filepath.Join(dir, "local.ini")
+	defaultD := filepath.Join(dir, "default.d", "10-vendor.ini")
+	localD := filepath.Join(dir, "local.d", "10-extra.ini")
+	writeFile(t, defaultIni, "[httpd]\nport = 5984\nbind_address = 127.0.0.1\n\n[couch_httpd_auth]\ntimeout = 600\nsecret = abc\n")
+	writeFile(t, defaultD, "[httpd]\nport = 5986\n\n[uuids]\nalgorithm = random\n")
+	writeFile(t, localIni, "[httpd]\nport = 5985\n")
+	writeFile(t, localD, "[log]\nlevel = debug\n")
+
+	conf, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.environ = func() []string {
+		return []string{"PATH=/bin", "KIVIK_COUCH_HTTPD_AUTH_TIMEOUT=60", "KIVIK_FOO_BAR_BAZ=qux"}
+	}
+	ctx := context.Background()
+
+	all, err := conf.GetAllContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]map[string]string{
+		"httpd":            {"port": "5985", "bind_address": "127.0.0.1"},
+		"couch_httpd_auth": {"timeout": "60", "secret": "abc"},
+		"log":              {"level": "debug"},
+		"foo":              {"bar_baz": "qux"},
+		"uuids":            {"algorithm": "random"},
+	}
+	if d := diff.AsJSON(expected, all); d != "" {
+		t.Errorf("Unexpected config:\n%s\n", d)
+	}
+	origins, err := conf.OriginsContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedOrigins := map[string]map[string]string{
+		"httpd":            {"port": localIni, "bind_address": defaultIni},
+		"couch_httpd_auth": {"timeout": EnvOrigin, "secret": defaultIni},
+		"log":              {"level": localD},
+		"foo":              {"bar_baz": EnvOrigin},
+		"uuids":            {"algorithm": defaultD},
+	}
+	if d := diff.AsJSON(expectedOrigins, origins); d != "" {
+		t.Errorf("Unexpected origins:\n%s\n", d)
+	}
+	if value, _ := conf.GetContext(ctx, "couch_httpd_auth", "timeout"); value != "60" {
+		t.Errorf("Expected environment override, got %s", value)
+	}
+	if value, _ := conf.GetContext(ctx, "httpd", "port"); value != "5985" {
+		t.Errorf("Expected local.ini value, got %s", value)
+	}
+
+	if err := conf.SetContext(ctx, "httpd", "bind_address", "0.0.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	content, _ := ioutil.ReadFile(localD)
+	if string(content) != "[log]\nlevel = debug\n\n[httpd]\nbind_address = 0.0.0.0\n" {
+		t.Errorf("Change was not written to the last file:\n%s", content)
+	}
+	content, _ = ioutil.ReadFile(defaultIni)
+	if string(content) != "[httpd]\nport = 5984\nbind_address = 127.0.0.1\n\n[couch_httpd_auth]\ntimeout = 600\nsecret = abc\n" {
+		t.Errorf("default.ini was modified:\n%s", content)
+	}
+
+	if err := conf.DeleteContext(ctx, "httpd", "bind_address"); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := conf.GetContext(ctx, "httpd", "bind_address"); value != "127.0.0.1" {
+		t.Errorf("Expected default value after delete, got %s", value)
+	}
+	if err := conf.DeleteContext(ctx, "httpd", "bind_address"); errors.StatusCode(err) != 403 {
+		t.Errorf("Expected Forbidden deleting read-only value, got %v", err)
+	}
+	if err := conf.DeleteContext(ctx, "httpd", "missing"); errors.StatusCode(err) != 404 {
+		t.Errorf("Expected Not Found deleting missing value, got %v", err)
+	}
+	if err := conf.DeleteContext(ctx, "uuids", "algorithm"); errors.StatusCode(err) != 403 {
+		t.Errorf("Expected Forbidden deleting a default.d value, got %v", err)
+	}
+
+	before, _ := ioutil.ReadFile(localD)
+	if err := conf.SetContext(ctx, "couch_httpd_auth", "timeout", "300"); errors.StatusCode(err) != 409 {
+		t.Errorf("Expected Conflict setting a value overridden by the environment, got %v", err)
+	}
+	content, _ = ioutil.ReadFile(localD)
+	if string(content) != string(before) {
+		t.Errorf("Shadowed change was written:\n%s", content)
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	conf := New()
+	if err := conf.SetContext(context.Background(), "a", "b", "c"); errors.StatusCode(err) != 403 {
+		t.Errorf("Expected Forbidden, got %v", err)
+	}
+}
+
+func TestOverrideLayer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "layered")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	localIni := filepath.Join(dir, "local.ini")
+	writeFile(t, localIni, "[log]\nlevel = info\n")
+	files, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files.environ = nil
+	ctx := context.Background()
+	flags := memconf.New()
+	_ = flags.SetContext(ctx, "log", "file", "/tmp/kivik.log")
+	conf := New(
+		&Layer{Name: dir, Config: files},
+		&Layer{Name: "command line", Config: flags, ReadOnly: true},
+	)
+	if value, _ := conf.GetContext(ctx, "log", "file"); value != "/tmp/kivik.log" {
+		t.Errorf("Expected override value, got %s", value)
+	}
+	if err := conf.SetContext(ctx, "log", "level", "debug"); err != nil {
+		t.Fatal(err)
+	}
+	if err := conf.SetContext(ctx, "log", "file", "/tmp/other.log"); errors.StatusCode(err) != 409 {
+		t.Errorf("Expected Conflict setting a value overridden by a later layer, got %v", err)
+	}
+	content, _ := ioutil.ReadFile(localIni)
+	if string(content) != "[log]\nlevel = debug\n" {
+		t.Errorf("Unexpected file content:\n%s", content)
+	}
+	origins, err := conf.OriginsContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]map[string]string{
+		"log": {"level": localIni, "file": "command line"},
+	}
+	if d := diff.AsJSON(expected, origins); d != "" {
+		t.Errorf("Unexpected origins:\n%s\n", d)
+	}
+}
+
+func TestEnvRegisteredKeys(t *testing.T) {
+	config.Register(&config.Key{Section: "couch_httpd_auth", Name: "secret"})
+	conf := New()
+	conf.environ = func() []string {
+		return []string{"KIVIK_COUCH_HTTPD_AUTH_SECRET=abc", "KIVIK_FOO_BAR_BAZ=qux"}
+	}
+	ctx := context.Background()
+	all, err := conf.GetAllContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]map[string]string{
+		"couch_httpd_auth": {"secret": "abc"},
+		"foo":              {"bar_baz": "qux"},
+	}
+	if d := diff.AsJSON(expected, all); d != "" {
+		t.Errorf("Unexpected config:\n%s\n", d)
+	}
+	for secName, sec := range all {
+		for key, value := range sec {
+			if v, err := conf.GetContext(ctx, secName, key); err != nil || v != value {
+				t.Errorf("GetContext(%s, %s) = %q, %v; GetAllContext reported %q", secName, key, v, err, value)
+			}
+		}
+	}
+}
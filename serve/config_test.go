@@ -0,0 +1,103 @@
+package serve
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/kivik/auth"
+	"github.com/flimzy/kivik/config"
+	"github.com/flimzy/kivik/serve/config/fileconf"
+)
+
+type configRouteTest struct {
+	Name     string
+	Method   string
+	Path     string
+	Body     string
+	User     string
+	Roles    string
+	Status   int
+	Expected string
+}
+
+func TestConfigWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kivik-serve-config-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "local.ini")
+	if err = ioutil.WriteFile(filename, []byte("[log]\nlevel = info\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	conf, err := fileconf.New(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Service{
+		LogWriter:    &initCounter{},
+		AuthHandlers: []auth.Handler{headerAuth{}},
+	}
+	s.SetConfig(config.New(conf))
+	handler, err := s.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []configRouteTest{
+		{Name: "PutNonAdmin", Method: "PUT", Path: "/_config/foo/bar", Body: `"baz"`,
+			User: "bob", Status: http.StatusForbidden},
+		{Name: "PutAnon", Method: "PUT", Path: "/_config/foo/bar", Body: `"baz"`,
+			Status: http.StatusUnauthorized},
+		{Name: "PutNew", Method: "PUT", Path: "/_config/foo/bar", Body: `"baz"`,
+			Roles: "_admin", Status: http.StatusOK, Expected: `""`},
+		{Name: "PutReplace", Method: "PUT", Path: "/_config/foo/bar", Body: `"qux"`,
+			Roles: "_admin", Status: http.StatusOK, Expected: `"baz"`},
+		{Name: "GetPut", Method: "GET", Path: "/_config/foo/bar",
+			Roles: "_admin", Status: http.StatusOK, Expected: `"qux"`},
+		{Name: "PutNotJSON", Method: "PUT", Path: "/_config/foo/bar", Body: `qux`,
+			Roles: "_admin", Status: http.StatusBadRequest},
+		{Name: "PutNewline", Method: "PUT", Path: "/_config/log/level", Body: `"info\n[admins]\nevil = -pbkdf2-x,y,10"`,
+			Roles: "_admin", Status: http.StatusBadRequest},
+		{Name: "DeleteNonAdmin", Method: "DELETE", Path: "/_config/foo/bar",
+			User: "bob", Status: http.StatusForbidden},
+		{Name: "Delete", Method: "DELETE", Path: "/_config/foo/bar",
+			Roles: "_admin", Status: http.StatusOK, Expected: `"qux"`},
+		{Name: "DeleteMissing", Method: "DELETE", Path: "/_config/foo/bar",
+			Roles: "_admin", Status: http.StatusNotFound},
+	}
+	for _, test := range tests {
+		func(test configRouteTest) {
+			t.Run(test.Name, func(t *testing.T) {
+				req := httptest.NewRequest(test.Method, test.Path, strings.NewReader(test.Body))
+				if test.User != "" || test.Roles != "" {
+					user := test.User
+					if user == "" {
+						user = "admin"
+					}
+					req.Header.Set("X-User", user)
+					req.Header.Set("X-Roles", test.Roles)
+				}
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, req)
+				if w.Code != test.Status {
+					t.Errorf("Unexpected status. Expected %d, Actual %d: %s", test.Status, w.Code, w.Body.String())
+				}
+				if test.Expected != "" && strings.TrimSpace(w.Body.String()) != test.Expected {
+					t.Errorf("Unexpected body. Expected %s, Actual %s", test.Expected, w.Body.String())
+				}
+			})
+		}(test)
+	}
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "[log]\nlevel = info\n\n[foo]\n" {
+		t.Errorf("Unexpected file content:\n%s", content)
+	}
+}
@@ -20,13 +20,15 @@ var (
 	ClientContextKey = &contextKey{"client"}
 	// ServiceContextKey is a context key used to access the serve.Service struct.
 	ServiceContextKey = &contextKey{"service"}
+	// RequestIDKey is a context key used to access the request ID.
+	RequestIDKey = &contextKey{"request_id"}
 )
 
 func setContext(s *Service) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			ctx := r.Context()
-			ctx = context.WithValue(ctx, ClientContextKey, s.Client)
+			ctx = context.WithValue(ctx, ClientContextKey, s.client)
 			ctx = context.WithValue(ctx, ServiceContextKey, s)
 			r = r.WithContext(ctx)
 			next.ServeHTTP(w, r)
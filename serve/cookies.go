@@ -7,6 +7,7 @@ import (
 	"encoding/base64"
 	"fmt"
 	"strconv"
+	"time"
 
 	"golang.org/x/net/context"
 
@@ -14,6 +15,8 @@ import (
 	"github.com/pkg/errors"
 )
 
+var now = time.Now
+
 // CreateAuthToken hashes a user name, salt, timestamp, and the server secret
 // into an authentication token.
 func (s *Service) CreateAuthToken(ctx context.Context, name, salt string, time int64) (string, error) {
@@ -28,7 +31,9 @@ func (s *Service) CreateAuthToken(ctx context.Context, name, salt string, time i
 	return base64.RawURLEncoding.EncodeToString([]byte(sessionData + ":" + hashData)), nil
 }
 
-// ValidateCookie validates a cookie against a user context.
+// ValidateCookie validates a cookie against a user context. A cookie is valid
+// if it was issued with the current secret, and is not older than
+// couch_httpd_auth.timeout.
 func (s *Service) ValidateCookie(ctx context.Context, user *authdb.UserContext, cookie string) (bool, error) {
 	name, t, err := DecodeCookie(cookie)
 	if err != nil {
@@ -38,7 +43,35 @@ func (s *Service) ValidateCookie(ctx context.Context, user *authdb.UserContext,
 	if err != nil {
 		return false, err
 	}
-	return token == cookie, nil
+	if !hmac.Equal([]byte(token), []byte(cookie)) {
+		return false, nil
+	}
+	timeout, err := s.SessionTimeout(ctx)
+	if err != nil {
+		return false, err
+	}
+	return now().Unix() < t+int64(timeout), nil
+}
+
+// CookieNeedsRefresh returns true if more than 10% of the session timeout has
+// elapsed since the cookie was created, in which case a fresh cookie should be
+// issued, as CouchDB does.
+func (s *Service) CookieNeedsRefresh(ctx context.Context, cookie string) (bool, error) {
+	_, t, err := DecodeCookie(cookie)
+	if err != nil {
+		return false, err
+	}
+	timeout, err := s.SessionTimeout(ctx)
+	if err != nil {
+		return false, err
+	}
+	return (now().Unix()-t)*10 > int64(timeout), nil
+}
+
+// Now returns the current time, as used to create and validate session
+// cookies.
+func Now() time.Time {
+	return now()
 }
 
 // DecodeCookie decodes a Base64-encoded cookie, and returns its component
@@ -49,6 +82,9 @@ func DecodeCookie(cookie string) (name string, created int64, err error) {
 		return "", 0, err
 	}
 	parts := bytes.SplitN(data, []byte(":"), 3)
+	if len(parts) != 3 {
+		return "", 0, errors.New("invalid cookie")
+	}
 	t, err := strconv.ParseInt(string(parts[1]), 16, 64)
 	if err != nil {
 		return "", 0, errors.Wrap(err, "invalid timestamp")
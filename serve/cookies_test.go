@@ -2,6 +2,7 @@ package serve
 
 import (
 	"testing"
+	"time"
 
 	"github.com/flimzy/kivik/authdb"
 	"github.com/flimzy/kivik/test/kt"
@@ -12,9 +13,13 @@ type validateTest struct {
 	Cookie string
 	User   *authdb.UserContext
 	Valid  bool
+	Now    int64
 	Err    string
 }
 
+// cookieCreated is the creation time of the test cookies, 0x58C5437F
+const cookieCreated = 1489322879
+
 func TestValidateCookie(t *testing.T) {
 	s := &Service{}
 	tests := []validateTest{
@@ -22,10 +27,19 @@ func TestValidateCookie(t *testing.T) {
 			User: &authdb.UserContext{Name: "admin", Salt: "foo bar baz"}},
 		{Name: "WrongSalt", Cookie: "YWRtaW46NThDNTQzN0Y697rnaWCa_rarAm25wbOg3Gm3mqc", Valid: false,
 			User: &authdb.UserContext{Name: "admin", Salt: "123"}},
+		{Name: "Expired", Cookie: "YWRtaW46NThDNTQzN0Y6OnE2cBAuoQKvVBHF2l4PIqKHqDM", Valid: false,
+			Now:  cookieCreated + DefaultSessionTimeout,
+			User: &authdb.UserContext{Name: "admin", Salt: "foo bar baz"}},
 	}
+	defer func() { now = time.Now }()
 	for _, test := range tests {
 		func(test validateTest) {
 			t.Run(test.Name, func(t *testing.T) {
+				ts := test.Now
+				if ts == 0 {
+					ts = cookieCreated + 10
+				}
+				now = func() time.Time { return time.Unix(ts, 0) }
 				valid, err := s.ValidateCookie(kt.CTX, test.User, test.Cookie)
 				var errMsg string
 				if err != nil {
@@ -42,6 +56,30 @@ func TestValidateCookie(t *testing.T) {
 	}
 }
 
+func TestSecretChangeRejectsCookies(t *testing.T) {
+	s := &Service{LogWriter: &initCounter{}}
+	if err := s.Config().Set("couch_httpd_auth", "secret", "old"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Init(); err != nil {
+		t.Fatal(err)
+	}
+	user := &authdb.UserContext{Name: "bob", Salt: "salt"}
+	cookie, err := s.CreateAuthToken(kt.CTX, user.Name, user.Salt, now().Unix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if valid, err := s.ValidateCookie(kt.CTX, user, cookie); err != nil || !valid {
+		t.Fatalf("Expected the new cookie to be valid, got %t, %v", valid, err)
+	}
+	if err := s.Config().Set("couch_httpd_auth", "secret", "new"); err != nil {
+		t.Fatal(err)
+	}
+	if valid, err := s.ValidateCookie(kt.CTX, user, cookie); err != nil || valid {
+		t.Errorf("Expected a cookie signed with the old secret to be rejected, got %t, %v", valid, err)
+	}
+}
+
 type cookieTest struct {
 	TestName string
 	Input    string
@@ -113,3 +151,20 @@ func TestCreateAuthToken(t *testing.T) {
 	}
 
 }
+
+func TestCookieNeedsRefresh(t *testing.T) {
+	s := &Service{}
+	_ = s.Config().Set("couch_httpd_auth", "timeout", "1000")
+	defer func() { now = time.Now }()
+	cookie := "YWRtaW46NThDNTQzN0Y6OnE2cBAuoQKvVBHF2l4PIqKHqDM"
+	for elapsed, expected := range map[int64]bool{10: false, 100: false, 101: true, 900: true} {
+		now = func() time.Time { return time.Unix(cookieCreated+elapsed, 0) }
+		refresh, err := s.CookieNeedsRefresh(kt.CTX, cookie)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if refresh != expected {
+			t.Errorf("After %d seconds, expected refresh=%t, got %t", elapsed, expected, refresh)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package serve
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Defaults for the [cors] configuration section, as used by CouchDB.
+const (
+	defaultCORSMethods = "GET, HEAD, POST, PUT, DELETE, TRACE, CONNECT, COPY, OPTIONS"
+	defaultCORSHeaders = "accept, authorization, content-type, origin, referer, x-csrf-token"
+	corsExposedHeaders = "cache-control, content-type, etag, server, x-couch-request-id, x-request-id, x-couch-update-newrev, x-couchdb-body-time"
+)
+
+// corsConfig is a snapshot of the [cors] configuration section.
+type corsConfig struct {
+	origins     []string
+	credentials bool
+	methods     []string
+	headers     []string
+	maxAge      string
+}
+
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func (s *Service) corsConfig() (*corsConfig, bool) {
+	conf := s.Config()
+	if !conf.GetBool("httpd", "enable_cors") {
+		return nil, false
+	}
+	methods := conf.GetString("cors", "methods")
+	if methods == "" {
+		methods = defaultCORSMethods
+	}
+	headers := conf.GetString("cors", "headers")
+	if headers == "" {
+		headers = defaultCORSHeaders
+	}
+	return &corsConfig{
+		origins:     splitList(conf.GetString("cors", "origins")),
+		credentials: conf.GetBool("cors", "credentials"),
+		methods:     splitList(strings.ToUpper(methods)),
+		headers:     splitList(strings.ToLower(headers)),
+		maxAge:      conf.GetString("cors", "max_age"),
+	}, true
+}
+
+// allowOrigin returns the value for the Access-Control-Allow-Origin header,
+// or an empty string if the origin is not permitted.
+func (c *corsConfig) allowOrigin(origin string) string {
+	for _, o := range c.origins {
+		if o == "*" {
+			if c.credentials {
+				// A wildcard is not permitted in combination with credentials,
+				// so the origin is echoed back instead.
+				return origin
+			}
+			return "*"
+		}
+		if strings.EqualFold(o, origin) {
+			return origin
+		}
+	}
+	return ""
+}
+
+func contains(list []string, item string) bool {
+	for _, i := range list {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
+
+// preflight validates the requested method and headers of a preflight request.
+func (c *corsConfig) preflight(r *http.Request) bool {
+	if !contains(c.methods, strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))) {
+		return false
+	}
+	for _, header := range splitList(strings.ToLower(r.Header.Get("Access-Control-Request-Headers"))) {
+		if !contains(c.headers, header) {
+			return false
+		}
+	}
+	return true
+}
+
+// corsHandler adds CORS headers to responses, according to httpd.enable_cors
+// and the [cors] configuration section, and answers preflight requests. It
+// must run before authentication, as browsers do not send credentials with
+// preflight requests.
+func corsHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		conf, ok := GetService(r).corsConfig()
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		allowed := conf.allowOrigin(origin)
+		isPreflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		if isPreflight {
+			if allowed != "" && conf.preflight(r) {
+				h := w.Header()
+				setCORSOrigin(h, allowed, conf.credentials)
+				h.Set("Access-Control-Allow-Methods", strings.Join(conf.methods, ", "))
+				h.Set("Access-Control-Allow-Headers", strings.Join(conf.headers, ", "))
+				if _, err := strconv.Atoi(conf.maxAge); err == nil {
+					h.Set("Access-Control-Max-Age", conf.maxAge)
+				}
+			}
+			// Preflight requests are never passed on, so that they are not
+			// subject to authentication.
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if allowed != "" {
+			h := w.Header()
+			setCORSOrigin(h, allowed, conf.credentials)
+			h.Set("Access-Control-Expose-Headers", corsExposedHeaders)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func setCORSOrigin(h http.Header, origin string, credentials bool) {
+	h.Set("Access-Control-Allow-Origin", origin)
+	if origin != "*" {
+		h.Add("Vary", "Origin")
+	}
+	if credentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
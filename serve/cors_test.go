@@ -0,0 +1,106 @@
+package serve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type corsTest struct {
+	Name    string
+	Conf    map[string]string
+	Method  string
+	Headers map[string]string
+	Status  int
+	Want    map[string]string
+}
+
+func TestCORS(t *testing.T) {
+	enabled := map[string]string{"origins": "http://example.com"}
+	tests := []corsTest{
+		{Name: "Disabled", Method: "GET",
+			Headers: map[string]string{"Origin": "http://example.com"},
+			Status:  http.StatusOK,
+			Want:    map[string]string{"Access-Control-Allow-Origin": ""}},
+		{Name: "AllowedOrigin", Conf: enabled, Method: "GET",
+			Headers: map[string]string{"Origin": "http://example.com"},
+			Status:  http.StatusOK,
+			Want: map[string]string{
+				"Access-Control-Allow-Origin":      "http://example.com",
+				"Access-Control-Allow-Credentials": "",
+			}},
+		{Name: "OtherOrigin", Conf: enabled, Method: "GET",
+			Headers: map[string]string{"Origin": "http://evil.com"},
+			Status:  http.StatusOK,
+			Want:    map[string]string{"Access-Control-Allow-Origin": ""}},
+		{Name: "Wildcard", Conf: map[string]string{"origins": "*"}, Method: "GET",
+			Headers: map[string]string{"Origin": "http://example.com"},
+			Status:  http.StatusOK,
+			Want:    map[string]string{"Access-Control-Allow-Origin": "*"}},
+		{Name: "WildcardCredentials", Conf: map[string]string{"origins": "*", "credentials": "true"}, Method: "GET",
+			Headers: map[string]string{"Origin": "http://example.com"},
+			Status:  http.StatusOK,
+			Want: map[string]string{
+				"Access-Control-Allow-Origin":      "http://example.com",
+				"Access-Control-Allow-Credentials": "true",
+			}},
+		{Name: "Preflight", Conf: map[string]string{"origins": "http://example.com", "max_age": "3600"}, Method: "OPTIONS",
+			Headers: map[string]string{
+				"Origin":                         "http://example.com",
+				"Access-Control-Request-Method":  "PUT",
+				"Access-Control-Request-Headers": "Content-Type",
+			},
+			Status: http.StatusOK,
+			Want: map[string]string{
+				"Access-Control-Allow-Origin":  "http://example.com",
+				"Access-Control-Allow-Methods": defaultCORSMethods,
+				"Access-Control-Max-Age":       "3600",
+			}},
+		{Name: "PreflightBadMethod", Conf: map[string]string{"origins": "http://example.com", "methods": "GET"}, Method: "OPTIONS",
+			Headers: map[string]string{
+				"Origin":                        "http://example.com",
+				"Access-Control-Request-Method": "DELETE",
+			},
+			Status: http.StatusOK,
+			Want:   map[string]string{"Access-Control-Allow-Origin": ""}},
+		{Name: "PreflightBadHeader", Conf: enabled, Method: "OPTIONS",
+			Headers: map[string]string{
+				"Origin":                         "http://example.com",
+				"Access-Control-Request-Method":  "GET",
+				"Access-Control-Request-Headers": "X-Secret",
+			},
+			Status: http.StatusOK,
+			Want:   map[string]string{"Access-Control-Allow-Origin": ""}},
+	}
+	for _, test := range tests {
+		func(test corsTest) {
+			t.Run(test.Name, func(t *testing.T) {
+				s := &Service{LogWriter: &initCounter{}}
+				if test.Conf != nil {
+					_ = s.Config().Set("httpd", "enable_cors", "true")
+					for key, value := range test.Conf {
+						_ = s.Config().Set("cors", key, value)
+					}
+				}
+				handler, err := s.Init()
+				if err != nil {
+					t.Fatal(err)
+				}
+				req := httptest.NewRequest(test.Method, "/", nil)
+				for key, value := range test.Headers {
+					req.Header.Set(key, value)
+				}
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, req)
+				if w.Code != test.Status {
+					t.Errorf("Unexpected status. Expected %d, Actual %d", test.Status, w.Code)
+				}
+				for key, want := range test.Want {
+					if got := w.Header().Get(key); got != want {
+						t.Errorf("Unexpected %s header. Expected '%s', Actual '%s'", key, want, got)
+					}
+				}
+			})
+		}(test)
+	}
+}
@@ -0,0 +1,125 @@
+package serve
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/flimzy/kivik"
+	"github.com/flimzy/kivik/errors"
+)
+
+// docID returns the document ID from the request path, restoring the
+// _design/ prefix for design documents.
+func docID(r *http.Request) string {
+	params := getParams(r)
+	if ddoc, ok := params["ddoc"]; ok {
+		return "_design/" + ddoc
+	}
+	return params["docid"]
+}
+
+// queryOptions converts the request's query parameters into kivik options.
+func queryOptions(r *http.Request) kivik.Options {
+	opts := kivik.Options{}
+	for key := range r.URL.Query() {
+		opts[key] = r.URL.Query().Get(key)
+	}
+	return opts
+}
+
+func getDoc(w http.ResponseWriter, r *http.Request) error {
+	if isUserDoc(r) {
+		return getUserDoc(w, r)
+	}
+	db, err := getClient(r).DBContext(r.Context(), getParams(r)["db"])
+	if err != nil {
+		return err
+	}
+	var doc json.RawMessage
+	if err := db.GetContext(r.Context(), docID(r), &doc, queryOptions(r)); err != nil {
+		return err
+	}
+	return serveJSON(w, doc)
+}
+
+// putDoc stores a document. The rev query parameter may be used in place of
+// the document's _rev field, and batch=ok is accepted, although the write is
+// not deferred. Storing revisions as given (new_edits=false) is not supported.
+func putDoc(w http.ResponseWriter, r *http.Request) error {
+	var doc map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&doc); err != nil {
+		return errors.WrapStatus(http.StatusBadRequest, err)
+	}
+	if rev, ok := StringQueryParam(r, "rev"); ok {
+		if bodyRev, ok := doc["_rev"]; ok && bodyRev != rev {
+			return errors.Status(http.StatusBadRequest, "Document rev from request body and query string have different values")
+		}
+		doc["_rev"] = rev
+	}
+	if newEdits, ok := StringQueryParam(r, "new_edits"); ok && newEdits == "false" {
+		return errors.Status(http.StatusNotImplemented, "new_edits=false is not supported")
+	}
+	id := docID(r)
+	rev, err := putDocument(r, id, doc)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", typeJSON)
+	if batch, _ := StringQueryParam(r, "batch"); batch == "ok" {
+		w.WriteHeader(http.StatusAccepted)
+		return json.NewEncoder(w).Encode(map[string]interface{}{
+			"ok": true,
+			"id": id,
+		})
+	}
+	w.Header().Set("ETag", `"`+rev+`"`)
+	w.WriteHeader(http.StatusCreated)
+	return json.NewEncoder(w).Encode(map[string]interface{}{
+		"ok":  true,
+		"id":  id,
+		"rev": rev,
+	})
+}
+
+func deleteDoc(w http.ResponseWriter, r *http.Request) error {
+	rev, ok := StringQueryParam(r, "rev")
+	if !ok {
+		return errors.Status(http.StatusConflict, "Document update conflict.")
+	}
+	id := docID(r)
+	newRev, err := deleteDocument(r, id, rev)
+	if err != nil {
+		return err
+	}
+	return serveJSON(w, map[string]interface{}{
+		"ok":  true,
+		"id":  id,
+		"rev": newRev,
+	})
+}
+
+// putDocument stores doc in the requested database. User documents are
+// passed through usersdb for validation.
+func putDocument(r *http.Request, id string, doc map[string]interface{}) (rev string, err error) {
+	if isUserDoc(r) {
+		return putUserDoc(r, doc)
+	}
+	db, err := getClient(r).DBContext(r.Context(), getParams(r)["db"])
+	if err != nil {
+		return "", err
+	}
+	return db.PutContext(r.Context(), id, doc)
+}
+
+// deleteDocument deletes a document from the requested database. User
+// documents are passed through usersdb for validation.
+func deleteDocument(r *http.Request, id, rev string) (newRev string, err error) {
+	if isUserDoc(r) {
+		return deleteUserDoc(r, rev)
+	}
+	db, err := getClient(r).DBContext(r.Context(), getParams(r)["db"])
+	if err != nil {
+		return "", err
+	}
+	return db.DeleteContext(r.Context(), id, rev)
+}
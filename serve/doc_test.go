@@ -0,0 +1,112 @@
+package serve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/kivik"
+	"github.com/flimzy/kivik/auth"
+	"github.com/flimzy/kivik/authdb"
+	_ "github.com/flimzy/kivik/driver/memory"
+)
+
+// headerAuth authenticates the user named in the X-User header, with the
+// comma-separated roles in the X-Roles header.
+type headerAuth struct{}
+
+func (headerAuth) MethodName() string { return "header" }
+
+func (headerAuth) Authenticate(_ http.ResponseWriter, r *http.Request) (*authdb.UserContext, error) {
+	name := r.Header.Get("X-User")
+	if name == "" {
+		return nil, nil
+	}
+	user := &authdb.UserContext{Name: name}
+	if roles := r.Header.Get("X-Roles"); roles != "" {
+		user.Roles = strings.Split(roles, ",")
+	}
+	return user, nil
+}
+
+// newDocService returns a service backed by the memory driver, with a
+// database "db" whose admin is bob, and whose only member is alice.
+func newDocService(t *testing.T) (http.Handler, *kivik.DB) {
+	client, err := kivik.New("memory", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = client.CreateDB("db"); err != nil {
+		t.Fatal(err)
+	}
+	db, err := client.DB("db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.SetSecurity(&kivik.Security{
+		Admins:  kivik.Members{Names: []string{"bob"}},
+		Members: kivik.Members{Names: []string{"alice"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	s := &Service{
+		Client:       client,
+		LogWriter:    &initCounter{},
+		AuthHandlers: []auth.Handler{headerAuth{}},
+	}
+	handler, err := s.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return handler, db
+}
+
+type docRouteTest struct {
+	Name   string
+	Method string
+	Path   string
+	User   string
+	Roles  string
+	Body   string
+	Status int
+}
+
+func TestDocRoutes(t *testing.T) {
+	handler, db := newDocService(t)
+	rev, err := db.Put("foo", map[string]interface{}{"value": "foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []docRouteTest{
+		{Name: "MemberRead", Method: "GET", Path: "/db/foo", User: "alice", Status: http.StatusOK},
+		{Name: "AdminRead", Method: "GET", Path: "/db/foo", User: "bob", Status: http.StatusOK},
+		{Name: "AnonRead", Method: "GET", Path: "/db/foo", Status: http.StatusUnauthorized},
+		{Name: "NonMemberRead", Method: "GET", Path: "/db/foo", User: "eve", Status: http.StatusForbidden},
+		{Name: "NonMemberWrite", Method: "PUT", Path: "/db/bar", User: "eve", Body: `{}`, Status: http.StatusForbidden},
+		{Name: "MemberWrite", Method: "PUT", Path: "/db/bar", User: "alice", Body: `{}`, Status: http.StatusCreated},
+		{Name: "MemberDesignWrite", Method: "PUT", Path: "/db/_design/foo", User: "alice", Body: `{}`, Status: http.StatusForbidden},
+		{Name: "DBAdminDesignWrite", Method: "PUT", Path: "/db/_design/foo", User: "bob", Body: `{}`, Status: http.StatusCreated},
+		{Name: "ServerAdminDesignWrite", Method: "PUT", Path: "/db/_design/bar", User: "root", Roles: "_admin", Body: `{}`, Status: http.StatusCreated},
+		{Name: "MemberDesignRead", Method: "GET", Path: "/db/_design/foo", User: "alice", Status: http.StatusOK},
+		{Name: "QueryRev", Method: "PUT", Path: "/db/foo?rev=" + rev, User: "alice", Body: `{"value":"new"}`, Status: http.StatusCreated},
+		{Name: "RevMismatch", Method: "PUT", Path: "/db/foo?rev=" + rev, User: "alice", Body: `{"_rev":"1-x"}`, Status: http.StatusBadRequest},
+		{Name: "Batch", Method: "PUT", Path: "/db/baz?batch=ok", User: "alice", Body: `{}`, Status: http.StatusAccepted},
+	}
+	for _, test := range tests {
+		func(test docRouteTest) {
+			t.Run(test.Name, func(t *testing.T) {
+				req := httptest.NewRequest(test.Method, test.Path, strings.NewReader(test.Body))
+				if test.User != "" {
+					req.Header.Set("X-User", test.User)
+					req.Header.Set("X-Roles", test.Roles)
+				}
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, req)
+				if w.Code != test.Status {
+					t.Errorf("Unexpected status. Expected %d, Actual %d: %s", test.Status, w.Code, w.Body.String())
+				}
+			})
+		}(test)
+	}
+}
@@ -0,0 +1,13 @@
+// +build go1.7,!go1.8
+
+package serve
+
+import "context"
+
+// shutdownServer stops srv from accepting new connections. http.Server does not
+// support draining in-flight requests prior to Go 1.8, so they are not waited
+// for.
+func shutdownServer(_ context.Context, srv *server) error {
+	srv.srv.SetKeepAlivesEnabled(false)
+	return srv.ln.Close()
+}
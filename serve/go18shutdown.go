@@ -0,0 +1,11 @@
+// +build go1.8
+
+package serve
+
+import "context"
+
+// shutdownServer gracefully shuts down srv, waiting for in-flight requests to
+// complete, or for ctx to be cancelled.
+func shutdownServer(ctx context.Context, srv *server) error {
+	return srv.srv.Shutdown(ctx)
+}
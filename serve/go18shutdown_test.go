@@ -0,0 +1,122 @@
+// +build go1.8
+
+package serve
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/flimzy/kivik"
+	"github.com/flimzy/kivik/driver"
+)
+
+// slowShutdown is registered once, as drivers can't be registered twice.
+var slowShutdown = &slowDriver{gates: make(map[string]*slowGate)}
+
+func init() {
+	kivik.Register("slowShutdown", slowShutdown)
+}
+
+// slowGate is closed to release the AllDBs calls of a slowDriver client.
+type slowGate struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+// slowDriver is a driver whose AllDBs blocks until the gate registered for
+// the client's DSN is released.
+type slowDriver struct {
+	mu    sync.Mutex
+	gates map[string]*slowGate
+}
+
+// newGate registers a new gate for clients of dsn.
+func (d *slowDriver) newGate(dsn string) *slowGate {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	g := &slowGate{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	d.gates[dsn] = g
+	return g
+}
+
+func (d *slowDriver) NewClientContext(_ context.Context, dsn string) (driver.Client, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return &slowClient{slowGate: d.gates[dsn]}, nil
+}
+
+type slowClient struct {
+	driver.Client
+	*slowGate
+}
+
+func (c *slowClient) AllDBsContext(_ context.Context) ([]string, error) {
+	close(c.started)
+	<-c.release
+	return []string{}, nil
+}
+
+func TestStartWaitsForShutdown(t *testing.T) {
+	gate := slowShutdown.newGate(t.Name())
+	client, err := kivik.New("slowShutdown", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+	s := &Service{Client: client, LogWriter: &initCounter{}}
+	if err = s.Bind(addr); err != nil {
+		t.Fatal(err)
+	}
+	result := make(chan error, 1)
+	go func() {
+		result <- s.Start()
+	}()
+	go func() {
+		for i := 0; i < 100; i++ {
+			resp, e := http.Get("http://" + addr + "/_all_dbs")
+			if e == nil {
+				_ = resp.Body.Close()
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+	select {
+	case <-gate.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Request never reached the driver")
+	}
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- s.Shutdown(context.Background())
+	}()
+	select {
+	case err = <-result:
+		t.Fatalf("Start returned before in-flight requests completed: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(gate.release)
+	if err = <-shutdown; err != nil {
+		t.Errorf("Shutdown failed: %s", err)
+	}
+	select {
+	case err = <-result:
+		if err != nil {
+			t.Errorf("Start returned an error after Shutdown: %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("Start did not return after Shutdown")
+	}
+}
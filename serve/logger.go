@@ -7,18 +7,37 @@ import (
 	"time"
 
 	"github.com/flimzy/kivik"
+	"github.com/flimzy/kivik/auth"
 	"github.com/flimzy/kivik/logger"
 )
 
 func (s *Service) log(level logger.LogLevel, format string, args ...interface{}) {
-	l, ok := logger.StringToLogLevel(s.Config().GetString("log", "level"))
+	s.logFields(level, nil, format, args...)
+}
+
+// logFields logs a message with structured fields. Fields are passed to the
+// LogWriter only if it implements logger.FieldWriter. Otherwise, the request
+// ID and user, if present, are prepended to the message.
+func (s *Service) logFields(level logger.LogLevel, fields logger.Fields, format string, args ...interface{}) {
+	l, ok := logLevel(s.Config().GetString("log", "level"))
 	if !ok {
 		l = logger.DefaultLogLevel
 	}
 	msg := strings.TrimSpace(fmt.Sprintf(format, args...))
-	if l < level {
+	if level < l {
+		return
+	}
+	if fw, ok := s.LogWriter.(logger.FieldWriter); ok && len(fields) > 0 {
+		fw.WriteLogFields(level, msg, fields)
 		return
 	}
+	if id, ok := fields[logger.FieldRequestID]; ok {
+		user, _ := fields[logger.FieldUser].(string)
+		if user == "" {
+			user = "-"
+		}
+		msg = fmt.Sprintf("%s %s %s", id, user, msg)
+	}
 	if s.LogWriter == nil {
 		fmt.Printf("[%s] [%s] [--] %s\n", time.Now().Format(logger.TimeFormat), level, msg)
 		return
@@ -46,9 +65,58 @@ func (s *Service) Error(format string, args ...interface{}) {
 	s.log(logger.LogLevelError, format, args...)
 }
 
+// RequestLogger logs messages in the context of a request. Each message
+// includes the request ID and the authenticated user, if any.
+type RequestLogger struct {
+	s *Service
+	r *http.Request
+}
+
+// GetLogger returns a logger for the request.
+func GetLogger(r *http.Request) *RequestLogger {
+	return &RequestLogger{s: GetService(r), r: r}
+}
+
+// fields returns the request ID and user name, as of now.
+func (l *RequestLogger) fields() logger.Fields {
+	fields := logger.Fields{}
+	if id := GetRequestID(l.r); id != "" {
+		fields[logger.FieldRequestID] = id
+	}
+	if session, ok := l.r.Context().Value(SessionKey).(**auth.Session); ok && *session != nil && (*session).User != nil {
+		fields[logger.FieldUser] = (*session).User.Name
+	}
+	return fields
+}
+
+func (l *RequestLogger) log(level logger.LogLevel, format string, args ...interface{}) {
+	l.s.logFields(level, l.fields(), format, args...)
+}
+
+// Debug logs a debug message.
+func (l *RequestLogger) Debug(format string, args ...interface{}) {
+	l.log(logger.LogLevelDebug, format, args...)
+}
+
+// Info logs an informational message.
+func (l *RequestLogger) Info(format string, args ...interface{}) {
+	l.log(logger.LogLevelInfo, format, args...)
+}
+
+// Warn logs a warning message.
+func (l *RequestLogger) Warn(format string, args ...interface{}) {
+	l.log(logger.LogLevelWarn, format, args...)
+}
+
+// Error logs an error message.
+func (l *RequestLogger) Error(format string, args ...interface{}) {
+	l.log(logger.LogLevelError, format, args...)
+}
+
 type statusWriter struct {
 	http.ResponseWriter
 	status int
+	bytes  int64
 }
 
 func (w *statusWriter) WriteHeader(status int) {
@@ -56,16 +124,40 @@ func (w *statusWriter) WriteHeader(status int) {
 	w.ResponseWriter.WriteHeader(status)
 }
 
+func (w *statusWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Flush satisfies the http.Flusher interface, so that streaming responses may
+// be flushed through the logger.
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 func requestLogger(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := now()
 		sw := &statusWriter{ResponseWriter: w}
 		next.ServeHTTP(sw, r)
+		duration := now().Sub(start)
 		ip := r.RemoteAddr
 		ip = ip[0:strings.LastIndex(ip, ":")]
 		status := sw.status
 		if status == 0 {
 			status = kivik.StatusOK
 		}
-		GetService(r).Info("%s - - %s %s %d", ip, r.Method, r.URL.String(), status)
+		l := GetLogger(r)
+		fields := l.fields()
+		fields[logger.FieldRemoteAddr] = ip
+		fields[logger.FieldMethod] = r.Method
+		fields[logger.FieldPath] = r.URL.Path
+		fields[logger.FieldStatus] = status
+		fields[logger.FieldBytes] = sw.bytes
+		fields[logger.FieldDuration] = duration
+		l.s.logFields(logger.LogLevelInfo, fields, "%s - - %s %s %d %d %dms", ip, r.Method, r.URL.String(), status, sw.bytes, duration/time.Millisecond)
 	})
 }
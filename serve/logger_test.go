@@ -0,0 +1,91 @@
+package serve
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/flimzy/diff"
+	"github.com/flimzy/kivik/auth"
+	"github.com/flimzy/kivik/authdb"
+	"github.com/flimzy/kivik/logger"
+)
+
+// fieldLogger records the fields of each structured log entry.
+type fieldLogger struct {
+	initCounter
+	entries []logger.Fields
+}
+
+func (l *fieldLogger) WriteLogFields(_ logger.LogLevel, _ string, fields logger.Fields) error {
+	l.entries = append(l.entries, fields)
+	return nil
+}
+
+func TestRequestLoggerFields(t *testing.T) {
+	start := time.Date(2017, 5, 1, 12, 0, 0, 0, time.UTC)
+	calls := 0
+	now = func() time.Time {
+		calls++
+		return start.Add(time.Duration(calls-1) * 250 * time.Millisecond)
+	}
+	defer func() { now = time.Now }()
+	lw := &fieldLogger{}
+	s := &Service{LogWriter: lw}
+	session := &auth.Session{}
+	h := requestLogger(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session.User = &authdb.UserContext{Name: "bob"}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("{}\n"))
+	}))
+	req := httptest.NewRequest("PUT", "/db/doc?batch=ok", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	ctx := context.WithValue(req.Context(), ServiceContextKey, s)
+	ctx = context.WithValue(ctx, SessionKey, &session)
+	ctx = context.WithValue(ctx, RequestIDKey, "abc123")
+	h.ServeHTTP(httptest.NewRecorder(), req.WithContext(ctx))
+	expected := []logger.Fields{{
+		logger.FieldRemoteAddr: "10.0.0.1",
+		logger.FieldMethod:     "PUT",
+		logger.FieldPath:       "/db/doc",
+		logger.FieldStatus:     http.StatusCreated,
+		logger.FieldBytes:      3,
+		logger.FieldDuration:   250 * time.Millisecond,
+		logger.FieldUser:       "bob",
+		logger.FieldRequestID:  "abc123",
+	}}
+	if d := diff.AsJSON(expected, lw.entries); d != "" {
+		t.Error(d)
+	}
+}
+
+// textLogger records messages from a logger without field support.
+type textLogger struct {
+	initCounter
+	messages []string
+}
+
+func (l *textLogger) WriteLog(_ logger.LogLevel, msg string) error {
+	l.messages = append(l.messages, msg)
+	return nil
+}
+
+func TestRequestLoggerText(t *testing.T) {
+	lw := &textLogger{}
+	s := &Service{LogWriter: lw}
+	session := &auth.Session{}
+	req := httptest.NewRequest("GET", "/", nil)
+	ctx := context.WithValue(req.Context(), ServiceContextKey, s)
+	ctx = context.WithValue(ctx, SessionKey, &session)
+	ctx = context.WithValue(ctx, RequestIDKey, "abc123")
+	req = req.WithContext(ctx)
+	GetLogger(req).Warn("anonymous")
+	session.User = &authdb.UserContext{Name: "bob"}
+	GetLogger(req).Warn("authenticated")
+	expected := []string{"abc123 - anonymous", "abc123 bob authenticated"}
+	if d := diff.TextSlices(expected, lw.messages); d != "" {
+		t.Error(d)
+	}
+}
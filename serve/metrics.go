@@ -0,0 +1,143 @@
+package serve
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/dimfeld/httptreemux"
+	"golang.org/x/net/context"
+
+	"github.com/flimzy/kivik"
+	"github.com/flimzy/kivik/driver"
+	"github.com/flimzy/kivik/driver/instrument"
+	"github.com/flimzy/kivik/metrics"
+)
+
+// Metric names.
+const (
+	MetricRequests       = "couchdb.httpd.requests"
+	MetricRequestTime    = "couchdb.httpd.request_time_seconds"
+	MetricChangesClients = "couchdb.httpd.clients_requesting_changes"
+	MetricAuthSuccesses  = "couchdb.auth.successes"
+	MetricAuthFailures   = "couchdb.auth.failures"
+)
+
+// unmatchedRoute is the route label of requests which match no route.
+const unmatchedRoute = "unmatched"
+
+// otherMethod is the method label of requests with methods not used by
+// CouchDB, so that clients can't create an unbounded number of series.
+const otherMethod = "OTHER"
+
+// methodLabel returns the method label of a request.
+func methodLabel(method string) string {
+	switch method {
+	case kivik.MethodGet, kivik.MethodHead, kivik.MethodPost, kivik.MethodPut,
+		kivik.MethodDelete, kivik.MethodCopy, http.MethodOptions:
+		return method
+	}
+	return otherMethod
+}
+
+// routeKey is the context key of a pointer to the matched route, which is set
+// by the router, and read by the metrics handler.
+var routeKey = &contextKey{"route"}
+
+// serviceMetrics holds the metrics recorded by the service.
+type serviceMetrics struct {
+	registry       *metrics.Registry
+	requests       *metrics.Counter
+	requestTime    *metrics.Histogram
+	changesClients *metrics.Gauge
+	authSuccesses  *metrics.Counter
+	authFailures   *metrics.Counter
+}
+
+// Metrics returns the service's metrics registry. Other components, such as
+// instrumented drivers, may register their own metrics here, to be exposed
+// alongside those of the service.
+func (s *Service) Metrics() *metrics.Registry {
+	return s.serviceMetrics().registry
+}
+
+func (s *Service) serviceMetrics() *serviceMetrics {
+	s.metricsOnce.Do(func() {
+		r := metrics.NewRegistry()
+		s.metrics = &serviceMetrics{
+			registry:       r,
+			requests:       r.Counter(MetricRequests, "number of HTTP requests", "route", "method", "status"),
+			requestTime:    r.Histogram(MetricRequestTime, "length of a request inside CouchDB without MochiWeb", nil, "route", "method", "status"),
+			changesClients: r.Gauge(MetricChangesClients, "number of clients for continuous _changes"),
+			authSuccesses:  r.Counter(MetricAuthSuccesses, "number of successful authentications", "method"),
+			authFailures:   r.Counter(MetricAuthFailures, "number of failed authentications", "method"),
+		}
+	})
+	return s.metrics
+}
+
+// instrumentClient sets the client used to serve requests to Client, with
+// its driver wrapped to record the duration of each driver call in the
+// service's metrics.
+func (s *Service) instrumentClient() {
+	if s.Client == nil {
+		s.client = nil
+		return
+	}
+	hook := instrument.MetricsHook(s.Metrics())
+	s.client = s.Client.Wrap(func(c driver.Client) driver.Client {
+		return instrument.WrapClient(c, s.Client.Driver(), hook)
+	})
+}
+
+// routeGroup registers routes, recording the matched route for metrics.
+type routeGroup struct {
+	*httptreemux.ContextGroup
+}
+
+func (g routeGroup) Handler(method, path string, handler http.Handler) {
+	g.ContextGroup.Handler(method, path, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route, ok := r.Context().Value(routeKey).(*string); ok {
+			*route = path
+		}
+		handler.ServeHTTP(w, r)
+	}))
+}
+
+// metricsHandler records request counts and times.
+func metricsHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := now()
+		route := unmatchedRoute
+		r = r.WithContext(context.WithValue(r.Context(), routeKey, &route))
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+		status := sw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		m := GetService(r).serviceMetrics()
+		code := strconv.Itoa(status)
+		method := methodLabel(r.Method)
+		m.requests.Inc(route, method, code)
+		m.requestTime.Observe(now().Sub(start).Seconds(), route, method, code)
+	})
+}
+
+// getStats serves the metrics as CouchDB-style statistics.
+func getStats(w http.ResponseWriter, r *http.Request) error {
+	return serveJSON(w, GetService(r).Metrics().Stats())
+}
+
+// getPrometheus serves the metrics in the Prometheus text format.
+func getPrometheus(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", metrics.PrometheusContentType)
+	return GetService(r).Metrics().WritePrometheus(w)
+}
+
+// trackChangesClient increments the count of continuous changes clients, and
+// returns a function to decrement it.
+func (s *Service) trackChangesClient() func() {
+	g := s.serviceMetrics().changesClients
+	g.Inc()
+	return func() { g.Dec() }
+}
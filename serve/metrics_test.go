@@ -0,0 +1,76 @@
+package serve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/kivik"
+	_ "github.com/flimzy/kivik/driver/memory"
+)
+
+func TestMetrics(t *testing.T) {
+	s := &Service{LogWriter: &initCounter{}}
+	handler, err := s.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/", "/", "/_config/foo/bar/baz"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("BOGUS", "/", nil))
+	m := s.serviceMetrics()
+	if v := m.requests.Value("/", "GET", "200"); v != 2 {
+		t.Errorf("Expected 2 requests for /, got %v", v)
+	}
+	if v := m.requests.Value(unmatchedRoute, "GET", "404"); v != 1 {
+		t.Errorf("Expected 1 unmatched request, got %v", v)
+	}
+	if v := m.requests.Value(unmatchedRoute, otherMethod, "405"); v != 1 {
+		t.Errorf("Expected 1 request with an unknown method, got %v", v)
+	}
+	if v := m.requests.Value(unmatchedRoute, "BOGUS", "405"); v != 0 {
+		t.Errorf("Expected no series for an unknown method, got %v", v)
+	}
+	if n := m.requestTime.Count("/", "GET", "200"); n != 2 {
+		t.Errorf("Expected 2 request time observations, got %d", n)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/_node/_local/_prometheus", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Unexpected status %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `couchdb_httpd_requests_total{route="/",method="GET",status="200"} 2`+"\n") {
+		t.Errorf("Request count missing from Prometheus output:\n%s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/_node/_local/_stats", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Unexpected status %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `"requests":{"/":{"GET":{"200":{"desc":"number of HTTP requests","type":"counter","value":2}}}`) {
+		t.Errorf("Request count missing from stats:\n%s", w.Body.String())
+	}
+}
+
+func TestDriverMetrics(t *testing.T) {
+	client, err := kivik.New("memory", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Service{Client: client, LogWriter: &initCounter{}}
+	handler, err := s.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/_all_dbs", nil))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/_node/_local/_prometheus", nil))
+	expected := `couchdb_driver_call_time_seconds_count{driver="memory",method="AllDBsContext",status="0"} 1`
+	if !strings.Contains(w.Body.String(), expected+"\n") {
+		t.Errorf("Driver call time missing from Prometheus output:\n%s", w.Body.String())
+	}
+}
@@ -0,0 +1,115 @@
+package serve
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/NYTimes/gziphandler"
+
+	"github.com/flimzy/kivik/errors"
+)
+
+// subscribe registers for notification of configuration changes which can be
+// applied to the running server. Subscriptions from a previous call to Init
+// are cancelled.
+func (s *Service) subscribe() {
+	for _, unsubscribe := range s.unsubscribe {
+		unsubscribe()
+	}
+	s.unsubscribe = []func(){
+		s.Config().Subscribe(s.configChanged),
+	}
+}
+
+func (s *Service) configChanged(secName, key string) {
+	switch secName {
+	case "log":
+		if err := s.initLogWriter(); err != nil {
+			s.Error("Failed to apply log.%s change: %s", key, err)
+			return
+		}
+		s.Info("Applied log.%s change", key)
+	case "couch_httpd_auth":
+		switch key {
+		case "secret":
+			s.Info("couch_httpd_auth.secret changed; session cookies signed with the old secret will be rejected")
+		case "timeout":
+			s.Info("couch_httpd_auth.timeout changed; new sessions will use the new timeout")
+		}
+	}
+}
+
+// initLogWriter (re)initializes the LogWriter with the current log
+// configuration. CouchDB log levels unknown to the logger package are passed
+// on as the nearest known level.
+func (s *Service) initLogWriter() error {
+	if s.LogWriter == nil {
+		return nil
+	}
+	logConf, _ := s.Config().GetSection("log")
+	if level, ok := couchLogLevels[strings.ToLower(logConf["level"])]; ok {
+		conf := make(map[string]string, len(logConf))
+		for key, value := range logConf {
+			conf[key] = value
+		}
+		conf["level"] = level
+		logConf = conf
+	}
+	if err := s.LogWriter.Init(logConf); err != nil {
+		return errors.Wrap(err, "failed to initialize logger")
+	}
+	return nil
+}
+
+// swapHandler is an http.Handler which may be replaced while serving.
+type swapHandler struct {
+	mu sync.RWMutex
+	h  http.Handler
+}
+
+func (h *swapHandler) set(handler http.Handler) {
+	h.mu.Lock()
+	h.h = handler
+	h.mu.Unlock()
+}
+
+func (h *swapHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.RLock()
+	handler := h.h
+	h.mu.RUnlock()
+	handler.ServeHTTP(w, r)
+}
+
+// gzipHandler compresses responses according to httpd.enable_compression and
+// httpd.compression_level. Changes to either are applied without a restart.
+func gzipHandler(s *Service) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		h := &swapHandler{}
+		h.set(s.compressionHandler(next))
+		s.unsubscribe = append(s.unsubscribe, s.Config().Subscribe(func(secName, key string) {
+			if secName == "httpd" && (key == "compression_level" || key == "enable_compression") {
+				h.set(s.compressionHandler(next))
+			}
+		}))
+		return h
+	}
+}
+
+func (s *Service) compressionHandler(next http.Handler) http.Handler {
+	if s.Config().IsSet("httpd", "enable_compression") && !s.Config().GetBool("httpd", "enable_compression") {
+		s.Info("HTTPD compression disabled")
+		return next
+	}
+	level := s.Config().GetInt("httpd", "compression_level")
+	if level == 0 {
+		level = 8
+	}
+	gzipHandler, err := gziphandler.NewGzipLevelHandler(int(level))
+	if err != nil {
+		s.Warn("invalid httpd.compression_level '%d'", level)
+		return next
+	}
+	s.Info("Enabling HTTPD compression, level %d", level)
+	return gzipHandler(next)
+}
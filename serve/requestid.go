@@ -0,0 +1,63 @@
+package serve
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"golang.org/x/net/context"
+)
+
+// Request ID headers. X-Request-ID is honored if sent by the client. Both are
+// set on every response.
+const (
+	HeaderRequestID      = "X-Request-ID"
+	HeaderCouchRequestID = "X-Couch-Request-ID"
+)
+
+// maxRequestIDLength is the maximum length of a client-supplied request ID.
+const maxRequestIDLength = 200
+
+// GetRequestID returns the ID of the request, or an empty string if none has
+// been assigned.
+func GetRequestID(r *http.Request) string {
+	id, _ := r.Context().Value(RequestIDKey).(string)
+	return id
+}
+
+// requestID assigns an ID to each request, and sets it in the response
+// headers.
+func requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(HeaderRequestID)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(HeaderRequestID, id)
+		w.Header().Set(HeaderCouchRequestID, id)
+		r = r.WithContext(context.WithValue(r.Context(), RequestIDKey, id))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// validRequestID returns true if id is a non-empty string of printable ASCII
+// characters, which is safe to log and echo back.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,64 @@
+package serve
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/flimzy/kivik/errors"
+)
+
+type requestIDTest struct {
+	Name     string
+	Header   string
+	Expected string
+}
+
+func TestRequestID(t *testing.T) {
+	tests := []requestIDTest{
+		{Name: "Generated"},
+		{Name: "Honored", Header: "client-id-1", Expected: "client-id-1"},
+		{Name: "Invalid", Header: "has spaces"},
+	}
+	for _, test := range tests {
+		func(test requestIDTest) {
+			t.Run(test.Name, func(t *testing.T) {
+				var ctxID string
+				h := requestID(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+					ctxID = GetRequestID(r)
+				}))
+				req := httptest.NewRequest("GET", "/", nil)
+				if test.Header != "" {
+					req.Header.Set(HeaderRequestID, test.Header)
+				}
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, req)
+				id := w.Header().Get(HeaderRequestID)
+				if test.Expected != "" && id != test.Expected {
+					t.Errorf("Expected ID %s, got %s", test.Expected, id)
+				}
+				if len(id) == 0 || id == test.Header && test.Expected == "" {
+					t.Errorf("Expected a generated ID, got '%s'", id)
+				}
+				if couchID := w.Header().Get(HeaderCouchRequestID); couchID != id {
+					t.Errorf("X-Couch-Request-ID %s does not match %s", couchID, id)
+				}
+				if ctxID != id {
+					t.Errorf("Context ID %s does not match %s", ctxID, id)
+				}
+			})
+		}(test)
+	}
+}
+
+func TestReportErrorRequestID(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req = req.WithContext(context.WithValue(req.Context(), RequestIDKey, "abc123"))
+	w := httptest.NewRecorder()
+	reportError(w, req, errors.Status(http.StatusNotFound, "missing"))
+	expected := `{"error":"not found","reason":"missing","request_id":"abc123"}` + "\n"
+	if body := w.Body.String(); body != expected {
+		t.Errorf("Unexpected body: %s", body)
+	}
+}
@@ -3,7 +3,6 @@ package serve
 import (
 	"net/http"
 
-	"github.com/NYTimes/gziphandler"
 	"github.com/dimfeld/httptreemux"
 	"github.com/justinas/alice"
 )
@@ -11,47 +10,51 @@ import (
 func (s *Service) setupRoutes() (http.Handler, error) {
 	router := httptreemux.New()
 	router.HeadCanUseGet = true
-	ctxRoot := router.UsingContext()
+	ctxRoot := routeGroup{router.UsingContext()}
 	ctxRoot.Handler(mGET, "/", handler(root))
 	ctxRoot.Handler(mGET, "/favicon.ico", handler(favicon))
 	ctxRoot.Handler(mGET, "/_all_dbs", handler(allDBs))
-	ctxRoot.Handler(mGET, "/_log", handler(log))
-	ctxRoot.Handler(mPUT, "/:db", handler(createDB))
-	ctxRoot.Handler(mHEAD, "/:db", handler(dbExists))
-	ctxRoot.Handler(mPOST, "/:db/_ensure_full_commit", handler(flush))
-	ctxRoot.Handler(mGET, "/_config", handler(getConfig))
-	ctxRoot.Handler(mGET, "/_config/:section", handler(getConfigSection))
-	ctxRoot.Handler(mGET, "/_config/:section/:key", handler(getConfigItem))
+	ctxRoot.Handler(mGET, "/_log", handler(adminRequired(log)))
+	ctxRoot.Handler(mGET, "/_active_tasks", handler(adminRequired(activeTasks)))
+	ctxRoot.Handler(mPUT, "/:db", handler(adminRequired(createDB)))
+	ctxRoot.Handler(mDELETE, "/:db", handler(adminRequired(destroyDB)))
+	ctxRoot.Handler(mHEAD, "/:db", handler(dbMemberRequired(dbExists)))
+	ctxRoot.Handler(mPOST, "/:db/_ensure_full_commit", handler(dbMemberRequired(flush)))
+	ctxRoot.Handler(mGET, "/:db/_changes", handler(dbMemberRequired(changes)))
+	ctxRoot.Handler(mGET, "/:db/_security", handler(dbMemberRequired(getSecurityDoc)))
+	ctxRoot.Handler(mPUT, "/:db/_security", handler(dbAdminRequired(putSecurityDoc)))
+	ctxRoot.Handler(mGET, "/:db/_design/:ddoc", handler(dbMemberRequired(getDoc)))
+	ctxRoot.Handler(mPUT, "/:db/_design/:ddoc", handler(dbAdminRequired(putDoc)))
+	ctxRoot.Handler(mDELETE, "/:db/_design/:ddoc", handler(dbAdminRequired(deleteDoc)))
+	ctxRoot.Handler(mGET, "/:db/:docid", handler(dbMemberRequired(getDoc)))
+	ctxRoot.Handler(mPUT, "/:db/:docid", handler(dbMemberRequired(putDoc)))
+	ctxRoot.Handler(mDELETE, "/:db/:docid", handler(dbMemberRequired(deleteDoc)))
+	ctxRoot.Handler(mGET, "/_config", handler(adminRequired(getConfig)))
+	ctxRoot.Handler(mGET, "/_config/_origins", handler(adminRequired(getConfigOrigins)))
+	ctxRoot.Handler(mGET, "/_config/_schema", handler(adminRequired(getConfigSchema)))
+	ctxRoot.Handler(mGET, "/_config/:section", handler(adminRequired(getConfigSection)))
+	ctxRoot.Handler(mGET, "/_config/:section/:key", handler(adminRequired(getConfigItem)))
+	ctxRoot.Handler(mPUT, "/_config/:section/:key", handler(adminRequired(putConfigItem)))
+	ctxRoot.Handler(mDELETE, "/_config/:section/:key", handler(adminRequired(deleteConfigItem)))
+
+	ctxRoot.Handler(mGET, "/_node/_local/_stats", handler(adminRequired(getStats)))
+	ctxRoot.Handler(mGET, "/_node/_local/_prometheus", handler(adminRequired(getPrometheus)))
 
 	ctxRoot.Handler(mGET, "/_session", handler(getSession))
 	// Note that DELETE and POST for the /_session endpoint are handled by the
 	// cookie auth handler. This means if you aren't using cookie auth, that
 	// these methods will return 405.
 
-	// ctxRoot.Handler(mDELETE, "/:db", handler(destroyDB) )
 	// ctxRoot.Handler(http.MethodGet, "/:db", handler(getDB))
 
 	return alice.New(
 		setContext(s),
+		requestID,
 		setSession(),
 		requestLogger,
+		metricsHandler,
+		corsHandler,
 		gzipHandler(s),
 		authHandler,
 	).Then(router), nil
 }
-
-func gzipHandler(s *Service) func(http.Handler) http.Handler {
-	level := s.Config().GetInt("httpd", "compression_level")
-	if level == 0 {
-		level = 8
-	}
-	gzipHandler, err := gziphandler.NewGzipLevelHandler(int(level))
-	if err != nil {
-		s.Warn("invalid httpd.compression_level '%s'", level)
-		return func(h http.Handler) http.Handler {
-			return h
-		}
-	}
-	s.Info("Enabling HTTPD cmpression, level %d", level)
-	return gzipHandler
-}
@@ -0,0 +1,114 @@
+package serve
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/flimzy/kivik/config"
+	"github.com/flimzy/kivik/logger"
+)
+
+// couchLogLevels maps the log levels accepted by CouchDB, which have no
+// equivalent logger.LogLevel, to the nearest level.
+var couchLogLevels = map[string]string{
+	"notice":    "info",
+	"err":       "error",
+	"critical":  "error",
+	"crit":      "error",
+	"alert":     "error",
+	"emergency": "error",
+	"emerg":     "error",
+	"none":      "error",
+}
+
+// logLevelNone is above every logger.LogLevel, so disables logging, as
+// log.level = none does in CouchDB.
+const logLevelNone = logger.LogLevelError + 1
+
+// logLevel returns the minimum level of messages to log, for a log.level
+// value.
+func logLevel(value string) (logger.LogLevel, bool) {
+	value = strings.ToLower(value)
+	if value == "none" {
+		return logLevelNone, true
+	}
+	if level, ok := couchLogLevels[value]; ok {
+		value = level
+	}
+	return logger.StringToLogLevel(value)
+}
+
+func init() {
+	config.Register(
+		&config.Key{Section: "httpd", Name: "bind_address",
+			Description: "IP address on which to listen for HTTP requests"},
+		&config.Key{Section: "httpd", Name: "port", Type: config.TypeInt, Min: 0, Max: 65535,
+			Description: "Port on which to listen for HTTP requests"},
+		&config.Key{Section: "httpd", Name: "enable_compression", Type: config.TypeBool,
+			Description: "Compress responses with gzip, when the client supports it"},
+		&config.Key{Section: "httpd", Name: "compression_level", Type: config.TypeInt, Min: 0, Max: 9,
+			Description: "gzip compression level, from 0 (none) to 9 (smallest)"},
+		&config.Key{Section: "httpd", Name: "enable_cors", Type: config.TypeBool,
+			Description: "Enable Cross-Origin Resource Sharing, as configured in the [cors] section"},
+		&config.Key{Section: "httpd", Name: "authentication_handlers",
+			Description: "Authentication handlers to use, in order"},
+		&config.Key{Section: "httpd", Name: "WWW-Authenticate",
+			Description: "Challenge sent with 401 Unauthorized responses"},
+		&config.Key{Section: "log", Name: "level", Default: "info",
+			Values:      []string{"debug", "info", "notice", "warn", "warning", "error", "err", "critical", "crit", "alert", "emergency", "emerg", "none"},
+			Description: "Minimum level of messages to log"},
+		&config.Key{Section: "couch_httpd_auth", Name: "authentication_db", Default: defaultUsersDB,
+			Description: "Database containing user documents"},
+		&config.Key{Section: "couch_httpd_auth", Name: "secret",
+			Description: "Secret used to sign session cookies"},
+		&config.Key{Section: "couch_httpd_auth", Name: "timeout", Type: config.TypeInt, Min: 1, Max: 1 << 31,
+			Description: "Session cookie lifetime, in seconds"},
+		&config.Key{Section: "couch_httpd_auth", Name: "require_valid_user", Type: config.TypeBool,
+			Description: "Reject requests from anonymous users"},
+		&config.Key{Section: "chttpd", Name: "require_valid_user", Type: config.TypeBool,
+			Description: "Reject requests from anonymous users"},
+		&config.Key{Section: "cors", Name: "origins",
+			Description: "Comma-separated list of permitted origins, or *"},
+		&config.Key{Section: "cors", Name: "credentials", Type: config.TypeBool,
+			Description: "Allow credentials in CORS requests"},
+		&config.Key{Section: "cors", Name: "methods",
+			Description: "Comma-separated list of permitted methods"},
+		&config.Key{Section: "cors", Name: "headers",
+			Description: "Comma-separated list of permitted request headers"},
+		&config.Key{Section: "cors", Name: "max_age", Type: config.TypeInt, Min: 0, Max: 1 << 31,
+			Description: "Time, in seconds, for which preflight responses may be cached"},
+		&config.Key{Section: "ssl", Name: "enable", Type: config.TypeBool,
+			Description: "Enable HTTPS"},
+		&config.Key{Section: "ssl", Name: "port", Type: config.TypeInt, Min: 0, Max: 65535, Default: strconv.Itoa(DefaultSSLPort),
+			Description: "Port on which to listen for HTTPS requests"},
+		&config.Key{Section: "ssl", Name: "cert_file",
+			Description: "PEM-encoded server certificate"},
+		&config.Key{Section: "ssl", Name: "key_file",
+			Description: "PEM-encoded server private key"},
+		&config.Key{Section: "ssl", Name: "cacert_file",
+			Description: "PEM-encoded CA certificates used to verify client certificates"},
+		&config.Key{Section: "ssl", Name: "verify_ssl_certificates", Type: config.TypeBool,
+			Description: "Request and verify client certificates"},
+		&config.Key{Section: "ssl", Name: "fail_if_no_peer_cert", Type: config.TypeBool,
+			Description: "Reject clients which don't present a certificate"},
+		&config.Key{Section: "ssl", Name: "tls_versions",
+			Description: "Permitted TLS versions, e.g. [tlsv1.2, 'tlsv1.3']. Defaults to TLS 1.2 and later"},
+	)
+}
+
+// getConfigSchema describes the registered configuration keys.
+func getConfigSchema(w http.ResponseWriter, r *http.Request) error {
+	schema := GetService(r).Config().Schema()
+	if schema == nil {
+		schema = config.DefaultSchema
+	}
+	result := make(map[string]map[string]*config.Key)
+	for _, k := range schema.Keys() {
+		if _, ok := result[k.Section]; !ok {
+			result[k.Section] = make(map[string]*config.Key)
+		}
+		result[k.Section][k.Name] = k
+	}
+	return serveJSON(w, result)
+}
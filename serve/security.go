@@ -0,0 +1,181 @@
+package serve
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/flimzy/kivik"
+	"github.com/flimzy/kivik/authdb"
+	"github.com/flimzy/kivik/errors"
+)
+
+// adminRole is the role which identifies a server admin.
+const adminRole = "_admin"
+
+func hasRole(user *authdb.UserContext, roles []string) bool {
+	if user == nil {
+		return false
+	}
+	for _, want := range roles {
+		for _, role := range user.Roles {
+			if role == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasName(user *authdb.UserContext, names []string) bool {
+	if user == nil || user.Name == "" {
+		return false
+	}
+	for _, name := range names {
+		if name == user.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// isServerAdmin returns true if the user has the _admin role.
+func isServerAdmin(user *authdb.UserContext) bool {
+	return hasRole(user, []string{adminRole})
+}
+
+// isDBAdmin returns true if the user is a server admin, or is listed in the
+// admins section of the database's security object.
+func isDBAdmin(user *authdb.UserContext, sec *kivik.Security) bool {
+	if isServerAdmin(user) {
+		return true
+	}
+	return hasName(user, sec.Admins.Names) || hasRole(user, sec.Admins.Roles)
+}
+
+// isDBMember returns true if the user is a database admin, or is listed in the
+// members section of the database's security object. A database with no
+// members is public, so every user is considered a member.
+func isDBMember(user *authdb.UserContext, sec *kivik.Security) bool {
+	if len(sec.Members.Names) == 0 && len(sec.Members.Roles) == 0 {
+		return true
+	}
+	if isDBAdmin(user, sec) {
+		return true
+	}
+	return hasName(user, sec.Members.Names) || hasRole(user, sec.Members.Roles)
+}
+
+// denied returns a 401 error for unauthenticated users, or a 403 error for
+// authenticated users who lack the required privileges.
+func denied(user *authdb.UserContext, reason string) error {
+	if user == nil {
+		return errors.Status(http.StatusUnauthorized, reason)
+	}
+	return errors.Status(http.StatusForbidden, reason)
+}
+
+func checkServerAdmin(user *authdb.UserContext) error {
+	if isServerAdmin(user) {
+		return nil
+	}
+	return denied(user, "You are not a server admin.")
+}
+
+func checkDBAdmin(user *authdb.UserContext, sec *kivik.Security) error {
+	if isDBAdmin(user, sec) {
+		return nil
+	}
+	return denied(user, "You are not a db or server admin.")
+}
+
+func checkDBMember(user *authdb.UserContext, sec *kivik.Security) error {
+	if isDBMember(user, sec) {
+		return nil
+	}
+	return denied(user, "You are not allowed to access this db.")
+}
+
+// getSecurity fetches the security object for the database named in the
+// request.
+func getSecurity(r *http.Request) (*kivik.Security, error) {
+	db, err := getClient(r).DBContext(r.Context(), getParams(r)["db"])
+	if err != nil {
+		return nil, err
+	}
+	return db.SecurityContext(r.Context())
+}
+
+func currentUser(r *http.Request) *authdb.UserContext {
+	return MustGetSession(r.Context()).User
+}
+
+// adminRequired wraps next, so that it may only be called by server admins.
+func adminRequired(next handler) handler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		if err := checkServerAdmin(currentUser(r)); err != nil {
+			return err
+		}
+		return next(w, r)
+	}
+}
+
+// dbAdminRequired wraps next, so that it may only be called by admins of the
+// requested database.
+func dbAdminRequired(next handler) handler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		user := currentUser(r)
+		if !isServerAdmin(user) {
+			sec, err := getSecurity(r)
+			if err != nil {
+				return err
+			}
+			if err := checkDBAdmin(user, sec); err != nil {
+				return err
+			}
+		}
+		return next(w, r)
+	}
+}
+
+// dbMemberRequired wraps next, so that it may only be called by members of
+// the requested database.
+func dbMemberRequired(next handler) handler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		user := currentUser(r)
+		if !isServerAdmin(user) {
+			sec, err := getSecurity(r)
+			if err != nil {
+				return err
+			}
+			if err := checkDBMember(user, sec); err != nil {
+				return err
+			}
+		}
+		return next(w, r)
+	}
+}
+
+func getSecurityDoc(w http.ResponseWriter, r *http.Request) error {
+	sec, err := getSecurity(r)
+	if err != nil {
+		return err
+	}
+	return serveJSON(w, sec)
+}
+
+func putSecurityDoc(w http.ResponseWriter, r *http.Request) error {
+	sec := &kivik.Security{}
+	if err := json.NewDecoder(r.Body).Decode(sec); err != nil {
+		return errors.WrapStatus(http.StatusBadRequest, err)
+	}
+	db, err := getClient(r).DBContext(r.Context(), getParams(r)["db"])
+	if err != nil {
+		return err
+	}
+	if err := db.SetSecurityContext(r.Context(), sec); err != nil {
+		return err
+	}
+	return serveJSON(w, map[string]interface{}{
+		"ok": true,
+	})
+}
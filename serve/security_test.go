@@ -0,0 +1,71 @@
+package serve
+
+import (
+	"testing"
+
+	"github.com/flimzy/kivik"
+	"github.com/flimzy/kivik/authdb"
+	"github.com/flimzy/kivik/errors"
+)
+
+type securityTest struct {
+	Name        string
+	User        *authdb.UserContext
+	Security    *kivik.Security
+	AdminStatus int
+	MemberStat  int
+}
+
+func TestDBSecurity(t *testing.T) {
+	restricted := &kivik.Security{
+		Admins:  kivik.Members{Names: []string{"bob"}, Roles: []string{"boss"}},
+		Members: kivik.Members{Names: []string{"alice"}, Roles: []string{"staff"}},
+	}
+	tests := []securityTest{
+		{Name: "AnonPublic", Security: &kivik.Security{},
+			AdminStatus: kivik.StatusUnauthorized},
+		{Name: "AnonRestricted", Security: restricted,
+			AdminStatus: kivik.StatusUnauthorized, MemberStat: kivik.StatusUnauthorized},
+		{Name: "ServerAdmin", Security: restricted,
+			User: &authdb.UserContext{Name: "root", Roles: []string{"_admin"}}},
+		{Name: "AdminByName", Security: restricted,
+			User: &authdb.UserContext{Name: "bob"}},
+		{Name: "AdminByRole", Security: restricted,
+			User: &authdb.UserContext{Name: "carol", Roles: []string{"boss"}}},
+		{Name: "MemberByName", Security: restricted,
+			User:        &authdb.UserContext{Name: "alice"},
+			AdminStatus: kivik.StatusForbidden},
+		{Name: "MemberByRole", Security: restricted,
+			User:        &authdb.UserContext{Name: "dave", Roles: []string{"staff"}},
+			AdminStatus: kivik.StatusForbidden},
+		{Name: "Stranger", Security: restricted,
+			User:        &authdb.UserContext{Name: "eve", Roles: []string{"other"}},
+			AdminStatus: kivik.StatusForbidden, MemberStat: kivik.StatusForbidden},
+	}
+	for _, test := range tests {
+		func(test securityTest) {
+			t.Run(test.Name, func(t *testing.T) {
+				if status := errors.StatusCode(checkDBAdmin(test.User, test.Security)); status != test.AdminStatus {
+					t.Errorf("Unexpected admin status. Expected %d, Actual %d", test.AdminStatus, status)
+				}
+				if status := errors.StatusCode(checkDBMember(test.User, test.Security)); status != test.MemberStat {
+					t.Errorf("Unexpected member status. Expected %d, Actual %d", test.MemberStat, status)
+				}
+			})
+		}(test)
+	}
+}
+
+func TestCheckServerAdmin(t *testing.T) {
+	if status := errors.StatusCode(checkServerAdmin(nil)); status != kivik.StatusUnauthorized {
+		t.Errorf("Expected 401 for anonymous user, got %d", status)
+	}
+	user := &authdb.UserContext{Name: "bob", Roles: []string{"boss"}}
+	if status := errors.StatusCode(checkServerAdmin(user)); status != kivik.StatusForbidden {
+		t.Errorf("Expected 403 for non-admin user, got %d", status)
+	}
+	user.Roles = append(user.Roles, "_admin")
+	if err := checkServerAdmin(user); err != nil {
+		t.Errorf("Unexpected error for admin user: %s", err)
+	}
+}
@@ -2,11 +2,14 @@ package serve
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/flimzy/kivik"
 	"github.com/flimzy/kivik/auth"
@@ -14,6 +17,8 @@ import (
 	"github.com/flimzy/kivik/config"
 	"github.com/flimzy/kivik/errors"
 	"github.com/flimzy/kivik/logger"
+	"github.com/flimzy/kivik/tasks"
+	"golang.org/x/net/http2"
 )
 
 // Version is the version of this library.
@@ -61,34 +66,64 @@ type Service struct {
 	config *config.Config
 
 	// authHandlers is a map version of AuthHandlers for easier internal
-	// use.
+	// use, and authHandlerNames lists the handlers' names in the order
+	// configured.
 	authHandlers     map[string]auth.Handler
 	authHandlerNames []string
+
+	// unsubscribe cancels the configuration subscriptions made by Init.
+	unsubscribe []func()
+
+	metricsOnce sync.Once
+	metrics     *serviceMetrics
+
+	// client is Client, instrumented by Init to record driver metrics.
+	client *kivik.Client
+
+	tasksOnce sync.Once
+	tasks     *tasks.Registry
+
+	// mu guards servers, done and drained
+	mu      sync.Mutex
+	servers []*server
+	// done is closed when Shutdown is called, and drained once Shutdown has
+	// finished waiting for in-flight requests.
+	done    chan struct{}
+	drained chan struct{}
 }
 
 // Config returns a connection to the configuration backend.
 func (s *Service) Config() *config.Config {
 	if s.config == nil {
-		s.config = defaultConfig()
+		s.SetConfig(defaultConfig())
 	}
 	return s.config
 }
 
-// SetConfig sets the configuration backend.
-func (s *Service) SetConfig(config *config.Config) {
-	s.config = config
+// SetConfig sets the configuration backend. If the configuration has no
+// schema attached, config.DefaultSchema is attached, so that invalid values
+// are rejected.
+func (s *Service) SetConfig(conf *config.Config) {
+	if conf.Schema() == nil {
+		conf.SetSchema(config.DefaultSchema)
+	}
+	s.config = conf
 }
 
 // Init initializes a configured server. This is automatically called when
 // Start() is called, so this is meant to be used if you want to bind the server
-// yourself.
+// yourself. Configured values which are invalid according to the
+// configuration schema are logged as warnings, so that existing
+// configurations continue to work, though new invalid values are rejected.
 func (s *Service) Init() (http.Handler, error) {
-	if s.LogWriter != nil {
-		logConf, _ := s.Config().GetSection("log")
-		if err := s.LogWriter.Init(logConf); err != nil {
-			return nil, errors.Wrap(err, "failed to initialize logger")
-		}
+	if err := s.initLogWriter(); err != nil {
+		return nil, err
+	}
+	if err := s.Config().Validate(); err != nil {
+		s.Warn("Invalid configuration: %s", errors.Reason(err))
 	}
+	s.subscribe()
+	s.instrumentClient()
 	s.authHandlersSetup()
 	if s.Config().GetString("couch_httpd_auth", "secret") == "" {
 		s.Warn("couch_httpd_auth.secret is not set. This is insecure!")
@@ -96,18 +131,173 @@ func (s *Service) Init() (http.Handler, error) {
 	return s.setupRoutes()
 }
 
-// Start begins serving connections.
+// Start begins serving connections. Plain HTTP is served on httpd.port and, if
+// ssl.enable is true, HTTPS is served on ssl.port. Start blocks until the
+// service fails, or is stopped with Shutdown, in which case nil is returned
+// once Shutdown has completed.
 func (s *Service) Start() error {
-	server, err := s.Init()
+	handler, err := s.Init()
 	if err != nil {
 		return err
 	}
-	addr := fmt.Sprintf("%s:%d",
-		s.Config().GetString("httpd", "bind_address"),
-		s.Config().GetInt("httpd", "port"),
-	)
-	s.Info("Listening on %s", addr)
-	return http.ListenAndServe(addr, server)
+	host := s.Config().GetString("httpd", "bind_address")
+	servers := []*server{
+		{
+			addr: fmt.Sprintf("%s:%d", host, s.Config().GetInt("httpd", "port")),
+			srv:  &http.Server{Handler: handler},
+		},
+	}
+	if s.sslEnabled() {
+		tlsConf, err := s.tlsConfig()
+		if err != nil {
+			return err
+		}
+		port := s.Config().GetInt("ssl", "port")
+		if port == 0 {
+			port = DefaultSSLPort
+		}
+		srv := &http.Server{Handler: handler, TLSConfig: tlsConf}
+		if err := http2.ConfigureServer(srv, &http2.Server{}); err != nil {
+			return errors.Wrap(err, "failed to configure HTTP/2")
+		}
+		servers = append(servers, &server{
+			addr: fmt.Sprintf("%s:%d", host, port),
+			srv:  srv,
+			tls:  true,
+		})
+	}
+	if err := s.listen(servers); err != nil {
+		if err == errShutdown {
+			return nil
+		}
+		return err
+	}
+	errc := make(chan error, len(servers))
+	for _, srv := range servers {
+		go func(srv *server) {
+			errc <- srv.srv.Serve(srv.ln)
+		}(srv)
+	}
+	for range servers {
+		err := <-errc
+		select {
+		case <-s.shuttingDown():
+			// Errors are expected after Shutdown has closed the listeners.
+			continue
+		default:
+		}
+		_ = s.Shutdown(context.Background())
+		return err
+	}
+	<-s.shutdownComplete()
+	return nil
+}
+
+// server is a single HTTP or HTTPS listener.
+type server struct {
+	addr string
+	srv  *http.Server
+	ln   net.Listener
+	tls  bool
+}
+
+// errShutdown is returned by listen if Shutdown has already been called.
+var errShutdown = errors.New("kivik: service shut down")
+
+// listen opens all of the listeners before any of them are served, so that a
+// failure to bind any port is reported immediately.
+func (s *Service) listen(servers []*server) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.initShutdown()
+	select {
+	case <-s.done:
+		return errShutdown
+	default:
+	}
+	for _, srv := range servers {
+		ln, err := net.Listen("tcp", srv.addr)
+		if err != nil {
+			for _, opened := range servers {
+				if opened.ln != nil {
+					_ = opened.ln.Close()
+				}
+			}
+			return err
+		}
+		if srv.tls {
+			ln = tls.NewListener(ln, srv.srv.TLSConfig)
+			s.Info("Listening on %s (HTTPS)", srv.addr)
+		} else {
+			s.Info("Listening on %s", srv.addr)
+		}
+		srv.ln = ln
+	}
+	s.servers = servers
+	return nil
+}
+
+// initShutdown creates the shutdown channels, if they don't yet exist. The
+// caller must hold s.mu.
+func (s *Service) initShutdown() {
+	if s.done == nil {
+		s.done = make(chan struct{})
+		s.drained = make(chan struct{})
+	}
+}
+
+// shuttingDown returns a channel which is closed when Shutdown is called.
+func (s *Service) shuttingDown() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.initShutdown()
+	return s.done
+}
+
+// shutdownComplete returns a channel which is closed when Shutdown has
+// finished.
+func (s *Service) shutdownComplete() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.initShutdown()
+	return s.drained
+}
+
+// Shutdown gracefully stops the service. The listeners are closed immediately,
+// and continuous changes feeds are terminated. Other in-flight requests are
+// allowed to complete, until ctx is cancelled. Subsequent calls wait for the
+// first to complete, until ctx is cancelled.
+func (s *Service) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.initShutdown()
+	select {
+	case <-s.done:
+		drained := s.drained
+		s.mu.Unlock()
+		select {
+		case <-drained:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	default:
+		close(s.done)
+	}
+	defer close(s.drained)
+	servers := s.servers
+	s.servers = nil
+	for _, unsubscribe := range s.unsubscribe {
+		unsubscribe()
+	}
+	s.unsubscribe = nil
+	s.mu.Unlock()
+	var firstErr error
+	for _, srv := range servers {
+		if err := shutdownServer(ctx, srv); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
 }
 
 func (s *Service) authHandlersSetup() {
@@ -124,6 +314,13 @@ func (s *Service) authHandlersSetup() {
 		s.authHandlers[name] = handler
 		s.authHandlerNames = append(s.authHandlerNames, name)
 	}
+	if value := s.Config().GetString("httpd", "authentication_handlers"); value != "" {
+		for _, name := range parseAuthHandlers(value) {
+			if _, ok := s.authHandlers[name]; !ok {
+				s.Warn("httpd.authentication_handlers: no auth handler registered for `%s`", name)
+			}
+		}
+	}
 	if s.UserStore == nil {
 		s.UserStore = &perpetualAdminParty{}
 	}
@@ -187,16 +384,21 @@ type handler func(w http.ResponseWriter, r *http.Request) error
 
 func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if err := h(w, r); err != nil {
-		reportError(w, err)
+		reportError(w, r, err)
 	}
 }
 
-func reportError(w http.ResponseWriter, err error) {
+func reportError(w http.ResponseWriter, r *http.Request, err error) {
 	w.Header().Add("Content-Type", typeJSON)
 	status := errors.StatusCode(err)
 	if status == 0 {
 		status = 500
 	}
+	if status == http.StatusUnauthorized {
+		if challenge := GetService(r).wwwAuthenticate(); challenge != "" {
+			w.Header().Set("WWW-Authenticate", challenge)
+		}
+	}
 	w.WriteHeader(status)
 	short := err.Error()
 	reason := errors.Reason(err)
@@ -205,10 +407,14 @@ func reportError(w http.ResponseWriter, err error) {
 	} else {
 		short = strings.ToLower(http.StatusText(status))
 	}
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	body := map[string]interface{}{
 		"error":  short,
 		"reason": reason,
-	})
+	}
+	if id := GetRequestID(r); id != "" {
+		body["request_id"] = id
+	}
+	json.NewEncoder(w).Encode(body)
 }
 
 func root(w http.ResponseWriter, r *http.Request) error {
@@ -254,6 +460,17 @@ func createDB(w http.ResponseWriter, r *http.Request) error {
 	})
 }
 
+func destroyDB(w http.ResponseWriter, r *http.Request) error {
+	params := getParams(r)
+	client := getClient(r)
+	if err := client.DestroyDBContext(r.Context(), params["db"]); err != nil {
+		return err
+	}
+	return serveJSON(w, map[string]interface{}{
+		"ok": true,
+	})
+}
+
 func dbExists(w http.ResponseWriter, r *http.Request) error {
 	params := getParams(r)
 	client := getClient(r)
@@ -1,6 +1,19 @@
 package serve
 
-import "testing"
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flimzy/kivik/config"
+	"github.com/flimzy/kivik/logger"
+	"github.com/flimzy/kivik/logger/memlogger"
+	"github.com/flimzy/kivik/serve/config/memconf"
+)
 
 func TestBind(t *testing.T) {
 	s := &Service{}
@@ -14,3 +27,219 @@ func TestBind(t *testing.T) {
 		t.Errorf("Port is '%d', expected '9000'", port)
 	}
 }
+
+type initCounter struct {
+	inits int
+	level string
+}
+
+func (l *initCounter) Init(conf map[string]string) error {
+	l.inits++
+	l.level = conf["level"]
+	return nil
+}
+
+func (l *initCounter) WriteLog(_ logger.LogLevel, _ string) error { return nil }
+
+func TestLiveLogReconfig(t *testing.T) {
+	lw := &initCounter{}
+	s := &Service{LogWriter: lw}
+	if _, err := s.Init(); err != nil {
+		t.Fatalf("Init failed: %s", err)
+	}
+	if err := s.Config().Set("log", "level", "debug"); err != nil {
+		t.Fatal(err)
+	}
+	if lw.inits != 2 {
+		t.Errorf("Expected LogWriter to be initialized twice, got %d", lw.inits)
+	}
+	if lw.level != "debug" {
+		t.Errorf("Expected new log level 'debug', got '%s'", lw.level)
+	}
+	// A second Init must not leave stale subscriptions behind
+	if _, err := s.Init(); err != nil {
+		t.Fatalf("Init failed: %s", err)
+	}
+	_ = s.Config().Set("log", "level", "info")
+	if lw.inits != 4 {
+		t.Errorf("Expected 4 initializations, got %d", lw.inits)
+	}
+}
+
+func TestStartShutdown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+	s := &Service{LogWriter: &initCounter{}}
+	if err = s.Bind(addr); err != nil {
+		t.Fatal(err)
+	}
+	result := make(chan error)
+	go func() {
+		result <- s.Start()
+	}()
+	var resp *http.Response
+	for i := 0; i < 100; i++ {
+		if resp, err = http.Get("http://" + addr + "/"); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("Server never started: %s", err)
+	}
+	_ = resp.Body.Close()
+	if err = s.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown failed: %s", err)
+	}
+	select {
+	case err = <-result:
+		if err != nil {
+			t.Errorf("Start returned an error after Shutdown: %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("Start did not return after Shutdown")
+	}
+}
+
+func TestGzipReconfig(t *testing.T) {
+	s := &Service{LogWriter: &initCounter{}}
+	body := strings.Repeat("x", 4096)
+	h := gzipHandler(s)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(body))
+	}))
+	encoding := func() string {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w.Header().Get("Content-Encoding")
+	}
+	if enc := encoding(); enc != "gzip" {
+		t.Errorf("Expected gzip encoding by default, got '%s'", enc)
+	}
+	if err := s.Config().Set("httpd", "enable_compression", "false"); err != nil {
+		t.Fatal(err)
+	}
+	if enc := encoding(); enc != "" {
+		t.Errorf("Expected no encoding after disabling compression, got '%s'", enc)
+	}
+	if err := s.Config().Set("httpd", "enable_compression", "true"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Config().Set("httpd", "compression_level", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if enc := encoding(); enc != "gzip" {
+		t.Errorf("Expected gzip encoding after re-enabling compression, got '%s'", enc)
+	}
+}
+
+type levelRecorder struct {
+	levels []logger.LogLevel
+}
+
+func (l *levelRecorder) Init(_ map[string]string) error { return nil }
+
+func (l *levelRecorder) WriteLog(level logger.LogLevel, _ string) error {
+	l.levels = append(l.levels, level)
+	return nil
+}
+
+func TestLogLevelFilter(t *testing.T) {
+	lw := &levelRecorder{}
+	s := &Service{LogWriter: lw}
+	if err := s.Config().Set("log", "level", "warn"); err != nil {
+		t.Fatal(err)
+	}
+	s.Debug("debug")
+	s.Info("info")
+	s.Warn("warn")
+	s.Error("error")
+	expected := []logger.LogLevel{logger.LogLevelWarn, logger.LogLevelError}
+	if len(lw.levels) != len(expected) {
+		t.Fatalf("Expected %d messages, got %d (%v)", len(expected), len(lw.levels), lw.levels)
+	}
+	for i, level := range expected {
+		if lw.levels[i] != level {
+			t.Errorf("Message %d: expected level %s, got %s", i, level, lw.levels[i])
+		}
+	}
+	lw.levels = nil
+	if err := s.Config().Set("log", "level", "none"); err != nil {
+		t.Fatal(err)
+	}
+	s.Error("error")
+	if len(lw.levels) != 0 {
+		t.Errorf("Expected no messages with log.level=none, got %v", lw.levels)
+	}
+}
+
+func TestCouchLogLevels(t *testing.T) {
+	lw := &levelRecorder{}
+	s := &Service{LogWriter: lw}
+	if err := s.Config().Set("log", "level", "notice"); err != nil {
+		t.Fatal(err)
+	}
+	s.Debug("debug")
+	s.Info("info")
+	if len(lw.levels) != 1 || lw.levels[0] != logger.LogLevelInfo {
+		t.Errorf("Expected notice to log info messages, got %v", lw.levels)
+	}
+
+	// Backends are given the nearest level they understand.
+	s = &Service{LogWriter: &memlogger.Logger{}}
+	if err := s.Config().Set("log", "level", "crit"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Init(); err != nil {
+		t.Errorf("Expected log.level=crit to be accepted, got %s", err)
+	}
+}
+
+func TestInitInvalidConfig(t *testing.T) {
+	conf := memconf.New()
+	_ = conf.SetContext(context.Background(), "httpd", "port", "http")
+	lw := &textLogger{}
+	s := &Service{LogWriter: lw}
+	s.SetConfig(config.New(conf))
+	if _, err := s.Init(); err != nil {
+		t.Fatalf("Expected an existing invalid value not to prevent Init, got %s", err)
+	}
+	warned := false
+	for _, msg := range lw.messages {
+		warned = warned || strings.Contains(msg, "httpd.port")
+	}
+	if !warned {
+		t.Errorf("Expected invalid httpd.port to be logged, got %v", lw.messages)
+	}
+	if err := s.Config().Set("httpd", "port", "https"); err == nil {
+		t.Errorf("Expected a new invalid value to be rejected")
+	}
+}
+
+func TestShutdownBeforeStart(t *testing.T) {
+	s := &Service{LogWriter: &initCounter{}}
+	if err := s.Bind("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %s", err)
+	}
+	result := make(chan error)
+	go func() {
+		result <- s.Start()
+	}()
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Errorf("Start returned an error after Shutdown: %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("Start did not return after an earlier Shutdown")
+	}
+}
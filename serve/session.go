@@ -3,6 +3,7 @@ package serve
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
 
 	"golang.org/x/net/context"
 
@@ -37,6 +38,19 @@ func (s *Service) getAuthSecret(ctx context.Context) (string, error) {
 	return secret, nil
 }
 
+// SessionTimeout returns the session timeout, in seconds, as configured by
+// couch_httpd_auth.timeout.
+func (s *Service) SessionTimeout(ctx context.Context) (int, error) {
+	timeout, err := s.Config().GetContext(ctx, "couch_httpd_auth", "timeout")
+	if errors.StatusCode(err) == kivik.StatusNotFound {
+		return DefaultSessionTimeout, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(timeout)
+}
+
 func setSession() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,102 @@
+package serve
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/flimzy/kivik/errors"
+)
+
+// DefaultSSLPort is the default HTTPS port, used if ssl.port is unset.
+const DefaultSSLPort = 6984
+
+// versionTLS13 is defined here, as crypto/tls only provides it as of Go 1.12.
+const versionTLS13 = 0x0304
+
+var tlsVersions = map[string]uint16{
+	"tlsv1":   tls.VersionTLS10,
+	"tlsv1.1": tls.VersionTLS11,
+	"tlsv1.2": tls.VersionTLS12,
+	"tlsv1.3": versionTLS13,
+}
+
+// parseTLSVersions parses ssl.tls_versions, which may be given either as a
+// single version, or in CouchDB's Erlang list syntax, i.e. "[tlsv1.1, tlsv1.2]".
+// The lowest listed version is returned, to be used as the minimum version.
+func parseTLSVersions(value string) (uint16, error) {
+	value = strings.TrimSpace(value)
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+	var min uint16
+	for _, v := range strings.Split(value, ",") {
+		v = strings.ToLower(strings.Trim(strings.TrimSpace(v), `'"`))
+		if v == "" {
+			continue
+		}
+		version, ok := tlsVersions[v]
+		if !ok {
+			return 0, errors.Statusf(http.StatusBadRequest, "unsupported TLS version '%s'", v)
+		}
+		if min == 0 || version < min {
+			min = version
+		}
+	}
+	return min, nil
+}
+
+// sslEnabled returns true if the [ssl] section enables HTTPS.
+func (s *Service) sslEnabled() bool {
+	return s.Config().GetBool("ssl", "enable")
+}
+
+// tlsConfig builds the TLS configuration from the [ssl] configuration section.
+func (s *Service) tlsConfig() (*tls.Config, error) {
+	conf := s.Config()
+	certFile := conf.GetString("ssl", "cert_file")
+	keyFile := conf.GetString("ssl", "key_file")
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("ssl.cert_file and ssl.key_file must be set to enable SSL")
+	}
+	if conf.GetString("ssl", "password") != "" {
+		return nil, errors.New("encrypted private keys (ssl.password) are not supported")
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load SSL certificate")
+	}
+	tlsConf := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if versions := conf.GetString("ssl", "tls_versions"); versions != "" {
+		min, err := parseTLSVersions(versions)
+		if err != nil {
+			return nil, err
+		}
+		if min != 0 {
+			tlsConf.MinVersion = min
+		}
+	}
+	if conf.GetBool("ssl", "verify_ssl_certificates") {
+		caFile := conf.GetString("ssl", "cacert_file")
+		if caFile == "" {
+			return nil, errors.New("ssl.cacert_file must be set to verify client certificates")
+		}
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read CA certificates")
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("no certificates found in %s", caFile)
+		}
+		tlsConf.ClientCAs = pool
+		tlsConf.ClientAuth = tls.VerifyClientCertIfGiven
+		if conf.GetBool("ssl", "fail_if_no_peer_cert") {
+			tlsConf.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+	return tlsConf, nil
+}
@@ -0,0 +1,41 @@
+package serve
+
+import (
+	"crypto/tls"
+	"testing"
+)
+
+type tlsVersionTest struct {
+	Name     string
+	Input    string
+	Expected uint16
+	Err      string
+}
+
+func TestParseTLSVersions(t *testing.T) {
+	tests := []tlsVersionTest{
+		{Name: "Single", Input: "tlsv1.2", Expected: tls.VersionTLS12},
+		{Name: "ErlangList", Input: "[tlsv1.1, tlsv1.2]", Expected: tls.VersionTLS11},
+		{Name: "Unordered", Input: "[tlsv1.2,tlsv1]", Expected: tls.VersionTLS10},
+		{Name: "TLS13", Input: "[tlsv1.3]", Expected: versionTLS13},
+		{Name: "Empty", Input: "[]", Expected: 0},
+		{Name: "Unknown", Input: "[sslv3]", Err: "400 unsupported TLS version 'sslv3'"},
+	}
+	for _, test := range tests {
+		func(test tlsVersionTest) {
+			t.Run(test.Name, func(t *testing.T) {
+				result, err := parseTLSVersions(test.Input)
+				var errMsg string
+				if err != nil {
+					errMsg = err.Error()
+				}
+				if errMsg != test.Err {
+					t.Errorf("Unexpected error.\nExpected: %s\n  Actual: %s\n", test.Err, errMsg)
+				}
+				if result != test.Expected {
+					t.Errorf("Unexpected result. Expected %x, Actual %x", test.Expected, result)
+				}
+			})
+		}(test)
+	}
+}
@@ -0,0 +1,70 @@
+package serve
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/flimzy/kivik/authdb"
+	"github.com/flimzy/kivik/authdb/usersdb"
+)
+
+// defaultUsersDB is the name of the authentication database, if
+// couch_httpd_auth.authentication_db is not set.
+const defaultUsersDB = "_users"
+
+// usersDBName returns the name of the authentication database.
+func (s *Service) usersDBName() string {
+	if name := s.Config().GetString("couch_httpd_auth", "authentication_db"); name != "" {
+		return name
+	}
+	return defaultUsersDB
+}
+
+// isUserDoc returns true if the request refers to a document, other than a
+// design document, in the authentication database. All such documents are
+// managed by usersdb, which enforces CouchDB's _users validation rules (so IDs
+// without the org.couchdb.user: prefix are rejected), only permits admins and
+// the owner to read a user document, and hashes passwords.
+func isUserDoc(r *http.Request) bool {
+	return getParams(r)["db"] == GetService(r).usersDBName() && !strings.HasPrefix(docID(r), "_design/")
+}
+
+// userManager returns a usersdb.Manager for the authentication database.
+func userManager(r *http.Request) (*usersdb.Manager, error) {
+	s := GetService(r)
+	db, err := getClient(r).DBContext(r.Context(), s.usersDBName())
+	if err != nil {
+		return nil, err
+	}
+	m := usersdb.NewManager(db)
+	m.Policy = authdb.ConfigPolicy(s.Config())
+	return m, nil
+}
+
+func getUserDoc(w http.ResponseWriter, r *http.Request) error {
+	m, err := userManager(r)
+	if err != nil {
+		return err
+	}
+	doc, err := m.GetUser(r.Context(), currentUser(r), docID(r), queryOptions(r))
+	if err != nil {
+		return err
+	}
+	return serveJSON(w, doc)
+}
+
+func putUserDoc(r *http.Request, doc map[string]interface{}) (rev string, err error) {
+	m, err := userManager(r)
+	if err != nil {
+		return "", err
+	}
+	return m.PutUser(r.Context(), currentUser(r), docID(r), doc)
+}
+
+func deleteUserDoc(r *http.Request, rev string) (newRev string, err error) {
+	m, err := userManager(r)
+	if err != nil {
+		return "", err
+	}
+	return m.DeleteUser(r.Context(), currentUser(r), docID(r), rev)
+}
@@ -0,0 +1,99 @@
+package serve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/flimzy/kivik"
+	"github.com/flimzy/kivik/auth"
+	"github.com/flimzy/kivik/errors"
+)
+
+func TestUserDocRoutes(t *testing.T) {
+	// The memory driver doesn't permit the _users name
+	const usersDB = "users"
+	client, err := kivik.New("memory", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = client.CreateDB(usersDB); err != nil {
+		t.Fatal(err)
+	}
+	db, err := client.DB(usersDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = db.Put("org.couchdb.user:alice", map[string]interface{}{
+		"name":  "alice",
+		"type":  "user",
+		"roles": []string{},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = db.Put("secret", map[string]interface{}{"value": "hidden"}); err != nil {
+		t.Fatal(err)
+	}
+	s := &Service{
+		Client:       client,
+		LogWriter:    &initCounter{},
+		AuthHandlers: []auth.Handler{headerAuth{}},
+	}
+	if err = s.Config().Set("couch_httpd_auth", "authentication_db", usersDB); err != nil {
+		t.Fatal(err)
+	}
+	handler, err := s.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const (
+		newUser   = `{"name":"carol","type":"user","roles":[],"password":"abc123"}`
+		nonUser   = `{"name":"carol","type":"user","roles":[]}`
+		designDoc = `{"validate_doc_update":"function(){}"}`
+	)
+	tests := []docRouteTest{
+		{Name: "OwnerRead", Method: "GET", Path: "/users/org.couchdb.user:alice", User: "alice", Status: http.StatusOK},
+		{Name: "AdminRead", Method: "GET", Path: "/users/org.couchdb.user:alice", User: "root", Roles: "_admin", Status: http.StatusOK},
+		{Name: "OtherUserRead", Method: "GET", Path: "/users/org.couchdb.user:alice", User: "eve", Status: http.StatusForbidden},
+		{Name: "AnonRead", Method: "GET", Path: "/users/org.couchdb.user:alice", Status: http.StatusUnauthorized},
+		{Name: "NonUserDocRead", Method: "GET", Path: "/users/secret", User: "eve", Status: http.StatusForbidden},
+		{Name: "NonUserDocAnonRead", Method: "GET", Path: "/users/secret", Status: http.StatusUnauthorized},
+		{Name: "NonUserDocAdminRead", Method: "GET", Path: "/users/secret", User: "root", Roles: "_admin", Status: http.StatusOK},
+		{Name: "NonUserDocWrite", Method: "PUT", Path: "/users/carol", User: "eve", Body: nonUser, Status: http.StatusForbidden},
+		{Name: "NonUserDocAnonWrite", Method: "PUT", Path: "/users/carol", Body: nonUser, Status: http.StatusForbidden},
+		{Name: "NonUserDocAdminWrite", Method: "PUT", Path: "/users/carol", User: "root", Roles: "_admin", Body: nonUser, Status: http.StatusForbidden},
+		{Name: "ArbitraryDocAdminWrite", Method: "PUT", Path: "/users/other", User: "root", Roles: "_admin", Body: `{"value":"x"}`, Status: http.StatusForbidden},
+		{Name: "SignUp", Method: "PUT", Path: "/users/org.couchdb.user:carol", Body: newUser, Status: http.StatusCreated},
+		{Name: "DesignDocWrite", Method: "PUT", Path: "/users/_design/auth", User: "eve", Body: designDoc, Status: http.StatusForbidden},
+		{Name: "DesignDocAdminWrite", Method: "PUT", Path: "/users/_design/auth", User: "root", Roles: "_admin", Body: designDoc, Status: http.StatusCreated},
+	}
+	for _, test := range tests {
+		func(test docRouteTest) {
+			t.Run(test.Name, func(t *testing.T) {
+				req := httptest.NewRequest(test.Method, test.Path, strings.NewReader(test.Body))
+				if test.User != "" {
+					req.Header.Set("X-User", test.User)
+					req.Header.Set("X-Roles", test.Roles)
+				}
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, req)
+				if w.Code != test.Status {
+					t.Errorf("Unexpected status. Expected %d, Actual %d: %s", test.Status, w.Code, w.Body.String())
+				}
+			})
+		}(test)
+	}
+	var doc map[string]interface{}
+	if err := db.Get("org.couchdb.user:carol", &doc, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := doc["password"]; ok {
+		t.Errorf("Plain-text password was stored")
+	}
+	for _, id := range []string{"carol", "other"} {
+		if err := db.Get(id, &doc, nil); errors.StatusCode(err) != kivik.StatusNotFound {
+			t.Errorf("Expected %s not to be stored, got: %v", id, err)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+// Package tasks provides a registry of long-running tasks, such as compaction,
+// view indexing and replication, into which drivers and the server may report
+// progress. A Registry implements driver.ActiveTasker, so it may be embedded
+// in a driver client to serve /_active_tasks.
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/flimzy/kivik/driver"
+)
+
+// Task types, as reported by CouchDB.
+const (
+	TypeDatabaseCompaction = "database_compaction"
+	TypeViewCompaction     = "view_compaction"
+	TypeIndexer            = "indexer"
+	TypeReplication        = "replication"
+)
+
+var now = time.Now
+
+// Registry is a collection of running tasks.
+type Registry struct {
+	// Node is reported as the node of each task, if not set by the task
+	// itself.
+	Node string
+
+	mu    sync.Mutex
+	tasks map[*Task]struct{}
+	seq   int
+}
+
+var _ driver.ActiveTasker = &Registry{}
+
+// NewRegistry returns a new, empty registry.
+func NewRegistry() *Registry {
+	return &Registry{tasks: make(map[*Task]struct{})}
+}
+
+// Task is a running task.
+type Task struct {
+	r   *Registry
+	seq int
+
+	mu   sync.Mutex
+	info driver.ActiveTask
+}
+
+// Start registers a new task, described by info, which is reported until Done
+// is called. StartedOn and UpdatedOn are set to the current time, and PID, if
+// empty, is set to a value unique within the registry.
+func (r *Registry) Start(info driver.ActiveTask) *Task {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.tasks == nil {
+		r.tasks = make(map[*Task]struct{})
+	}
+	r.seq++
+	ts := now().Unix()
+	info.StartedOn = ts
+	info.UpdatedOn = ts
+	if info.PID == "" {
+		info.PID = fmt.Sprintf("<0.%d.0>", r.seq)
+	}
+	if info.Node == "" {
+		info.Node = r.Node
+	}
+	t := &Task{r: r, seq: r.seq, info: info}
+	r.tasks[t] = struct{}{}
+	return t
+}
+
+// Update calls fn to modify the task's description, then sets UpdatedOn to
+// the current time.
+func (t *Task) Update(fn func(info *driver.ActiveTask)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fn(&t.info)
+	t.info.UpdatedOn = now().Unix()
+}
+
+// SetProgress records the number of changes processed, out of total, and
+// sets Progress to the corresponding percentage.
+func (t *Task) SetProgress(done, total int64) {
+	t.Update(func(info *driver.ActiveTask) {
+		info.ChangesDone = done
+		info.TotalChanges = total
+		info.Progress = 0
+		if total > 0 {
+			info.Progress = int(done * 100 / total)
+		}
+	})
+}
+
+// Info returns a copy of the task's current description.
+func (t *Task) Info() driver.ActiveTask {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.info
+}
+
+// Done removes the task from the registry. It is safe to call Done more than
+// once.
+func (t *Task) Done() {
+	t.r.mu.Lock()
+	defer t.r.mu.Unlock()
+	delete(t.r.tasks, t)
+}
+
+// Tasks returns the running tasks, in the order they were started.
+func (r *Registry) Tasks() []driver.ActiveTask {
+	r.mu.Lock()
+	running := make([]*Task, 0, len(r.tasks))
+	for t := range r.tasks {
+		running = append(running, t)
+	}
+	r.mu.Unlock()
+	sort.Sort(bySeq(running))
+	tasks := make([]driver.ActiveTask, len(running))
+	for i, t := range running {
+		tasks[i] = t.Info()
+	}
+	return tasks
+}
+
+// Running returns true if a task of the given type is running against the
+// named database.
+func (r *Registry) Running(typ, dbName string) bool {
+	for _, task := range r.Tasks() {
+		if task.Type == typ && task.Database == dbName {
+			return true
+		}
+	}
+	return false
+}
+
+// ActiveTasksContext satisfies the driver.ActiveTasker interface.
+func (r *Registry) ActiveTasksContext(_ context.Context) ([]driver.ActiveTask, error) {
+	return r.Tasks(), nil
+}
+
+type bySeq []*Task
+
+func (s bySeq) Len() int           { return len(s) }
+func (s bySeq) Less(i, j int) bool { return s[i].seq < s[j].seq }
+func (s bySeq) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
@@ -0,0 +1,43 @@
+package tasks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/flimzy/diff"
+
+	"github.com/flimzy/kivik/driver"
+)
+
+func TestRegistry(t *testing.T) {
+	defer func(n func() time.Time) { now = n }(now)
+	var tick int64
+	now = func() time.Time {
+		tick++
+		return time.Unix(tick, 0)
+	}
+	r := NewRegistry()
+	r.Node = "node1"
+	compact := r.Start(driver.ActiveTask{Type: TypeDatabaseCompaction, Database: "foo"})
+	index := r.Start(driver.ActiveTask{Type: TypeIndexer, Database: "bar", DesignDocument: "_design/baz"})
+	index.SetProgress(25, 200)
+	expected := []driver.ActiveTask{
+		{Node: "node1", PID: "<0.1.0>", Type: TypeDatabaseCompaction, Database: "foo", StartedOn: 1, UpdatedOn: 1},
+		{Node: "node1", PID: "<0.2.0>", Type: TypeIndexer, Database: "bar", DesignDocument: "_design/baz",
+			Progress: 12, ChangesDone: 25, TotalChanges: 200, StartedOn: 2, UpdatedOn: 3},
+	}
+	if d := diff.AsJSON(expected, r.Tasks()); d != "" {
+		t.Error(d)
+	}
+	if !r.Running(TypeDatabaseCompaction, "foo") {
+		t.Error("Expected compaction of foo to be running")
+	}
+	compact.Done()
+	compact.Done()
+	if r.Running(TypeDatabaseCompaction, "foo") {
+		t.Error("Expected compaction of foo to be done")
+	}
+	if n := len(r.Tasks()); n != 1 {
+		t.Errorf("Expected 1 task, got %d", n)
+	}
+}